@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+func accessTokensIssueHandler(
+	atsUC usecases.AccessTokens,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		// access tokens are only issued for other credentials, otherwise a
+		// token would keep renewing itself
+		if authHeader, err := buildAuth(r.Header.Get("authorization")); err == nil &&
+			authHeader.Type == models.AuthenticationTypes.OAuth2 &&
+			atsUC.IsAccessToken(authHeader.Content) {
+			WriteJSON(w, http.StatusForbidden, ErrorResponse{
+				Error: "access tokens can't be issued for access tokens",
+			})
+			return
+		}
+		saID, _ := getServiceAccountID(r.Context())
+		issued, err := atsUC.WithContext(r.Context()).Issue(saID)
+		if err != nil {
+			l.WithError(err).Error("accessTokensIssueHandler atsUC.Issue failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusCreated, issued)
+	}
+}
+
+func jwksHandler(
+	atsUC usecases.AccessTokens,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		WriteJSON(w, http.StatusOK, atsUC.KeySet())
+	}
+}
@@ -0,0 +1,86 @@
+// +build integration
+
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/jwt"
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func TestAccessTokensIssueHandler(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service1::RL::Do::*",
+	)
+	app := helpers.GetApp(t)
+	app.SetAccessTokens(helpers.GetAccessTokensUseCase(t, false))
+	router := app.GetRouter()
+	req, _ := http.NewRequest("POST", "/access_tokens", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", sa.KeyID, sa.KeySecret))
+	rec := helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201. Got %d", rec.Code)
+	}
+	issued := &models.IssuedAccessToken{}
+	if err := json.Unmarshal(rec.Body.Bytes(), issued); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req, _ = http.NewRequest(
+		"GET", "/permissions/has?permission=Service1::RL::Do::x", nil,
+	)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", issued.AccessToken))
+	rec = helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200. Got %d", rec.Code)
+	}
+	req, _ = http.NewRequest("POST", "/access_tokens", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", issued.AccessToken))
+	rec = helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403. Got %d", rec.Code)
+	}
+}
+
+func TestAccessTokensInvalidToken(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	issued, err := helpers.GetAccessTokensUseCase(t, false).Issue(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	app := helpers.GetApp(t)
+	app.SetAccessTokens(helpers.GetAccessTokensUseCase(t, false))
+	req, _ := http.NewRequest("GET", "/sso/auth", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", issued.AccessToken))
+	rec := helpers.DoRequest(t, req, app.GetRouter())
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401. Got %d", rec.Code)
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	app := helpers.GetApp(t)
+	ats := helpers.GetAccessTokensUseCase(t, false)
+	app.SetAccessTokens(ats)
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	rec := helpers.DoRequest(t, req, app.GetRouter())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200. Got %d", rec.Code)
+	}
+	keys := jwt.KeySet{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &keys); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(keys.Keys) != 1 || keys.Keys[0].KeyID != "test" {
+		t.Errorf("Unexpected JWKS %#v", keys)
+	}
+}
@@ -1,15 +1,23 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/topfreegames/Will.IAM/constants"
+	"github.com/topfreegames/Will.IAM/decisionlog"
+	"github.com/topfreegames/Will.IAM/jwt"
 	"github.com/topfreegames/Will.IAM/models"
 	"github.com/topfreegames/Will.IAM/oauth2"
 	"github.com/topfreegames/Will.IAM/repositories"
@@ -17,6 +25,7 @@ import (
 	"github.com/topfreegames/extensions/jaeger"
 	"github.com/topfreegames/extensions/middleware"
 	"github.com/topfreegames/extensions/router"
+	"google.golang.org/grpc"
 )
 
 // App struct
@@ -26,9 +35,21 @@ type App struct {
 	logger          logrus.FieldLogger
 	router          *mux.Router
 	server          *http.Server
+	grpcServer      *grpc.Server
 	metricsReporter middleware.MetricsReporter
 	storage         *repositories.Storage
-	oauth2Provider  oauth2.Provider
+	oauth2Providers *oauth2.Registry
+	decisionLogger  *decisionlog.Logger
+	// permissionsCache is nil unless permissionsCache.enabled is set
+	permissionsCache *usecases.PermissionsCache
+	// accessTokens is nil unless accessTokens.enabled is set
+	accessTokens     usecases.AccessTokens
+	roleBindingRules []models.RoleBindingRule
+	// trustedProxies are allowed to tell client addresses in X-Forwarded-For
+	trustedProxies []*net.IPNet
+	// trustedCallers are allowed to tell what permissions conditions are
+	// evaluated against
+	trustedCallers []*net.IPNet
 }
 
 // NewApp creates a new app
@@ -64,20 +85,37 @@ func (a *App) configureApp() error {
 	if err := a.configurePG(); err != nil {
 		return err
 	}
+	if err := a.configureDecisionLog(); err != nil {
+		return err
+	}
+	a.configurePermissionsCache()
+	if err := a.configureAccessTokens(); err != nil {
+		return err
+	}
+	if err := a.configureRoleBindingRules(); err != nil {
+		return err
+	}
+	if err := a.configureTrustedAddresses(); err != nil {
+		return err
+	}
 
-	a.configureGoogleOAuth2Provider()
+	if err := a.configureOAuth2Providers(); err != nil {
+		return err
+	}
 	a.configureServer()
+	a.configureGRPCServer()
 
 	return nil
 }
 
 func (a *App) configureServer() {
+	a.config.SetDefault("http.shutdownTimeout", "30s")
 	a.router = a.GetRouter()
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"HEAD", "GET", "POST", "PUT", "PATCH", "DELETE"},
 		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"x-access-token", "x-email"},
+		ExposedHeaders:   []string{"x-access-token", "x-email", requestIDHeader},
 		AllowCredentials: false,
 	})
 	handler := c.Handler(a.router)
@@ -87,6 +125,16 @@ func (a *App) configureServer() {
 	}
 }
 
+func (a *App) configureGRPCServer() {
+	repo := repositories.New(a.storage)
+	sasUC := usecases.NewServiceAccountsWithOptions(
+		repo, a.oauth2Providers, a.serviceAccountsOptions(),
+	)
+	a.grpcServer = NewGRPCServer(
+		sasUC, a.logger, a.trustedProxies, a.trustedCallers,
+	)
+}
+
 func (a *App) configurePG() error {
 	if a.storage != nil && a.storage.PG != nil {
 		return nil
@@ -107,20 +155,221 @@ func (a *App) configureJaeger() error {
 	return err
 }
 
-func (a *App) configureGoogleOAuth2Provider() {
+// configureOAuth2Providers registers every oauth2.providers entry, or the
+// single provider configured by oauth2.provider when there is none
+func (a *App) configureOAuth2Providers() error {
+	repo := repositories.New(a.storage)
+	registry := oauth2.NewRegistry(repo)
+	names := []string{}
+	for name := range a.config.GetStringMap("oauth2.providers") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		a.config.SetDefault("oauth2.provider", "google")
+		kind := a.config.GetString("oauth2.provider")
+		p, err := a.buildOAuth2Provider(kind, kind, fmt.Sprintf("oauth2.%s", kind))
+		if err != nil {
+			return err
+		}
+		registry.Register(kind, p)
+		a.oauth2Providers = registry
+		return nil
+	}
+	for _, name := range names {
+		prefix := fmt.Sprintf("oauth2.providers.%s", name)
+		kind := a.config.GetString(fmt.Sprintf("%s.type", prefix))
+		p, err := a.buildOAuth2Provider(name, kind, prefix)
+		if err != nil {
+			return err
+		}
+		registry.Register(name, p)
+	}
+	defaultName := a.config.GetString("oauth2.defaultProvider")
+	if defaultName == "" && len(names) > 1 {
+		return fmt.Errorf("oauth2.defaultProvider is required with many providers")
+	}
+	if defaultName != "" {
+		if err := registry.SetDefault(defaultName); err != nil {
+			return err
+		}
+	}
+	a.oauth2Providers = registry
+	return nil
+}
+
+func (a *App) buildOAuth2Provider(
+	name, kind, prefix string,
+) (oauth2.Provider, error) {
 	repo := repositories.New(a.storage)
-	google := oauth2.NewGoogle(oauth2.GoogleConfig{
-		ClientID:      a.config.GetString("oauth2.google.clientId"),
-		ClientSecret:  a.config.GetString("oauth2.google.clientSecret"),
-		RedirectURL:   a.config.GetString("oauth2.google.redirectUrl"),
-		HostedDomains: a.config.GetStringSlice("oauth2.google.hostedDomains"),
-	}, repo)
-	a.oauth2Provider = google
+	key := func(k string) string {
+		return fmt.Sprintf("%s.%s", prefix, k)
+	}
+	switch kind {
+	case "google":
+		return oauth2.NewGoogle(oauth2.GoogleConfig{
+			Name:          name,
+			ClientID:      a.config.GetString(key("clientId")),
+			ClientSecret:  a.config.GetString(key("clientSecret")),
+			RedirectURL:   a.config.GetString(key("redirectUrl")),
+			HostedDomains: a.config.GetStringSlice(key("hostedDomains")),
+		}, repo), nil
+	case "oidc":
+		return oauth2.NewOIDC(oauth2.OIDCConfig{
+			Name:          name,
+			Issuer:        a.config.GetString(key("issuer")),
+			ClientID:      a.config.GetString(key("clientId")),
+			ClientSecret:  a.config.GetString(key("clientSecret")),
+			RedirectURL:   a.config.GetString(key("redirectUrl")),
+			Scopes:        a.config.GetStringSlice(key("scopes")),
+			HostedDomains: a.config.GetStringSlice(key("hostedDomains")),
+			EmailClaim:    a.config.GetString(key("claims.email")),
+			PictureClaim:  a.config.GetString(key("claims.picture")),
+			GroupsClaim:   a.config.GetString(key("claims.groups")),
+			TokenEndpointAuthMethod: a.config.GetString(
+				key("tokenEndpointAuthMethod"),
+			),
+		}, repo), nil
+	}
+	return nil, fmt.Errorf("unknown oauth2 provider type %s for %s", kind, name)
+}
+
+func (a *App) configureRoleBindingRules() error {
+	rules := []models.RoleBindingRule{}
+	if err := a.config.UnmarshalKey("roleBindingRules", &rules); err != nil {
+		return fmt.Errorf("roleBindingRules: %s", err.Error())
+	}
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return fmt.Errorf("roleBindingRules: %s", err.Error())
+		}
+	}
+	a.roleBindingRules = rules
+	return nil
+}
+
+func (a *App) configureTrustedAddresses() error {
+	proxies, err := ParseIPNets(a.config.GetStringSlice("http.trustedProxies"))
+	if err != nil {
+		return fmt.Errorf("http.trustedProxies: %s", err.Error())
+	}
+	a.trustedProxies = proxies
+	callers, err := ParseIPNets(a.config.GetStringSlice("conditions.trustedCallers"))
+	if err != nil {
+		return fmt.Errorf("conditions.trustedCallers: %s", err.Error())
+	}
+	a.trustedCallers = callers
+	return nil
+}
+
+func (a *App) configureDecisionLog() error {
+	dl, err := decisionlog.New(a.config, a.logger, a.storage)
+	if err != nil {
+		return err
+	}
+	a.decisionLogger = dl
+	return nil
+}
+
+func (a *App) configurePermissionsCache() {
+	a.config.SetDefault("permissionsCache.enabled", false)
+	a.config.SetDefault("permissionsCache.ttl", "1m")
+	if !a.config.GetBool("permissionsCache.enabled") {
+		return
+	}
+	a.permissionsCache = usecases.NewPermissionsCache(
+		a.config.GetDuration("permissionsCache.ttl"),
+	)
+}
+
+func (a *App) configureAccessTokens() error {
+	a.config.SetDefault("accessTokens.enabled", false)
+	a.config.SetDefault("accessTokens.issuer", "Will.IAM")
+	a.config.SetDefault("accessTokens.ttl", "15m")
+	a.config.SetDefault("accessTokens.includePermissions", false)
+	if !a.config.GetBool("accessTokens.enabled") {
+		return nil
+	}
+	config := usecases.AccessTokensConfig{
+		Issuer:             a.config.GetString("accessTokens.issuer"),
+		TTL:                a.config.GetDuration("accessTokens.ttl"),
+		SigningKeyID:       a.config.GetString("accessTokens.signingKeyId"),
+		IncludePermissions: a.config.GetBool("accessTokens.includePermissions"),
+	}
+	for kid := range a.config.GetStringMap("accessTokens.keys") {
+		prefix := fmt.Sprintf("accessTokens.keys.%s", kid)
+		pemBytes := []byte(a.config.GetString(prefix + ".privateKey"))
+		if file := a.config.GetString(prefix + ".privateKeyFile"); file != "" {
+			var err error
+			if pemBytes, err = ioutil.ReadFile(file); err != nil {
+				return err
+			}
+		}
+		key, err := jwt.ParsePrivateKeyPEM(pemBytes)
+		if err != nil {
+			return fmt.Errorf("accessTokens.keys.%s: %s", kid, err.Error())
+		}
+		config.Keys = append(config.Keys, usecases.SigningKey{
+			KeyID: kid, PrivateKey: key,
+		})
+	}
+	if config.SigningKeyID == "" && len(config.Keys) == 1 {
+		config.SigningKeyID = config.Keys[0].KeyID
+	}
+	ats, err := usecases.NewAccessTokens(repositories.New(a.storage), config)
+	if err != nil {
+		return err
+	}
+	a.accessTokens = ats
+	return nil
+}
+
+func (a *App) serviceAccountsOptions() usecases.ServiceAccountsOptions {
+	return usecases.ServiceAccountsOptions{
+		PermissionsCache: a.permissionsCache,
+		AccessTokens:     a.accessTokens,
+		RoleBindingRules: a.roleBindingRules,
+	}
 }
 
-// SetOAuth2Provider sets a provider in App
+// SetRoleBindingRules makes App bind service accounts to roles by rules as
+// they log in
+func (a *App) SetRoleBindingRules(rules []models.RoleBindingRule) {
+	a.roleBindingRules = rules
+}
+
+// SetTrustedProxies makes App read client addresses in X-Forwarded-For of
+// requests sent by trustedProxies
+func (a *App) SetTrustedProxies(trustedProxies []*net.IPNet) {
+	a.trustedProxies = trustedProxies
+}
+
+// SetTrustedCallers makes App evaluate permissions conditions against the
+// address and attributes trustedCallers tell
+func (a *App) SetTrustedCallers(trustedCallers []*net.IPNet) {
+	a.trustedCallers = trustedCallers
+}
+
+// SetAccessTokens makes App issue and accept access tokens signed by ats
+func (a *App) SetAccessTokens(ats usecases.AccessTokens) {
+	a.accessTokens = ats
+}
+
+// SetDecisionLogger sets a decision logger in App
+func (a *App) SetDecisionLogger(dl *decisionlog.Logger) {
+	a.decisionLogger = dl
+}
+
+// SetOAuth2Provider sets provider as App's only login provider
 func (a *App) SetOAuth2Provider(provider oauth2.Provider) {
-	a.oauth2Provider = provider
+	registry := oauth2.NewRegistry(repositories.New(a.storage))
+	registry.Register("default", provider)
+	a.oauth2Providers = registry
+}
+
+// SetOAuth2Providers sets App's login providers
+func (a *App) SetOAuth2Providers(providers *oauth2.Registry) {
+	a.oauth2Providers = providers
 }
 
 // GetRouter returns App's *mux.Router reference
@@ -129,6 +378,9 @@ func (a *App) GetRouter() *mux.Router {
 	r.Use(middleware.Version(constants.AppInfo.Version))
 	r.Use(middleware.Logging(a.logger))
 	r.Use(middleware.Metrics(a.metricsReporter))
+	r.Use(requestIDMiddleware)
+	r.Use(clientIPMiddlewareBuilder(a.trustedProxies))
+	r.Use(conditionsMiddlewareBuilder(a.trustedCallers))
 
 	repo := repositories.New(a.storage)
 
@@ -137,14 +389,16 @@ func (a *App) GetRouter() *mux.Router {
 	)).Methods("GET").Name("healthcheck")
 
 	r.HandleFunc("/sso/auth/do",
-		authenticationBuildURLHandler(a.oauth2Provider),
+		authenticationBuildURLHandler(a.oauth2Providers),
 	).Methods("GET").Name("ssoAuthDo")
 
 	psUC := usecases.NewPermissions(repo)
-	sasUC := usecases.NewServiceAccounts(repo, a.oauth2Provider)
+	sasUC := usecases.NewServiceAccountsWithOptions(
+		repo, a.oauth2Providers, a.serviceAccountsOptions(),
+	)
 
 	r.HandleFunc("/sso/auth/done",
-		authenticationExchangeCodeHandler(a.oauth2Provider, sasUC),
+		authenticationExchangeCodeHandler(a.oauth2Providers, sasUC),
 	).Methods("GET").Name("ssoAuthDone")
 
 	r.HandleFunc("/sso/auth/valid",
@@ -158,6 +412,24 @@ func (a *App) GetRouter() *mux.Router {
 		authMiddle(http.HandlerFunc(authenticationHandler)),
 	).Methods("GET").Name("ssoAuth")
 
+	r.HandleFunc("/sso/auth/logout",
+		authenticationLogoutHandler(sasUC),
+	).Methods("POST").Name("authenticationLogoutHandler")
+
+	if a.accessTokens != nil {
+		r.Handle("/access_tokens",
+			authMiddle(http.HandlerFunc(accessTokensIssueHandler(a.accessTokens))),
+		).Methods("POST").Name("accessTokensIssueHandler")
+
+		r.HandleFunc("/.well-known/jwks.json",
+			jwksHandler(a.accessTokens),
+		).Methods("GET").Name("jwksHandler")
+
+		r.HandleFunc("/oauth2/token",
+			oauth2TokenHandler(sasUC, a.accessTokens),
+		).Methods("POST").Name("oauth2TokenHandler")
+	}
+
 	r.PathPrefix("/sso").Handler(http.StripPrefix("/sso", http.FileServer(
 		http.Dir("./assets/sso/")),
 	)).Methods("GET").Name("sso")
@@ -250,6 +522,106 @@ func (a *App) GetRouter() *mux.Router {
 	).
 		Methods("PUT").Name("serviceAccountsUpdateHandler")
 
+	r.Handle(
+		"/service_accounts/{id}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsDeleteHandler(sasUC),
+		))),
+	).
+		Methods("DELETE").Name("serviceAccountsDeleteHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/disable",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsDisableHandler(sasUC),
+		))),
+	).
+		Methods("POST").Name("serviceAccountsDisableHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/reactivate",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsReactivateHandler(sasUC),
+		))),
+	).
+		Methods("POST").Name("serviceAccountsReactivateHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/offboard",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsOffboardHandler(sasUC),
+		))),
+	).
+		Methods("POST").Name("serviceAccountsOffboardHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/keys",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsListKeysHandler(sasUC),
+		))),
+	).
+		Methods("GET").Name("serviceAccountsListKeysHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/keys",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsCreateKeyHandler(sasUC),
+		))),
+	).
+		Methods("POST").Name("serviceAccountsCreateKeyHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/keys/{keyId}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsDeleteKeyHandler(sasUC),
+		))),
+	).
+		Methods("DELETE").Name("serviceAccountsDeleteKeyHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/sessions",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsListSessionsHandler(sasUC),
+		))),
+	).
+		Methods("GET").Name("serviceAccountsListSessionsHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/sessions",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsRevokeSessionsHandler(sasUC),
+		))),
+	).
+		Methods("DELETE").Name("serviceAccountsRevokeSessionsHandler")
+
+	r.Handle(
+		"/service_accounts/{id}/sessions/{sessionId}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditServiceAccount", "{id}",
+		), http.HandlerFunc(
+			serviceAccountsRevokeSessionsHandler(sasUC),
+		))),
+	).
+		Methods("DELETE").Name("serviceAccountsRevokeSessionHandler")
+
 	// roles
 
 	rsUC := usecases.NewRoles(repo)
@@ -304,6 +676,82 @@ func (a *App) GetRouter() *mux.Router {
 	).
 		Methods("POST").Name("rolesCreateHandler")
 
+	// groups
+
+	gsUC := usecases.NewGroups(repo)
+
+	r.Handle(
+		"/groups",
+		authMiddle(http.HandlerFunc(groupsListHandler(gsUC))),
+	).
+		Methods("GET").Name("groupsListHandler")
+
+	r.Handle(
+		"/groups/search",
+		authMiddle(http.HandlerFunc(groupsSearchHandler(gsUC))),
+	).
+		Methods("GET").Name("groupsSearchHandler")
+
+	r.Handle(
+		"/groups",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"CreateGroups", "*",
+		), http.HandlerFunc(
+			groupsCreateHandler(gsUC),
+		))),
+	).
+		Methods("POST").Name("groupsCreateHandler")
+
+	r.Handle(
+		"/groups/{id}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditGroup", "{id}",
+		), http.HandlerFunc(
+			groupsGetHandler(gsUC),
+		))),
+	).
+		Methods("GET").Name("groupsGetHandler")
+
+	r.Handle(
+		"/groups/{id}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditGroup", "{id}",
+		), http.HandlerFunc(
+			groupsUpdateHandler(sasUC, gsUC),
+		))),
+	).
+		Methods("PUT").Name("groupsUpdateHandler")
+
+	r.Handle(
+		"/groups/{id}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditGroup", "{id}",
+		), http.HandlerFunc(
+			groupsDeleteHandler(gsUC),
+		))),
+	).
+		Methods("DELETE").Name("groupsDeleteHandler")
+
+	r.Handle(
+		"/groups/{id}/members/{serviceAccountId}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditGroup", "{id}",
+		), http.HandlerFunc(
+			groupsAddMemberHandler(sasUC, gsUC),
+		))),
+	).
+		Methods("PUT").Name("groupsAddMemberHandler")
+
+	r.Handle(
+		"/groups/{id}/members/{serviceAccountId}",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"EditGroup", "{id}",
+		), http.HandlerFunc(
+			groupsRemoveMemberHandler(gsUC),
+		))),
+	).
+		Methods("DELETE").Name("groupsRemoveMemberHandler")
+
 	// permissions
 
 	r.Handle(
@@ -333,15 +781,23 @@ func (a *App) GetRouter() *mux.Router {
 	r.Handle(
 		"/permissions/has",
 		authMiddle(http.HandlerFunc(
-			permissionsHasHandler(sasUC),
+			permissionsHasHandler(sasUC, a.decisionLogger),
 		)),
 	).
 		Methods("GET").Name("permissionsHasHandler")
 
+	r.Handle(
+		"/permissions/explain",
+		authMiddle(http.HandlerFunc(
+			permissionsExplainHandler(sasUC),
+		)),
+	).
+		Methods("GET").Name("permissionsExplainHandler")
+
 	r.Handle(
 		"/permissions/hasMany",
 		authMiddle(http.HandlerFunc(
-			permissionsHasManyHandler(sasUC),
+			permissionsHasManyHandler(sasUC, a.decisionLogger),
 		)),
 	).
 		Methods("POST").Name("permissionsHasManyHandler")
@@ -382,20 +838,165 @@ func (a *App) GetRouter() *mux.Router {
 	).
 		Methods("GET").Name("permissionsHasHandler")
 
+	// audit
+
+	aesUC := usecases.NewAuditEvents(repo)
+
+	r.Handle(
+		"/audit",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"ListAuditEvents", "*",
+		), http.HandlerFunc(
+			auditEventsListHandler(aesUC),
+		))),
+	).
+		Methods("GET").Name("auditEventsListHandler")
+
+	// policies
+
+	policiesUC := usecases.NewPolicies(repo)
+
+	r.Handle(
+		"/policies/apply",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"ApplyPolicy", "*",
+		), http.HandlerFunc(
+			policiesApplyHandler(sasUC, policiesUC),
+		))),
+	).
+		Methods("POST").Name("policiesApplyHandler")
+
+	r.Handle(
+		"/policies/export",
+		authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+			"ExportPolicy", "*",
+		), http.HandlerFunc(
+			policiesExportHandler(policiesUC),
+		))),
+	).
+		Methods("GET").Name("policiesExportHandler")
+
+	// scim
+
+	scimUC := usecases.NewSCIM(repo, sasUC)
+	scim := func(
+		path, method, name string, h func(http.ResponseWriter, *http.Request),
+	) {
+		r.Handle(
+			scimBasePath+path,
+			authMiddle(hasPermissionMiddle(models.BuildWillIAMPermissionLender(
+				"Provision", "*",
+			), http.HandlerFunc(h))),
+		).
+			Methods(method).Name(name)
+	}
+
+	scim("/ServiceProviderConfig", "GET", "scimServiceProviderConfigHandler",
+		scimServiceProviderConfigHandler)
+	scim("/ResourceTypes", "GET", "scimResourceTypesHandler",
+		scimResourceTypesHandler)
+	scim("/Users", "GET", "scimUsersListHandler", scimUsersListHandler(scimUC))
+	scim("/Users", "POST", "scimUsersCreateHandler", scimUsersCreateHandler(scimUC))
+	scim("/Users/{id}", "GET", "scimUsersGetHandler", scimUsersGetHandler(scimUC))
+	scim("/Users/{id}", "PUT", "scimUsersReplaceHandler",
+		scimUsersReplaceHandler(scimUC))
+	scim("/Users/{id}", "PATCH", "scimUsersPatchHandler",
+		scimUsersPatchHandler(scimUC))
+	scim("/Users/{id}", "DELETE", "scimUsersDeleteHandler",
+		scimUsersDeleteHandler(scimUC))
+	scim("/Groups", "GET", "scimGroupsListHandler", scimGroupsListHandler(scimUC))
+	scim("/Groups", "POST", "scimGroupsCreateHandler",
+		scimGroupsCreateHandler(scimUC))
+	scim("/Groups/{id}", "GET", "scimGroupsGetHandler", scimGroupsGetHandler(scimUC))
+	scim("/Groups/{id}", "PUT", "scimGroupsReplaceHandler",
+		scimGroupsReplaceHandler(scimUC))
+	scim("/Groups/{id}", "PATCH", "scimGroupsPatchHandler",
+		scimGroupsPatchHandler(scimUC))
+	scim("/Groups/{id}", "DELETE", "scimGroupsDeleteHandler",
+		scimGroupsDeleteHandler(scimUC))
+
 	return r
 }
 
-//ListenAndServe requests
+// ListenAndServeGRPC serves rpc.AuthorizationServer at address
+func (a *App) ListenAndServeGRPC(address string) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		a.logger.WithError(err).Error("Failed to listen gRPC")
+		return
+	}
+
+	defer listener.Close()
+
+	err = a.grpcServer.Serve(listener)
+	if err != nil {
+		a.logger.WithError(err).Error("Closed gRPC listener")
+	}
+}
+
+//ListenAndServe requests until SIGINT or SIGTERM, then Shutdown
 func (a *App) ListenAndServe() {
 	listener, err := net.Listen("tcp", a.address)
 	if err != nil {
 		a.logger.WithError(err).Error("Failed to listen HTTP")
+		return
 	}
 
 	defer listener.Close()
 
-	err = a.server.Serve(listener)
-	if err != nil {
+	if a.permissionsCache != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go a.storage.ListenAuthorizationChanges(
+			ctx, a.permissionsCache.Invalidate, func(err error) {
+				a.logger.WithError(err).
+					Error("Listening to authorization changes failed, cache flushed")
+			},
+		)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	served := make(chan error, 1)
+	go func() {
+		served <- a.server.Serve(listener)
+	}()
+	select {
+	case err = <-served:
 		a.logger.WithError(err).Error("Closed http listener")
+	case <-sigs:
+		a.logger.Info("stopping Will.IAM, waiting for running requests")
+	}
+	a.Shutdown()
+}
+
+// Shutdown stops taking HTTP and gRPC requests and waits up to
+// http.shutdownTimeout for running ones to finish before cancelling them.
+// Then it flushes the decision log
+func (a *App) Shutdown() {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), a.config.GetDuration("http.shutdownTimeout"),
+	)
+	defer cancel()
+	if err := a.server.Shutdown(ctx); err != nil {
+		a.logger.WithError(err).Warn("shutdown timeout reached, closing http connections")
+		a.server.Close()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.logger.Warn("shutdown timeout reached, closing gRPC connections")
+		a.grpcServer.Stop()
+		<-stopped
+	}
+	if err := a.decisionLogger.Close(); err != nil {
+		a.logger.WithError(err).Error("Failed to close decision log")
 	}
+	a.logger.Info("Will.IAM stopped")
 }
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/repositories"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+func buildAuditEventsFilter(
+	r *http.Request,
+) (*repositories.AuditEventsFilter, error) {
+	q := r.URL.Query()
+	f := &repositories.AuditEventsFilter{
+		ActorServiceAccountID: q.Get("actorServiceAccountId"),
+		Action:                q.Get("action"),
+		TargetType:            q.Get("targetType"),
+		TargetID:              q.Get("targetId"),
+		RequestID:             q.Get("requestId"),
+	}
+	for param, nt := range map[string]*pg.NullTime{
+		"from": &f.From, "to": &f.To,
+	} {
+		str := q.Get(param)
+		if str == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return nil, errors.NewInvalidListFilterError(param, str)
+		}
+		*nt = pg.NullTime{Time: t}
+	}
+	return f, nil
+}
+
+func auditEventsListHandler(
+	aesUC usecases.AuditEvents,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		listOptions, err := buildListOptions(r)
+		if err != nil {
+			WriteJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+			return
+		}
+		filter, err := buildAuditEventsFilter(r)
+		if err != nil {
+			WriteJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+			return
+		}
+		aeSl, count, err := aesUC.WithContext(r.Context()).List(filter, listOptions)
+		if err != nil {
+			l.WithError(err).Error("auditEventsListHandler aesUC.List failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, ListResponse{Count: count, Results: aeSl})
+	}
+}
@@ -0,0 +1,111 @@
+// +build integration
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func TestAuditEventsListHandler(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "any", "any@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	app := helpers.GetApp(t)
+
+	service := &models.Service{
+		Name:                    "Some Service",
+		PermissionName:          "SomeService",
+		CreatorServiceAccountID: rootSA.ID,
+		AMURL:                   "http://localhost:3333/am",
+	}
+	bts, err := json.Marshal(service)
+	if err != nil {
+		t.Fatalf("Marshal() returned error = %v", err)
+	}
+	req, _ := http.NewRequest("POST", "/services", bytes.NewBuffer(bts))
+	req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret))
+	resp := helpers.DoRequest(t, req, app.GetRouter())
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("Code = %v, want %v", resp.Code, http.StatusCreated)
+	}
+	requestID := resp.Header().Get("x-request-id")
+	if requestID == "" {
+		t.Fatal("Expected x-request-id header")
+	}
+
+	testCases := []struct {
+		name       string
+		sa         *models.ServiceAccount
+		query      string
+		wantStatus int
+		wantCount  int64
+	}{
+		{
+			name:       "WithoutPermission",
+			sa:         sa,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "InvalidFrom",
+			sa:         rootSA,
+			query:      "?from=yesterday",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "FilterByAction",
+			sa:         rootSA,
+			query:      "?action=CreateService",
+			wantStatus: http.StatusOK,
+			wantCount:  1,
+		},
+		{
+			name:       "FilterByRequestID",
+			sa:         rootSA,
+			query:      "?requestId=" + requestID,
+			wantStatus: http.StatusOK,
+			wantCount:  1,
+		},
+		{
+			name:       "FilterByOtherActor",
+			sa:         rootSA,
+			query:      "?actorServiceAccountId=" + sa.ID,
+			wantStatus: http.StatusOK,
+			wantCount:  0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/audit"+testCase.query, nil)
+			req.Header.Set("Authorization", fmt.Sprintf(
+				"KeyPair %s:%s", testCase.sa.KeyID, testCase.sa.KeySecret,
+			))
+			resp := helpers.DoRequest(t, req, app.GetRouter())
+			if resp.Code != testCase.wantStatus {
+				t.Fatalf("Code = %v, want %v", resp.Code, testCase.wantStatus)
+			}
+			if resp.Code != http.StatusOK {
+				return
+			}
+			var body struct {
+				Count   int64               `json:"count"`
+				Results []models.AuditEvent `json:"results"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Unmarshal() returned error = %v", err)
+			}
+			if body.Count != testCase.wantCount {
+				t.Fatalf("Count = %v, want %v", body.Count, testCase.wantCount)
+			}
+		})
+	}
+}
@@ -61,6 +61,8 @@ func authMiddleware(sasUC usecases.ServiceAccounts) func(http.Handler) http.Hand
 				return
 			}
 
+			saID, _ := getServiceAccountID(ctx)
+			ctx = usecases.WithAuditActor(ctx, saID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -111,9 +113,13 @@ func handleKeyPairAuth(
 	accessKeyPairAuth, err := sasUC.WithContext(r.Context()).AuthenticateKeyPair(keyPair[0], keyPair[1])
 
 	if err != nil {
-		if _, ok := err.(*errors.EntityNotFoundError); ok {
+		switch err.(type) {
+		case *errors.EntityNotFoundError:
 			w.WriteHeader(http.StatusUnauthorized)
 			return nil, err
+		case *errors.InactiveServiceAccountError:
+			w.WriteHeader(http.StatusForbidden)
+			return nil, err
 		}
 
 		w.WriteHeader(http.StatusInternalServerError)
@@ -136,9 +142,13 @@ func handleOAuth2TokenAuth(
 	accessTokenAuth, err := sasUC.WithContext(r.Context()).AuthenticateAccessToken(accessToken)
 
 	if err != nil {
-		if _, ok := err.(*errors.EntityNotFoundError); ok {
+		switch err.(type) {
+		case *errors.EntityNotFoundError, *errors.InvalidAccessTokenError:
 			w.WriteHeader(http.StatusUnauthorized)
 			return nil, err
+		case *errors.InactiveServiceAccountError:
+			w.WriteHeader(http.StatusForbidden)
+			return nil, err
 		}
 
 		w.WriteHeader(http.StatusInternalServerError)
@@ -14,7 +14,7 @@ import (
 )
 
 func authenticationBuildURLHandler(
-	provider oauth2.Provider,
+	providers *oauth2.Registry,
 ) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		qs := r.URL.Query()
@@ -25,13 +25,27 @@ func authenticationBuildURLHandler(
 			)
 			return
 		}
+		provider, ok := providers.Get(qs.Get("provider"))
+		if !ok {
+			Write(
+				w, http.StatusUnprocessableEntity,
+				`{ "error": "querystrings.provider is unknown" }`,
+			)
+			return
+		}
 		authURL := provider.WithContext(r.Context()).BuildAuthURL(qs["referer"][0])
+		if authURL == "" {
+			middleware.GetLogger(r.Context()).
+				Error("authenticationBuildURLHandler provider is unavailable")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
 		http.Redirect(w, r, authURL, http.StatusSeeOther)
 	}
 }
 
 func authenticationExchangeCodeHandler(
-	provider oauth2.Provider, sasUC usecases.ServiceAccounts,
+	providers *oauth2.Registry, sasUC usecases.ServiceAccounts,
 ) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		l := middleware.GetLogger(r.Context())
@@ -44,6 +58,11 @@ func authenticationExchangeCodeHandler(
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
+		provider, ok := providers.Get(qs.Get("provider"))
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 		code := qs["code"][0]
 		authResult, err := provider.WithContext(r.Context()).ExchangeCode(code)
 		if _, ok := err.(*errors.NonAllowedEmailDomainError); ok {
@@ -62,7 +81,15 @@ func authenticationExchangeCodeHandler(
 			Picture:            authResult.Picture,
 			AuthenticationType: models.AuthenticationTypes.OAuth2,
 		}
-		if _, err = sasUC.WithContext(r.Context()).ForEmail(authResult.Email); err != nil {
+		existing, err := sasUC.WithContext(r.Context()).ForEmail(authResult.Email)
+		if err == nil && !existing.IsActive() {
+			l.WithError(errors.NewInactiveServiceAccountError(
+				existing.ID, existing.Status.String(),
+			)).Error("authenticationExchangeCodeHandler inactive service account")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if err != nil {
 			if _, ok := err.(*errors.EntityNotFoundError); ok {
 				if err = sasUC.WithContext(r.Context()).Create(sa); err != nil {
 					l.WithError(err).
@@ -71,11 +98,34 @@ func authenticationExchangeCodeHandler(
 					return
 				}
 			}
+		} else {
+			sa = existing
+		}
+		if sa.ID != "" {
+			if err := sasUC.WithContext(r.Context()).ApplyRoleBindingRules(
+				sa.ID, authResult.Groups,
+			); err != nil {
+				l.WithError(err).
+					Error("authenticationExchangeCodeHandler sasUC.ApplyRoleBindingRules failed")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		if _, err := sasUC.WithContext(r.Context()).CreateSession(
+			authResult.AccessToken, r.UserAgent(), getClientIP(r.Context()),
+		); err != nil {
+			l.WithError(err).
+				Error("authenticationExchangeCodeHandler sasUC.CreateSession failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 		v := url.Values{}
 		v.Add("accessToken", authResult.AccessToken)
 		v.Add("email", authResult.Email)
 		v.Add("referer", qs["state"][0])
+		if authResult.Provider != "" {
+			v.Add("provider", authResult.Provider)
+		}
 		redirectTo := fmt.Sprintf("/sso?%s", v.Encode())
 		http.Redirect(w, r, redirectTo, http.StatusSeeOther)
 	}
@@ -108,6 +158,9 @@ func authenticationValidHandler(
 			l.WithError(err).Error("authenticationValidHandler AuthenticateAccessToken failed")
 			v := url.Values{}
 			v.Add("referer", referer)
+			if provider := qs.Get("provider"); provider != "" {
+				v.Add("provider", provider)
+			}
 			http.Redirect(
 				w, r, fmt.Sprintf("/sso/auth/do?%s", v.Encode()), http.StatusSeeOther,
 			)
@@ -127,6 +180,32 @@ func authenticationValidHandler(
 	}
 }
 
+func authenticationLogoutHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		authHeader, err := buildAuth(r.Header.Get("authorization"))
+		if err != nil || authHeader.Type != models.AuthenticationTypes.OAuth2 {
+			handleInvalidAuth(w, l)
+			return
+		}
+		err = sasUC.WithContext(r.Context()).Logout(authHeader.Content)
+		if _, ok := err.(*errors.InvalidAccessTokenError); ok {
+			WriteJSON(
+				w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()},
+			)
+			return
+		}
+		if err != nil {
+			l.WithError(err).Error("authenticationLogoutHandler sasUC.Logout failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func authenticationHandler(w http.ResponseWriter, r *http.Request) {
 	// Work is in authMiddleware
 	w.WriteHeader(200)
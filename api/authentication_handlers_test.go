@@ -0,0 +1,167 @@
+// +build integration
+
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/api"
+	"github.com/topfreegames/Will.IAM/oauth2"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+// setupLoginProviders registers an employees provider, the default one, and a
+// contractors provider, each backed by its own stand-in identity provider
+func setupLoginProviders(
+	t *testing.T, app *api.App,
+) (*helpers.OIDCServer, *helpers.OIDCServer) {
+	t.Helper()
+	employees := helpers.NewOIDCServer(t)
+	contractors := helpers.NewOIDCServer(t)
+	repo := helpers.GetRepo(t)
+	registry := oauth2.NewRegistry(repo)
+	for _, p := range []struct {
+		name          string
+		idp           *helpers.OIDCServer
+		hostedDomains []string
+	}{
+		{"employees", employees, []string{"company.com"}},
+		{"contractors", contractors, []string{"contractor.com"}},
+	} {
+		registry.Register(p.name, oauth2.NewOIDC(oauth2.OIDCConfig{
+			Name:          p.name,
+			Issuer:        p.idp.URL,
+			ClientID:      p.idp.ClientID,
+			ClientSecret:  p.idp.ClientSecret,
+			RedirectURL:   "http://localhost/sso/auth/done?provider=" + p.name,
+			HostedDomains: p.hostedDomains,
+		}, repo))
+	}
+	app.SetOAuth2Providers(registry)
+	return employees, contractors
+}
+
+func TestAuthenticationBuildURLHandlerProvider(t *testing.T) {
+	app := helpers.GetApp(t)
+	employees, contractors := setupLoginProviders(t, app)
+	defer employees.Close()
+	defer contractors.Close()
+	router := app.GetRouter()
+	type testCase struct {
+		provider string
+		status   int
+		location string
+	}
+	testCases := []testCase{
+		{"", http.StatusSeeOther, employees.URL},
+		{"employees", http.StatusSeeOther, employees.URL},
+		{"Contractors", http.StatusSeeOther, contractors.URL},
+		{"unknown", http.StatusUnprocessableEntity, ""},
+	}
+	for _, tt := range testCases {
+		req, _ := http.NewRequest("GET", fmt.Sprintf(
+			"/sso/auth/do?referer=http://referer&provider=%s", tt.provider,
+		), nil)
+		rec := helpers.DoRequest(t, req, router)
+		if rec.Code != tt.status {
+			t.Errorf("Expected status %d for %s. Got %d", tt.status, tt.provider, rec.Code)
+			continue
+		}
+		if location := rec.Header().Get("Location"); !strings.HasPrefix(location, tt.location) {
+			t.Errorf("Expected redirect to %s. Got %s", tt.location, location)
+		}
+	}
+}
+
+func TestAuthenticationExchangeCodeHandlerProvider(t *testing.T) {
+	helpers.CleanupPG(t)
+	app := helpers.GetApp(t)
+	employees, contractors := setupLoginProviders(t, app)
+	defer employees.Close()
+	defer contractors.Close()
+	router := app.GetRouter()
+	code := contractors.Code(map[string]interface{}{"email": "someone@contractor.com"})
+	req, _ := http.NewRequest("GET", fmt.Sprintf(
+		"/sso/auth/done?provider=contractors&state=http://referer&code=%s", code,
+	), nil)
+	rec := helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected status 303. Got %d", rec.Code)
+	}
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if provider := location.Query().Get("provider"); provider != "contractors" {
+		t.Errorf("Expected provider contractors. Got %s", provider)
+	}
+	accessToken := location.Query().Get("accessToken")
+	token, err := helpers.GetRepo(t).Tokens.Get(accessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token.Provider != "contractors" {
+		t.Errorf("Expected token provider contractors. Got %s", token.Provider)
+	}
+	req, _ = http.NewRequest("GET", "/sso/auth", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	rec = helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200. Got %d", rec.Code)
+	}
+}
+
+func TestAuthenticationExchangeCodeHandlerProviderHostedDomains(t *testing.T) {
+	helpers.CleanupPG(t)
+	app := helpers.GetApp(t)
+	employees, contractors := setupLoginProviders(t, app)
+	defer employees.Close()
+	defer contractors.Close()
+	router := app.GetRouter()
+	code := contractors.Code(map[string]interface{}{"email": "ceo@company.com"})
+	req, _ := http.NewRequest("GET", fmt.Sprintf(
+		"/sso/auth/done?provider=contractors&state=http://referer&code=%s", code,
+	), nil)
+	rec := helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401. Got %d", rec.Code)
+	}
+}
+
+func TestAuthenticationLogoutHandler(t *testing.T) {
+	helpers.CleanupPG(t)
+	app := helpers.GetApp(t)
+	employees, contractors := setupLoginProviders(t, app)
+	defer employees.Close()
+	defer contractors.Close()
+	router := app.GetRouter()
+	code := employees.Code(map[string]interface{}{"email": "leaver@company.com"})
+	req, _ := http.NewRequest("GET", fmt.Sprintf(
+		"/sso/auth/done?provider=employees&state=http://referer&code=%s", code,
+	), nil)
+	rec := helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected status 303. Got %d", rec.Code)
+	}
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	accessToken := location.Query().Get("accessToken")
+	req, _ = http.NewRequest("POST", "/sso/auth/logout", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	rec = helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204. Got %d", rec.Code)
+	}
+	req, _ = http.NewRequest("GET", "/sso/auth", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	rec = helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401. Got %d", rec.Code)
+	}
+	req, _ = http.NewRequest("POST", "/sso/auth/logout", nil)
+	req.Header.Set("Authorization", "KeyPair id:secret")
+	rec = helpers.DoRequest(t, req, router)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401. Got %d", rec.Code)
+	}
+}
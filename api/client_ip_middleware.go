@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPCtxKeyType string
+
+const clientIPCtxKey = clientIPCtxKeyType("clientIP")
+
+func getClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPCtxKey).(string)
+	return ip
+}
+
+// ParseIPNets parses CIDRs or single addresses, as http.trustedProxies and
+// conditions.trustedCallers are configured
+func ParseIPNets(strs []string) ([]*net.IPNet, error) {
+	ipNets := []*net.IPNet{}
+	for _, str := range strs {
+		if !strings.Contains(str, "/") {
+			ip := net.ParseIP(str)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %s", str)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(str)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+func ipNetsContain(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP is the address of the client that sent r. X-Forwarded-For is only
+// trusted when r comes from one of trustedProxies, and then it's read from
+// the right, skipping other trusted proxies, so clients can't make it up.
+// Values that aren't addresses are never returned
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	return clientIP(r.RemoteAddr, r.Header["X-Forwarded-For"], trustedProxies)
+}
+
+// clientIP is ClientIP of a client connected from remoteAddr, forwardedFor
+// being its X-Forwarded-For values
+func clientIP(
+	remoteAddr string, forwardedFor []string, trustedProxies []*net.IPNet,
+) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+	if !ipNetsContain(trustedProxies, ip) {
+		return ip.String()
+	}
+	hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !ipNetsContain(trustedProxies, ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// clientIPMiddlewareBuilder puts the address of the client, as told by
+// ClientIP, in requests context
+func clientIPMiddlewareBuilder(
+	trustedProxies []*net.IPNet,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(
+				r.Context(), clientIPCtxKey, ClientIP(r, trustedProxies),
+			)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
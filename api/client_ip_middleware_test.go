@@ -0,0 +1,53 @@
+// +build unit
+
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/api"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := api.ParseIPNets([]string{"10.0.0.0/8", "192.168.0.1"})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	type testCase struct {
+		remoteAddr string
+		xff        string
+		expected   string
+	}
+	tt := []testCase{
+		testCase{"203.0.113.7:4242", "", "203.0.113.7"},
+		// untrusted clients can't tell their address
+		testCase{"203.0.113.7:4242", "198.51.100.1", "203.0.113.7"},
+		testCase{"10.0.0.2:4242", "198.51.100.1", "198.51.100.1"},
+		// only the rightmost untrusted address is the client
+		testCase{"10.0.0.2:4242", "1.1.1.1, 198.51.100.1, 192.168.0.1", "198.51.100.1"},
+		testCase{"10.0.0.2:4242", "10.1.1.1", "10.1.1.1"},
+		// values that aren't addresses stop the walk
+		testCase{"10.0.0.2:4242", "198.51.100.1, not-an-ip-address-but-a-very-long-value-indeed", "10.0.0.2"},
+		testCase{"[2001:db8::1]:4242", "", "2001:db8::1"},
+		testCase{"", "198.51.100.1", ""},
+	}
+	for _, tt := range tt {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if ip := api.ClientIP(r, trustedProxies); ip != tt.expected {
+			t.Errorf("Expected %s for %s from %s. Got %s", tt.expected, tt.xff, tt.remoteAddr, ip)
+		}
+	}
+}
+
+func TestParseIPNetsInvalid(t *testing.T) {
+	for _, str := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := api.ParseIPNets([]string{str}); err == nil {
+			t.Errorf("Expected error for %s", str)
+		}
+	}
+}
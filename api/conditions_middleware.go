@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/conditions"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+type trustedCallerCtxKeyType string
+
+const trustedCallerCtxKey = trustedCallerCtxKeyType("trustedCaller")
+
+func isTrustedCaller(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustedCallerCtxKey).(bool)
+	return trusted
+}
+
+// conditionsMiddlewareBuilder makes permission checks of requests, Will.IAM's
+// own included, evaluate conditions against the client address. Clients in
+// trustedCallers are told apart, as only they may tell the address and
+// attributes of the requests they authorize on behalf of others
+func conditionsMiddlewareBuilder(
+	trustedCallers []*net.IPNet,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := withCallerConditionsContext(r.Context(), trustedCallers)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withCallerConditionsContext returns a copy of ctx evaluating conditions
+// against the client address in it, telling if the client is one of
+// trustedCallers
+func withCallerConditionsContext(
+	ctx context.Context, trustedCallers []*net.IPNet,
+) context.Context {
+	ip := net.ParseIP(getClientIP(ctx))
+	ctx = usecases.WithConditionsContext(ctx, &conditions.Context{
+		Time: time.Now(), IP: ip,
+	})
+	if ip != nil && ipNetsContain(trustedCallers, ip) {
+		ctx = context.WithValue(ctx, trustedCallerCtxKey, true)
+	}
+	return ctx
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// callerServiceHeader is sent by Will.IAM clients checking permissions on
+// behalf of their users, see pkg/http
+const callerServiceHeader = "x-caller-service"
+
+// buildDecision describes a permission check for the decision log. The
+// caller service defaults to the permission's service
+func buildDecision(
+	w http.ResponseWriter, r *http.Request, saID, permission string,
+	allowed bool, match *models.Permission, latency time.Duration,
+) models.Decision {
+	callerService := r.Header.Get(callerServiceHeader)
+	if callerService == "" {
+		callerService = strings.Split(permission, "::")[0]
+	}
+	d := models.Decision{
+		ServiceAccountID: saID,
+		Permission:       permission,
+		Allowed:          allowed,
+		LatencyMs:        float64(latency) / float64(time.Millisecond),
+		CallerService:    callerService,
+		RequestID:        w.Header().Get(requestIDHeader),
+	}
+	d.SetMatch(match)
+	return d
+}
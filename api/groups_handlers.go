@@ -0,0 +1,267 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+// ownsGroupRoles tells if the requester owns every permission members of
+// groupID get through it, which adding members grants them
+func ownsGroupRoles(
+	r *http.Request, sasUC usecases.ServiceAccounts, gsUC usecases.Groups,
+	groupID string,
+) (bool, error) {
+	rolesIDs, err := gsUC.WithContext(r.Context()).RolesIDs(groupID)
+	if err != nil {
+		return false, err
+	}
+	saID, _ := getServiceAccountID(r.Context())
+	return sasUC.WithContext(r.Context()).
+		HasAllOwnerRolesPermissions(saID, rolesIDs)
+}
+
+// writeGroupError responds to errors writing groups, telling if err was one
+// of them
+func writeGroupError(w http.ResponseWriter, err error) bool {
+	switch e := err.(type) {
+	case *errors.GroupInclusionCycleError:
+		WriteBytes(w, e.StatusCode(), e.Serialize())
+		return true
+	case *errors.GroupNameTakenError:
+		WriteBytes(w, e.StatusCode(), e.Serialize())
+		return true
+	case *errors.EntityNotFoundError:
+		WriteBytes(w, http.StatusUnprocessableEntity, e.Serialize())
+		return true
+	}
+	return false
+}
+
+func groupsListHandler(
+	gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		listOptions, err := buildListOptions(r)
+		if err != nil {
+			Write(
+				w, http.StatusUnprocessableEntity,
+				fmt.Sprintf(`{ "error": "%s"  }`, err.Error()),
+			)
+			return
+		}
+		gSl, count, err := gsUC.WithContext(r.Context()).List(listOptions)
+		if err != nil {
+			l.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		results, err := keepJSONFields(gSl, "id", "name")
+		if err != nil {
+			l.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"count":   count,
+			"results": results,
+		})
+	}
+}
+
+func groupsSearchHandler(
+	gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		term := r.URL.Query().Get("term")
+		listOptions, err := buildListOptions(r)
+		if err != nil {
+			Write(
+				w, http.StatusUnprocessableEntity,
+				fmt.Sprintf(`{ "error": "%s"  }`, err.Error()),
+			)
+			return
+		}
+		gSl, count, err := gsUC.WithContext(r.Context()).Search(term, listOptions)
+		if err != nil {
+			l.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		results, err := keepJSONFields(gSl, "id", "name")
+		if err != nil {
+			l.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"count":   count,
+			"results": results,
+		})
+	}
+}
+
+func groupsGetHandler(
+	gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		g, err := gsUC.WithContext(r.Context()).Get(mux.Vars(r)["id"])
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("groupsGetHandler gsUC.Get")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, g)
+	}
+}
+
+func groupsCreateHandler(
+	gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		gwn := &usecases.GroupWithNested{}
+		if err := unmarshalBodyTo(r, gwn); err != nil {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		v := gwn.Validate()
+		if !v.Valid() {
+			WriteBytes(w, http.StatusUnprocessableEntity, v.Errors())
+			return
+		}
+		err := gsUC.WithContext(r.Context()).Create(gwn)
+		if writeGroupError(w, err) {
+			return
+		}
+		if err != nil {
+			l.WithError(err).Error("groupsCreateHandler gsUC.Create")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusCreated, map[string]string{"id": gwn.ID})
+	}
+}
+
+func groupsUpdateHandler(
+	sasUC usecases.ServiceAccounts, gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		gwn := &usecases.GroupWithNested{}
+		if err := unmarshalBodyTo(r, gwn); err != nil {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		v := gwn.Validate()
+		if !v.Valid() {
+			WriteBytes(w, http.StatusUnprocessableEntity, v.Errors())
+			return
+		}
+		gwn.ID = mux.Vars(r)["id"]
+		has, err := ownsGroupRoles(r, sasUC, gsUC, gwn.ID)
+		if err != nil {
+			l.WithError(err).Error("groupsUpdateHandler ownsGroupRoles")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !has {
+			e := errors.NewUserDoesntHaveAllPermissionsError()
+			WriteBytes(w, e.StatusCode(), e.Serialize())
+			return
+		}
+		err = gsUC.WithContext(r.Context()).Update(gwn)
+		if writeGroupError(w, err) {
+			return
+		}
+		if err != nil {
+			l.WithError(err).Error("groupsUpdateHandler gsUC.Update")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func groupsDeleteHandler(
+	gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		err := gsUC.WithContext(r.Context()).Delete(mux.Vars(r)["id"])
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("groupsDeleteHandler gsUC.Delete")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func groupsAddMemberHandler(
+	sasUC usecases.ServiceAccounts, gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		vars := mux.Vars(r)
+		has, err := ownsGroupRoles(r, sasUC, gsUC, vars["id"])
+		if err != nil {
+			l.WithError(err).Error("groupsAddMemberHandler ownsGroupRoles")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !has {
+			e := errors.NewUserDoesntHaveAllPermissionsError()
+			WriteBytes(w, e.StatusCode(), e.Serialize())
+			return
+		}
+		err = gsUC.WithContext(r.Context()).
+			AddMember(vars["id"], vars["serviceAccountId"])
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("groupsAddMemberHandler gsUC.AddMember")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func groupsRemoveMemberHandler(
+	gsUC usecases.Groups,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		vars := mux.Vars(r)
+		err := gsUC.WithContext(r.Context()).
+			RemoveMember(vars["id"], vars["serviceAccountId"])
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("groupsRemoveMemberHandler gsUC.RemoveMember")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,115 @@
+// +build integration
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestGroupsHandlers(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(
+		t, "rootSAKeyPair", "rootSAKeyPair@test.com",
+	)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	router := helpers.GetApp(t).GetRouter()
+	do := func(method, path, body string, i interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", fmt.Sprintf(
+			"KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret,
+		))
+		rec := helpers.DoRequest(t, req, router)
+		if i != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), i); err != nil {
+				t.Fatalf("Unexpected error unmarshalling %s: %v", rec.Body.String(), err)
+			}
+		}
+		return rec.Code
+	}
+
+	created := map[string]string{}
+	if status := do(http.MethodPost, "/groups", `{"name": "team"}`, &created); status != http.StatusCreated {
+		t.Fatalf("Expected status 201. Got %d", status)
+	}
+	id := created["id"]
+	if status := do(http.MethodPut, "/groups/"+id+"/members/"+sa.ID, "", nil); status != http.StatusNoContent {
+		t.Fatalf("Expected status 204. Got %d", status)
+	}
+	group := struct {
+		Name            string                   `json:"name"`
+		ServiceAccounts []map[string]interface{} `json:"serviceAccounts"`
+	}{}
+	if status := do(http.MethodGet, "/groups/"+id, "", &group); status != http.StatusOK {
+		t.Fatalf("Expected status 200. Got %d", status)
+	}
+	if group.Name != "team" || len(group.ServiceAccounts) != 1 ||
+		group.ServiceAccounts[0]["id"] != sa.ID {
+		t.Errorf("Expected team with member %s. Got %#v", sa.ID, group)
+	}
+
+	type testCase struct {
+		method string
+		path   string
+		body   string
+		status int
+	}
+	testCases := []testCase{
+		{http.MethodPost, "/groups", `{"name": "Team"}`, http.StatusConflict},
+		{http.MethodPost, "/groups", `{}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/groups/" + id, fmt.Sprintf(`{"name": "team", "includedGroupsIds": ["%s"]}`, id), http.StatusUnprocessableEntity},
+		{http.MethodDelete, "/groups/" + id + "/members/" + sa.ID, "", http.StatusNoContent},
+		{http.MethodDelete, "/groups/" + id, "", http.StatusNoContent},
+		{http.MethodGet, "/groups/" + id, "", http.StatusNotFound},
+	}
+	for _, tt := range testCases {
+		if status := do(tt.method, tt.path, tt.body, nil); status != tt.status {
+			t.Errorf("Expected status %d for %s %s. Got %d", tt.status, tt.method, tt.path, status)
+		}
+	}
+}
+
+func TestGroupsAddMemberHandlerRequiresOwningGroupRoles(t *testing.T) {
+	helpers.CleanupPG(t)
+	editor := helpers.CreateServiceAccountWithPermissions(
+		t, "editor", "editor@test.com", models.AuthenticationTypes.KeyPair,
+		"Will.IAM::RL::EditGroup::*",
+	)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	gwn := &usecases.GroupWithNested{Name: "admins"}
+	if err := helpers.GetGroupsUseCase(t).Create(gwn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p, err := models.BuildPermission("Maestro::RL::*::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := helpers.GetRolesUseCase(t).Create(&usecases.RoleWithNested{
+		Name: "admin", Permissions: []models.Permission{p},
+		GroupsIDs: []string{gwn.ID},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req, _ := http.NewRequest(
+		http.MethodPut, "/groups/"+gwn.ID+"/members/"+sa.ID, nil,
+	)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"KeyPair %s:%s", editor.KeyID, editor.KeySecret,
+	))
+	rec := helpers.DoRequest(t, req, helpers.GetApp(t).GetRouter())
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403. Got %d", rec.Code)
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/Will.IAM/conditions"
+	"github.com/topfreegames/Will.IAM/constants"
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+	"github.com/topfreegames/Will.IAM/rpc"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const authorizationMetadata = "authorization"
+const accessTokenMetadata = "x-access-token"
+const forwardedForMetadata = "x-forwarded-for"
+
+type grpcAuthCtxKeyType string
+
+const grpcAuthCtxKey = grpcAuthCtxKeyType("grpcAuth")
+
+// grpcServer implements rpc.AuthorizationServer with the same usecases as the
+// HTTP API
+type grpcServer struct {
+	sasUC          usecases.ServiceAccounts
+	logger         logrus.FieldLogger
+	trustedProxies []*net.IPNet
+	trustedCallers []*net.IPNet
+}
+
+// NewGRPCServer builds a *grpc.Server serving rpc.AuthorizationServer.
+// trustedProxies and trustedCallers are as http.trustedProxies and
+// conditions.trustedCallers, with x-forwarded-for metadata read as the
+// X-Forwarded-For header
+func NewGRPCServer(
+	sasUC usecases.ServiceAccounts, logger logrus.FieldLogger,
+	trustedProxies, trustedCallers []*net.IPNet, opts ...grpc.ServerOption,
+) *grpc.Server {
+	gs := &grpcServer{
+		sasUC:          sasUC,
+		logger:         logger,
+		trustedProxies: trustedProxies,
+		trustedCallers: trustedCallers,
+	}
+	opts = append(opts, grpc.UnaryInterceptor(gs.authInterceptor))
+	s := grpc.NewServer(opts...)
+	rpc.RegisterAuthorizationServer(s, gs)
+	return s
+}
+
+// authInterceptor authenticates every call from its authorization metadata,
+// the same way authMiddleware does with the Authorization header
+func (gs *grpcServer) authInterceptor(
+	ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	l := gs.logger.WithField("method", info.FullMethod)
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationMetadata)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization is required")
+	}
+	auth, err := gs.authenticate(ctx, values[0])
+	if err != nil {
+		if s, ok := status.FromError(err); ok && s.Code() == codes.Unauthenticated {
+			l.WithError(err).Info("auth failed")
+			return nil, err
+		}
+		l.WithError(err).Error("auth failed")
+		return nil, status.Error(codes.Internal, "auth failed")
+	}
+	if auth.AccessToken != "" && auth.AccessToken != auth.presentedToken {
+		grpc.SetHeader(ctx, metadata.Pairs(accessTokenMetadata, auth.AccessToken))
+	}
+	ctx = context.WithValue(ctx, serviceAccountIDCtxKey, auth.ServiceAccountId)
+	ctx = context.WithValue(ctx, grpcAuthCtxKey, auth)
+	ctx = usecases.WithAuditActor(ctx, auth.ServiceAccountId)
+	ctx = context.WithValue(ctx, clientIPCtxKey, gs.clientIP(ctx))
+	ctx = withCallerConditionsContext(ctx, gs.trustedCallers)
+	res, err := handler(ctx, req)
+	l = l.WithField("latency", time.Since(start))
+	if err != nil {
+		if s, ok := status.FromError(err); ok && s.Code() == codes.Internal {
+			l.WithError(err).Error("grpc call failed")
+		}
+		return nil, err
+	}
+	l.Debug("grpc call done")
+	return res, nil
+}
+
+// clientIP is ClientIP of the peer of a call
+func (gs *grpcServer) clientIP(ctx context.Context) string {
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return clientIP(remoteAddr, md.Get(forwardedForMetadata), gs.trustedProxies)
+}
+
+// withRequestConditionsContext returns a copy of ctx evaluating conditions
+// against the ip and attributes of a request, if any. As
+// withConditionsContext, only trusted callers may tell them
+func withRequestConditionsContext(
+	ctx context.Context, ip string, attributes map[string]string,
+) (context.Context, error) {
+	if ip == "" && len(attributes) == 0 {
+		return ctx, nil
+	}
+	if !isTrustedCaller(ctx) {
+		return nil, status.Error(
+			codes.InvalidArgument,
+			"ip and attributes are only accepted from trusted callers",
+		)
+	}
+	cc := &conditions.Context{
+		Time:       time.Now(),
+		IP:         net.ParseIP(getClientIP(ctx)),
+		Attributes: attributes,
+	}
+	if ip != "" {
+		if cc.IP = net.ParseIP(ip); cc.IP == nil {
+			return nil, status.Error(codes.InvalidArgument, "ip must be an IP")
+		}
+	}
+	return usecases.WithConditionsContext(ctx, cc), nil
+}
+
+type grpcAuth struct {
+	rpc.AuthenticateResponse
+	presentedToken string
+}
+
+func (gs *grpcServer) authenticate(
+	ctx context.Context, header string,
+) (*grpcAuth, error) {
+	authHeader, err := buildAuth(header)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	sasUC := gs.sasUC.WithContext(ctx)
+	auth := &grpcAuth{}
+	switch authHeader.Type {
+	case models.AuthenticationTypes.KeyPair:
+		keyPair := strings.Split(authHeader.Content, ":")
+		if len(keyPair) != 2 {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				errors.NewInvalidAuthorizationTypeError().Error(),
+			)
+		}
+		kpAuth, err := sasUC.AuthenticateKeyPair(keyPair[0], keyPair[1])
+		if err != nil {
+			return nil, authenticationError(err)
+		}
+		auth.ServiceAccountId = kpAuth.ServiceAccountID
+		auth.Name = kpAuth.Name
+	case models.AuthenticationTypes.OAuth2:
+		atAuth, err := sasUC.AuthenticateAccessToken(authHeader.Content)
+		if err != nil {
+			return nil, authenticationError(err)
+		}
+		auth.ServiceAccountId = atAuth.ServiceAccountID
+		auth.Email = atAuth.Email
+		auth.AccessToken = atAuth.AccessToken
+		auth.presentedToken = authHeader.Content
+	default:
+		return nil, status.Error(
+			codes.Unauthenticated,
+			errors.NewInvalidAuthorizationTypeError().Error(),
+		)
+	}
+	return auth, nil
+}
+
+func authenticationError(err error) error {
+	switch err.(type) {
+	case *errors.EntityNotFoundError, *errors.InvalidAccessTokenError:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case *errors.InactiveServiceAccountError:
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return err
+}
+
+func (gs *grpcServer) Authenticate(
+	ctx context.Context, req *rpc.AuthenticateRequest,
+) (*rpc.AuthenticateResponse, error) {
+	auth := ctx.Value(grpcAuthCtxKey).(*grpcAuth)
+	res := auth.AuthenticateResponse
+	return &res, nil
+}
+
+func (gs *grpcServer) HasPermission(
+	ctx context.Context, req *rpc.HasPermissionRequest,
+) (*rpc.HasPermissionResponse, error) {
+	if _, err := models.BuildPermission(req.Permission); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, err := withRequestConditionsContext(ctx, req.Ip, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	saID, _ := getServiceAccountID(ctx)
+	has, err := gs.sasUC.WithContext(ctx).HasPermissionString(saID, req.Permission)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &rpc.HasPermissionResponse{Has: has}, nil
+}
+
+func (gs *grpcServer) HasPermissions(
+	ctx context.Context, req *rpc.HasPermissionsRequest,
+) (*rpc.HasPermissionsResponse, error) {
+	if _, err := models.BuildPermissions(req.Permissions); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, err := withRequestConditionsContext(ctx, req.Ip, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	saID, _ := getServiceAccountID(ctx)
+	has, err := gs.sasUC.WithContext(ctx).
+		HasPermissionsStrings(saID, req.Permissions)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &rpc.HasPermissionsResponse{Has: has}, nil
+}
+
+func (gs *grpcServer) ListServiceAccountsWithPermission(
+	ctx context.Context, req *rpc.ListServiceAccountsWithPermissionRequest,
+) (*rpc.ListServiceAccountsWithPermissionResponse, error) {
+	permission, err := models.BuildPermission(req.Permission)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Page < 0 || req.PageSize < 0 {
+		return nil, status.Error(
+			codes.InvalidArgument, "page and pageSize must not be negative",
+		)
+	}
+	lo := &repositories.ListOptions{
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+	}
+	if lo.PageSize == 0 {
+		lo.PageSize = constants.DefaultListOptionsPageSize
+	}
+	saID, _ := getServiceAccountID(ctx)
+	saSl, count, err := gs.sasUC.WithContext(ctx).
+		ListWithPermission(saID, lo, permission)
+	if err != nil {
+		if _, ok := err.(*errors.UserDoesntHavePermissionError); ok {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	res := &rpc.ListServiceAccountsWithPermissionResponse{
+		Count:           count,
+		ServiceAccounts: make([]*rpc.ServiceAccount, len(saSl)),
+	}
+	for i, sa := range saSl {
+		res.ServiceAccounts[i] = &rpc.ServiceAccount{
+			Id:                 sa.ID,
+			Name:               sa.Name,
+			Email:              sa.Email,
+			Picture:            sa.Picture,
+			BaseRoleId:         sa.BaseRoleID,
+			AuthenticationType: string(sa.AuthenticationType),
+		}
+	}
+	return res, nil
+}
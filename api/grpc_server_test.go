@@ -0,0 +1,219 @@
+// +build integration
+
+package api_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/api"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/pkg/client"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dialGRPCServer(
+	t *testing.T, auth client.Auth,
+) (*client.GRPCClient, func()) {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	s := api.NewGRPCServer(
+		helpers.GetServiceAccountsUseCase(t), helpers.GetLogger(t), nil, nil,
+	)
+	go s.Serve(lis)
+	gc, err := client.DialGRPC("bufnet", auth,
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return gc, func() {
+		gc.Close()
+		s.Stop()
+	}
+}
+
+func TestGRPCServerHasPermission(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service1::RL::Do::x::*",
+	)
+	gc, stop := dialGRPCServer(t, client.KeyPair(sa.KeyID, sa.KeySecret))
+	defer stop()
+	ctx := context.Background()
+	auth, err := gc.Authenticate(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if auth.ServiceAccountId != sa.ID {
+		t.Errorf("Expected service account %s. Got %s", sa.ID, auth.ServiceAccountId)
+	}
+	has, err := gc.HasPermission(ctx, "Service1::RL::Do::x::y")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected permission to be granted")
+	}
+	hasMany, err := gc.HasMany(ctx, []string{
+		"Service1::RL::Do::x::y", "Service1::RL::Do::z::y",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hasMany) != 2 || !hasMany[0] || hasMany[1] {
+		t.Errorf("Expected [true false]. Got %v", hasMany)
+	}
+	_, err = gc.HasPermission(ctx, "Service1::RL")
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument. Got %v", err)
+	}
+}
+
+// dialGRPCServerTCP is dialGRPCServer over loopback TCP, so calls come from
+// 127.0.0.1
+func dialGRPCServerTCP(
+	t *testing.T, auth client.Auth, trustedCallers []*net.IPNet,
+) (*client.GRPCClient, func()) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := api.NewGRPCServer(
+		helpers.GetServiceAccountsUseCase(t), helpers.GetLogger(t),
+		nil, trustedCallers,
+	)
+	go s.Serve(lis)
+	gc, err := client.DialGRPC(lis.Addr().String(), auth, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return gc, func() {
+		gc.Close()
+		s.Stop()
+	}
+}
+
+func TestGRPCServerHasPermissionConditions(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	for _, pc := range []struct{ permission, condition string }{
+		{"Service1::RL::Local::*", `inCIDR(request.ip, "127.0.0.0/8")`},
+		{"Service1::RL::VPN::*", `inCIDR(request.ip, "10.8.0.0/16")`},
+		{"Service1::RL::Ticket::*", `context.ticket != ""`},
+	} {
+		p, err := models.BuildPermission(pc.permission)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		p.Condition = pc.condition
+		err = helpers.GetServiceAccountsUseCase(t).CreatePermission(sa.ID, &p)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	trustedCallers, err := api.ParseIPNets([]string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	auth := client.KeyPair(sa.KeyID, sa.KeySecret)
+	untrusted, stop := dialGRPCServerTCP(t, auth, nil)
+	defer stop()
+	trusted, stop := dialGRPCServerTCP(t, auth, trustedCallers)
+	defer stop()
+	vpn := &client.ConditionsContext{IP: "10.8.1.2"}
+	ticket := &client.ConditionsContext{
+		Attributes: map[string]string{"ticket": "OPS-1"},
+	}
+
+	testCases := []struct {
+		name       string
+		gc         *client.GRPCClient
+		permission string
+		cc         *client.ConditionsContext
+		want       bool
+		wantCode   codes.Code
+	}{
+		{"FromPeerAddress", untrusted, "Service1::RL::Local::x", nil, true, codes.OK},
+		{"FromOtherPeerAddress", untrusted, "Service1::RL::VPN::x", nil, false, codes.OK},
+		{"UntrustedCallerTellingIP", untrusted, "Service1::RL::VPN::x", vpn, false, codes.InvalidArgument},
+		{"UntrustedCallerTellingAttributes", untrusted, "Service1::RL::Ticket::x", ticket, false, codes.InvalidArgument},
+		{"TrustedCallerTellingIP", trusted, "Service1::RL::VPN::x", vpn, true, codes.OK},
+		{"TrustedCallerTellingAttributes", trusted, "Service1::RL::Ticket::x", ticket, true, codes.OK},
+		{"TrustedCallerTellingInvalidIP", trusted, "Service1::RL::VPN::x", &client.ConditionsContext{IP: "10.8"}, false, codes.InvalidArgument},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			has, err := tt.gc.HasPermissionIn(ctx, tt.permission, tt.cc)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Expected %v. Got %v", tt.wantCode, err)
+			}
+			if has != tt.want {
+				t.Errorf("Expected HasPermission %v. Got %v", tt.want, has)
+			}
+			hasMany, err := tt.gc.HasManyIn(ctx, []string{tt.permission}, tt.cc)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Expected %v. Got %v", tt.wantCode, err)
+			}
+			if err == nil && (len(hasMany) != 1 || hasMany[0] != tt.want) {
+				t.Errorf("Expected HasPermissions [%v]. Got %v", tt.want, hasMany)
+			}
+		})
+	}
+}
+
+func TestGRPCServerUnauthenticated(t *testing.T) {
+	helpers.CleanupPG(t)
+	gc, stop := dialGRPCServer(t, client.KeyPair("unknown", "unknown"))
+	defer stop()
+	_, err := gc.HasPermission(context.Background(), "Service1::RL::Do::*")
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated. Got %v", err)
+	}
+}
+
+func TestGRPCServerListServiceAccountsWithPermission(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service1::RL::Do::*",
+	)
+	gc, stop := dialGRPCServer(t, client.KeyPair(rootSA.KeyID, rootSA.KeySecret))
+	defer stop()
+	l, err := gc.ListServiceAccountsWithPermission(
+		context.Background(), "Service1::RL::Do::*", nil,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := false
+	for _, r := range l.Results {
+		found = found || r.ID == sa.ID
+	}
+	if !found {
+		t.Errorf("Expected %s to be listed. Got %#v", sa.ID, l.Results)
+	}
+	gc, stop = dialGRPCServer(t, client.KeyPair(sa.KeyID, sa.KeySecret))
+	defer stop()
+	_, err = gc.ListServiceAccountsWithPermission(
+		context.Background(), "Service1::RO::Do::*", nil,
+	)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied. Got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+// oauth2TokenResponse is the RFC 6749 access token response
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// oauth2ErrorResponse is the RFC 6749 error response
+type oauth2ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// oauth2TokenHandler implements the client_credentials grant: key pair
+// service accounts authenticate as clients, with HTTP Basic or form params,
+// and get a short-lived access token signed by Will.IAM
+func oauth2TokenHandler(
+	sasUC usecases.ServiceAccounts, atsUC usecases.AccessTokens,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		if err := r.ParseForm(); err != nil {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+			writeOAuth2Error(
+				w, http.StatusBadRequest, "unsupported_grant_type",
+				"only client_credentials is supported",
+			)
+			return
+		}
+		clientID, clientSecret, basic := r.BasicAuth()
+		if basic {
+			if r.PostForm.Get("client_id") != "" || r.PostForm.Get("client_secret") != "" {
+				writeOAuth2Error(
+					w, http.StatusBadRequest, "invalid_request",
+					"client authenticated more than once",
+				)
+				return
+			}
+			// RFC 6749 form-urlencodes credentials before base64 encoding them
+			clientID, _ = url.QueryUnescape(clientID)
+			clientSecret, _ = url.QueryUnescape(clientSecret)
+		} else {
+			clientID = r.PostForm.Get("client_id")
+			clientSecret = r.PostForm.Get("client_secret")
+		}
+		if clientID == "" || clientSecret == "" {
+			writeOAuth2InvalidClient(w, basic)
+			return
+		}
+		kpAuth, err := sasUC.WithContext(r.Context()).
+			AuthenticateKeyPair(clientID, clientSecret)
+		switch err.(type) {
+		case *errors.EntityNotFoundError, *errors.InactiveServiceAccountError:
+			l.WithError(err).Info("oauth2TokenHandler invalid client")
+			writeOAuth2InvalidClient(w, basic)
+			return
+		}
+		if err != nil {
+			l.WithError(err).Error("oauth2TokenHandler AuthenticateKeyPair failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		issued, err := atsUC.WithContext(r.Context()).Issue(kpAuth.ServiceAccountID)
+		if err != nil {
+			l.WithError(err).Error("oauth2TokenHandler atsUC.Issue failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, oauth2TokenResponse{
+			AccessToken: issued.AccessToken,
+			TokenType:   issued.TokenType,
+			ExpiresIn:   issued.ExpiresIn,
+		})
+	}
+}
+
+func writeOAuth2InvalidClient(w http.ResponseWriter, basic bool) {
+	if basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Will.IAM"`)
+	}
+	writeOAuth2Error(
+		w, http.StatusUnauthorized, "invalid_client", "client authentication failed",
+	)
+}
+
+func writeOAuth2Error(
+	w http.ResponseWriter, status int, code, description string,
+) {
+	WriteJSON(w, status, oauth2ErrorResponse{
+		Error: code, ErrorDescription: description,
+	})
+}
@@ -0,0 +1,127 @@
+// +build integration
+
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func newOAuth2TokenRequest(form url.Values) *http.Request {
+	req, _ := http.NewRequest(
+		"POST", "/oauth2/token", strings.NewReader(form.Encode()),
+	)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestOAuth2TokenHandlerClientCredentials(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service1::RL::Do::*",
+	)
+	app := helpers.GetApp(t)
+	app.SetAccessTokens(helpers.GetAccessTokensUseCase(t, false))
+	router := app.GetRouter()
+	basic := newOAuth2TokenRequest(url.Values{"grant_type": {"client_credentials"}})
+	basic.SetBasicAuth(sa.KeyID, sa.KeySecret)
+	for _, req := range []*http.Request{
+		basic,
+		newOAuth2TokenRequest(url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {sa.KeyID},
+			"client_secret": {sa.KeySecret},
+		}),
+	} {
+		rec := helpers.DoRequest(t, req, router)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200. Got %d", rec.Code)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("Expected Cache-Control no-store. Got %s", cc)
+		}
+		body := map[string]interface{}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if body["token_type"] != "Bearer" || body["expires_in"] == nil {
+			t.Errorf("Unexpected body %v", body)
+		}
+		req, _ = http.NewRequest(
+			"GET", "/permissions/has?permission=Service1::RL::Do::x", nil,
+		)
+		req.Header.Set(
+			"Authorization", fmt.Sprintf("Bearer %s", body["access_token"]),
+		)
+		rec = helpers.DoRequest(t, req, router)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200. Got %d", rec.Code)
+		}
+	}
+}
+
+func TestOAuth2TokenHandlerErrors(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	app := helpers.GetApp(t)
+	app.SetAccessTokens(helpers.GetAccessTokensUseCase(t, false))
+	router := app.GetRouter()
+	wrongBasic := newOAuth2TokenRequest(url.Values{"grant_type": {"client_credentials"}})
+	wrongBasic.SetBasicAuth(sa.KeyID, "wrong")
+	twice := newOAuth2TokenRequest(url.Values{
+		"grant_type": {"client_credentials"}, "client_id": {sa.KeyID},
+	})
+	twice.SetBasicAuth(sa.KeyID, sa.KeySecret)
+	type testCase struct {
+		req    *http.Request
+		status int
+		error  string
+	}
+	testCases := []testCase{
+		{newOAuth2TokenRequest(url.Values{
+			"grant_type":    {"password"},
+			"client_id":     {sa.KeyID},
+			"client_secret": {sa.KeySecret},
+		}), http.StatusBadRequest, "unsupported_grant_type"},
+		{newOAuth2TokenRequest(url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {sa.KeyID},
+			"client_secret": {"wrong"},
+		}), http.StatusUnauthorized, "invalid_client"},
+		{newOAuth2TokenRequest(url.Values{
+			"grant_type": {"client_credentials"},
+		}), http.StatusUnauthorized, "invalid_client"},
+		{wrongBasic, http.StatusUnauthorized, "invalid_client"},
+		{twice, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tt := range testCases {
+		rec := helpers.DoRequest(t, tt.req, router)
+		if rec.Code != tt.status {
+			t.Errorf("Expected status %d. Got %d", tt.status, rec.Code)
+		}
+		body := map[string]string{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if body["error"] != tt.error {
+			t.Errorf("Expected error %s. Got %s", tt.error, body["error"])
+		}
+	}
+}
+
+func TestOAuth2TokenHandlerDisabled(t *testing.T) {
+	app := helpers.GetApp(t)
+	req := newOAuth2TokenRequest(url.Values{"grant_type": {"client_credentials"}})
+	rec := helpers.DoRequest(t, req, app.GetRouter())
+	if rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected /oauth2/token not to be routed. Got %d", rec.Code)
+	}
+}
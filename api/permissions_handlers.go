@@ -2,10 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/mux"
+	"github.com/topfreegames/Will.IAM/conditions"
+	"github.com/topfreegames/Will.IAM/decisionlog"
 	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
 	"github.com/topfreegames/Will.IAM/usecases"
@@ -28,6 +36,7 @@ func permissionsDeleteHandler(
 			return
 		}
 		p.OwnershipLevel = models.OwnershipLevels.Owner
+		p.Deny = false
 		saID, _ := getServiceAccountID(r.Context())
 		has, err := sasUC.WithContext(r.Context()).HasPermissionString(saID, p.String())
 		if err != nil {
@@ -68,6 +77,11 @@ func permissionsAttributeHandler(
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		v := pa.Validate()
+		if !v.Valid() {
+			WriteBytes(w, http.StatusUnprocessableEntity, v.Errors())
+			return
+		}
 		pa.Permissions, err = models.BuildPermissions(pa.PermissionsStrings)
 		if err != nil {
 			l.WithError(err).Error("BuildPermissions failed")
@@ -78,6 +92,12 @@ func permissionsAttributeHandler(
 			if alias, ok := pa.PermissionsAliases[pa.PermissionsStrings[i]]; ok {
 				pa.Permissions[i].Alias = alias
 			}
+			if expiresAt, ok := pa.PermissionsExpiresAt[pa.PermissionsStrings[i]]; ok {
+				pa.Permissions[i].ExpiresAt = pg.NullTime{Time: expiresAt}
+			}
+			if condition, ok := pa.PermissionsConditions[pa.PermissionsStrings[i]]; ok {
+				pa.Permissions[i].Condition = condition
+			}
 		}
 		saID, _ := getServiceAccountID(r.Context())
 		has, err := sasUC.WithContext(r.Context()).
@@ -120,6 +140,11 @@ func permissionsAttributeToEmailsHandler(
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		v := pa.Validate()
+		if !v.Valid() {
+			WriteBytes(w, http.StatusUnprocessableEntity, v.Errors())
+			return
+		}
 		pa.Permissions, err = models.BuildPermissions(pa.PermissionsStrings)
 		if err != nil {
 			l.WithError(err).Error("BuildPermissions failed")
@@ -130,6 +155,12 @@ func permissionsAttributeToEmailsHandler(
 			if alias, ok := pa.PermissionsAliases[pa.PermissionsStrings[i]]; ok {
 				pa.Permissions[i].Alias = alias
 			}
+			if expiresAt, ok := pa.PermissionsExpiresAt[pa.PermissionsStrings[i]]; ok {
+				pa.Permissions[i].ExpiresAt = pg.NullTime{Time: expiresAt}
+			}
+			if condition, ok := pa.PermissionsConditions[pa.PermissionsStrings[i]]; ok {
+				pa.Permissions[i].Condition = condition
+			}
 		}
 		saID, _ := getServiceAccountID(r.Context())
 		has, err := sasUC.WithContext(r.Context()).
@@ -154,11 +185,52 @@ func permissionsAttributeToEmailsHandler(
 	}
 }
 
+// conditionsContextAttributePrefix prefixes querystrings telling context
+// attributes to permissions conditions
+const conditionsContextAttributePrefix = "context."
+
+// withConditionsContext returns a copy of r whose checks evaluate permissions
+// conditions against the time of the request, the client address and, from
+// trusted callers only, querystrings.ip and every
+// querystrings.context.<attribute>. Other callers could make them up to dodge
+// conditioned denies
+func withConditionsContext(r *http.Request) (*http.Request, error) {
+	qs := r.URL.Query()
+	trusted := isTrustedCaller(r.Context())
+	cc := &conditions.Context{
+		Time:       time.Now(),
+		IP:         net.ParseIP(getClientIP(r.Context())),
+		Attributes: map[string]string{},
+	}
+	if ip := qs.Get("ip"); ip != "" {
+		if !trusted {
+			return nil, fmt.Errorf("querystrings.ip is only accepted from trusted callers")
+		}
+		if cc.IP = net.ParseIP(ip); cc.IP == nil {
+			return nil, fmt.Errorf("querystrings.ip must be an IP")
+		}
+	}
+	for k, vs := range qs {
+		if strings.HasPrefix(k, conditionsContextAttributePrefix) && len(vs) > 0 {
+			if !trusted {
+				return nil, fmt.Errorf("querystrings.%s is only accepted from trusted callers", k)
+			}
+			cc.Attributes[strings.TrimPrefix(k, conditionsContextAttributePrefix)] = vs[0]
+		}
+	}
+	return r.WithContext(usecases.WithConditionsContext(r.Context(), cc)), nil
+}
+
 func permissionsHasHandler(
-	sasUC usecases.ServiceAccounts,
+	sasUC usecases.ServiceAccounts, dl *decisionlog.Logger,
 ) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		l := middleware.GetLogger(r.Context())
+		r, err := withConditionsContext(r)
+		if err != nil {
+			WriteJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+			return
+		}
 		qs := r.URL.Query()
 		permissionSl := qs["permission"]
 		if len(permissionSl) == 0 {
@@ -167,14 +239,25 @@ func permissionsHasHandler(
 			return
 		}
 		saID, _ := getServiceAccountID(r.Context())
+		start := time.Now()
 		has, err :=
 			sasUC.WithContext(r.Context()).HasPermissionString(saID, permissionSl[0])
+		latency := time.Since(start)
 		if err != nil {
 			l.Error(err)
 			Write(w, http.StatusUnprocessableEntity,
 				`{"error": "Incomplete permission. Expected format: Service::OwnershipLevel::Action::{ResourceHierarchy}"}`)
 			return
 		}
+		if dl.ShouldLog(has) {
+			// the grant deciding has is only looked up for sampled checks
+			match, _, err := sasUC.WithContext(r.Context()).
+				MatchPermissionString(saID, permissionSl[0])
+			if err != nil {
+				l.WithError(err).Error("permissionsHas MatchPermissionString failed")
+			}
+			dl.Log(buildDecision(w, r, saID, permissionSl[0], has, match, latency))
+		}
 		if !has {
 			w.WriteHeader(http.StatusForbidden)
 			return
@@ -184,10 +267,15 @@ func permissionsHasHandler(
 }
 
 func permissionsHasManyHandler(
-	sasUC usecases.ServiceAccounts,
+	sasUC usecases.ServiceAccounts, dl *decisionlog.Logger,
 ) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		l := middleware.GetLogger(r.Context())
+		r, err := withConditionsContext(r)
+		if err != nil {
+			WriteJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+			return
+		}
 		body, err := ioutil.ReadAll(r.Body)
 		defer r.Body.Close()
 		if err != nil {
@@ -203,13 +291,22 @@ func permissionsHasManyHandler(
 			return
 		}
 		saID, _ := getServiceAccountID(r.Context())
-		resultStatus, err :=
-			sasUC.WithContext(r.Context()).HasPermissionsStrings(saID, permissions)
+		start := time.Now()
+		matches, resultStatus, err :=
+			sasUC.WithContext(r.Context()).MatchPermissionsStrings(saID, permissions)
+		latency := time.Since(start)
 		if err != nil {
 			l.Error(err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		for i := range permissions {
+			if dl.ShouldLog(resultStatus[i]) {
+				dl.Log(buildDecision(
+					w, r, saID, permissions[i], resultStatus[i], matches[i], latency,
+				))
+			}
+		}
 		bts, err := json.Marshal(resultStatus)
 		if err != nil {
 			l.WithError(err).Error("permissionsHasMany json.Marshal error")
@@ -219,3 +316,61 @@ func permissionsHasManyHandler(
 		WriteBytes(w, http.StatusOK, bts)
 	}
 }
+
+func permissionsExplainHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		r, err := withConditionsContext(r)
+		if err != nil {
+			WriteJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+			return
+		}
+		qs := r.URL.Query()
+		permission, err := models.BuildPermission(qs.Get("permission"))
+		if err != nil {
+			Write(w, http.StatusUnprocessableEntity,
+				`{"error": "Incomplete permission. Expected format: Service::OwnershipLevel::Action::{ResourceHierarchy}"}`)
+			return
+		}
+		saID, _ := getServiceAccountID(r.Context())
+		targetSAID := qs.Get("serviceAccountId")
+		if targetSAID == "" {
+			targetSAID = saID
+		} else if _, err := uuid.FromString(targetSAID); err != nil {
+			Write(w, http.StatusUnprocessableEntity,
+				`{"error": "querystrings.serviceAccountId must be an uuid"}`)
+			return
+		}
+		if targetSAID != saID {
+			// same rule as listing who has a permission: only its owners can
+			ownerPermission := permission
+			ownerPermission.OwnershipLevel = models.OwnershipLevels.Owner
+			ownerPermission.Deny = false
+			has, err := sasUC.WithContext(r.Context()).
+				HasPermissionString(saID, ownerPermission.String())
+			if err != nil {
+				l.WithError(err).Error("permissionsExplain HasPermissionString failed")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !has {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		pe, err := sasUC.WithContext(r.Context()).
+			ExplainPermission(targetSAID, permission)
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("permissionsExplain ExplainPermission failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, pe)
+	}
+}
@@ -3,12 +3,17 @@
 package api_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/topfreegames/Will.IAM/models"
 	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/topfreegames/Will.IAM/api"
+	"github.com/topfreegames/Will.IAM/decisionlog"
 	helpers "github.com/topfreegames/Will.IAM/testing"
 )
 
@@ -189,3 +194,219 @@ func TestPermissionsHasHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPermissionsHasHandlerDecisionLog(t *testing.T) {
+	beforeEachPermissionsHandlers(t)
+	if _, err := helpers.GetStorage(t).PG.DB.Exec("DELETE FROM decisions"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service::RL::TestAction::*", "!Service::RL::TestAction::prod::*",
+	)
+	dl := decisionlog.NewWithSink(
+		decisionlog.NewPostgresSink(helpers.GetRepo(t)), helpers.GetLogger(t),
+		decisionlog.Options{SampleRate: 1, BufferSize: 10, FlushInterval: time.Hour},
+	)
+	app := helpers.GetApp(t)
+	app.SetDecisionLogger(dl)
+	for _, permission := range []string{
+		"Service::RL::TestAction::dev::x", "Service::RL::TestAction::prod::x",
+	} {
+		req, _ := http.NewRequest("GET", "/permissions/has?permission="+permission, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", sa.KeyID, sa.KeySecret))
+		req.Header.Set("x-caller-service", "SomeGame")
+		helpers.DoRequest(t, req, app.GetRouter())
+	}
+	// shutting down flushes the decision log
+	app.Shutdown()
+	var ds []models.Decision
+	if _, err := helpers.GetStorage(t).PG.DB.Query(
+		&ds, "SELECT * FROM decisions ORDER BY permission",
+	); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ds) != 2 {
+		t.Fatalf("Expected 2 decisions. Got %d", len(ds))
+	}
+	if !ds[0].Allowed || ds[0].MatchingGrant != "Service::RL::TestAction::*" {
+		t.Errorf("Unexpected decision %v", ds[0])
+	}
+	if ds[1].Allowed || ds[1].MatchingGrant != "!Service::RL::TestAction::prod::*" {
+		t.Errorf("Unexpected decision %v", ds[1])
+	}
+	if ds[1].ServiceAccountID != sa.ID || ds[1].CallerService != "SomeGame" ||
+		ds[1].MatchingGrantID == "" || ds[1].RequestID == "" {
+		t.Errorf("Unexpected decision %v", ds[1])
+	}
+}
+
+func TestPermissionsExplainHandler(t *testing.T) {
+	beforeEachPermissionsHandlers(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service::RL::TestAction::*", "!Service::RL::TestAction::prod::*",
+		"Other::RL::Secret::*",
+	)
+	owner := helpers.CreateServiceAccountWithPermissions(
+		t, "owner", "owner@test.com", models.AuthenticationTypes.KeyPair,
+		"Service::RO::TestAction::dev::*",
+	)
+	app := helpers.GetApp(t)
+
+	testCases := []struct {
+		name        string
+		requester   *models.ServiceAccount
+		query       string
+		wantStatus  int
+		wantAllowed bool
+		wantReason  string
+		wantMatches []string
+		wantResults []string
+	}{
+		{
+			name:       "InvalidPermission",
+			requester:  sa,
+			query:      "permission=X",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "SelfAllowed",
+			requester:   sa,
+			query:       "permission=Service::RL::TestAction::dev::x",
+			wantStatus:  http.StatusOK,
+			wantAllowed: true,
+			wantReason:  "granted by Service::RL::TestAction::*",
+			wantMatches: []string{"*"},
+			wantResults: []string{
+				"Service::RL::TestAction::*", "!Service::RL::TestAction::prod::*",
+			},
+		},
+		{
+			name:       "OtherWithoutOwnership",
+			requester:  sa,
+			query:      "permission=Service::RL::TestAction::dev::x&serviceAccountId=" + rootSA.ID,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "OtherDenied",
+			requester:   rootSA,
+			query:       "permission=Service::RL::TestAction::prod::x&serviceAccountId=" + sa.ID,
+			wantStatus:  http.StatusOK,
+			wantAllowed: false,
+			wantReason:  "denied by !Service::RL::TestAction::prod::*",
+			wantMatches: []string{"*", "prod::*"},
+			wantResults: []string{
+				"Service::RL::TestAction::*", "!Service::RL::TestAction::prod::*",
+			},
+		},
+		{
+			name:        "OtherOwnedLeavesOtherPermissionsOut",
+			requester:   owner,
+			query:       "permission=Service::RL::TestAction::dev::x&serviceAccountId=" + sa.ID,
+			wantStatus:  http.StatusOK,
+			wantAllowed: true,
+			wantReason:  "granted by Service::RL::TestAction::*",
+			wantMatches: []string{"*"},
+			wantResults: []string{
+				"Service::RL::TestAction::*", "!Service::RL::TestAction::prod::*",
+			},
+		},
+		{
+			name:       "OtherNotFound",
+			requester:  rootSA,
+			query:      "permission=Service::RL::TestAction::prod::x&serviceAccountId=" + uuid.Must(uuid.NewV4()).String(),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/permissions/explain?"+testCase.query, nil)
+			req.Header.Set("Authorization", fmt.Sprintf(
+				"KeyPair %s:%s", testCase.requester.KeyID, testCase.requester.KeySecret,
+			))
+			rec := helpers.DoRequest(t, req, app.GetRouter())
+			if rec.Code != testCase.wantStatus {
+				t.Fatalf("Expected status %d. Got %d", testCase.wantStatus, rec.Code)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			pe := &models.PermissionExplanation{}
+			if err := json.Unmarshal(rec.Body.Bytes(), pe); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if pe.Allowed != testCase.wantAllowed || pe.Reason != testCase.wantReason {
+				t.Errorf("Unexpected explanation %v", pe)
+			}
+			if !reflect.DeepEqual(pe.ResourceHierarchyMatches, testCase.wantMatches) {
+				t.Errorf(
+					"Expected resource hierarchy matches %v. Got %v",
+					testCase.wantMatches, pe.ResourceHierarchyMatches,
+				)
+			}
+			if len(pe.Roles) != 1 || len(pe.Roles[0].PermissionsResults) == 0 {
+				t.Fatalf("Expected base role with its permissions. Got %v", pe.Roles)
+			}
+			results := []string{}
+			for _, ev := range pe.Roles[0].PermissionsResults {
+				results = append(results, ev.Permission)
+			}
+			if !reflect.DeepEqual(results, testCase.wantResults) {
+				t.Errorf("Expected permissions %v. Got %v", testCase.wantResults, results)
+			}
+		})
+	}
+}
+
+func TestPermissionsHasHandlerConditionsIP(t *testing.T) {
+	beforeEachPermissionsHandlers(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	p, err := models.BuildPermission("Service::RL::Deploy::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p.Condition = `inCIDR(request.ip, "10.8.0.0/16")`
+	if err := helpers.GetServiceAccountsUseCase(t).CreatePermission(sa.ID, &p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	app := helpers.GetApp(t)
+	trustedCallers, err := api.ParseIPNets([]string{"192.0.2.10"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	app.SetTrustedCallers(trustedCallers)
+	router := app.GetRouter()
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		query      string
+		wantStatus int
+	}{
+		{"FromClientAddress", "10.8.1.2:4242", "", http.StatusOK},
+		{"FromOtherClientAddress", "203.0.113.1:4242", "", http.StatusForbidden},
+		{"UntrustedCallerTellingIP", "203.0.113.1:4242", "&ip=10.8.1.2", http.StatusUnprocessableEntity},
+		{"UntrustedCallerTellingContext", "10.8.1.2:4242", "&context.ticket=OPS-1", http.StatusUnprocessableEntity},
+		{"TrustedCallerTellingIP", "192.0.2.10:4242", "&ip=10.8.1.2", http.StatusOK},
+		{"TrustedCallerOwnAddress", "192.0.2.10:4242", "", http.StatusForbidden},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(
+				"GET", "/permissions/has?permission=Service::RL::Deploy::x"+tt.query, nil,
+			)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("Authorization", fmt.Sprintf(
+				"KeyPair %s:%s", sa.KeyID, sa.KeySecret,
+			))
+			if rec := helpers.DoRequest(t, req, router); rec.Code != tt.wantStatus {
+				t.Errorf("Expected HTTP status %d. Got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+	"gopkg.in/yaml.v2"
+)
+
+// PolicyApplyResponse tells the changes applying a policy made, or would
+// make if DryRun
+type PolicyApplyResponse struct {
+	DryRun  bool                  `json:"dryRun"`
+	Changes []models.PolicyChange `json:"changes"`
+}
+
+// ownsPolicy tells if the requester owns every permission roles of policy
+// grant, along with those of stored roles they include, as editing each of
+// those roles would require
+func ownsPolicy(
+	r *http.Request, sasUC usecases.ServiceAccounts,
+	policiesUC usecases.Policies, policy *models.Policy,
+) (bool, error) {
+	ps, err := policy.Permissions()
+	if err != nil {
+		return false, err
+	}
+	saID, _ := getServiceAccountID(r.Context())
+	has, err := sasUC.WithContext(r.Context()).HasAllOwnerPermissions(saID, ps)
+	if err != nil || !has {
+		return false, err
+	}
+	rolesIDs, err := policiesUC.WithContext(r.Context()).
+		IncludedRolesIDs(policy)
+	if err != nil {
+		return false, err
+	}
+	return sasUC.WithContext(r.Context()).
+		HasAllOwnerRolesPermissions(saID, rolesIDs)
+}
+
+// writePolicyError responds to errors resolving what a policy refers to,
+// telling if err was one of them
+func writePolicyError(w http.ResponseWriter, err error) bool {
+	switch e := err.(type) {
+	case *errors.AmbiguousRoleNameError:
+		WriteBytes(w, e.StatusCode(), e.Serialize())
+		return true
+	case *errors.RoleInclusionCycleError:
+		WriteBytes(w, e.StatusCode(), e.Serialize())
+		return true
+	case *errors.EntityNotFoundError:
+		WriteBytes(w, http.StatusUnprocessableEntity, e.Serialize())
+		return true
+	}
+	return false
+}
+
+func policiesApplyHandler(
+	sasUC usecases.ServiceAccounts, policiesUC usecases.Policies,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		body, err := ioutil.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			l.WithError(err).Error("policiesApplyHandler ioutil.ReadAll failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		policy := &models.Policy{}
+		if err := yaml.UnmarshalStrict(body, policy); err != nil {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		v := policy.Validate()
+		if !v.Valid() {
+			WriteBytes(w, http.StatusUnprocessableEntity, v.Errors())
+			return
+		}
+		owns, err := ownsPolicy(r, sasUC, policiesUC, policy)
+		if writePolicyError(w, err) {
+			return
+		}
+		if err != nil {
+			l.WithError(err).Error("policiesApplyHandler ownsPolicy failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !owns {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		dryRun := r.URL.Query().Get("dryRun") == "true"
+		apply := policiesUC.WithContext(r.Context()).Apply
+		if dryRun {
+			apply = policiesUC.WithContext(r.Context()).Plan
+		}
+		changes, err := apply(policy)
+		if writePolicyError(w, err) {
+			return
+		}
+		if err != nil {
+			l.WithError(err).Error("policiesApplyHandler policiesUC.Apply failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, PolicyApplyResponse{
+			DryRun: dryRun, Changes: changes,
+		})
+	}
+}
+
+func policiesExportHandler(
+	policiesUC usecases.Policies,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		policy, err := policiesUC.WithContext(r.Context()).Export()
+		if err != nil {
+			l.WithError(err).Error("policiesExportHandler policiesUC.Export failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		bts, err := yaml.Marshal(policy)
+		if err != nil {
+			l.WithError(err).Error("policiesExportHandler yaml.Marshal failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bts)
+	}
+}
@@ -0,0 +1,140 @@
+// +build integration
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/api"
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPoliciesApplyHandler(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "any", "any@test.com", models.AuthenticationTypes.KeyPair,
+		"Will.IAM::RL::ApplyPolicy::*",
+	)
+	app := helpers.GetApp(t)
+	policy := `services:
+- name: Some Service
+  permissionName: SomeService
+  amUrl: http://localhost:3333/am
+roles:
+- name: operators
+  permissions:
+  - SomeService::RL::Deploy::*
+  serviceAccounts:
+  - any@test.com
+`
+
+	testCases := []struct {
+		name        string
+		sa          *models.ServiceAccount
+		query       string
+		body        string
+		wantStatus  int
+		wantChanges int
+	}{
+		{
+			name:       "WithoutOwningPermissions",
+			sa:         sa,
+			body:       policy,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "InvalidYAML",
+			sa:         rootSA,
+			body:       "roles: [",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "UnknownField",
+			sa:         rootSA,
+			body:       "roles:\n- name: operators\n  members: []\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "InvalidPolicy",
+			sa:         rootSA,
+			body:       "roles:\n- permissions:\n  - SomeService::RX::Deploy::*\n",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "MissingServiceAccount",
+			sa:         rootSA,
+			body:       "roles:\n- name: operators\n  serviceAccounts:\n  - nobody@test.com\n",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "DryRun",
+			sa:          rootSA,
+			query:       "?dryRun=true",
+			body:        policy,
+			wantStatus:  http.StatusOK,
+			wantChanges: 4,
+		},
+		{
+			name:        "Apply",
+			sa:          rootSA,
+			body:        policy,
+			wantStatus:  http.StatusOK,
+			wantChanges: 4,
+		},
+		{
+			name:       "ApplyAgain",
+			sa:         rootSA,
+			body:       policy,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(
+				"POST", "/policies/apply"+tt.query, bytes.NewBufferString(tt.body),
+			)
+			req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", tt.sa.KeyID, tt.sa.KeySecret))
+			resp := helpers.DoRequest(t, req, app.GetRouter())
+			if resp.Code != tt.wantStatus {
+				t.Fatalf("Code = %v, want %v. Body: %s", resp.Code, tt.wantStatus, resp.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			body := api.PolicyApplyResponse{}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Unmarshal() returned error = %v", err)
+			}
+			if body.DryRun != (tt.query != "") {
+				t.Errorf("DryRun = %v, want %v", body.DryRun, tt.query != "")
+			}
+			if len(body.Changes) != tt.wantChanges {
+				t.Errorf("len(Changes) = %v, want %v. Changes: %v", len(body.Changes), tt.wantChanges, body.Changes)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest("GET", "/policies/export", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret))
+	resp := helpers.DoRequest(t, req, app.GetRouter())
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Code = %v, want %v", resp.Code, http.StatusOK)
+	}
+	exported := models.Policy{}
+	if err := yaml.UnmarshalStrict(resp.Body.Bytes(), &exported); err != nil {
+		t.Fatalf("UnmarshalStrict() returned error = %v", err)
+	}
+	if len(exported.Services) != 1 || exported.Services[0].PermissionName != "SomeService" {
+		t.Errorf("Services = %v, want SomeService", exported.Services)
+	}
+	if len(exported.Roles) != 1 || exported.Roles[0].Name != "operators" {
+		t.Errorf("Roles = %v, want operators", exported.Roles)
+	}
+}
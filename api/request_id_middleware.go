@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+const requestIDHeader = "x-request-id"
+
+// requestIDMiddleware exposes the request ID logged by middleware.Logging
+// to clients and to audited usecases, so audit events match log lines
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestID string
+		if e, ok := middleware.GetLogger(r.Context()).(*logrus.Entry); ok {
+			requestID, _ = e.Data["requestID"].(string)
+		}
+		if requestID == "" {
+			requestID = uuid.Must(uuid.NewV4()).String()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := usecases.WithAuditRequestID(r.Context(), requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/go-pg/pg"
 	"github.com/gorilla/mux"
 	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
@@ -30,6 +31,7 @@ func rolesCreatePermissionHandler(
 			return
 		}
 		sameP.OwnershipLevel = models.OwnershipLevels.Owner
+		sameP.Deny = false
 
 		saID, _ := getServiceAccountID(r.Context())
 		has, err := sasUC.WithContext(r.Context()).
@@ -81,6 +83,9 @@ func rolesCreateHandler(
 			return
 		}
 		err = rsUC.WithContext(r.Context()).Create(rwn)
+		if writeRoleInclusionsError(w, err) {
+			return
+		}
 		if err != nil {
 			l.Error(err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -112,7 +117,11 @@ func rolesUpdateHandler(
 			return
 		}
 		rwn.ID = mux.Vars(r)["id"]
-		if err = rsUC.WithContext(r.Context()).Update(rwn); err != nil {
+		err = rsUC.WithContext(r.Context()).Update(rwn)
+		if writeRoleInclusionsError(w, err) {
+			return
+		}
+		if err != nil {
 			l.WithError(err).Error("rolesUpdateHandler rsUC.Update")
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -144,6 +153,12 @@ func processRoleWithNestedFromReq(
 		if alias, ok := rwn.PermissionsAliases[rwn.PermissionsStrings[i]]; ok {
 			rwn.Permissions[i].Alias = alias
 		}
+		if expiresAt, ok := rwn.PermissionsExpiresAt[rwn.PermissionsStrings[i]]; ok {
+			rwn.Permissions[i].ExpiresAt = pg.NullTime{Time: expiresAt}
+		}
+		if condition, ok := rwn.PermissionsConditions[rwn.PermissionsStrings[i]]; ok {
+			rwn.Permissions[i].Condition = condition
+		}
 	}
 	has, err := sasUC.WithContext(r.Context()).
 		HasAllOwnerPermissions(saID, rwn.Permissions)
@@ -153,9 +168,32 @@ func processRoleWithNestedFromReq(
 	if !has {
 		return nil, errors.NewUserDoesntHaveAllPermissionsError()
 	}
+	// including roles grants their permissions too
+	has, err = sasUC.WithContext(r.Context()).
+		HasAllOwnerRolesPermissions(saID, rwn.IncludedRolesIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, errors.NewUserDoesntHaveAllPermissionsError()
+	}
 	return rwn, nil
 }
 
+// writeRoleInclusionsError responds to errors including roles, telling if
+// err was one of them
+func writeRoleInclusionsError(w http.ResponseWriter, err error) bool {
+	switch e := err.(type) {
+	case *errors.RoleInclusionCycleError:
+		WriteBytes(w, e.StatusCode(), e.Serialize())
+		return true
+	case *errors.EntityNotFoundError:
+		WriteBytes(w, http.StatusUnprocessableEntity, e.Serialize())
+		return true
+	}
+	return false
+}
+
 func rolesListHandler(
 	rsUC usecases.Roles,
 ) func(http.ResponseWriter, *http.Request) {
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+const (
+	scimContentType     = "application/scim+json"
+	scimBasePath        = "/scim/v2"
+	scimDefaultCount    = 100
+	scimMaxResults      = 200
+	scimServiceProvider = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimResourceType    = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+func writeSCIM(w http.ResponseWriter, status int, i interface{}) {
+	bts, err := json.Marshal(i)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	w.Write(bts)
+}
+
+// writeSCIMError responds with err if it's a *models.SCIMError and with 500
+// otherwise
+func writeSCIMError(w http.ResponseWriter, r *http.Request, err error) {
+	if scimErr, ok := err.(*models.SCIMError); ok {
+		writeSCIM(w, scimErr.StatusCode(), scimErr)
+		return
+	}
+	middleware.GetLogger(r.Context()).Error(err)
+	writeSCIM(w, http.StatusInternalServerError, models.NewSCIMError(
+		http.StatusInternalServerError, "", "internal server error",
+	))
+}
+
+func unmarshalSCIMBodyTo(r *http.Request, i interface{}) error {
+	if err := unmarshalBodyTo(r, i); err != nil {
+		return models.NewSCIMError(
+			http.StatusBadRequest, "invalidSyntax", err.Error(),
+		)
+	}
+	return nil
+}
+
+// buildSCIMListOptions maps SCIM 1-based startIndex and count to pages of
+// count resources, startIndex is rounded down to the start of its page
+func buildSCIMListOptions(
+	r *http.Request,
+) (*repositories.ListOptions, *models.SCIMFilter, error) {
+	qs := r.URL.Query()
+	startIndex, count := 1, scimDefaultCount
+	if str := qs.Get("startIndex"); str != "" {
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, nil, models.NewSCIMError(
+				http.StatusBadRequest, "invalidValue", "startIndex must be an integer",
+			)
+		}
+		if i > 1 {
+			startIndex = i
+		}
+	}
+	if str := qs.Get("count"); str != "" {
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, nil, models.NewSCIMError(
+				http.StatusBadRequest, "invalidValue", "count must be an integer",
+			)
+		}
+		count = i
+	}
+	if count > scimMaxResults {
+		count = scimMaxResults
+	}
+	if count < 1 {
+		count = 1
+	}
+	filter, err := models.ParseSCIMFilter(qs.Get("filter"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &repositories.ListOptions{
+		PageSize: count,
+		Page:     (startIndex - 1) / count,
+	}, filter, nil
+}
+
+func buildSCIMListResponse(
+	lo *repositories.ListOptions, resources interface{}, length int, total int64,
+) *models.SCIMListResponse {
+	return &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   lo.Offset() + 1,
+		ItemsPerPage: length,
+		Resources:    resources,
+	}
+}
+
+func scimUsersListHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lo, filter, err := buildSCIMListOptions(r)
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		users, total, err := scimUC.WithContext(r.Context()).ListUsers(filter, lo)
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, buildSCIMListResponse(
+			lo, users, len(users), total,
+		))
+	}
+}
+
+func scimUsersGetHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, err := scimUC.WithContext(r.Context()).GetUser(mux.Vars(r)["id"])
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, u)
+	}
+}
+
+func scimUsersCreateHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// active is optional and users are active by default
+		u := &models.SCIMUser{Active: true}
+		if err := unmarshalSCIMBodyTo(r, u); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		if err := scimUC.WithContext(r.Context()).CreateUser(u); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		w.Header().Set("Location", u.Meta.Location)
+		writeSCIM(w, http.StatusCreated, u)
+	}
+}
+
+func scimUsersReplaceHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// active is optional and users are active by default
+		u := &models.SCIMUser{Active: true}
+		if err := unmarshalSCIMBodyTo(r, u); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		u.ID = mux.Vars(r)["id"]
+		if err := scimUC.WithContext(r.Context()).ReplaceUser(u); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, u)
+	}
+}
+
+func scimUsersPatchHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &models.SCIMPatchRequest{}
+		if err := unmarshalSCIMBodyTo(r, req); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		u, err := scimUC.WithContext(r.Context()).
+			PatchUser(mux.Vars(r)["id"], req.Operations)
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, u)
+	}
+}
+
+func scimUsersDeleteHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := scimUC.WithContext(r.Context()).DeleteUser(mux.Vars(r)["id"])
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func scimGroupsListHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lo, filter, err := buildSCIMListOptions(r)
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		groups, total, err := scimUC.WithContext(r.Context()).ListGroups(filter, lo)
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, buildSCIMListResponse(
+			lo, groups, len(groups), total,
+		))
+	}
+}
+
+func scimGroupsGetHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, err := scimUC.WithContext(r.Context()).GetGroup(mux.Vars(r)["id"])
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, g)
+	}
+}
+
+func scimGroupsCreateHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g := &models.SCIMGroup{}
+		if err := unmarshalSCIMBodyTo(r, g); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		if err := scimUC.WithContext(r.Context()).CreateGroup(g); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		w.Header().Set("Location", g.Meta.Location)
+		writeSCIM(w, http.StatusCreated, g)
+	}
+}
+
+func scimGroupsReplaceHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g := &models.SCIMGroup{}
+		if err := unmarshalSCIMBodyTo(r, g); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		g.ID = mux.Vars(r)["id"]
+		if err := scimUC.WithContext(r.Context()).ReplaceGroup(g); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, g)
+	}
+}
+
+func scimGroupsPatchHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &models.SCIMPatchRequest{}
+		if err := unmarshalSCIMBodyTo(r, req); err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		g, err := scimUC.WithContext(r.Context()).
+			PatchGroup(mux.Vars(r)["id"], req.Operations)
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, g)
+	}
+}
+
+func scimGroupsDeleteHandler(
+	scimUC usecases.SCIM,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := scimUC.WithContext(r.Context()).DeleteGroup(mux.Vars(r)["id"])
+		if err != nil {
+			writeSCIMError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// scimServiceProviderConfigHandler tells identity providers which SCIM
+// features are supported
+func scimServiceProviderConfigHandler(w http.ResponseWriter, r *http.Request) {
+	supported := func(s bool) map[string]interface{} {
+		return map[string]interface{}{"supported": s}
+	}
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimServiceProvider},
+		"patch":          supported(true),
+		"bulk":           supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"changePassword": supported(false),
+		"filter": map[string]interface{}{
+			"supported":  true,
+			"maxResults": scimMaxResults,
+		},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Will.IAM access token of a service account",
+			"primary":     true,
+		}},
+		"meta": models.SCIMMeta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     scimBasePath + "/ServiceProviderConfig",
+		},
+	})
+}
+
+func scimResourceTypesHandler(w http.ResponseWriter, r *http.Request) {
+	resourceType := func(name, endpoint, schema string) map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":  []string{scimResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta": models.SCIMMeta{
+				ResourceType: "ResourceType",
+				Location:     scimBasePath + "/ResourceTypes/" + name,
+			},
+		}
+	}
+	resources := []map[string]interface{}{
+		resourceType("User", "/Users", models.SCIMUserSchema),
+		resourceType("Group", "/Groups", models.SCIMGroupSchema),
+	}
+	writeSCIM(w, http.StatusOK, &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMListResponseSchema},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
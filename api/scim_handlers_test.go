@@ -0,0 +1,164 @@
+// +build integration
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/usecases"
+
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func TestSCIMHandlers(t *testing.T) {
+	beforeEachServiceAccountsHandlers(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(
+		t, "rootSAKeyPair", "rootSAKeyPair@test.com",
+	)
+	router := helpers.GetApp(t).GetRouter()
+	do := func(method, path, body string, i interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, "/scim/v2"+path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", fmt.Sprintf(
+			"KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret,
+		))
+		rec := helpers.DoRequest(t, req, router)
+		if i != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), i); err != nil {
+				t.Fatalf("Unexpected error unmarshalling %s: %v", rec.Body.String(), err)
+			}
+		}
+		return rec.Code
+	}
+
+	user := &models.SCIMUser{}
+	status := do(http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "john@test.com",
+		"name": {"givenName": "John", "familyName": "Doe"}
+	}`, user)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status 201. Got %d", status)
+	}
+	if user.ID == "" || !user.Active || user.DisplayName != "John Doe" {
+		t.Errorf("Unexpected user %#v", user)
+	}
+	status = do(http.MethodPost, "/Users", `{"userName": "john@test.com"}`, nil)
+	if status != http.StatusConflict {
+		t.Errorf("Expected status 409 for duplicated userName. Got %d", status)
+	}
+
+	list := &struct {
+		TotalResults int64             `json:"totalResults"`
+		Resources    []models.SCIMUser `json:"Resources"`
+	}{}
+	filter := url.QueryEscape(`userName eq "john@test.com"`)
+	if status := do(http.MethodGet, "/Users?filter="+filter, "", list); status != http.StatusOK {
+		t.Fatalf("Expected status 200. Got %d", status)
+	}
+	if list.TotalResults != 1 || list.Resources[0].ID != user.ID {
+		t.Errorf("Expected to find john@test.com. Got %#v", list)
+	}
+
+	status = do(http.MethodPatch, "/Users/"+user.ID, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "value": {"active": false}}]
+	}`, user)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200. Got %d", status)
+	}
+	sa, err := helpers.GetRepo(t).ServiceAccounts.Get(user.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user.Active || sa.Status != models.ServiceAccountStatuses.Disabled {
+		t.Errorf("Expected user to be disabled. Got %s", sa.Status)
+	}
+
+	group := &models.SCIMGroup{}
+	status = do(http.MethodPost, "/Groups", fmt.Sprintf(`{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "devs",
+		"members": [{"value": "%s"}]
+	}`, user.ID), group)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status 201. Got %d", status)
+	}
+	if ids := group.MembersIDs(); len(ids) != 1 || ids[0] != user.ID {
+		t.Errorf("Expected group members [%s]. Got %v", user.ID, ids)
+	}
+	// bindings not made through SCIM are kept
+	if err := helpers.GetRepo(t).Roles.Bind(&models.RoleBinding{
+		RoleID: group.ID, ServiceAccountID: rootSA.ID,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status = do(http.MethodPatch, "/Groups/"+group.ID, fmt.Sprintf(`{
+		"Operations": [{"op": "remove", "path": "members[value eq \"%s\"]"}]
+	}`, user.ID), group)
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200. Got %d", status)
+	}
+	rbs, err := helpers.GetRepo(t).Roles.Bindings(group.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(group.Members) != 1 || len(rbs) != 1 || rbs[0].ServiceAccountID != rootSA.ID {
+		t.Errorf("Expected group to keep only the binding made by hand. Got %v", rbs)
+	}
+
+	// roles not created through SCIM aren't groups
+	admins := &usecases.RoleWithNested{Name: "admins"}
+	if err := helpers.GetRolesUseCase(t).Create(admins); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	groups := &models.SCIMListResponse{}
+	if status := do(http.MethodGet, "/Groups", "", groups); status != http.StatusOK {
+		t.Fatalf("Expected status 200. Got %d", status)
+	}
+	if groups.TotalResults != 1 {
+		t.Errorf("Expected only SCIM groups to be listed. Got %d", groups.TotalResults)
+	}
+
+	type testCase struct {
+		method string
+		path   string
+		status int
+	}
+	testCases := []testCase{
+		{http.MethodGet, "/Groups/" + admins.ID, http.StatusNotFound},
+		{http.MethodDelete, "/Groups/" + admins.ID, http.StatusNotFound},
+		{http.MethodDelete, "/Groups/" + group.ID, http.StatusNoContent},
+		{http.MethodGet, "/Groups/" + group.ID, http.StatusNotFound},
+		{http.MethodDelete, "/Users/" + user.ID, http.StatusNoContent},
+		{http.MethodGet, "/Users/" + user.ID, http.StatusNotFound},
+		{http.MethodGet, "/Users?filter=" + url.QueryEscape(`title eq "x"`), http.StatusBadRequest},
+		{http.MethodGet, "/ServiceProviderConfig", http.StatusOK},
+	}
+	for _, tt := range testCases {
+		if status := do(tt.method, tt.path, "", nil); status != tt.status {
+			t.Errorf("Expected status %d for %s %s. Got %d", tt.status, tt.method, tt.path, status)
+		}
+	}
+}
+
+func TestSCIMHandlersRequireProvisionPermission(t *testing.T) {
+	beforeEachServiceAccountsHandlers(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	req, _ := http.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"KeyPair %s:%s", sa.KeyID, sa.KeySecret,
+	))
+	rec := helpers.DoRequest(t, req, helpers.GetApp(t).GetRouter())
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403. Got %d", rec.Code)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/go-pg/pg"
 	"github.com/gorilla/mux"
 	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
@@ -132,6 +133,12 @@ func processServiceAccountWithNestedFromReq(
 		if alias, ok := sawn.PermissionsAliases[sawn.PermissionsStrings[i]]; ok {
 			sawn.Permissions[i].Alias = alias
 		}
+		if expiresAt, ok := sawn.PermissionsExpiresAt[sawn.PermissionsStrings[i]]; ok {
+			sawn.Permissions[i].ExpiresAt = pg.NullTime{Time: expiresAt}
+		}
+		if condition, ok := sawn.PermissionsConditions[sawn.PermissionsStrings[i]]; ok {
+			sawn.Permissions[i].Condition = condition
+		}
 	}
 	has, err := uc.HasAllOwnerPermissions(saID, sawn.Permissions)
 	if err != nil {
@@ -269,3 +276,179 @@ func serviceAccountsSearchHandler(
 		WriteJSON(w, 200, ret)
 	}
 }
+
+func serviceAccountsListKeysHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		ks, err := sasUC.WithContext(r.Context()).ListKeys(mux.Vars(r)["id"])
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("serviceAccountsListKeysHandler sasUC.ListKeys failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, ks)
+	}
+}
+
+func serviceAccountsCreateKeyHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		k, err := sasUC.WithContext(r.Context()).CreateKey(mux.Vars(r)["id"])
+		if err != nil {
+			switch err.(type) {
+			case *errors.EntityNotFoundError:
+				w.WriteHeader(http.StatusNotFound)
+			case *errors.NotKeyPairServiceAccountError:
+				WriteJSON(
+					w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()},
+				)
+			default:
+				l.WithError(err).Error("serviceAccountsCreateKeyHandler sasUC.CreateKey failed")
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+		WriteJSON(w, http.StatusCreated, k)
+	}
+}
+
+func serviceAccountsDeleteKeyHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		vars := mux.Vars(r)
+		if err := sasUC.WithContext(r.Context()).
+			DeleteKey(vars["id"], vars["keyId"]); err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).Error("serviceAccountsDeleteKeyHandler sasUC.DeleteKey failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func serviceAccountsListSessionsHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		ss, err := sasUC.WithContext(r.Context()).ListSessions(mux.Vars(r)["id"])
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).
+				Error("serviceAccountsListSessionsHandler sasUC.ListSessions failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, http.StatusOK, ss)
+	}
+}
+
+func serviceAccountsRevokeSessionsHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		vars := mux.Vars(r)
+		var err error
+		if sessionID, ok := vars["sessionId"]; ok {
+			err = sasUC.WithContext(r.Context()).RevokeSession(vars["id"], sessionID)
+		} else {
+			err = sasUC.WithContext(r.Context()).RevokeSessions(vars["id"])
+		}
+		if err != nil {
+			if _, ok := err.(*errors.EntityNotFoundError); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			l.WithError(err).
+				Error("serviceAccountsRevokeSessionsHandler revoke failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func serviceAccountsDisableHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return serviceAccountsLifecycleHandler(
+		"serviceAccountsDisableHandler",
+		func(r *http.Request, id string) error {
+			return sasUC.WithContext(r.Context()).Disable(id)
+		},
+	)
+}
+
+func serviceAccountsDeleteHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return serviceAccountsLifecycleHandler(
+		"serviceAccountsDeleteHandler",
+		func(r *http.Request, id string) error {
+			return sasUC.WithContext(r.Context()).Delete(id)
+		},
+	)
+}
+
+func serviceAccountsReactivateHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return serviceAccountsLifecycleHandler(
+		"serviceAccountsReactivateHandler",
+		func(r *http.Request, id string) error {
+			return sasUC.WithContext(r.Context()).Reactivate(id)
+		},
+	)
+}
+
+func serviceAccountsOffboardHandler(
+	sasUC usecases.ServiceAccounts,
+) func(http.ResponseWriter, *http.Request) {
+	return serviceAccountsLifecycleHandler(
+		"serviceAccountsOffboardHandler",
+		func(r *http.Request, id string) error {
+			return sasUC.WithContext(r.Context()).Offboard(id)
+		},
+	)
+}
+
+// serviceAccountsLifecycleHandler responds change of the service account
+// status, named name in logs
+func serviceAccountsLifecycleHandler(
+	name string, change func(*http.Request, string) error,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := middleware.GetLogger(r.Context())
+		if err := change(r, mux.Vars(r)["id"]); err != nil {
+			switch err.(type) {
+			case *errors.EntityNotFoundError:
+				w.WriteHeader(http.StatusNotFound)
+			case *errors.InactiveServiceAccountError:
+				WriteJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+			default:
+				l.WithError(err).Errorf("%s failed", name)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
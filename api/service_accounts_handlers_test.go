@@ -7,9 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
+	"github.com/topfreegames/Will.IAM/api"
 	"github.com/topfreegames/Will.IAM/models"
 
 	helpers "github.com/topfreegames/Will.IAM/testing"
@@ -242,3 +244,214 @@ func TestServiceAccountListWithPermissionHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceAccountKeysHandlers(t *testing.T) {
+	beforeEachServiceAccountsHandlers(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(
+		t, "rootSAKeyPair", "rootSAKeyPair@test.com",
+	)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	router := helpers.GetApp(t).GetRouter()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf(
+			"KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret,
+		))
+		return helpers.DoRequest(t, req, router)
+	}
+	keysPath := fmt.Sprintf("/service_accounts/%s/keys", sa.ID)
+	rec := do(http.MethodPost, keysPath)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d. Got %d", http.StatusCreated, rec.Code)
+	}
+	k := &models.ServiceAccountKey{}
+	json.Unmarshal(rec.Body.Bytes(), k)
+	if k.ID == "" || k.Secret == "" {
+		t.Fatalf("Expected key id and secret. Got %s", rec.Body.String())
+	}
+	rec = do(http.MethodGet, keysPath)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d. Got %d", http.StatusOK, rec.Code)
+	}
+	ks := []map[string]interface{}{}
+	json.Unmarshal(rec.Body.Bytes(), &ks)
+	if len(ks) != 2 {
+		t.Fatalf("Expected 2 keys. Got %s", rec.Body.String())
+	}
+	for _, lk := range ks {
+		if _, ok := lk["secret"]; ok {
+			t.Errorf("Expected listed keys not to have secrets")
+		}
+	}
+	rec = do(http.MethodDelete, fmt.Sprintf("%s/%s", keysPath, sa.KeyID))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d. Got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = do(http.MethodDelete, fmt.Sprintf("%s/%s", keysPath, sa.KeyID))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d. Got %d", http.StatusNotFound, rec.Code)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/sso/auth", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", k.ID, k.Secret))
+	if rec := helpers.DoRequest(t, req, router); rec.Code != http.StatusOK {
+		t.Errorf("Expected new key to authenticate. Got %d", rec.Code)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("KeyPair %s:%s", sa.KeyID, sa.KeySecret))
+	if rec := helpers.DoRequest(t, req, router); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected deleted key to be rejected. Got %d", rec.Code)
+	}
+	oauth2SA := helpers.CreateServiceAccountWithPermissions(
+		t, "oauth2", "oauth2@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	rec = do(http.MethodPost, fmt.Sprintf("/service_accounts/%s/keys", oauth2SA.ID))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d. Got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestServiceAccountSessionsHandlers(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(
+		t, "rootSAKeyPair", "rootSAKeyPair@test.com",
+	)
+	app := helpers.GetApp(t)
+	employees, contractors := setupLoginProviders(t, app)
+	defer employees.Close()
+	defer contractors.Close()
+	trustedProxies, err := api.ParseIPNets([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	app.SetTrustedProxies(trustedProxies)
+	router := app.GetRouter()
+	login := func(userAgent string) string {
+		code := employees.Code(map[string]interface{}{"email": "leaver@company.com"})
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf(
+			"/sso/auth/done?provider=employees&state=http://referer&code=%s", code,
+		), nil)
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = "10.0.0.3:4242"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1, 10.0.0.2")
+		rec := helpers.DoRequest(t, req, router)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("Expected status 303. Got %d", rec.Code)
+		}
+		location, _ := url.Parse(rec.Header().Get("Location"))
+		return location.Query().Get("accessToken")
+	}
+	authenticates := func(accessToken string) bool {
+		req, _ := http.NewRequest(http.MethodGet, "/sso/auth", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+		return helpers.DoRequest(t, req, router).Code == http.StatusOK
+	}
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf(
+			"KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret,
+		))
+		return helpers.DoRequest(t, req, router)
+	}
+	laptop := login("laptop")
+	phone := login("phone")
+	sa, err := helpers.GetRepo(t).ServiceAccounts.ForEmail("leaver@company.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sessionsPath := fmt.Sprintf("/service_accounts/%s/sessions", sa.ID)
+	rec := do(http.MethodGet, sessionsPath)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d. Got %d", http.StatusOK, rec.Code)
+	}
+	ss := []models.Session{}
+	json.Unmarshal(rec.Body.Bytes(), &ss)
+	if len(ss) != 2 {
+		t.Fatalf("Expected 2 sessions. Got %s", rec.Body.String())
+	}
+	var laptopSession models.Session
+	for _, s := range ss {
+		if s.IP != "198.51.100.1" || s.Provider != "employees" || s.CreatedAt.IsZero() {
+			t.Errorf("Unexpected session %#v", s)
+		}
+		if s.UserAgent == "laptop" {
+			laptopSession = s
+		}
+	}
+	rec = do(http.MethodDelete, fmt.Sprintf("%s/%s", sessionsPath, laptopSession.ID))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d. Got %d", http.StatusNoContent, rec.Code)
+	}
+	if authenticates(laptop) {
+		t.Errorf("Expected revoked session token to be rejected")
+	}
+	if !authenticates(phone) {
+		t.Errorf("Expected other session token to authenticate")
+	}
+	rec = do(http.MethodDelete, fmt.Sprintf("%s/%s", sessionsPath, laptopSession.ID))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d. Got %d", http.StatusNotFound, rec.Code)
+	}
+	rec = do(http.MethodDelete, sessionsPath)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d. Got %d", http.StatusNoContent, rec.Code)
+	}
+	if authenticates(phone) {
+		t.Errorf("Expected every token to be revoked")
+	}
+	rec = do(http.MethodGet, sessionsPath)
+	ss = []models.Session{}
+	json.Unmarshal(rec.Body.Bytes(), &ss)
+	if len(ss) != 0 {
+		t.Errorf("Expected no sessions. Got %s", rec.Body.String())
+	}
+}
+
+func TestServiceAccountLifecycleHandlers(t *testing.T) {
+	beforeEachServiceAccountsHandlers(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(
+		t, "rootSAKeyPair", "rootSAKeyPair@test.com",
+	)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	router := helpers.GetApp(t).GetRouter()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf(
+			"KeyPair %s:%s", rootSA.KeyID, rootSA.KeySecret,
+		))
+		return helpers.DoRequest(t, req, router)
+	}
+	authenticate := func() int {
+		req, _ := http.NewRequest(http.MethodGet, "/sso/auth", nil)
+		req.Header.Set("Authorization", fmt.Sprintf(
+			"KeyPair %s:%s", sa.KeyID, sa.KeySecret,
+		))
+		return helpers.DoRequest(t, req, router).Code
+	}
+	saPath := fmt.Sprintf("/service_accounts/%s", sa.ID)
+	type testCase struct {
+		method     string
+		path       string
+		status     int
+		authStatus int
+	}
+	testCases := []testCase{
+		{http.MethodPost, saPath + "/disable", http.StatusNoContent, http.StatusForbidden},
+		{http.MethodPost, saPath + "/reactivate", http.StatusNoContent, http.StatusOK},
+		{http.MethodDelete, saPath, http.StatusNoContent, http.StatusForbidden},
+		{http.MethodPost, saPath + "/disable", http.StatusConflict, http.StatusForbidden},
+		{http.MethodPost, saPath + "/reactivate", http.StatusNoContent, http.StatusOK},
+		{http.MethodPost, saPath + "/offboard", http.StatusNoContent, http.StatusForbidden},
+		{http.MethodPost, "/service_accounts/00000000-0000-0000-0000-000000000000/offboard", http.StatusNotFound, http.StatusForbidden},
+	}
+	for _, tt := range testCases {
+		if rec := do(tt.method, tt.path); rec.Code != tt.status {
+			t.Errorf("Expected status %d for %s %s. Got %d", tt.status, tt.method, tt.path, rec.Code)
+		}
+		if status := authenticate(); status != tt.authStatus {
+			t.Errorf("Expected auth status %d after %s %s. Got %d", tt.authStatus, tt.method, tt.path, status)
+		}
+	}
+}
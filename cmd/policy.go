@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/Will.IAM/constants"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+	"github.com/topfreegames/Will.IAM/usecases"
+	"github.com/topfreegames/Will.IAM/utils"
+	"gopkg.in/yaml.v2"
+)
+
+var policyFile string
+var policyOutput string
+var policyActor string
+var policyDryRun bool
+
+// policyCmd represents the policy command
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "manages access as code",
+	Long: `applies and exports YAML policies declaring services, roles,
+permissions, included roles and bindings.`,
+}
+
+// policyApplyCmd represents the policy apply command
+var policyApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "applies a policy",
+	Long: `diffs a policy against what's stored and applies the changes in a
+single transaction, or only prints them with --dry-run.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		constants.Set(config)
+		log := utils.GetLogger("", 0, verbose, json)
+		policy, err := readPolicy(policyFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		if v := policy.Validate(); !v.Valid() {
+			log.Fatal(v.Error().Error())
+		}
+		repo, err := newPolicyRepo()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		ctx := context.Background()
+		if policyActor != "" {
+			sa, err := repo.ServiceAccounts.ForEmail(policyActor)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			ctx = usecases.WithAuditActor(ctx, sa.ID)
+		}
+		uc := usecases.NewPolicies(repo).WithContext(ctx)
+		apply := uc.Apply
+		if policyDryRun {
+			apply = uc.Plan
+		}
+		changes, err := apply(policy)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		for _, c := range changes {
+			fmt.Println(c.String())
+		}
+		if len(changes) == 0 {
+			fmt.Println("no changes")
+		} else if policyDryRun {
+			fmt.Println("dry run, no changes applied")
+		}
+	},
+}
+
+// policyExportCmd represents the policy export command
+var policyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "exports stored access as a policy",
+	Long:  `exports every service and role stored as a YAML policy.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		constants.Set(config)
+		log := utils.GetLogger("", 0, verbose, json)
+		repo, err := newPolicyRepo()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		policy, err := usecases.NewPolicies(repo).Export()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		bts, err := yaml.Marshal(policy)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		if policyOutput == "-" {
+			os.Stdout.Write(bts)
+			return
+		}
+		if err := ioutil.WriteFile(policyOutput, bts, 0644); err != nil {
+			log.Fatal(err.Error())
+		}
+	},
+}
+
+// readPolicy reads a policy from path, or from stdin if path is -
+func readPolicy(path string) (*models.Policy, error) {
+	var bts []byte
+	var err error
+	if path == "-" {
+		bts, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		bts, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy := &models.Policy{}
+	if err := yaml.UnmarshalStrict(bts, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func newPolicyRepo() (*repositories.All, error) {
+	storage := repositories.NewStorage()
+	if err := storage.ConfigurePG(config); err != nil {
+		return nil, err
+	}
+	return repositories.New(storage), nil
+}
+
+func init() {
+	policyApplyCmd.Flags().StringVarP(
+		&policyFile, "file", "f", "", "policy file, - for stdin",
+	)
+	policyApplyCmd.MarkFlagRequired("file")
+	policyApplyCmd.Flags().BoolVar(
+		&policyDryRun, "dry-run", false, "only print the changes",
+	)
+	policyApplyCmd.Flags().StringVar(
+		&policyActor, "actor", "",
+		"email of the service account recorded as making the changes",
+	)
+	policyExportCmd.Flags().StringVarP(
+		&policyOutput, "output", "o", "-", "output file, - for stdout",
+	)
+	policyCmd.AddCommand(policyApplyCmd)
+	policyCmd.AddCommand(policyExportCmd)
+	RootCmd.AddCommand(policyCmd)
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/topfreegames/Will.IAM/api"
 	"github.com/topfreegames/Will.IAM/constants"
@@ -21,15 +23,20 @@ var startAPICmd = &cobra.Command{
 			log.Panic(err.Error())
 		}
 
+		if grpcPort > 0 {
+			go app.ListenAndServeGRPC(fmt.Sprintf("%s:%d", bind, grpcPort))
+		}
 		app.ListenAndServe()
 	},
 }
 
 var bind string
 var port int
+var grpcPort int
 
 func init() {
 	startAPICmd.Flags().StringVarP(&bind, "host", "b", "0.0.0.0", "bind address")
 	startAPICmd.Flags().IntVarP(&port, "port", "p", 4040, "bind port")
+	startAPICmd.Flags().IntVarP(&grpcPort, "grpc-port", "g", 4041, "gRPC bind port, 0 disables it")
 	RootCmd.AddCommand(startAPICmd)
 }
@@ -1,9 +1,15 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/topfreegames/Will.IAM/constants"
+	"github.com/topfreegames/Will.IAM/utils"
+	"github.com/topfreegames/Will.IAM/worker"
 )
 
 // startWorkerCmd represents the start-worker command
@@ -12,7 +18,23 @@ var startWorkerCmd = &cobra.Command{
 	Short: "starts the worker",
 	Long:  `starts the worker.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("start-worker called")
+		constants.Set(config)
+		log := utils.GetLogger("", 0, verbose, json)
+		log.Info("starting Will.IAM worker")
+		w, err := worker.NewWorker(config, log, nil)
+		if err != nil {
+			log.Panic(err.Error())
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			cancel()
+		}()
+
+		w.Start(ctx)
 	},
 }
 
@@ -0,0 +1,110 @@
+// Package conditions implements the small expression language permissions
+// can be conditioned on. A condition is a boolean expression over the time
+// of the check, the IP of the request being authorized and attributes the
+// caller supplies, eg:
+//
+//	weekday("America/Sao_Paulo") in ["mon", "tue", "wed", "thu", "fri"] &&
+//	clock("America/Sao_Paulo") >= "09:00" &&
+//	clock("America/Sao_Paulo") < "18:00" &&
+//	inCIDR(request.ip, "10.8.0.0/16")
+package conditions
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Context is what conditions are evaluated against. A zero Time is taken
+// as now
+type Context struct {
+	Time       time.Time
+	IP         net.IP
+	Attributes map[string]string
+}
+
+// Condition is a parsed condition expression
+type Condition struct {
+	source string
+	root   node
+}
+
+// Parse parses expr, failing if it's malformed or refers to unknown
+// variables, functions or time zones
+func Parse(expr string) (*Condition, error) {
+	p := &parser{}
+	if err := p.lex(expr); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+	}
+	return &Condition{source: expr, root: root}, nil
+}
+
+// Validate tells why expr isn't a valid condition, if it isn't
+func Validate(expr string) error {
+	_, err := Parse(expr)
+	return err
+}
+
+// Eval tells if c holds in ctx. It fails if c refers to request data ctx
+// lacks, like a context attribute that wasn't supplied, or if it compares
+// values of different types
+func (c *Condition) Eval(ctx *Context) (bool, error) {
+	if ctx == nil {
+		ctx = &Context{}
+	}
+	if ctx.Time.IsZero() {
+		cpy := *ctx
+		cpy.Time = time.Now()
+		ctx = &cpy
+	}
+	v, err := c.root.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluates to %s, not a boolean", describe(v))
+	}
+	return b, nil
+}
+
+// String returns the expression c was parsed from
+func (c *Condition) String() string {
+	return c.source
+}
+
+// timeFields are the parts of the time of a check conditions can refer to,
+// either in UTC as time.<field> or in a time zone as <field>("Zone/Name")
+var timeFields = map[string]func(time.Time) interface{}{
+	"hour":   func(t time.Time) interface{} { return int64(t.Hour()) },
+	"minute": func(t time.Time) interface{} { return int64(t.Minute()) },
+	"weekday": func(t time.Time) interface{} {
+		return weekdays[t.Weekday()]
+	},
+	"clock": func(t time.Time) interface{} { return t.Format("15:04") },
+	"date":  func(t time.Time) interface{} { return t.Format("2006-01-02") },
+}
+
+var weekdays = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// describe names the type of v for error messages
+func describe(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,120 @@
+// +build unit
+
+package conditions_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/conditions"
+)
+
+func TestParseRejectsMalformedConditions(t *testing.T) {
+	malformed := []string{
+		"",
+		"time.hour >=",
+		"time.hour >= 9 &&",
+		"(time.hour >= 9",
+		`"unterminated`,
+		"time.century == 21",
+		"request.port == 80",
+		"context. == \"a\"",
+		"now() == 1",
+		`inCIDR(request.ip)`,
+		`inCIDR(request.ip, "10.0.0.0/33")`,
+		`hour("Mars/Olympus_Mons") == 9`,
+		`hour() == 9`,
+		"time.hour >= 9 time.hour < 18",
+		"time.hour # 9",
+	}
+	for _, expr := range malformed {
+		if _, err := conditions.Parse(expr); err == nil {
+			t.Errorf("Expected %q to be malformed", expr)
+		}
+	}
+}
+
+func TestConditionEval(t *testing.T) {
+	// a Wednesday, 14:30 UTC, 11:30 in Sao Paulo
+	at := time.Date(2026, 10, 14, 14, 30, 0, 0, time.UTC)
+	ctx := &conditions.Context{
+		Time:       at,
+		IP:         net.ParseIP("10.8.1.2"),
+		Attributes: map[string]string{"env": "prod", "ticket": "OPS-1"},
+	}
+	type testCase struct {
+		expr  string
+		holds bool
+	}
+	testCases := []testCase{
+		{"time.hour == 14", true},
+		{"time.hour >= 9 && time.hour < 18", true},
+		{"time.hour < 9 || time.hour >= 18", false},
+		{`time.clock >= "14:00" && time.clock < "14:30"`, false},
+		{`time.weekday in ["mon", "tue", "wed", "thu", "fri"]`, true},
+		{`time.date == "2026-10-14"`, true},
+		{`hour("America/Sao_Paulo") == 11`, true},
+		{`weekday("Asia/Tokyo") == "wed"`, true},
+		{`clock("Asia/Tokyo") == "23:30"`, true},
+		{`inCIDR(request.ip, "192.168.0.0/16", "10.8.0.0/16")`, true},
+		{`inCIDR(request.ip, "192.168.0.0/16")`, false},
+		{`request.ip == "10.8.1.2"`, true},
+		{`context.env == "prod" && context.ticket != ""`, true},
+		{`!(context.env in ["prod", "stag"])`, false},
+		{`context.env == "dev" || time.minute == 30`, true},
+		{"true && !false", true},
+		{`context.missing == "x" || true`, false},
+	}
+	for _, tt := range testCases {
+		c, err := conditions.Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", tt.expr, err)
+			continue
+		}
+		holds, err := c.Eval(ctx)
+		if err != nil && tt.holds {
+			t.Errorf("Unexpected error evaluating %q: %s", tt.expr, err)
+			continue
+		}
+		if holds != tt.holds {
+			t.Errorf("Expected %q to be %t. Got %t", tt.expr, tt.holds, holds)
+		}
+	}
+}
+
+func TestConditionEvalFailsWithoutRequestData(t *testing.T) {
+	ctx := &conditions.Context{Time: time.Now()}
+	exprs := []string{
+		`inCIDR(request.ip, "10.0.0.0/8")`,
+		`context.env == "prod"`,
+		`time.hour == "9"`,
+		`time.hour < "9"`,
+		"time.hour",
+		`time.hour in 9`,
+	}
+	for _, expr := range exprs {
+		c, err := conditions.Parse(expr)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", expr, err)
+			continue
+		}
+		if _, err := c.Eval(ctx); err == nil {
+			t.Errorf("Expected evaluating %q to fail", expr)
+		}
+	}
+}
+
+func TestConditionEvalShortCircuits(t *testing.T) {
+	c, err := conditions.Parse(`time.hour >= 0 || context.missing == "x"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	holds, err := c.Eval(&conditions.Context{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !holds {
+		t.Errorf("Expected condition to hold")
+	}
+}
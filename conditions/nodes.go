@@ -0,0 +1,269 @@
+package conditions
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// node is an expression, evaluating to a string, an int64, a bool or a
+// []interface{} of those
+type node interface {
+	eval(*Context) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(*Context) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(ctx *Context) (interface{}, error) {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type requestIPNode struct{}
+
+func (n *requestIPNode) eval(ctx *Context) (interface{}, error) {
+	if ctx.IP == nil {
+		return nil, fmt.Errorf("request.ip wasn't supplied")
+	}
+	return ctx.IP.String(), nil
+}
+
+type attributeNode struct {
+	key string
+}
+
+func (n *attributeNode) eval(ctx *Context) (interface{}, error) {
+	v, ok := ctx.Attributes[n.key]
+	if !ok {
+		return nil, fmt.Errorf("context.%s wasn't supplied", n.key)
+	}
+	return v, nil
+}
+
+type timeNode struct {
+	field    func(time.Time) interface{}
+	location node
+}
+
+func (n *timeNode) eval(ctx *Context) (interface{}, error) {
+	name, err := evalString(n.location, ctx)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	return n.field(ctx.Time.In(loc)), nil
+}
+
+type inCIDRNode struct {
+	ip    node
+	cidrs []node
+}
+
+func (n *inCIDRNode) eval(ctx *Context) (interface{}, error) {
+	s, err := evalString(n.ip, ctx)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("%q isn't an IP", s)
+	}
+	for _, c := range n.cidrs {
+		s, err := evalString(c, ctx)
+		if err != nil {
+			return nil, err
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		if ipNet.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func validateCIDR(cidr string) error {
+	_, _, err := net.ParseCIDR(cidr)
+	return err
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(ctx *Context) (interface{}, error) {
+	b, err := evalBool(n.operand, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+// logicalNode is either && or ||, both short-circuiting
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(ctx *Context) (interface{}, error) {
+	left, err := evalBool(n.left, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if left == n.or {
+		return left, nil
+	}
+	return evalBool(n.right, ctx)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(ctx *Context) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		eq, err := equal(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return !eq, nil
+	}
+	cmp, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+type inNode struct {
+	left, right node
+}
+
+func (n *inNode) eval(ctx *Context) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := right.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("in needs a list, got %s", describe(right))
+	}
+	for _, v := range values {
+		eq, err := equal(left, v)
+		if err != nil {
+			return nil, err
+		}
+		if eq {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func equal(left, right interface{}) (bool, error) {
+	switch left.(type) {
+	case string, int64, bool:
+		if describe(left) == describe(right) {
+			return left == right, nil
+		}
+	}
+	return false, fmt.Errorf("can't compare %s to %s", describe(left), describe(right))
+}
+
+// compare orders integers numerically and strings lexicographically, so
+// "HH:MM" clocks and "YYYY-MM-DD" dates order chronologically
+func compare(left, right interface{}) (int, error) {
+	switch l := left.(type) {
+	case int64:
+		if r, ok := right.(int64); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("can't order %s and %s", describe(left), describe(right))
+}
+
+func evalBool(n node, ctx *Context) (bool, error) {
+	v, err := n.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %s", describe(v))
+	}
+	return b, nil
+}
+
+func evalString(n node, ctx *Context) (string, error) {
+	v, err := n.eval(ctx)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %s", describe(v))
+	}
+	return s, nil
+}
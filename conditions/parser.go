@@ -0,0 +1,328 @@
+package conditions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenInt
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of condition"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators, longest first so lexing is greedy
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",",
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) lex(expr string) error {
+	i := 0
+	for i < len(expr) {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != '"' {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return fmt.Errorf("unterminated string at %d", i)
+			}
+			s, err := strconv.Unquote(expr[i : j+1])
+			if err != nil {
+				return fmt.Errorf("malformed string at %d: %s", i, err)
+			}
+			p.tokens = append(p.tokens, token{tokenString, expr[i : j+1], s, i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+				j++
+			}
+			n, err := strconv.ParseInt(expr[i:j], 10, 64)
+			if err != nil {
+				return fmt.Errorf("malformed integer at %d: %s", i, err)
+			}
+			p.tokens = append(p.tokens, token{tokenInt, expr[i:j], n, i})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '.' ||
+				unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokenIdent, expr[i:j], nil, i})
+			i = j
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			p.tokens = append(p.tokens, token{tokenOp, op, nil, i})
+			i += len(op)
+		}
+	}
+	p.tokens = append(p.tokens, token{tokenEOF, "", nil, len(expr)})
+	return nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(tokenOp, text) {
+		t := p.peek()
+		return fmt.Errorf("expected %q, got %s at %d", text, t, t.pos)
+	}
+	return nil
+}
+
+// or := and ("||" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+// and := unary ("&&" unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// unary := "!" unary | comparison
+func (p *parser) parseUnary() (node, error) {
+	if p.accept(tokenOp, "!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// comparison := operand ((== | != | < | <= | > | >=) operand | in operand)?
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokenOp && isComparison(t.text):
+		p.advance()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: t.text, left: left, right: right}, nil
+	case t.kind == tokenIdent && t.text == "in":
+		p.advance()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// operand := string | integer | true | false | list | "(" or ")" |
+// variable | function "(" args ")"
+func (p *parser) parseOperand() (node, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenString, tokenInt:
+		return &literalNode{t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return &literalNode{t.text == "true"}, nil
+		}
+		if p.accept(tokenOp, "(") {
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			return buildCall(t, args)
+		}
+		return buildVariable(t)
+	case tokenOp:
+		switch t.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+}
+
+// parseList parses comma separated expressions up to closing
+func (p *parser) parseList(closing string) ([]node, error) {
+	items := []node{}
+	if p.accept(tokenOp, closing) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept(tokenOp, closing) {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func buildVariable(t token) (node, error) {
+	switch {
+	case t.text == "request.ip":
+		return &requestIPNode{}, nil
+	case strings.HasPrefix(t.text, "context.") && len(t.text) > len("context."):
+		return &attributeNode{strings.TrimPrefix(t.text, "context.")}, nil
+	case strings.HasPrefix(t.text, "time."):
+		if field, ok := timeFields[strings.TrimPrefix(t.text, "time.")]; ok {
+			return &timeNode{field: field, location: &literalNode{"UTC"}}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown variable %s at %d", t, t.pos)
+}
+
+func buildCall(t token, args []node) (node, error) {
+	if t.text == "inCIDR" {
+		if len(args) < 2 {
+			return nil, fmt.Errorf(
+				"inCIDR at %d needs an IP and at least one CIDR", t.pos,
+			)
+		}
+		for _, arg := range args[1:] {
+			if err := validateLiteral(arg, validateCIDR); err != nil {
+				return nil, fmt.Errorf("inCIDR at %d: %s", t.pos, err)
+			}
+		}
+		return &inCIDRNode{ip: args[0], cidrs: args[1:]}, nil
+	}
+	field, ok := timeFields[t.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at %d", t, t.pos)
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s at %d needs a time zone", t.text, t.pos)
+	}
+	if err := validateLiteral(args[0], validateLocation); err != nil {
+		return nil, fmt.Errorf("%s at %d: %s", t.text, t.pos, err)
+	}
+	return &timeNode{field: field, location: args[0]}, nil
+}
+
+// validateLiteral checks n with validate if it's a string literal, so
+// mistakes are caught when conditions are written rather than checked
+func validateLiteral(n node, validate func(string) error) error {
+	if l, ok := n.(*literalNode); ok {
+		if s, ok := l.value.(string); ok {
+			return validate(s)
+		}
+	}
+	return nil
+}
+
+func validateLocation(name string) error {
+	_, err := time.LoadLocation(name)
+	return err
+}
@@ -12,8 +12,30 @@ var ServiceAccountsActions = []string{
 	"EditServiceAccount",
 }
 
+// GroupsActions are all possible actions over groups
+var GroupsActions = []string{
+	"CreateGroups",
+	"EditGroup",
+}
+
 // ServicesActions are all possible actions over services
 var ServicesActions = []string{
 	"CreateServices",
 	"EditService",
 }
+
+// AuditActions are all possible actions over the audit log
+var AuditActions = []string{
+	"ListAuditEvents",
+}
+
+// SCIMActions are all possible actions over SCIM provisioning
+var SCIMActions = []string{
+	"Provision",
+}
+
+// PoliciesActions are all possible actions over policies as code
+var PoliciesActions = []string{
+	"ApplyPolicy",
+	"ExportPolicy",
+}
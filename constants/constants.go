@@ -5,8 +5,12 @@ import "github.com/spf13/viper"
 // Metrics constants
 var Metrics = struct {
 	ResponseTime string
+	JobRuns      string
+	JobRunTime   string
 }{
 	ResponseTime: "response_time",
+	JobRuns:      "job_runs",
+	JobRunTime:   "job_run_time",
 }
 
 // AppInfo constants
@@ -0,0 +1,177 @@
+package decisionlog
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// Options tune a Logger
+type Options struct {
+	SampleRate      float64
+	AlwaysLogDenied bool
+	BufferSize      int
+	BatchSize       int
+	FlushInterval   time.Duration
+}
+
+// Logger samples permission check decisions and writes them to a Sink in
+// background, so checks never wait on it. A nil, disabled or closed Logger
+// logs nothing
+type Logger struct {
+	sink      Sink
+	logger    logrus.FieldLogger
+	options   Options
+	decisions chan models.Decision
+	done      chan struct{}
+	closedMu  sync.RWMutex
+	closed    bool
+	randMu    sync.Mutex
+	rand      *rand.Rand
+}
+
+func loadDefaultConfigDecisionLog(config *viper.Viper) {
+	config.SetDefault("decisionLog.enabled", false)
+	config.SetDefault("decisionLog.sink", "stdout")
+	config.SetDefault("decisionLog.sampleRate", 1)
+	config.SetDefault("decisionLog.alwaysLogDenied", true)
+	config.SetDefault("decisionLog.bufferSize", 10000)
+	config.SetDefault("decisionLog.batchSize", 100)
+	config.SetDefault("decisionLog.flushInterval", "1s")
+	config.SetDefault("decisionLog.file.path", "decisions.jsonl")
+}
+
+// New builds a Logger from decisionLog.* config, writing to the sink named
+// by decisionLog.sink. It returns a disabled Logger if decisionLog.enabled
+// is false
+func New(
+	config *viper.Viper, logger logrus.FieldLogger,
+	storage *repositories.Storage,
+) (*Logger, error) {
+	loadDefaultConfigDecisionLog(config)
+	if !config.GetBool("decisionLog.enabled") {
+		return nil, nil
+	}
+	sink, err := buildSink(config.GetString("decisionLog.sink"), config, storage)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithSink(sink, logger, Options{
+		SampleRate:      config.GetFloat64("decisionLog.sampleRate"),
+		AlwaysLogDenied: config.GetBool("decisionLog.alwaysLogDenied"),
+		BufferSize:      config.GetInt("decisionLog.bufferSize"),
+		BatchSize:       config.GetInt("decisionLog.batchSize"),
+		FlushInterval:   config.GetDuration("decisionLog.flushInterval"),
+	}), nil
+}
+
+// NewWithSink starts a Logger writing to sink
+func NewWithSink(
+	sink Sink, logger logrus.FieldLogger, options Options,
+) *Logger {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	l := &Logger{
+		sink:      sink,
+		logger:    logger,
+		options:   options,
+		decisions: make(chan models.Decision, options.BufferSize),
+		done:      make(chan struct{}),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	go l.loop()
+	return l
+}
+
+// ShouldLog tells whether a check that resulted in allowed is sampled. It's
+// meant to be called before the extra work of building a Decision
+func (l *Logger) ShouldLog(allowed bool) bool {
+	if l == nil {
+		return false
+	}
+	if !allowed && l.options.AlwaysLogDenied {
+		return true
+	}
+	l.randMu.Lock()
+	defer l.randMu.Unlock()
+	return l.rand.Float64() < l.options.SampleRate
+}
+
+// Log enqueues d to be written. d is dropped if the buffer is full
+func (l *Logger) Log(d models.Decision) {
+	if l == nil {
+		return
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	l.closedMu.RLock()
+	defer l.closedMu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.decisions <- d:
+	default:
+		l.logger.WithField("permission", d.Permission).
+			Warn("decision log buffer full, dropping decision")
+	}
+}
+
+// Close flushes pending decisions and closes the sink. Decisions logged
+// afterwards are dropped
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.closedMu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.decisions)
+	}
+	l.closedMu.Unlock()
+	<-l.done
+	return l.sink.Close()
+}
+
+func (l *Logger) loop() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.options.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]models.Decision, 0, l.options.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := l.sink.Write(batch); err != nil {
+			l.logger.WithError(err).
+				WithField("decisions", len(batch)).
+				Error("failed to write decisions")
+		}
+		batch = make([]models.Decision, 0, l.options.BatchSize)
+	}
+	for {
+		select {
+		case d, ok := <-l.decisions:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, d)
+			if len(batch) >= l.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// +build unit
+
+package decisionlog_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/Will.IAM/decisionlog"
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+type memorySink struct {
+	mu        sync.Mutex
+	decisions []models.Decision
+}
+
+func (ms *memorySink) Write(ds []models.Decision) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.decisions = append(ms.decisions, ds...)
+	return nil
+}
+
+func (ms *memorySink) Close() error {
+	return nil
+}
+
+func TestLoggerSampling(t *testing.T) {
+	sink := &memorySink{}
+	l := decisionlog.NewWithSink(sink, logrus.New(), decisionlog.Options{
+		SampleRate:      0,
+		AlwaysLogDenied: true,
+		BufferSize:      10,
+		BatchSize:       10,
+		FlushInterval:   time.Hour,
+	})
+	if l.ShouldLog(true) {
+		t.Errorf("Expected allowed check not to be sampled with rate 0")
+	}
+	if !l.ShouldLog(false) {
+		t.Errorf("Expected denied check to be always sampled")
+	}
+	l.Log(models.Decision{Permission: "Service::RL::Do::*"})
+	if err := l.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sink.decisions) != 1 {
+		t.Fatalf("Expected 1 decision flushed on Close. Got %d", len(sink.decisions))
+	}
+	if sink.decisions[0].CreatedAt.IsZero() {
+		t.Errorf("Expected CreatedAt to be set")
+	}
+}
+
+func TestClosedLoggerLogsNothing(t *testing.T) {
+	sink := &memorySink{}
+	l := decisionlog.NewWithSink(sink, logrus.New(), decisionlog.Options{
+		SampleRate: 1, BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour,
+	})
+	if err := l.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	l.Log(models.Decision{Permission: "Service::RL::Do::*"})
+	if err := l.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sink.decisions) != 0 {
+		t.Errorf("Expected no decisions after Close. Got %d", len(sink.decisions))
+	}
+}
+
+func TestNilLoggerLogsNothing(t *testing.T) {
+	var l *decisionlog.Logger
+	if l.ShouldLog(false) {
+		t.Errorf("Expected nil logger not to sample")
+	}
+	l.Log(models.Decision{})
+	if err := l.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decisionlog")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "decisions.jsonl")
+	sink, err := decisionlog.NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sink.Write([]models.Decision{
+		{Permission: "Service::RL::Do::x", Allowed: true},
+		{Permission: "Service::RL::Do::y", Allowed: false},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	var ds []models.Decision
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d models.Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ds = append(ds, d)
+	}
+	if len(ds) != 2 || ds[1].Permission != "Service::RL::Do::y" || ds[1].Allowed {
+		t.Errorf("Unexpected decisions %v", ds)
+	}
+}
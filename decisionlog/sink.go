@@ -0,0 +1,130 @@
+package decisionlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// Sink is where sampled decisions end up
+type Sink interface {
+	Write([]models.Decision) error
+	Close() error
+}
+
+// SinkBuilder builds a Sink from config, storage is already configured
+type SinkBuilder func(*viper.Viper, *repositories.Storage) (Sink, error)
+
+var (
+	sinksMu       sync.RWMutex
+	sinksBuilders = map[string]SinkBuilder{}
+)
+
+// RegisterSink makes a sink available as decisionLog.sink = name. It panics
+// if name is already taken
+func RegisterSink(name string, builder SinkBuilder) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if _, ok := sinksBuilders[name]; ok {
+		panic(fmt.Sprintf("decisionlog: sink %s registered twice", name))
+	}
+	sinksBuilders[name] = builder
+}
+
+func buildSink(
+	name string, config *viper.Viper, storage *repositories.Storage,
+) (Sink, error) {
+	sinksMu.RLock()
+	builder, ok := sinksBuilders[name]
+	sinksMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("decisionlog: unknown sink %s", name)
+	}
+	return builder(config, storage)
+}
+
+func init() {
+	RegisterSink("stdout", func(
+		*viper.Viper, *repositories.Storage,
+	) (Sink, error) {
+		return NewWriterSink(os.Stdout), nil
+	})
+	RegisterSink("file", func(
+		config *viper.Viper, _ *repositories.Storage,
+	) (Sink, error) {
+		return NewFileSink(config.GetString("decisionLog.file.path"))
+	})
+	RegisterSink("postgres", func(
+		_ *viper.Viper, storage *repositories.Storage,
+	) (Sink, error) {
+		return NewPostgresSink(repositories.New(storage)), nil
+	})
+}
+
+type writerSink struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewWriterSink writes decisions to w as JSON lines
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w, enc: json.NewEncoder(w)}
+}
+
+func (ws *writerSink) Write(ds []models.Decision) error {
+	for i := range ds {
+		if err := ws.enc.Encode(ds[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ws *writerSink) Close() error {
+	if c, ok := ws.w.(io.Closer); ok && ws.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewFileSink appends decisions to the file at path as JSON lines
+func NewFileSink(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(f), nil
+}
+
+type postgresSink struct {
+	repo *repositories.All
+}
+
+// NewPostgresSink writes decisions to the decisions table
+func NewPostgresSink(repo *repositories.All) Sink {
+	return &postgresSink{repo: repo}
+}
+
+func (ps *postgresSink) Write(ds []models.Decision) error {
+	return ps.repo.WithPGTx(context.Background(), func(
+		repo *repositories.All,
+	) error {
+		for i := range ds {
+			if err := repo.Decisions.Create(&ds[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (ps *postgresSink) Close() error {
+	return nil
+}
@@ -29,3 +29,93 @@ func (e *InvalidAuthorizationTypeError) Serialize() []byte {
 
 	return g
 }
+
+// InvalidAccessTokenError happens when an access token signed by Will.IAM
+// fails verification, e.g. because it expired
+type InvalidAccessTokenError struct {
+	reason string
+}
+
+// NewInvalidAccessTokenError ctor
+func NewInvalidAccessTokenError(reason string) *InvalidAccessTokenError {
+	return &InvalidAccessTokenError{reason: reason}
+}
+
+func (e *InvalidAccessTokenError) Error() string {
+	return fmt.Sprintf("Invalid access token: %s", e.reason)
+}
+
+// Serialize returns the error serialized
+func (e *InvalidAccessTokenError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-010",
+		"error":       "InvalidAccessTokenError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
+
+// NotKeyPairServiceAccountError happens when managing key pairs of a service
+// account that doesn't authenticate with them
+type NotKeyPairServiceAccountError struct {
+	serviceAccountID string
+}
+
+// NewNotKeyPairServiceAccountError ctor
+func NewNotKeyPairServiceAccountError(
+	serviceAccountID string,
+) *NotKeyPairServiceAccountError {
+	return &NotKeyPairServiceAccountError{serviceAccountID: serviceAccountID}
+}
+
+func (e *NotKeyPairServiceAccountError) Error() string {
+	return fmt.Sprintf(
+		"Service account %s doesn't authenticate with key pairs", e.serviceAccountID,
+	)
+}
+
+// Serialize returns the error serialized
+func (e *NotKeyPairServiceAccountError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-011",
+		"error":       "NotKeyPairServiceAccountError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
+
+// InactiveServiceAccountError happens when a disabled or deleted service
+// account authenticates, or is changed in a way its status doesn't allow
+type InactiveServiceAccountError struct {
+	serviceAccountID string
+	status           string
+}
+
+// NewInactiveServiceAccountError ctor
+func NewInactiveServiceAccountError(
+	serviceAccountID, status string,
+) *InactiveServiceAccountError {
+	return &InactiveServiceAccountError{
+		serviceAccountID: serviceAccountID, status: status,
+	}
+}
+
+func (e *InactiveServiceAccountError) Error() string {
+	return fmt.Sprintf("Service account %s is %s", e.serviceAccountID, e.status)
+}
+
+// Serialize returns the error serialized
+func (e *InactiveServiceAccountError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-012",
+		"error":       "InactiveServiceAccountError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
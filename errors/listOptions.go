@@ -60,3 +60,33 @@ func (e *InvalidPageSizeError) Serialize() []byte {
 
 	return g
 }
+
+// InvalidListFilterError happens when a list filter has an invalid value
+type InvalidListFilterError struct {
+	param string
+	str   string
+}
+
+// NewInvalidListFilterError ctor
+func NewInvalidListFilterError(param, str string) *InvalidListFilterError {
+	return &InvalidListFilterError{
+		param: param,
+		str:   str,
+	}
+}
+
+func (e *InvalidListFilterError) Error() string {
+	return fmt.Sprintf("%s is not a valid %s", e.str, e.param)
+}
+
+// Serialize returns the error serialized
+func (e *InvalidListFilterError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-009",
+		"error":       "InvalidListFilterError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
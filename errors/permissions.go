@@ -104,3 +104,147 @@ func (e *UserDoesntHaveAllPermissionsError) Serialize() []byte {
 func (e *UserDoesntHaveAllPermissionsError) StatusCode() int {
 	return 403
 }
+
+// RoleInclusionCycleError happens when a role would include itself, directly
+// or through other roles
+type RoleInclusionCycleError struct {
+	roleID         string
+	includedRoleID string
+}
+
+// NewRoleInclusionCycleError ctor
+func NewRoleInclusionCycleError(
+	roleID, includedRoleID string,
+) *RoleInclusionCycleError {
+	return &RoleInclusionCycleError{
+		roleID: roleID, includedRoleID: includedRoleID,
+	}
+}
+
+func (e *RoleInclusionCycleError) Error() string {
+	return fmt.Sprintf(
+		"role %s can't include role %s, which includes it",
+		e.roleID, e.includedRoleID,
+	)
+}
+
+// Serialize returns the error serialized
+func (e *RoleInclusionCycleError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-013",
+		"error":       "RoleInclusionCycleError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
+
+// StatusCode implements ErrorWithStatusCode
+func (e *RoleInclusionCycleError) StatusCode() int {
+	return 422
+}
+
+// GroupInclusionCycleError happens when a group would include itself,
+// directly or through other groups
+type GroupInclusionCycleError struct {
+	groupID         string
+	includedGroupID string
+}
+
+// NewGroupInclusionCycleError ctor
+func NewGroupInclusionCycleError(
+	groupID, includedGroupID string,
+) *GroupInclusionCycleError {
+	return &GroupInclusionCycleError{
+		groupID: groupID, includedGroupID: includedGroupID,
+	}
+}
+
+func (e *GroupInclusionCycleError) Error() string {
+	return fmt.Sprintf(
+		"group %s can't include group %s, which includes it",
+		e.groupID, e.includedGroupID,
+	)
+}
+
+// Serialize returns the error serialized
+func (e *GroupInclusionCycleError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-014",
+		"error":       "GroupInclusionCycleError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
+
+// StatusCode implements ErrorWithStatusCode
+func (e *GroupInclusionCycleError) StatusCode() int {
+	return 422
+}
+
+// GroupNameTakenError happens when creating or renaming a group to the name
+// of another one
+type GroupNameTakenError struct {
+	name string
+}
+
+// NewGroupNameTakenError ctor
+func NewGroupNameTakenError(name string) *GroupNameTakenError {
+	return &GroupNameTakenError{name: name}
+}
+
+func (e *GroupNameTakenError) Error() string {
+	return fmt.Sprintf("group name %s is already taken", e.name)
+}
+
+// Serialize returns the error serialized
+func (e *GroupNameTakenError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-015",
+		"error":       "GroupNameTakenError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
+
+// StatusCode implements ErrorWithStatusCode
+func (e *GroupNameTakenError) StatusCode() int {
+	return 409
+}
+
+// AmbiguousRoleNameError happens when a role is referred to by a name more
+// than one role has
+type AmbiguousRoleNameError struct {
+	name string
+}
+
+// NewAmbiguousRoleNameError ctor
+func NewAmbiguousRoleNameError(name string) *AmbiguousRoleNameError {
+	return &AmbiguousRoleNameError{name: name}
+}
+
+func (e *AmbiguousRoleNameError) Error() string {
+	return fmt.Sprintf("more than one role is named %s", e.name)
+}
+
+// Serialize returns the error serialized
+func (e *AmbiguousRoleNameError) Serialize() []byte {
+	g, _ := json.Marshal(map[string]interface{}{
+		"code":        "ERR-016",
+		"error":       "AmbiguousRoleNameError",
+		"description": e.Error(),
+		"success":     false,
+	})
+
+	return g
+}
+
+// StatusCode implements ErrorWithStatusCode
+func (e *AmbiguousRoleNameError) StatusCode() int {
+	return 422
+}
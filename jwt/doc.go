@@ -0,0 +1,3 @@
+// Package jwt signs and verifies RSA signed JSON Web Tokens and decodes JSON
+// Web Key Sets, which is all Will.IAM needs to talk to OIDC providers
+package jwt
@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// JSONWebKey is a RSA public key as described by RFC 7517
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// NewJSONWebKey builds the RS256 signature JSONWebKey of pub
+func NewJSONWebKey(pub *rsa.PublicKey, kid string) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "RSA",
+		Use:       "sig",
+		KeyID:     kid,
+		Algorithm: "RS256",
+		N:         encodeSegment(pub.N.Bytes()),
+		E:         encodeSegment(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// PublicKey decodes k into a *rsa.PublicKey
+func (k JSONWebKey) PublicKey() (*rsa.PublicKey, error) {
+	if k.KeyType != "RSA" {
+		return nil, fmt.Errorf("jwt: unsupported key type %s", k.KeyType)
+	}
+	n, err := decodeSegment(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: invalid key modulus")
+	}
+	e, err := decodeSegment(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("jwt: invalid key exponent")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// KeySet is a JWKS document
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Key returns the key identified by kid. A token without kid matches a
+// KeySet with a single key
+func (ks KeySet) Key(kid string) *JSONWebKey {
+	if kid == "" && len(ks.Keys) == 1 {
+		return &ks.Keys[0]
+	}
+	for i := range ks.Keys {
+		if ks.Keys[i].KeyID == kid && kid != "" {
+			return &ks.Keys[i]
+		}
+	}
+	return nil
+}
+
+// ParsePrivateKeyPEM decodes a PKCS #1 or PKCS #8 PEM encoded RSA private key
+func ParsePrivateKeyPEM(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("jwt: only RSA private keys are supported")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,210 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	// sha256 and sha512 register the hashes used by RS256, RS384 and RS512
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformed happens when a token isn't a JWS compact serialization
+	ErrMalformed = errors.New("jwt: malformed token")
+	// ErrUnsupportedAlgorithm happens when a token isn't signed with RS256,
+	// RS384 or RS512
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+	// ErrUnknownKey happens when no key in the KeySet matches the token kid
+	ErrUnknownKey = errors.New("jwt: unknown key")
+	// ErrInvalidSignature happens when the token signature doesn't match
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+)
+
+var hashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// Header of a JWT
+type Header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// Token is a JWT whose signature was verified
+type Token struct {
+	Header  Header
+	Claims  Claims
+	payload []byte
+}
+
+// UnmarshalClaims decodes the whole token payload into v
+func (t *Token) UnmarshalClaims(v interface{}) error {
+	return json.Unmarshal(t.payload, v)
+}
+
+// Sign serializes claims into a RS256 JWT signed with key, identified by kid
+func Sign(claims interface{}, key *rsa.PrivateKey, kid string) (string, error) {
+	header, err := json.Marshal(Header{Algorithm: "RS256", Type: "JWT", KeyID: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := fmt.Sprintf(
+		"%s.%s", encodeSegment(header), encodeSegment(payload),
+	)
+	h := crypto.SHA256.New()
+	h.Write([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s", signingInput, encodeSegment(sig)), nil
+}
+
+// Verify checks raw signature against keys and decodes its claims. It
+// doesn't validate claims, use Token.Claims.Validate for that
+func Verify(raw string, keys KeySet) (*Token, error) {
+	header, err := ParseHeader(raw)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(raw, ".")
+	t := &Token{Header: *header}
+	hash, ok := hashes[t.Header.Algorithm]
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	jwk := keys.Key(t.Header.KeyID)
+	if jwk == nil {
+		return nil, ErrUnknownKey
+	}
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	h := hash.New()
+	h.Write([]byte(fmt.Sprintf("%s.%s", parts[0], parts[1])))
+	if err := rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if t.payload, err = decodeSegment(parts[1]); err != nil {
+		return nil, ErrMalformed
+	}
+	if err := json.Unmarshal(t.payload, &t.Claims); err != nil {
+		return nil, ErrMalformed
+	}
+	return t, nil
+}
+
+// Claims are the registered claims Will.IAM cares about
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// Validate checks that c was issued by issuer to audience and that it is
+// valid at now, tolerating leeway of clock skew. Empty issuer or audience
+// aren't checked
+func (c Claims) Validate(
+	issuer, audience string, now time.Time, leeway time.Duration,
+) error {
+	if issuer != "" && c.Issuer != issuer {
+		return fmt.Errorf("jwt: unexpected issuer %s", c.Issuer)
+	}
+	if audience != "" && !c.Audience.Contains(audience) {
+		return fmt.Errorf("jwt: audience doesn't contain %s", audience)
+	}
+	if c.ExpiresAt == 0 {
+		return fmt.Errorf("jwt: exp is required")
+	}
+	if now.Add(-leeway).After(time.Unix(c.ExpiresAt, 0)) {
+		return fmt.Errorf("jwt: token is expired")
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return fmt.Errorf("jwt: token is not valid yet")
+	}
+	return nil
+}
+
+// Audience is either a single string or an array of strings
+type Audience []string
+
+// Contains tells if aud is in a
+func (a Audience) Contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON accepts both "aud" and ["aud"]
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var sl []string
+	if err := json.Unmarshal(b, &sl); err != nil {
+		return err
+	}
+	*a = Audience(sl)
+	return nil
+}
+
+// MarshalJSON writes a single audience as a string
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// ParseHeader decodes raw header without verifying anything
+func ParseHeader(raw string) (*Header, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	h := &Header{}
+	if err := json.Unmarshal(headerJSON, h); err != nil {
+		return nil, ErrMalformed
+	}
+	return h, nil
+}
@@ -0,0 +1,177 @@
+// +build unit
+
+package jwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/jwt"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return key
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key := generateKey(t)
+	keys := jwt.KeySet{Keys: []jwt.JSONWebKey{
+		jwt.NewJSONWebKey(&generateKey(t).PublicKey, "other"),
+		jwt.NewJSONWebKey(&key.PublicKey, "key1"),
+	}}
+	raw, err := jwt.Sign(map[string]interface{}{
+		"iss":   "https://idp",
+		"aud":   []string{"a", "b"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "user@test.com",
+	}, key, "key1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	token, err := jwt.Verify(raw, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token.Header.KeyID != "key1" || token.Header.Algorithm != "RS256" {
+		t.Errorf("Unexpected header %#v", token.Header)
+	}
+	if err := token.Claims.Validate("https://idp", "b", time.Now(), 0); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	claims := map[string]interface{}{}
+	if err := token.UnmarshalClaims(&claims); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if claims["email"] != "user@test.com" {
+		t.Errorf("Expected email user@test.com. Got %v", claims["email"])
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	key := generateKey(t)
+	keys := jwt.KeySet{Keys: []jwt.JSONWebKey{
+		jwt.NewJSONWebKey(&key.PublicKey, "key1"),
+	}}
+	claims := map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()}
+	unknown, _ := jwt.Sign(claims, key, "key2")
+	forged, _ := jwt.Sign(claims, generateKey(t), "key1")
+	valid, _ := jwt.Sign(claims, key, "key1")
+	parts := strings.Split(valid, ".")
+	tampered := strings.Join([]string{parts[0], "e30", parts[2]}, ".")
+	hs256 := strings.Join([]string{"eyJhbGciOiJIUzI1NiJ9", parts[1], parts[2]}, ".")
+	type testCase struct {
+		raw string
+		err error
+	}
+	testCases := []testCase{
+		{"a.b", jwt.ErrMalformed},
+		{unknown, jwt.ErrUnknownKey},
+		{forged, jwt.ErrInvalidSignature},
+		{tampered, jwt.ErrInvalidSignature},
+		{hs256, jwt.ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range testCases {
+		if _, err := jwt.Verify(tt.raw, keys); err != tt.err {
+			t.Errorf("Expected %v. Got %v", tt.err, err)
+		}
+	}
+}
+
+func TestClaimsValidate(t *testing.T) {
+	now := time.Now()
+	claims := jwt.Claims{
+		Issuer:    "https://idp",
+		Audience:  jwt.Audience{"client"},
+		ExpiresAt: now.Unix(),
+		NotBefore: now.Add(-time.Minute).Unix(),
+	}
+	type testCase struct {
+		issuer   string
+		audience string
+		at       time.Time
+		valid    bool
+	}
+	testCases := []testCase{
+		{"https://idp", "client", now.Add(-time.Second), true},
+		{"", "", now.Add(-time.Second), true},
+		{"https://other", "client", now.Add(-time.Second), false},
+		{"https://idp", "other", now.Add(-time.Second), false},
+		{"https://idp", "client", now.Add(2 * time.Minute), false},
+		{"https://idp", "client", now.Add(-3 * time.Minute), false},
+		{"https://idp", "client", now.Add(30 * time.Second), true},
+	}
+	for _, tt := range testCases {
+		err := claims.Validate(tt.issuer, tt.audience, tt.at, time.Minute)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected valid %v for %#v. Got %v", tt.valid, tt, err)
+		}
+	}
+}
+
+func TestAudienceJSON(t *testing.T) {
+	var c jwt.Claims
+	if err := json.Unmarshal([]byte(`{"aud":"a"}`), &c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !c.Audience.Contains("a") {
+		t.Errorf("Expected audience to contain a")
+	}
+	if err := json.Unmarshal([]byte(`{"aud":["a","b"]}`), &c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !c.Audience.Contains("b") {
+		t.Errorf("Expected audience to contain b")
+	}
+	b, _ := json.Marshal(jwt.Claims{Audience: jwt.Audience{"a"}})
+	if string(b) != `{"aud":"a"}` {
+		t.Errorf(`Expected {"aud":"a"}. Got %s`, b)
+	}
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	key := generateKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		parsed, err := jwt.ParsePrivateKeyPEM(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if parsed.N.Cmp(key.N) != 0 {
+			t.Errorf("Expected parsed %s key to match", block.Type)
+		}
+	}
+	if _, err := jwt.ParsePrivateKeyPEM([]byte("not a key")); err == nil {
+		t.Errorf("Expected error for invalid PEM")
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	raw, _ := jwt.Sign(map[string]interface{}{}, generateKey(t), "key1")
+	h, err := jwt.ParseHeader(raw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if h.KeyID != "key1" {
+		t.Errorf("Expected kid key1. Got %s", h.KeyID)
+	}
+	if _, err := jwt.ParseHeader("opaque-token"); err != jwt.ErrMalformed {
+		t.Errorf("Expected ErrMalformed. Got %v", err)
+	}
+}
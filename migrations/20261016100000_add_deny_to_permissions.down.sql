@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE deny;
+DROP INDEX IF EXISTS permissions_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS permissions_unique ON permissions (role_id, ownership_level, action, service, resource_hierarchy);
+ALTER TABLE permissions DROP COLUMN deny;
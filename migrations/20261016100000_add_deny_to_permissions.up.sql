@@ -0,0 +1,3 @@
+ALTER TABLE permissions ADD COLUMN deny BOOLEAN NOT NULL DEFAULT false;
+DROP INDEX IF EXISTS permissions_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS permissions_unique ON permissions (role_id, ownership_level, action, service, resource_hierarchy, deny);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS permissions_expires_at;
+DROP INDEX IF EXISTS role_bindings_expires_at;
+
+ALTER TABLE permissions DROP COLUMN expires_at;
+ALTER TABLE role_bindings DROP COLUMN expires_at;
+ALTER TABLE permissions_requests DROP COLUMN expires_at;
@@ -0,0 +1,6 @@
+ALTER TABLE permissions ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE role_bindings ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE permissions_requests ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX permissions_expires_at ON permissions (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX role_bindings_expires_at ON role_bindings (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS expired_grants (
+	id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+	kind VARCHAR(20) NOT NULL,
+	grant_id UUID NOT NULL,
+	role_id UUID NOT NULL,
+	service_account_id UUID,
+	permission VARCHAR(1500),
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	removed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX expired_grants_removed_at ON expired_grants (removed_at);
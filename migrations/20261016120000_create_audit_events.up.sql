@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+	id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+	actor_service_account_id UUID,
+	action VARCHAR(100) NOT NULL,
+	target_type VARCHAR(100) NOT NULL,
+	target_id VARCHAR(200) NOT NULL,
+	before JSONB,
+	after JSONB,
+	request_id VARCHAR(200) NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_created_at ON audit_events (created_at);
+CREATE INDEX audit_events_actor ON audit_events (actor_service_account_id, created_at);
+CREATE INDEX audit_events_target ON audit_events (target_type, target_id, created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS decisions (
+	id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+	service_account_id UUID NOT NULL,
+	permission VARCHAR(1500) NOT NULL,
+	allowed BOOLEAN NOT NULL,
+	matching_grant_id UUID,
+	matching_grant VARCHAR(1500) NOT NULL DEFAULT '',
+	latency_ms DOUBLE PRECISION NOT NULL,
+	caller_service VARCHAR(200) NOT NULL DEFAULT '',
+	request_id VARCHAR(200) NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX decisions_service_account ON decisions (service_account_id, created_at);
+CREATE INDEX decisions_created_at ON decisions (created_at);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS permissions_notify_authorization_change ON permissions;
+DROP TRIGGER IF EXISTS permissions_truncate_notify_authorization_change ON permissions;
+DROP TRIGGER IF EXISTS role_bindings_notify_authorization_change ON role_bindings;
+DROP TRIGGER IF EXISTS role_bindings_truncate_notify_authorization_change ON role_bindings;
+DROP TRIGGER IF EXISTS roles_notify_authorization_change ON roles;
+DROP TRIGGER IF EXISTS roles_truncate_notify_authorization_change ON roles;
+DROP FUNCTION IF EXISTS notify_authorization_change();
@@ -0,0 +1,48 @@
+CREATE OR REPLACE FUNCTION notify_authorization_change() RETURNS trigger AS $$
+DECLARE
+	r RECORD;
+	payload JSON;
+BEGIN
+	IF TG_OP = 'TRUNCATE' THEN
+		payload := json_build_object('table', TG_TABLE_NAME);
+	ELSE
+		IF TG_OP = 'DELETE' THEN
+			r := OLD;
+		ELSE
+			r := NEW;
+		END IF;
+		IF TG_TABLE_NAME = 'role_bindings' THEN
+			payload := json_build_object(
+				'table', TG_TABLE_NAME, 'serviceAccountId', r.service_account_id
+			);
+		ELSIF TG_TABLE_NAME = 'permissions' THEN
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.role_id);
+		ELSE
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.id);
+		END IF;
+	END IF;
+	PERFORM pg_notify('authorization_changes', payload::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER permissions_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON permissions
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER permissions_truncate_notify_authorization_change
+	AFTER TRUNCATE ON permissions
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
+
+CREATE TRIGGER role_bindings_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON role_bindings
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER role_bindings_truncate_notify_authorization_change
+	AFTER TRUNCATE ON role_bindings
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
+
+CREATE TRIGGER roles_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON roles
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER roles_truncate_notify_authorization_change
+	AFTER TRUNCATE ON roles
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
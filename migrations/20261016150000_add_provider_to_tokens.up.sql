@@ -0,0 +1 @@
+ALTER TABLE tokens ADD COLUMN provider VARCHAR(300) NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+ALTER TABLE service_accounts ADD COLUMN key_id VARCHAR(200), ADD COLUMN key_secret VARCHAR(200);
+
+-- secrets can't be recovered from their hashes, key pair service accounts
+-- get their oldest key id back and need a new secret set by hand
+UPDATE service_accounts sas SET key_id = k.id
+FROM (
+  SELECT DISTINCT ON (service_account_id) service_account_id, id
+  FROM service_account_keys ORDER BY service_account_id, created_at
+) k
+WHERE k.service_account_id = sas.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS service_accounts_key_id_key_secret ON service_accounts (key_id, key_secret);
+
+ALTER TABLE service_accounts DROP COLUMN authentication_type;
+
+DROP TABLE IF EXISTS service_account_keys;
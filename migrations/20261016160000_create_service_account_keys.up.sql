@@ -0,0 +1,33 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS service_account_keys (
+	id VARCHAR(200) PRIMARY KEY NOT NULL,
+	service_account_id UUID NOT NULL,
+	secret_hash VARCHAR(200) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	last_used_at TIMESTAMP WITH TIME ZONE,
+  FOREIGN KEY(service_account_id) REFERENCES service_accounts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX service_account_keys_service_account ON service_account_keys (service_account_id);
+
+ALTER TABLE service_accounts ADD COLUMN authentication_type VARCHAR(20) NOT NULL DEFAULT 'oauth2';
+
+UPDATE service_accounts SET authentication_type = 'keypair'
+WHERE key_id IS NOT NULL AND key_id != '';
+
+-- existing secrets are hashed the same way as models.HashKeySecret does
+INSERT INTO service_account_keys (id, service_account_id, secret_hash, created_at)
+SELECT key_id, id,
+  'sha256$' || encode(salt, 'hex') || '$' ||
+  encode(digest(salt || convert_to(key_secret, 'UTF8'), 'sha256'), 'hex'),
+  created_at
+FROM (
+  SELECT id, key_id, key_secret, created_at, gen_random_bytes(16) AS salt
+  FROM service_accounts
+  WHERE key_id IS NOT NULL AND key_id != '' AND key_secret IS NOT NULL
+) sas;
+
+DROP INDEX IF EXISTS service_accounts_key_id_key_secret;
+
+ALTER TABLE service_accounts DROP COLUMN key_id, DROP COLUMN key_secret;
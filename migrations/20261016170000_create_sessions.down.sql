@@ -0,0 +1,3 @@
+ALTER TABLE tokens DROP COLUMN session_id;
+
+DROP TABLE IF EXISTS sessions;
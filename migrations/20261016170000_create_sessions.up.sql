@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+	email VARCHAR(300) NOT NULL,
+	provider VARCHAR(300) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX sessions_email ON sessions (email);
+
+ALTER TABLE tokens ADD COLUMN session_id UUID;
+
+-- tokens in use become sessions of their own, with unknown user agent and ip
+UPDATE tokens SET session_id = uuid_generate_v4() WHERE expired_at IS NULL;
+INSERT INTO sessions (id, email, provider, created_at, last_used_at)
+SELECT session_id, email, provider, created_at, updated_at
+FROM tokens WHERE session_id IS NOT NULL;
+
+ALTER TABLE tokens ADD CONSTRAINT tokens_session_id_fkey
+FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE;
+
+CREATE INDEX tokens_session_id ON tokens (session_id);
@@ -0,0 +1 @@
+ALTER TABLE service_accounts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
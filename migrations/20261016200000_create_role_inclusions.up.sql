@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS role_inclusions (
+	role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+	included_role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (role_id, included_role_id),
+	CHECK (role_id != included_role_id)
+);
+
+CREATE INDEX role_inclusions_included_role ON role_inclusions (included_role_id);
+
+-- role_inclusions changes affect whoever has the including role
+CREATE OR REPLACE FUNCTION notify_authorization_change() RETURNS trigger AS $$
+DECLARE
+	r RECORD;
+	payload JSON;
+BEGIN
+	IF TG_OP = 'TRUNCATE' THEN
+		payload := json_build_object('table', TG_TABLE_NAME);
+	ELSE
+		IF TG_OP = 'DELETE' THEN
+			r := OLD;
+		ELSE
+			r := NEW;
+		END IF;
+		IF TG_TABLE_NAME = 'role_bindings' THEN
+			payload := json_build_object(
+				'table', TG_TABLE_NAME, 'serviceAccountId', r.service_account_id
+			);
+		ELSIF TG_TABLE_NAME IN ('permissions', 'role_inclusions') THEN
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.role_id);
+		ELSE
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.id);
+		END IF;
+	END IF;
+	PERFORM pg_notify('authorization_changes', payload::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER role_inclusions_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON role_inclusions
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER role_inclusions_truncate_notify_authorization_change
+	AFTER TRUNCATE ON role_inclusions
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
@@ -0,0 +1,39 @@
+DROP TRIGGER IF EXISTS group_members_notify_authorization_change ON group_members;
+DROP TRIGGER IF EXISTS group_members_truncate_notify_authorization_change ON group_members;
+DROP TRIGGER IF EXISTS group_inclusions_notify_authorization_change ON group_inclusions;
+DROP TRIGGER IF EXISTS group_inclusions_truncate_notify_authorization_change ON group_inclusions;
+DROP TRIGGER IF EXISTS group_bindings_notify_authorization_change ON group_bindings;
+DROP TRIGGER IF EXISTS group_bindings_truncate_notify_authorization_change ON group_bindings;
+DROP TABLE IF EXISTS group_bindings;
+DROP TABLE IF EXISTS group_inclusions;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+
+CREATE OR REPLACE FUNCTION notify_authorization_change() RETURNS trigger AS $$
+DECLARE
+	r RECORD;
+	payload JSON;
+BEGIN
+	IF TG_OP = 'TRUNCATE' THEN
+		payload := json_build_object('table', TG_TABLE_NAME);
+	ELSE
+		IF TG_OP = 'DELETE' THEN
+			r := OLD;
+		ELSE
+			r := NEW;
+		END IF;
+		IF TG_TABLE_NAME = 'role_bindings' THEN
+			payload := json_build_object(
+				'table', TG_TABLE_NAME, 'serviceAccountId', r.service_account_id
+			);
+		ELSIF TG_TABLE_NAME IN ('permissions', 'role_inclusions') THEN
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.role_id);
+		ELSE
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.id);
+		END IF;
+	END IF;
+	PERFORM pg_notify('authorization_changes', payload::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
@@ -0,0 +1,95 @@
+CREATE TABLE IF NOT EXISTS groups (
+	id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+	name VARCHAR(200) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX groups_name_unique ON groups (lower(name));
+
+CREATE TABLE IF NOT EXISTS group_members (
+	group_id UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+	service_account_id UUID NOT NULL REFERENCES service_accounts (id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (group_id, service_account_id)
+);
+
+CREATE INDEX group_members_service_account ON group_members (service_account_id);
+
+-- members of included groups are members of the including group too
+CREATE TABLE IF NOT EXISTS group_inclusions (
+	group_id UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+	included_group_id UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (group_id, included_group_id),
+	CHECK (group_id != included_group_id)
+);
+
+CREATE INDEX group_inclusions_included_group ON group_inclusions (included_group_id);
+
+CREATE TABLE IF NOT EXISTS group_bindings (
+	id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+	group_id UUID NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+	role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	UNIQUE (group_id, role_id)
+);
+
+CREATE INDEX group_bindings_role ON group_bindings (role_id);
+
+-- group changes affect its members, including members of included groups
+CREATE OR REPLACE FUNCTION notify_authorization_change() RETURNS trigger AS $$
+DECLARE
+	r RECORD;
+	payload JSON;
+BEGIN
+	IF TG_OP = 'TRUNCATE' THEN
+		payload := json_build_object('table', TG_TABLE_NAME);
+	ELSE
+		IF TG_OP = 'DELETE' THEN
+			r := OLD;
+		ELSE
+			r := NEW;
+		END IF;
+		IF TG_TABLE_NAME IN ('role_bindings', 'group_members') THEN
+			payload := json_build_object(
+				'table', TG_TABLE_NAME, 'serviceAccountId', r.service_account_id
+			);
+		ELSIF TG_TABLE_NAME IN ('permissions', 'role_inclusions') THEN
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.role_id);
+		ELSIF TG_TABLE_NAME = 'group_bindings' THEN
+			payload := json_build_object('table', TG_TABLE_NAME, 'groupId', r.group_id);
+		ELSIF TG_TABLE_NAME = 'group_inclusions' THEN
+			payload := json_build_object(
+				'table', TG_TABLE_NAME, 'groupId', r.included_group_id
+			);
+		ELSE
+			payload := json_build_object('table', TG_TABLE_NAME, 'roleId', r.id);
+		END IF;
+	END IF;
+	PERFORM pg_notify('authorization_changes', payload::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER group_members_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON group_members
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER group_members_truncate_notify_authorization_change
+	AFTER TRUNCATE ON group_members
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
+
+CREATE TRIGGER group_inclusions_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON group_inclusions
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER group_inclusions_truncate_notify_authorization_change
+	AFTER TRUNCATE ON group_inclusions
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
+
+CREATE TRIGGER group_bindings_notify_authorization_change
+	AFTER INSERT OR UPDATE OR DELETE ON group_bindings
+	FOR EACH ROW EXECUTE PROCEDURE notify_authorization_change();
+CREATE TRIGGER group_bindings_truncate_notify_authorization_change
+	AFTER TRUNCATE ON group_bindings
+	FOR EACH STATEMENT EXECUTE PROCEDURE notify_authorization_change();
@@ -0,0 +1,2 @@
+-- empty conditions always hold, so existing permissions behave as before
+ALTER TABLE permissions ADD COLUMN condition TEXT NOT NULL DEFAULT '';
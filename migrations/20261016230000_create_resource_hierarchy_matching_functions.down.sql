@@ -0,0 +1,4 @@
+DROP FUNCTION IF EXISTS rh_overlaps;
+DROP FUNCTION IF EXISTS rh_contains;
+DROP FUNCTION IF EXISTS rh_segments_overlap;
+DROP FUNCTION IF EXISTS rh_glob_match;
@@ -0,0 +1,111 @@
+-- resource hierarchies are :: separated segments. A trailing * segment
+-- matches one or more segments, any other * segment matches a single one and
+-- a * inside a segment matches any run of characters, eg sniper-*.
+-- These mirror models.ResourceHierarchy Contains and Overlaps
+
+-- rh_glob_match tells if segment $2 matches glob $1
+CREATE OR REPLACE FUNCTION rh_glob_match(text, text) RETURNS BOOLEAN AS $$
+  SELECT $2 LIKE replace(replace(replace(replace(
+    $1, '\', '\\'), '%', '\%'), '_', '\_'), '*', '%');
+$$ LANGUAGE 'sql' IMMUTABLE;
+
+-- rh_segments_overlap tells if some segment matches both globs $1 and $2
+CREATE OR REPLACE FUNCTION rh_segments_overlap(text, text) RETURNS BOOLEAN AS $$
+  DECLARE
+    p1 text;
+    p2 text;
+    s1 text;
+    s2 text;
+  BEGIN
+    IF strpos($1, '*') = 0 AND strpos($2, '*') = 0 THEN
+      RETURN $1 = $2;
+    ELSIF strpos($2, '*') = 0 THEN
+      RETURN rh_glob_match($1, $2);
+    ELSIF strpos($1, '*') = 0 THEN
+      RETURN rh_glob_match($2, $1);
+    END IF;
+    -- globs with a * each overlap if their prefixes and suffixes agree
+    p1 := split_part($1, '*', 1);
+    p2 := split_part($2, '*', 1);
+    s1 := reverse(split_part(reverse($1), '*', 1));
+    s2 := reverse(split_part(reverse($2), '*', 1));
+    RETURN (left(p1, length(p2)) = p2 OR left(p2, length(p1)) = p1)
+      AND (right(s1, length(s2)) = s2 OR right(s2, length(s1)) = s1);
+  END
+$$ LANGUAGE 'plpgsql' IMMUTABLE;
+
+-- rh_contains tells if every resource in hierarchy $2 is in hierarchy $1
+CREATE OR REPLACE FUNCTION rh_contains(text, text) RETURNS BOOLEAN AS $$
+  DECLARE
+    parts text[];
+    other text[];
+    size int;
+    other_size int;
+    is_open boolean;
+    other_is_open boolean;
+  BEGIN
+    parts := string_to_array($1, '::');
+    other := string_to_array($2, '::');
+    size := coalesce(array_length(parts, 1), 0);
+    other_size := coalesce(array_length(other, 1), 0);
+    is_open := parts[size] = '*';
+    other_is_open := other[other_size] = '*';
+    IF is_open THEN
+      size := size - 1;
+    END IF;
+    IF other_is_open THEN
+      other_size := other_size - 1;
+    END IF;
+    IF is_open THEN
+      IF other_size < size OR (NOT other_is_open AND other_size = size) THEN
+        RETURN false;
+      END IF;
+    ELSIF other_is_open OR other_size != size THEN
+      RETURN false;
+    END IF;
+    FOR i IN 1 .. size LOOP
+      IF parts[i] != '*' AND NOT rh_glob_match(parts[i], other[i]) THEN
+        RETURN false;
+      END IF;
+    END LOOP;
+    RETURN true;
+  END
+$$ LANGUAGE 'plpgsql' IMMUTABLE;
+
+-- rh_overlaps tells if hierarchies $1 and $2 have any resource in common
+CREATE OR REPLACE FUNCTION rh_overlaps(text, text) RETURNS BOOLEAN AS $$
+  DECLARE
+    parts text[];
+    other text[];
+    size int;
+    other_size int;
+    is_open boolean;
+    other_is_open boolean;
+  BEGIN
+    parts := string_to_array($1, '::');
+    other := string_to_array($2, '::');
+    size := coalesce(array_length(parts, 1), 0);
+    other_size := coalesce(array_length(other, 1), 0);
+    is_open := parts[size] = '*';
+    other_is_open := other[other_size] = '*';
+    IF is_open THEN
+      size := size - 1;
+    END IF;
+    IF other_is_open THEN
+      other_size := other_size - 1;
+    END IF;
+    IF NOT is_open AND NOT other_is_open AND size != other_size THEN
+      RETURN false;
+    ELSIF NOT is_open AND other_is_open AND size <= other_size THEN
+      RETURN false;
+    ELSIF is_open AND NOT other_is_open AND other_size <= size THEN
+      RETURN false;
+    END IF;
+    FOR i IN 1 .. least(size, other_size) LOOP
+      IF NOT rh_segments_overlap(parts[i], other[i]) THEN
+        RETURN false;
+      END IF;
+    END LOOP;
+    RETURN true;
+  END
+$$ LANGUAGE 'plpgsql' IMMUTABLE;
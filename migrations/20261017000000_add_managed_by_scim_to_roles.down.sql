@@ -0,0 +1 @@
+ALTER TABLE roles DROP COLUMN managed_by_scim;
@@ -0,0 +1 @@
+ALTER TABLE roles ADD COLUMN managed_by_scim BOOLEAN NOT NULL DEFAULT false;
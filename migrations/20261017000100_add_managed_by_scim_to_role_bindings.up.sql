@@ -0,0 +1 @@
+ALTER TABLE role_bindings ADD COLUMN managed_by_scim BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,7 @@
+DELETE FROM permissions p USING permissions q
+WHERE p.role_id = q.role_id AND p.ownership_level = q.ownership_level
+AND p.action = q.action AND p.service = q.service
+AND p.resource_hierarchy = q.resource_hierarchy AND p.deny = q.deny
+AND p.id > q.id;
+DROP INDEX IF EXISTS permissions_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS permissions_unique ON permissions (role_id, ownership_level, action, service, resource_hierarchy, deny);
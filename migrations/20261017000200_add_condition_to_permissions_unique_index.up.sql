@@ -0,0 +1,4 @@
+-- grants of the same permission under different conditions can't be merged
+-- without widening them, so they are kept apart
+DROP INDEX IF EXISTS permissions_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS permissions_unique ON permissions (role_id, ownership_level, action, service, resource_hierarchy, deny, condition);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS job_runs (
+	name VARCHAR(200) PRIMARY KEY NOT NULL,
+	ran_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
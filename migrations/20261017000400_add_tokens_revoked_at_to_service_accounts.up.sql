@@ -0,0 +1 @@
+ALTER TABLE service_accounts ADD COLUMN tokens_revoked_at TIMESTAMP WITH TIME ZONE;
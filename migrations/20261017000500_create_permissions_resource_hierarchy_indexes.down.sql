@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS permissions_resource_hierarchy_patterns;
+DROP INDEX IF EXISTS permissions_resource_hierarchy;
+DROP FUNCTION IF EXISTS rh_has_pattern;
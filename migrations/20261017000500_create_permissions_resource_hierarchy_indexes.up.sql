@@ -0,0 +1,13 @@
+-- rh_has_pattern tells if hierarchy $1 has a * other than a trailing *
+-- segment, which only rh_contains can match. Others are matched by equality
+-- to models.ResourceHierarchy PermissionMatches
+CREATE OR REPLACE FUNCTION rh_has_pattern(text) RETURNS BOOLEAN AS $$
+  SELECT strpos(CASE
+    WHEN $1 = '*' THEN ''
+    WHEN right($1, 3) = '::*' THEN left($1, -3)
+    ELSE $1
+  END, '*') > 0;
+$$ LANGUAGE 'sql' IMMUTABLE;
+
+CREATE INDEX IF NOT EXISTS permissions_resource_hierarchy ON permissions (resource_hierarchy);
+CREATE INDEX IF NOT EXISTS permissions_resource_hierarchy_patterns ON permissions (resource_hierarchy) WHERE rh_has_pattern(resource_hierarchy);
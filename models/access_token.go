@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/topfreegames/Will.IAM/jwt"
+)
+
+// AccessTokenClaims are the claims of access tokens signed by Will.IAM. The
+// subject is the service account ID
+type AccessTokenClaims struct {
+	jwt.Claims
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+	// Permissions are the service account permissions when the token was
+	// issued, only present if accessTokens.includePermissions is set
+	Permissions []string `json:"perms,omitempty"`
+}
+
+// IssuedAccessToken is an access token signed by Will.IAM
+type IssuedAccessToken struct {
+	AccessToken string    `json:"accessToken"`
+	TokenType   string    `json:"tokenType"`
+	ExpiresIn   int64     `json:"expiresIn"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// AuditAction is an operation recorded in the audit log
+type AuditAction string
+
+// AuditActions possible
+var AuditActions = struct {
+	CreatePermission         AuditAction
+	AttributePermissions     AuditAction
+	DeletePermission         AuditAction
+	UpdateRole               AuditAction
+	CreateRole               AuditAction
+	DeleteRole               AuditAction
+	CreateServiceAccount     AuditAction
+	UpdateServiceAccount     AuditAction
+	GrantPermissionRequest   AuditAction
+	DenyPermissionRequest    AuditAction
+	CreateService            AuditAction
+	CreateServiceAccountKey  AuditAction
+	DeleteServiceAccountKey  AuditAction
+	RevokeSessions           AuditAction
+	DisableServiceAccount    AuditAction
+	DeleteServiceAccount     AuditAction
+	ReactivateServiceAccount AuditAction
+	OffboardServiceAccount   AuditAction
+	ApplyRoleBindingRules    AuditAction
+	CreateGroup              AuditAction
+	UpdateGroup              AuditAction
+	DeleteGroup              AuditAction
+	AddGroupMember           AuditAction
+	RemoveGroupMember        AuditAction
+}{
+	CreatePermission:         "CreatePermission",
+	AttributePermissions:     "AttributePermissions",
+	DeletePermission:         "DeletePermission",
+	UpdateRole:               "UpdateRole",
+	CreateRole:               "CreateRole",
+	DeleteRole:               "DeleteRole",
+	CreateServiceAccount:     "CreateServiceAccount",
+	UpdateServiceAccount:     "UpdateServiceAccount",
+	GrantPermissionRequest:   "GrantPermissionRequest",
+	DenyPermissionRequest:    "DenyPermissionRequest",
+	CreateService:            "CreateService",
+	CreateServiceAccountKey:  "CreateServiceAccountKey",
+	DeleteServiceAccountKey:  "DeleteServiceAccountKey",
+	RevokeSessions:           "RevokeSessions",
+	DisableServiceAccount:    "DisableServiceAccount",
+	DeleteServiceAccount:     "DeleteServiceAccount",
+	ReactivateServiceAccount: "ReactivateServiceAccount",
+	OffboardServiceAccount:   "OffboardServiceAccount",
+	ApplyRoleBindingRules:    "ApplyRoleBindingRules",
+	CreateGroup:              "CreateGroup",
+	UpdateGroup:              "UpdateGroup",
+	DeleteGroup:              "DeleteGroup",
+	AddGroupMember:           "AddGroupMember",
+	RemoveGroupMember:        "RemoveGroupMember",
+}
+
+// AuditTargetType is the kind of entity changed by an audited operation
+type AuditTargetType string
+
+// AuditTargetTypes possible
+var AuditTargetTypes = struct {
+	Permission        AuditTargetType
+	Role              AuditTargetType
+	ServiceAccount    AuditTargetType
+	PermissionRequest AuditTargetType
+	Service           AuditTargetType
+	Group             AuditTargetType
+}{
+	Permission:        "permission",
+	Role:              "role",
+	ServiceAccount:    "service_account",
+	PermissionRequest: "permission_request",
+	Service:           "service",
+	Group:             "group",
+}
+
+// AuditEvent is an append-only record of an authorization-changing
+// operation. Before and After hold the target state around the change,
+// nil when it didn't exist
+type AuditEvent struct {
+	ID                    string                 `json:"id" pg:"id"`
+	ActorServiceAccountID string                 `json:"actorServiceAccountId" pg:"actor_service_account_id"`
+	Action                AuditAction            `json:"action" pg:"action"`
+	TargetType            AuditTargetType        `json:"targetType" pg:"target_type"`
+	TargetID              string                 `json:"targetId" pg:"target_id"`
+	Before                map[string]interface{} `json:"before" pg:"before"`
+	After                 map[string]interface{} `json:"after" pg:"after"`
+	RequestID             string                 `json:"requestId" pg:"request_id"`
+	CreatedAt             time.Time              `json:"createdAt" pg:"created_at"`
+}
+
+// String returns audit action as string
+func (aa AuditAction) String() string {
+	return string(aa)
+}
+
+// String returns audit target type as string
+func (att AuditTargetType) String() string {
+	return string(att)
+}
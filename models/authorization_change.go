@@ -0,0 +1,13 @@
+package models
+
+// AuthorizationChange is notified by Postgres whenever a permission, role
+// binding, role or group is written. ServiceAccountID is set for role
+// bindings and group members, RoleID for permissions and roles and GroupID
+// for group bindings and inclusions, whose members are affected; none is set
+// for a TRUNCATE
+type AuthorizationChange struct {
+	Table            string `json:"table"`
+	ServiceAccountID string `json:"serviceAccountId"`
+	RoleID           string `json:"roleId"`
+	GroupID          string `json:"groupId"`
+}
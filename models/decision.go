@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Decision is the outcome of a permission check, as kept by the decision log
+type Decision struct {
+	ID               string    `json:"id" pg:"id"`
+	ServiceAccountID string    `json:"serviceAccountId" pg:"service_account_id"`
+	Permission       string    `json:"permission" pg:"permission"`
+	Allowed          bool      `json:"allowed" pg:"allowed" sql:",notnull"`
+	MatchingGrantID  string    `json:"matchingGrantId" pg:"matching_grant_id"`
+	MatchingGrant    string    `json:"matchingGrant" pg:"matching_grant"`
+	LatencyMs        float64   `json:"latencyMs" pg:"latency_ms" sql:",notnull"`
+	CallerService    string    `json:"callerService" pg:"caller_service"`
+	RequestID        string    `json:"requestId" pg:"request_id"`
+	CreatedAt        time.Time `json:"createdAt" pg:"created_at"`
+}
+
+// SetMatch fills MatchingGrantID and MatchingGrant from the permission that
+// decided d, if any
+func (d *Decision) SetMatch(p *Permission) {
+	if p == nil {
+		return
+	}
+	d.MatchingGrantID = p.ID
+	d.MatchingGrant = p.String()
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ExpiredGrantKind tells which kind of grant expired
+type ExpiredGrantKind string
+
+// ExpiredGrantKinds are all possible expired grants
+var ExpiredGrantKinds = struct {
+	Permission  ExpiredGrantKind
+	RoleBinding ExpiredGrantKind
+}{
+	Permission:  "permission",
+	RoleBinding: "role_binding",
+}
+
+// ExpiredGrant records a permission or role binding removed after
+// reaching its expiresAt
+type ExpiredGrant struct {
+	ID               string           `json:"id" pg:"id"`
+	Kind             ExpiredGrantKind `json:"kind" pg:"kind"`
+	GrantID          string           `json:"grantId" pg:"grant_id"`
+	RoleID           string           `json:"roleId" pg:"role_id"`
+	ServiceAccountID string           `json:"serviceAccountId" pg:"service_account_id"`
+	Permission       string           `json:"permission" pg:"permission"`
+	ExpiresAt        time.Time        `json:"expiresAt" pg:"expires_at"`
+	RemovedAt        time.Time        `json:"removedAt" pg:"removed_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Group of service accounts. Roles bound to a group are bound to each of its
+// members, and to members of groups it includes
+type Group struct {
+	ID   string `json:"id" pg:"id"`
+	Name string `json:"name" pg:"name"`
+	CreatedUpdatedAt
+}
+
+// GroupBinding binds a group, and so its members, to a role
+type GroupBinding struct {
+	ID      string `json:"id" pg:"id"`
+	GroupID string `json:"groupId" pg:"group_id"`
+	RoleID  string `json:"roleId" pg:"role_id"`
+	CreatedUpdatedAt
+}
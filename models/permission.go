@@ -3,7 +3,10 @@ package models
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/conditions"
 	"github.com/topfreegames/Will.IAM/constants"
 )
 
@@ -53,10 +56,15 @@ func (a Action) String() string {
 	return string(a)
 }
 
-// ResourceHierarchy is either a complete or an open hierarchy to something
+// ResourceHierarchy is either a complete or an open hierarchy to something,
+// made of :: separated segments. A trailing * segment matches one or more
+// segments, any other * segment matches a single one and a * inside a
+// segment matches any run of characters
 // Eg:
 // Complete: maestro::sniper-3d::na::sniper3d-red
 // Open: maestro::sniper-3d::stag::*
+// Every game, prod only: maestro::*::prod::*
+// Every sniper game: maestro::sniper-*::*
 type ResourceHierarchy string
 
 // BuildResourceHierarchy returns an instance of ResourceHierarchy from a string
@@ -69,65 +77,131 @@ func (rh ResourceHierarchy) All() bool {
 	return string(rh) == "*"
 }
 
-// PermissionMatches returns a slice of all possible ways to check if a service account has
-// any level of permission over this ResourceHierarchy
+// PermissionMatches returns a slice of all possible open hierarchies ending
+// in a trailing * that contain this ResourceHierarchy
 // Example: rh = "x::y::z".PermissionMatches() = ["*", "x::*", "x::y::*", "x::y::z"]
+// Hierarchies with mid-path wildcards or globs may contain rh as well
 func (rh ResourceHierarchy) PermissionMatches() []string {
 	whole := string(rh)
 	if whole == "*" {
 		return []string{whole}
 	}
 	parts := strings.Split(whole, "::")
+	if parts[len(parts)-1] == "*" {
+		parts = parts[:len(parts)-1]
+	}
 	ret := []string{"*"}
 	for i := 1; i < len(parts); i++ {
-		if parts[i] == "*" {
-			break
-		}
 		match := strings.Join(parts[:i], "::")
 		ret = append(ret, match+"::*")
 	}
 	return append(ret, whole)
 }
 
+// resourceHierarchy is a ResourceHierarchy split in segments. fixed are the
+// segments before a trailing *, if open
 type resourceHierarchy struct {
-	size      int
-	hierarchy []string
+	fixed []string
+	open  bool
 }
 
 func buildResourceHierarchy(rh ResourceHierarchy) resourceHierarchy {
 	parts := strings.Split(string(rh), "::")
-	size := len(parts)
-	return resourceHierarchy{size: size, hierarchy: parts}
+	if parts[len(parts)-1] == "*" {
+		return resourceHierarchy{fixed: parts[:len(parts)-1], open: true}
+	}
+	return resourceHierarchy{fixed: parts}
 }
 
-// Contains checks whether rh.Hierarchy contains orh.Hierarchy
-func (rh ResourceHierarchy) Contains(orh ResourceHierarchy) bool {
+// Overlaps checks whether rh and orh have any resource in common
+func (rh ResourceHierarchy) Overlaps(orh ResourceHierarchy) bool {
 	rhh := buildResourceHierarchy(rh)
 	orhh := buildResourceHierarchy(orh)
-	if rhh.size > orhh.size {
+	switch {
+	case !rhh.open && !orhh.open && len(rhh.fixed) != len(orhh.fixed):
+		return false
+	case !rhh.open && orhh.open && len(rhh.fixed) <= len(orhh.fixed):
+		return false
+	case rhh.open && !orhh.open && len(orhh.fixed) <= len(rhh.fixed):
 		return false
 	}
-	for i := range orhh.hierarchy {
-		if i >= len(rhh.hierarchy) {
+	for i := 0; i < len(rhh.fixed) && i < len(orhh.fixed); i++ {
+		if !segmentsOverlap(rhh.fixed[i], orhh.fixed[i]) {
 			return false
 		}
-		if rhh.hierarchy[i] == "*" {
-			return true
+	}
+	return true
+}
+
+// Contains checks whether every resource in orh is in rh
+func (rh ResourceHierarchy) Contains(orh ResourceHierarchy) bool {
+	rhh := buildResourceHierarchy(rh)
+	orhh := buildResourceHierarchy(orh)
+	if rhh.open {
+		// orh must have more segments than rh fixes
+		if len(orhh.fixed) < len(rhh.fixed) ||
+			(!orhh.open && len(orhh.fixed) == len(rhh.fixed)) {
+			return false
 		}
-		if orhh.hierarchy[i] != rhh.hierarchy[i] {
+	} else if orhh.open || len(orhh.fixed) != len(rhh.fixed) {
+		return false
+	}
+	for i := range rhh.fixed {
+		if rhh.fixed[i] != "*" && !globMatch(rhh.fixed[i], orhh.fixed[i]) {
 			return false
 		}
 	}
 	return true
 }
 
+// globMatch tells if segment matches pattern, where * matches any run of
+// characters. A * in segment is only matched by a * in pattern, so patterns
+// match the globs they contain
+func globMatch(pattern, segment string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == segment
+	}
+	if !strings.HasPrefix(segment, parts[0]) {
+		return false
+	}
+	segment = segment[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(segment, part)
+		if i < 0 {
+			return false
+		}
+		segment = segment[i+len(part):]
+	}
+	return strings.HasSuffix(segment, parts[len(parts)-1])
+}
+
+// segmentsOverlap tells if some segment matches both globs a and b. Globs
+// with a * each do if their prefixes and suffixes agree
+func segmentsOverlap(a, b string) bool {
+	aGlob, bGlob := strings.Contains(a, "*"), strings.Contains(b, "*")
+	switch {
+	case !aGlob && !bGlob:
+		return a == b
+	case !bGlob:
+		return globMatch(a, b)
+	case !aGlob:
+		return globMatch(b, a)
+	}
+	ap, bp := a[:strings.Index(a, "*")], b[:strings.Index(b, "*")]
+	as, bs := a[strings.LastIndex(a, "*")+1:], b[strings.LastIndex(b, "*")+1:]
+	return (strings.HasPrefix(ap, bp) || strings.HasPrefix(bp, ap)) &&
+		(strings.HasSuffix(as, bs) || strings.HasSuffix(bs, as))
+}
+
 // String returns string representation
 func (rh ResourceHierarchy) String() string {
 	return string(rh)
 }
 
 // Permission is bound to a role and
-// defines the onwership level of an action over a resource
+// defines the onwership level of an action over a resource. Permissions
+// with a Condition only apply when it holds, see package conditions
 type Permission struct {
 	ID                string            `json:"id" pg:"id"`
 	RoleID            string            `json:"roleId" pg:"role_id"`
@@ -136,12 +210,56 @@ type Permission struct {
 	Action            Action            `json:"action" pg:"action"`
 	ResourceHierarchy ResourceHierarchy `json:"resourceHierarchy" pg:"resource_hierarchy"`
 	Alias             string            `json:"alias" pg:"alias"`
+	Deny              bool              `json:"deny" pg:"deny" sql:",notnull"`
+	ExpiresAt         pg.NullTime       `json:"expiresAt" pg:"expires_at"`
+	Condition         string            `json:"condition" pg:"condition" sql:",notnull"`
+}
+
+// Expired checks if p has an expiration date and it has already passed
+func (p Permission) Expired() bool {
+	return !p.ExpiresAt.IsZero() && !p.ExpiresAt.After(time.Now())
 }
 
+// Applies tells if p applies in cc, that is, if it has no condition or its
+// condition holds. Conditions failing to evaluate, eg for lack of a context
+// attribute, fail closed: denies apply and grants don't. The evaluation
+// error is returned either way
+func (p Permission) Applies(cc *conditions.Context) (bool, error) {
+	if p.Condition == "" {
+		return true, nil
+	}
+	c, err := conditions.Parse(p.Condition)
+	if err != nil {
+		return p.Deny, err
+	}
+	holds, err := c.Eval(cc)
+	if err != nil {
+		return p.Deny, err
+	}
+	return holds, nil
+}
+
+// ApplicablePermissions returns the permissions in ps that apply in cc
+func ApplicablePermissions(
+	ps []Permission, cc *conditions.Context,
+) []Permission {
+	applicable := make([]Permission, 0, len(ps))
+	for _, p := range ps {
+		if applies, _ := p.Applies(cc); applies {
+			applicable = append(applicable, p)
+		}
+	}
+	return applicable
+}
+
+// DenyPrefix marks a permission in string format as a deny
+// Eg: !Maestro::RL::*::prod::*
+const DenyPrefix = "!"
+
 // ValidatePermission validates a permission in string format
 func ValidatePermission(str string) (bool, error) {
-	// Format: OwnershipLevel::Action::Service::{ResourceHierarchy}
-	parts := strings.Split(str, "::")
+	// Format: [!]Service::OwnershipLevel::Action::{ResourceHierarchy}
+	parts := strings.Split(strings.TrimPrefix(str, DenyPrefix), "::")
 	if len(parts) < 4 {
 		return false, fmt.Errorf(
 			"Incomplete permission. Expected format: " +
@@ -165,7 +283,8 @@ func BuildPermission(str string) (Permission, error) {
 	if valid, err := ValidatePermission(str); !valid {
 		return Permission{}, err
 	}
-	parts := strings.Split(str, "::")
+	deny := strings.HasPrefix(str, DenyPrefix)
+	parts := strings.Split(strings.TrimPrefix(str, DenyPrefix), "::")
 	service := parts[0]
 	ol := OwnershipLevel(parts[1])
 	action := BuildAction(parts[2])
@@ -175,6 +294,7 @@ func BuildPermission(str string) (Permission, error) {
 		OwnershipLevel:    ol,
 		Action:            action,
 		ResourceHierarchy: rh,
+		Deny:              deny,
 	}, nil
 }
 
@@ -192,28 +312,74 @@ func BuildPermissions(ps []string) ([]Permission, error) {
 }
 
 // IsPresent checks if a permission is satisfied in a slice
+// Any deny permission overlapping p wins over every allow. p.Deny is ignored
 func (p Permission) IsPresent(permissions []Permission) bool {
-	for _, pp := range permissions {
+	_, has := p.Match(permissions)
+	return has
+}
+
+// Match returns the permission in a slice that decides whether p is present
+// and the decision: the first deny blocking p, else the first allow
+// satisfying it, else nil
+func (p Permission) Match(permissions []Permission) (*Permission, bool) {
+	for i, pp := range permissions {
+		if pp.Deny && !pp.Expired() && pp.Denies(p) {
+			return &permissions[i], false
+		}
+	}
+	for i, pp := range permissions {
+		if pp.Deny || pp.Expired() {
+			continue
+		}
 		if (pp.Service != "*" && pp.Service != p.Service) ||
 			(pp.Action != "*" && pp.Action != p.Action) ||
 			pp.OwnershipLevel.Less(p.OwnershipLevel) {
 			continue
 		}
 		if pp.ResourceHierarchy.Contains(p.ResourceHierarchy) {
-			return true
+			return &permissions[i], true
 		}
 	}
-	return false
+	return nil, false
+}
+
+// Denies checks if p, taken as a deny, blocks any part of op
+// A RL deny blocks both RL and RO checks, while a RO deny only blocks RO ones
+func (p Permission) Denies(op Permission) bool {
+	if p.Service != "*" && op.Service != "*" && p.Service != op.Service {
+		return false
+	}
+	if !p.Action.All() && !op.Action.All() && p.Action != op.Action {
+		return false
+	}
+	if op.OwnershipLevel.Less(p.OwnershipLevel) {
+		return false
+	}
+	return p.ResourceHierarchy.Overlaps(op.ResourceHierarchy)
 }
 
 // String converts a permission to it's equivalent string format
 func (p Permission) String() string {
+	prefix := ""
+	if p.Deny {
+		prefix = DenyPrefix
+	}
 	return fmt.Sprintf(
-		"%s::%s::%s::%s", p.Service, p.OwnershipLevel, p.Action,
+		"%s%s::%s::%s::%s", prefix, p.Service, p.OwnershipLevel, p.Action,
 		string(p.ResourceHierarchy),
 	)
 }
 
+// ConditionedString converts a permission to it's string format followed by
+// it's condition, if any. Roles may hold the same permission under
+// different conditions, this tells them apart
+func (p Permission) ConditionedString() string {
+	if p.Condition == "" {
+		return p.String()
+	}
+	return fmt.Sprintf("%s if %s", p.String(), p.Condition)
+}
+
 // HasServiceFullAccess checks if permission allows it's role
 // to execute any action over any resourch hierarchy under it's service
 func (p Permission) HasServiceFullAccess() bool {
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/conditions"
+)
+
+// PermissionEffects are what a permission row does to a requested permission
+var PermissionEffects = struct {
+	Grant string
+	Deny  string
+	None  string
+}{
+	Grant: "grant",
+	Deny:  "deny",
+	None:  "none",
+}
+
+// PermissionEvaluation details how a single permission row was compared to
+// a requested permission, mirroring IsPresent and Denies
+type PermissionEvaluation struct {
+	ID                       string         `json:"id"`
+	Permission               string         `json:"permission"`
+	Deny                     bool           `json:"deny"`
+	Expired                  bool           `json:"expired"`
+	ServiceMatches           bool           `json:"serviceMatches"`
+	ActionMatches            bool           `json:"actionMatches"`
+	OwnershipLevel           OwnershipLevel `json:"ownershipLevel"`
+	RequiredOwnershipLevel   OwnershipLevel `json:"requiredOwnershipLevel"`
+	OwnershipLevelMatches    bool           `json:"ownershipLevelMatches"`
+	ResourceHierarchyMatch   string         `json:"resourceHierarchyMatch"`
+	ResourceHierarchyMatches bool           `json:"resourceHierarchyMatches"`
+	Condition                string         `json:"condition,omitempty"`
+	ConditionHolds           bool           `json:"conditionHolds"`
+	Effect                   string         `json:"effect"`
+	Reason                   string         `json:"reason"`
+}
+
+// RoleExplanation groups the evaluations of a role bound to a service
+// account. Roles included by bound ones tell which bound role IncludedBy
+// them, and its binding. Roles bound to a group the service account is in
+// tell which group in BoundThroughGroup
+type RoleExplanation struct {
+	ID                 string                 `json:"id"`
+	Name               string                 `json:"name"`
+	IsBaseRole         bool                   `json:"isBaseRole"`
+	IncludedBy         string                 `json:"includedBy,omitempty"`
+	BoundThroughGroup  string                 `json:"boundThroughGroup,omitempty"`
+	BindingExpiresAt   pg.NullTime            `json:"bindingExpiresAt"`
+	BindingExpired     bool                   `json:"bindingExpired"`
+	PermissionsResults []PermissionEvaluation `json:"permissions"`
+}
+
+// PermissionExplanation tells why a service account has, or lacks, a
+// permission. ResourceHierarchyMatches are the resource hierarchies of
+// explained permissions matching the requested one, as HasPermission
+// matches them: grants containing it and denies overlapping it
+type PermissionExplanation struct {
+	ServiceAccountID         string            `json:"serviceAccountId"`
+	Permission               string            `json:"permission"`
+	Allowed                  bool              `json:"allowed"`
+	ResourceHierarchyMatches []string          `json:"resourceHierarchyMatches"`
+	DecidedBy                *string           `json:"decidedBy"`
+	Reason                   string            `json:"reason"`
+	Roles                    []RoleExplanation `json:"roles"`
+}
+
+// Evaluate compares pp, a permission row, to p, a requested permission,
+// taking pp condition as holding
+func (p Permission) Evaluate(pp Permission) PermissionEvaluation {
+	pe := PermissionEvaluation{
+		ID:                     pp.ID,
+		Permission:             pp.String(),
+		Deny:                   pp.Deny,
+		Expired:                pp.Expired(),
+		Condition:              pp.Condition,
+		ConditionHolds:         true,
+		OwnershipLevel:         pp.OwnershipLevel,
+		RequiredOwnershipLevel: p.OwnershipLevel,
+		Effect:                 PermissionEffects.None,
+	}
+	if pp.ResourceHierarchy.Contains(p.ResourceHierarchy) {
+		pe.ResourceHierarchyMatch = pp.ResourceHierarchy.String()
+	}
+	if pp.Deny {
+		pe.ServiceMatches = pp.Service == "*" || p.Service == "*" ||
+			pp.Service == p.Service
+		pe.ActionMatches = pp.Action.All() || p.Action.All() ||
+			pp.Action == p.Action
+		// a RL deny blocks RL and RO checks, a RO deny only RO ones
+		pe.OwnershipLevelMatches = !p.OwnershipLevel.Less(pp.OwnershipLevel)
+		pe.ResourceHierarchyMatches = pp.ResourceHierarchy.Overlaps(
+			p.ResourceHierarchy,
+		)
+	} else {
+		pe.ServiceMatches = pp.Service == "*" || pp.Service == p.Service
+		pe.ActionMatches = pp.Action.All() || pp.Action == p.Action
+		pe.OwnershipLevelMatches = !pp.OwnershipLevel.Less(p.OwnershipLevel)
+		pe.ResourceHierarchyMatches = pp.ResourceHierarchy.Contains(
+			p.ResourceHierarchy,
+		)
+	}
+	switch {
+	case pe.Expired:
+		pe.Reason = fmt.Sprintf("expired at %s", pp.ExpiresAt.Time)
+	case !pe.ServiceMatches:
+		pe.Reason = fmt.Sprintf("service %s doesn't match %s", pp.Service, p.Service)
+	case !pe.ActionMatches:
+		pe.Reason = fmt.Sprintf("action %s doesn't match %s", pp.Action, p.Action)
+	case !pe.OwnershipLevelMatches && pp.Deny:
+		pe.Reason = fmt.Sprintf(
+			"deny ownership level %s doesn't block %s", pp.OwnershipLevel,
+			p.OwnershipLevel,
+		)
+	case !pe.OwnershipLevelMatches:
+		pe.Reason = fmt.Sprintf(
+			"ownership level %s is less than required %s", pp.OwnershipLevel,
+			p.OwnershipLevel,
+		)
+	case !pe.ResourceHierarchyMatches && pp.Deny:
+		pe.Reason = fmt.Sprintf(
+			"resource hierarchy %s doesn't overlap %s", pp.ResourceHierarchy,
+			p.ResourceHierarchy,
+		)
+	case !pe.ResourceHierarchyMatches:
+		pe.Reason = fmt.Sprintf(
+			"resource hierarchy %s doesn't contain %s", pp.ResourceHierarchy,
+			p.ResourceHierarchy,
+		)
+	case pp.Deny:
+		pe.Effect = PermissionEffects.Deny
+		pe.Reason = "denies the requested permission"
+	default:
+		pe.Effect = PermissionEffects.Grant
+		pe.Reason = "grants the requested permission"
+	}
+	return pe
+}
+
+// EvaluateIn is Evaluate with pp condition evaluated in cc. Conditions that
+// don't hold leave pp without effect, unless it's a deny whose condition
+// failed to evaluate, see Applies
+func (p Permission) EvaluateIn(
+	pp Permission, cc *conditions.Context,
+) PermissionEvaluation {
+	pe := p.Evaluate(pp)
+	if pp.Condition == "" {
+		return pe
+	}
+	applies, err := pp.Applies(cc)
+	pe.ConditionHolds = applies && err == nil
+	if pe.Effect == PermissionEffects.None {
+		return pe
+	}
+	switch {
+	case err != nil && applies:
+		pe.Reason = fmt.Sprintf(
+			"denies the requested permission, as its condition failed: %s", err,
+		)
+	case err != nil:
+		pe.Effect = PermissionEffects.None
+		pe.Reason = fmt.Sprintf("condition failed: %s", err)
+	case !applies:
+		pe.Effect = PermissionEffects.None
+		pe.Reason = fmt.Sprintf("condition %s doesn't hold", pp.Condition)
+	}
+	return pe
+}
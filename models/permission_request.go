@@ -1,5 +1,7 @@
 package models
 
+import "github.com/go-pg/pg"
+
 // PermissionRequest type
 type PermissionRequest struct {
 	ID                        string                 `json:"id" pg:"id"`
@@ -14,6 +16,7 @@ type PermissionRequest struct {
 	RequesterPicture          string                 `json:"requesterPicture" pg:"requester_picture"`
 	RequesterName             string                 `json:"requesterName" pg:"requester_name"`
 	ModeratorServiceAccountID string                 `json:"moderatorServiceAccountId" pg:"moderator_service_account_id"`
+	ExpiresAt                 pg.NullTime            `json:"expiresAt" pg:"expires_at"`
 	CreatedUpdatedAt
 }
 
@@ -25,6 +28,7 @@ func (pr PermissionRequest) Permission() Permission {
 		OwnershipLevel:    pr.OwnershipLevel,
 		ResourceHierarchy: pr.ResourceHierarchy,
 		Alias:             pr.Alias,
+		ExpiresAt:         pr.ExpiresAt,
 	}
 }
 
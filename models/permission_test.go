@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/conditions"
 	"github.com/topfreegames/Will.IAM/models"
 )
 
@@ -87,6 +90,10 @@ func TestResourceHierarchyPermissionMatches(t *testing.T) {
 			str:      "x::y::z",
 			expected: []string{"*", "x::*", "x::y::*", "x::y::z"},
 		},
+		testCase{
+			str:      "x::*::z",
+			expected: []string{"*", "x::*", "x::*::*", "x::*::z"},
+		},
 	}
 	for _, tt := range tt {
 		rh := models.BuildResourceHierarchy(tt.str)
@@ -122,6 +129,17 @@ func TestBuildPermission(t *testing.T) {
 			},
 			err: nil,
 		},
+		testCase{
+			str: "!Maestro::RL::ListSchedulers::prod::*",
+			permission: models.Permission{
+				OwnershipLevel:    models.OwnershipLevels.Lender,
+				Action:            models.BuildAction("ListSchedulers"),
+				Service:           "Maestro",
+				ResourceHierarchy: models.ResourceHierarchy("prod::*"),
+				Deny:              true,
+			},
+			err: nil,
+		},
 	}
 
 	for _, tt := range tt {
@@ -165,6 +183,69 @@ func TestHasPermission(t *testing.T) {
 			permissions: buildPermissions(sniperPermissions),
 			isPresent:   false,
 		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::prod::sniper3d",
+			permissions: buildPermissions([]string{
+				"Maestro::RL::*::*", "!Maestro::RL::*::prod::*",
+			}),
+			isPresent: false,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::stag::sniper3d",
+			permissions: buildPermissions([]string{
+				"Maestro::RL::*::*", "!Maestro::RL::*::prod::*",
+			}),
+			isPresent: true,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::*",
+			permissions: buildPermissions([]string{
+				"Maestro::RL::*::*", "!Maestro::RL::*::prod::*",
+			}),
+			isPresent: false,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::prod::sniper3d",
+			permissions: buildPermissions([]string{
+				"Maestro::RO::*::*", "!Maestro::RO::*::prod::*",
+			}),
+			isPresent: true,
+		},
+		testCase{
+			permission: "Maestro::RO::ListSchedulers::prod::sniper3d",
+			permissions: buildPermissions([]string{
+				"Maestro::RO::*::*", "!Maestro::RO::*::prod::*",
+			}),
+			isPresent: false,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::prod::sniper3d",
+			permissions: buildPermissions([]string{
+				"*::RO::*::*", "!Maestro::RL::EditScheduler::prod::*",
+			}),
+			isPresent: true,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::sniper3d::prod::red",
+			permissions: buildPermissions([]string{
+				"Maestro::RL::*::*::prod::*",
+			}),
+			isPresent: true,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::sniper3d::stag::red",
+			permissions: buildPermissions([]string{
+				"Maestro::RL::*::*::prod::*",
+			}),
+			isPresent: false,
+		},
+		testCase{
+			permission: "Maestro::RL::ListSchedulers::sniper3d::prod::red",
+			permissions: buildPermissions([]string{
+				"Maestro::RL::*::sniper*::*", "!Maestro::RL::*::*::prod::*",
+			}),
+			isPresent: false,
+		},
 	}
 
 	for i, tt := range tt {
@@ -184,3 +265,217 @@ func TestHasPermission(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceHierarchyContains(t *testing.T) {
+	tt := []struct {
+		rh, orh  string
+		contains bool
+	}{
+		{"*", "x", true},
+		{"*", "*", true},
+		{"x::*", "x::y::z", true},
+		{"x::*", "x", false},
+		{"x::*", "*", false},
+		{"x::y", "x::y", true},
+		{"x::y", "x::y::z", false},
+		{"maestro::*::prod::*", "maestro::sniper3d::prod::red", true},
+		{"maestro::*::prod::*", "maestro::sniper3d::prod::*", true},
+		{"maestro::*::prod::*", "maestro::*::prod::*", true},
+		{"maestro::*::prod::*", "maestro::sniper3d::stag::red", false},
+		{"maestro::*::prod::*", "maestro::sniper3d::*", false},
+		{"maestro::*::prod::*", "maestro::sniper3d::prod", false},
+		{"maestro::*::prod", "maestro::sniper3d::prod", true},
+		{"maestro::*::prod", "maestro::sniper3d::prod::red", false},
+		{"*::*::prod::*", "maestro::sniper3d::prod::red", true},
+		{"maestro::sniper-*::*", "maestro::sniper-3d::prod::red", true},
+		{"maestro::sniper-*::*", "maestro::sniper-*::prod::*", true},
+		{"maestro::sniper-*::*", "maestro::war-machines::prod::red", false},
+		{"maestro::sniper-*::*", "maestro::*::prod::red", false},
+		{"maestro::*-3d::*", "maestro::sniper-3d::prod", true},
+		{"maestro::s*r*d::*", "maestro::sniper3d::prod", true},
+		{"maestro::s*r*d::*", "maestro::sniper3::prod", false},
+	}
+	for _, tc := range tt {
+		rh := models.BuildResourceHierarchy(tc.rh)
+		orh := models.BuildResourceHierarchy(tc.orh)
+		if contains := rh.Contains(orh); contains != tc.contains {
+			t.Errorf("Expected %s contains %s to be %t. Got %t", tc.rh, tc.orh, tc.contains, contains)
+		}
+	}
+}
+
+func TestResourceHierarchyOverlaps(t *testing.T) {
+	tt := []struct {
+		rh, orh  string
+		overlaps bool
+	}{
+		{"*", "x", true},
+		{"x::*", "x::y", true},
+		{"x::*", "x", false},
+		{"x::y", "x::z", false},
+		{"*::prod::*", "maestro::*", true},
+		{"*::prod::*", "maestro::prod", false},
+		{"*::prod::*", "maestro::prod::red", true},
+		{"*::prod::*", "maestro::stag::red", false},
+		{"maestro::*::prod::*", "maestro::sniper3d::*", true},
+		{"maestro::*::prod::*", "maestro::*::stag::*", false},
+		{"maestro::sniper-*::*", "maestro::*-3d::*", true},
+		{"maestro::sniper-*::*", "maestro::war-*::*", false},
+		{"maestro::*-3d::*", "maestro::*-2d::*", false},
+		{"maestro::sniper-*", "maestro::sniper-3d", true},
+	}
+	for _, tc := range tt {
+		rh := models.BuildResourceHierarchy(tc.rh)
+		orh := models.BuildResourceHierarchy(tc.orh)
+		if overlaps := rh.Overlaps(orh); overlaps != tc.overlaps {
+			t.Errorf("Expected %s overlaps %s to be %t. Got %t", tc.rh, tc.orh, tc.overlaps, overlaps)
+		}
+		if overlaps := orh.Overlaps(rh); overlaps != tc.overlaps {
+			t.Errorf("Expected %s overlaps %s to be %t. Got %t", tc.orh, tc.rh, tc.overlaps, overlaps)
+		}
+	}
+}
+
+func TestPermissionStringWithDeny(t *testing.T) {
+	str := "!Maestro::RL::ListSchedulers::prod::*"
+	p, err := models.BuildPermission(str)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.String() != str {
+		t.Errorf("Expected String() to be %s. Got %s", str, p.String())
+	}
+}
+
+func TestHasPermissionIgnoresExpired(t *testing.T) {
+	permission, _ := models.BuildPermission("Maestro::RL::ListSchedulers::prod::x")
+	permissions := buildPermissions([]string{
+		"Maestro::RL::*::*", "!Maestro::RL::*::prod::*",
+	})
+	permissions[0].ExpiresAt = pg.NullTime{Time: time.Now().Add(time.Hour)}
+	permissions[1].ExpiresAt = pg.NullTime{Time: time.Now().Add(-time.Second)}
+	if !permission.IsPresent(permissions) {
+		t.Errorf("Expected IsPresent to be true when deny is expired")
+	}
+	permissions[0].ExpiresAt = pg.NullTime{Time: time.Now().Add(-time.Second)}
+	if permission.IsPresent(permissions) {
+		t.Errorf("Expected IsPresent to be false when allow is expired")
+	}
+}
+
+func TestPermissionMatch(t *testing.T) {
+	permissions := buildPermissions([]string{
+		"Maestro::RL::ListSchedulers::*", "!Maestro::RL::*::prod::*",
+	})
+	tt := []struct {
+		permission string
+		match      string
+		has        bool
+	}{
+		{"Maestro::RL::ListSchedulers::dev::x", "Maestro::RL::ListSchedulers::*", true},
+		{"Maestro::RL::ListSchedulers::prod::x", "!Maestro::RL::*::prod::*", false},
+		{"Maestro::RL::EditScheduler::dev::x", "", false},
+	}
+	for _, tc := range tt {
+		p, _ := models.BuildPermission(tc.permission)
+		match, has := p.Match(permissions)
+		if has != tc.has {
+			t.Errorf("Expected %s has to be %v. Got %v", tc.permission, tc.has, has)
+		}
+		got := ""
+		if match != nil {
+			got = match.String()
+		}
+		if got != tc.match {
+			t.Errorf("Expected %s match to be %s. Got %s", tc.permission, tc.match, got)
+		}
+	}
+}
+
+func TestPermissionEvaluate(t *testing.T) {
+	p, _ := models.BuildPermission("Maestro::RO::ListSchedulers::prod::x")
+	tt := []struct {
+		permission string
+		effect     string
+		rhMatch    string
+		olMatches  bool
+	}{
+		{"Maestro::RO::ListSchedulers::prod::*", models.PermissionEffects.Grant, "prod::*", true},
+		{"Maestro::RL::ListSchedulers::prod::*", models.PermissionEffects.None, "prod::*", false},
+		{"Maestro::RO::*::*", models.PermissionEffects.Grant, "*", true},
+		{"Maestro::RO::ListSchedulers::dev::*", models.PermissionEffects.None, "", true},
+		{"!Maestro::RL::*::prod::*", models.PermissionEffects.Deny, "prod::*", true},
+		{"!Maestro::RO::*::prod::x", models.PermissionEffects.Deny, "prod::x", true},
+	}
+	for _, tc := range tt {
+		pp, _ := models.BuildPermission(tc.permission)
+		pe := p.Evaluate(pp)
+		if pe.Effect != tc.effect {
+			t.Errorf("Expected %s effect to be %s. Got %s (%s)", tc.permission, tc.effect, pe.Effect, pe.Reason)
+		}
+		if pe.ResourceHierarchyMatch != tc.rhMatch {
+			t.Errorf("Expected %s rh match to be %s. Got %s", tc.permission, tc.rhMatch, pe.ResourceHierarchyMatch)
+		}
+		if pe.OwnershipLevelMatches != tc.olMatches {
+			t.Errorf("Expected %s ownership level matches to be %v", tc.permission, tc.olMatches)
+		}
+	}
+}
+
+func TestApplicablePermissions(t *testing.T) {
+	permissions := buildPermissions([]string{
+		"Maestro::RL::ListSchedulers::*",
+		"Maestro::RL::Deploy::prod::*",
+		"Maestro::RL::Deploy::stag::*",
+		"!Maestro::RL::*::prod::secret",
+		"!Maestro::RL::*::stag::secret",
+	})
+	permissions[1].Condition = "time.hour >= 9 && time.hour < 18"
+	permissions[2].Condition = `context.ticket != ""`
+	permissions[3].Condition = `context.env == "prod"`
+	permissions[4].Condition = "time.hour < 9"
+	cc := &conditions.Context{Time: time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)}
+	applicable := models.ApplicablePermissions(permissions, cc)
+	got := []string{}
+	for _, p := range applicable {
+		got = append(got, p.String())
+	}
+	// missing context attributes keep denies and drop grants
+	want := []string{
+		"Maestro::RL::ListSchedulers::*",
+		"Maestro::RL::Deploy::prod::*",
+		"!Maestro::RL::*::prod::secret",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v. Got %v", want, got)
+	}
+}
+
+func TestPermissionEvaluateIn(t *testing.T) {
+	p, _ := models.BuildPermission("Maestro::RL::Deploy::prod::x")
+	cc := &conditions.Context{Time: time.Date(2026, 10, 14, 20, 0, 0, 0, time.UTC)}
+	tt := []struct {
+		permission string
+		condition  string
+		effect     string
+		holds      bool
+	}{
+		{"Maestro::RL::Deploy::prod::*", "", models.PermissionEffects.Grant, true},
+		{"Maestro::RL::Deploy::prod::*", "time.hour < 18", models.PermissionEffects.None, false},
+		{"Maestro::RL::Deploy::prod::*", "time.hour >= 18", models.PermissionEffects.Grant, true},
+		{"Maestro::RL::Deploy::prod::*", `context.ticket != ""`, models.PermissionEffects.None, false},
+		{"!Maestro::RL::*::prod::*", "time.hour < 18", models.PermissionEffects.None, false},
+		{"!Maestro::RL::*::prod::*", `context.env == "prod"`, models.PermissionEffects.Deny, false},
+	}
+	for _, tc := range tt {
+		pp, _ := models.BuildPermission(tc.permission)
+		pp.Condition = tc.condition
+		pe := p.EvaluateIn(pp, cc)
+		if pe.Effect != tc.effect {
+			t.Errorf("Expected %s if %s effect to be %s. Got %s (%s)", tc.permission, tc.condition, tc.effect, pe.Effect, pe.Reason)
+		}
+		if pe.ConditionHolds != tc.holds {
+			t.Errorf("Expected %s if %s to hold to be %v", tc.permission, tc.condition, tc.holds)
+		}
+	}
+}
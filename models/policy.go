@@ -0,0 +1,299 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/conditions"
+)
+
+// Policy declares services and roles, along with the permissions, included
+// roles and bindings of the latter, so access can be kept in version control
+// and reviewed before being applied. Roles and groups are referred to by
+// name and service accounts by email. Eg:
+//
+//	services:
+//	  - name: Maestro
+//	    permissionName: Maestro
+//	    amUrl: http://maestro/am
+//	roles:
+//	  - name: maestro-prod-operators
+//	    permissions:
+//	      - Maestro::RL::*::*::prod::*
+//	      - permission: Maestro::RL::Deploy::*::prod::*
+//	        alias: Deploy to prod
+//	        condition: time.hour >= 9 && time.hour < 18
+//	    includedRoles: [maestro-viewers]
+//	    serviceAccounts:
+//	      - ci@example.com
+//	      - email: oncall@example.com
+//	        expiresAt: 2027-01-01T00:00:00Z
+//	    groups: [sre]
+type Policy struct {
+	Services []PolicyService `yaml:"services,omitempty"`
+	Roles    []PolicyRole    `yaml:"roles,omitempty"`
+}
+
+// PolicyService declares a service, identified by its PermissionName
+type PolicyService struct {
+	Name           string `yaml:"name"`
+	PermissionName string `yaml:"permissionName"`
+	AMURL          string `yaml:"amUrl,omitempty"`
+}
+
+// PolicyRole declares a role, other than a base role, and everything it
+// grants to whom
+type PolicyRole struct {
+	Name            string             `yaml:"name"`
+	Permissions     []PolicyPermission `yaml:"permissions,omitempty"`
+	IncludedRoles   []string           `yaml:"includedRoles,omitempty"`
+	ServiceAccounts []PolicyBinding    `yaml:"serviceAccounts,omitempty"`
+	Groups          []string           `yaml:"groups,omitempty"`
+}
+
+// PolicyPermission declares a permission of a role. It can be written as
+// just the permission string when it has no alias, expiration or condition
+type PolicyPermission struct {
+	Permission string     `yaml:"permission"`
+	Alias      string     `yaml:"alias,omitempty"`
+	ExpiresAt  *time.Time `yaml:"expiresAt,omitempty"`
+	Condition  string     `yaml:"condition,omitempty"`
+}
+
+// UnmarshalYAML reads pp either from a permission string or a mapping
+func (pp *PolicyPermission) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&pp.Permission); err == nil {
+		return nil
+	}
+	type plain PolicyPermission
+	return unmarshal((*plain)(pp))
+}
+
+// MarshalYAML writes pp as just its permission string when that's enough
+func (pp PolicyPermission) MarshalYAML() (interface{}, error) {
+	if pp.Alias == "" && pp.ExpiresAt == nil && pp.Condition == "" {
+		return pp.Permission, nil
+	}
+	type plain PolicyPermission
+	return plain(pp), nil
+}
+
+// Build returns the Permission pp declares
+func (pp PolicyPermission) Build() (Permission, error) {
+	p, err := BuildPermission(pp.Permission)
+	if err != nil {
+		return Permission{}, err
+	}
+	p.Alias = pp.Alias
+	p.Condition = pp.Condition
+	if pp.ExpiresAt != nil {
+		p.ExpiresAt = pg.NullTime{Time: *pp.ExpiresAt}
+	}
+	return p, nil
+}
+
+// BuildPolicyPermission returns the PolicyPermission declaring p
+func BuildPolicyPermission(p Permission) PolicyPermission {
+	pp := PolicyPermission{
+		Permission: p.String(),
+		Alias:      p.Alias,
+		Condition:  p.Condition,
+	}
+	if !p.ExpiresAt.IsZero() {
+		expiresAt := p.ExpiresAt.Time
+		pp.ExpiresAt = &expiresAt
+	}
+	return pp
+}
+
+// PolicyBinding binds a service account, by email, to a role. It can be
+// written as just the email when the binding doesn't expire
+type PolicyBinding struct {
+	Email     string     `yaml:"email"`
+	ExpiresAt *time.Time `yaml:"expiresAt,omitempty"`
+}
+
+// UnmarshalYAML reads pb either from an email or a mapping
+func (pb *PolicyBinding) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&pb.Email); err == nil {
+		return nil
+	}
+	type plain PolicyBinding
+	return unmarshal((*plain)(pb))
+}
+
+// MarshalYAML writes pb as just its email when it doesn't expire
+func (pb PolicyBinding) MarshalYAML() (interface{}, error) {
+	if pb.ExpiresAt == nil {
+		return pb.Email, nil
+	}
+	type plain PolicyBinding
+	return plain(pb), nil
+}
+
+// Permissions returns every permission p roles declare
+func (p Policy) Permissions() ([]Permission, error) {
+	ps := []Permission{}
+	for _, r := range p.Roles {
+		for _, pp := range r.Permissions {
+			permission, err := pp.Build()
+			if err != nil {
+				return nil, err
+			}
+			ps = append(ps, permission)
+		}
+	}
+	return ps, nil
+}
+
+// Validate Policy fields. Names of services, roles and of what roles refer
+// to must be present and declared once, roles names regardless of case
+func (p Policy) Validate() Validation {
+	v := &Validation{}
+	permissionNames := map[string]bool{}
+	for i, s := range p.Services {
+		key := fmt.Sprintf("services[%d]", i)
+		if s.Name == "" {
+			v.AddError(key+".name", "required")
+		}
+		if s.PermissionName == "" {
+			v.AddError(key+".permissionName", "required")
+		} else if permissionNames[s.PermissionName] {
+			v.AddError(key+".permissionName", "declared more than once")
+		}
+		permissionNames[s.PermissionName] = true
+	}
+	roleNames := map[string]bool{}
+	for i, r := range p.Roles {
+		key := fmt.Sprintf("roles[%d]", i)
+		if r.Name == "" {
+			v.AddError(key+".name", "required")
+		} else if roleNames[strings.ToLower(r.Name)] {
+			v.AddError(key+".name", "declared more than once")
+		}
+		roleNames[strings.ToLower(r.Name)] = true
+		r.validate(v, key)
+	}
+	return *v
+}
+
+func (r PolicyRole) validate(v *Validation, key string) {
+	now := time.Now()
+	permissions := map[string]bool{}
+	for i, pp := range r.Permissions {
+		pKey := fmt.Sprintf("%s.permissions[%d]", key, i)
+		p, err := pp.Build()
+		if err != nil {
+			v.AddError(pKey, err.Error())
+			continue
+		}
+		if permissions[p.ConditionedString()] {
+			v.AddError(pKey, "declared more than once")
+		}
+		permissions[p.ConditionedString()] = true
+		if pp.ExpiresAt != nil && !pp.ExpiresAt.After(now) {
+			v.AddError(pKey+".expiresAt", "must be in the future")
+		}
+		if pp.Condition != "" {
+			if err := conditions.Validate(pp.Condition); err != nil {
+				v.AddError(pKey+".condition", err.Error())
+			}
+		}
+	}
+	emails := map[string]bool{}
+	for i, pb := range r.ServiceAccounts {
+		bKey := fmt.Sprintf("%s.serviceAccounts[%d]", key, i)
+		if pb.Email == "" {
+			v.AddError(bKey+".email", "required")
+		} else if emails[pb.Email] {
+			v.AddError(bKey+".email", "declared more than once")
+		}
+		emails[pb.Email] = true
+		if pb.ExpiresAt != nil && !pb.ExpiresAt.After(now) {
+			v.AddError(bKey+".expiresAt", "must be in the future")
+		}
+	}
+	validateNames(v, key+".includedRoles", r.IncludedRoles)
+	validateNames(v, key+".groups", r.Groups)
+}
+
+// validateNames checks names are present and unique regardless of case
+func validateNames(v *Validation, key string, names []string) {
+	seen := map[string]bool{}
+	for i, name := range names {
+		if name == "" {
+			v.AddError(fmt.Sprintf("%s[%d]", key, i), "required")
+		} else if seen[strings.ToLower(name)] {
+			v.AddError(fmt.Sprintf("%s[%d]", key, i), "declared more than once")
+		}
+		seen[strings.ToLower(name)] = true
+	}
+}
+
+// PolicyChangeAction is what a PolicyChange does to its target
+type PolicyChangeAction string
+
+// PolicyChangeActions possible
+var PolicyChangeActions = struct {
+	Create PolicyChangeAction
+	Update PolicyChangeAction
+	Delete PolicyChangeAction
+}{
+	Create: "create",
+	Update: "update",
+	Delete: "delete",
+}
+
+// PolicyChangeKind is the kind of thing a PolicyChange targets
+type PolicyChangeKind string
+
+// PolicyChangeKinds possible
+var PolicyChangeKinds = struct {
+	Service        PolicyChangeKind
+	Role           PolicyChangeKind
+	Permission     PolicyChangeKind
+	IncludedRole   PolicyChangeKind
+	ServiceAccount PolicyChangeKind
+	Group          PolicyChangeKind
+}{
+	Service:        "service",
+	Role:           "role",
+	Permission:     "permission",
+	IncludedRole:   "includedRole",
+	ServiceAccount: "serviceAccount",
+	Group:          "group",
+}
+
+// PolicyChange is a single difference between a Policy and what's stored,
+// which applying the policy makes. Role is the role Target belongs to, if
+// it isn't a service or a role itself, and Fields are what an update changes
+type PolicyChange struct {
+	Action PolicyChangeAction `json:"action"`
+	Kind   PolicyChangeKind   `json:"kind"`
+	Role   string             `json:"role,omitempty"`
+	Target string             `json:"target"`
+	Fields []string           `json:"fields,omitempty"`
+}
+
+// String renders pc like a diff line
+// Eg: + role maestro-prod-operators permission Maestro::RL::*::*::prod::*
+func (pc PolicyChange) String() string {
+	sign := map[PolicyChangeAction]string{
+		PolicyChangeActions.Create: "+",
+		PolicyChangeActions.Update: "~",
+		PolicyChangeActions.Delete: "-",
+	}[pc.Action]
+	str := fmt.Sprintf("%s %s %s", sign, pc.Kind, pc.Target)
+	if pc.Role != "" {
+		str = fmt.Sprintf(
+			"%s %s %s %s %s", sign, PolicyChangeKinds.Role, pc.Role, pc.Kind,
+			pc.Target,
+		)
+	}
+	if len(pc.Fields) > 0 {
+		str = fmt.Sprintf("%s (%s)", str, strings.Join(pc.Fields, ", "))
+	}
+	return str
+}
@@ -0,0 +1,195 @@
+// +build unit
+
+package models_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPolicyYAML(t *testing.T) {
+	expiresAt := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	str := `roles:
+- name: maestro-prod-operators
+  permissions:
+  - Maestro::RL::*::*::prod::*
+  - permission: Maestro::RL::Deploy::*::prod::*
+    alias: Deploy to prod
+  serviceAccounts:
+  - ci@example.com
+  - email: oncall@example.com
+    expiresAt: 2099-01-01T00:00:00Z
+  groups:
+  - sre
+`
+	expected := models.Policy{
+		Roles: []models.PolicyRole{
+			models.PolicyRole{
+				Name: "maestro-prod-operators",
+				Permissions: []models.PolicyPermission{
+					models.PolicyPermission{
+						Permission: "Maestro::RL::*::*::prod::*",
+					},
+					models.PolicyPermission{
+						Permission: "Maestro::RL::Deploy::*::prod::*",
+						Alias:      "Deploy to prod",
+					},
+				},
+				ServiceAccounts: []models.PolicyBinding{
+					models.PolicyBinding{Email: "ci@example.com"},
+					models.PolicyBinding{
+						Email: "oncall@example.com", ExpiresAt: &expiresAt,
+					},
+				},
+				Groups: []string{"sre"},
+			},
+		},
+	}
+	p := models.Policy{}
+	if err := yaml.UnmarshalStrict([]byte(str), &p); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected %#v. Got %#v", expected, p)
+	}
+	bts, err := yaml.Marshal(p)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if string(bts) != str {
+		t.Errorf("Expected %s. Got %s", str, string(bts))
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	type testCase struct {
+		policy models.Policy
+		errors map[string]string
+	}
+	tt := []testCase{
+		testCase{
+			policy: models.Policy{
+				Services: []models.PolicyService{
+					models.PolicyService{Name: "Maestro", PermissionName: "Maestro"},
+				},
+				Roles: []models.PolicyRole{
+					models.PolicyRole{
+						Name: "operators",
+						Permissions: []models.PolicyPermission{
+							models.PolicyPermission{
+								Permission: "Maestro::RL::*::*",
+								Condition:  "time.hour >= 9",
+							},
+							models.PolicyPermission{
+								Permission: "Maestro::RL::*::*",
+								Condition:  `context.ticket != ""`,
+							},
+						},
+						IncludedRoles: []string{"viewers"},
+						ServiceAccounts: []models.PolicyBinding{
+							models.PolicyBinding{Email: "ci@example.com"},
+						},
+						Groups: []string{"sre"},
+					},
+				},
+			},
+			errors: map[string]string{},
+		},
+		testCase{
+			policy: models.Policy{
+				Services: []models.PolicyService{
+					models.PolicyService{Name: "Maestro", PermissionName: "Maestro"},
+					models.PolicyService{PermissionName: "Maestro"},
+				},
+				Roles: []models.PolicyRole{
+					models.PolicyRole{Name: "operators"},
+					models.PolicyRole{
+						Name: "Operators",
+						Permissions: []models.PolicyPermission{
+							models.PolicyPermission{Permission: "Maestro::RX::*::*"},
+							models.PolicyPermission{
+								Permission: "Maestro::RL::*::*",
+								ExpiresAt:  &past,
+							},
+						},
+						IncludedRoles: []string{"viewers", "Viewers"},
+						ServiceAccounts: []models.PolicyBinding{
+							models.PolicyBinding{},
+						},
+						Groups: []string{""},
+					},
+				},
+			},
+			errors: map[string]string{
+				"services[1].name":                  "required",
+				"services[1].permissionName":        "declared more than once",
+				"roles[1].name":                     "declared more than once",
+				"roles[1].permissions[0]":           "OwnershipLevel needs to be RO or RL",
+				"roles[1].permissions[1].expiresAt": "must be in the future",
+				"roles[1].includedRoles[1]":         "declared more than once",
+				"roles[1].serviceAccounts[0].email": "required",
+				"roles[1].groups[0]":                "required",
+			},
+		},
+	}
+	for i, tt := range tt {
+		v := tt.policy.Validate()
+		errors := map[string]map[string]string{}
+		if err := json.Unmarshal(v.Errors(), &errors); err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+		if len(tt.errors) == 0 && len(errors["errors"]) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(errors["errors"], tt.errors) {
+			t.Errorf("%d: Expected %v. Got %v", i, tt.errors, errors["errors"])
+		}
+	}
+}
+
+func TestPolicyChangeString(t *testing.T) {
+	type testCase struct {
+		change   models.PolicyChange
+		expected string
+	}
+	tt := []testCase{
+		testCase{
+			change: models.PolicyChange{
+				Action: models.PolicyChangeActions.Create,
+				Kind:   models.PolicyChangeKinds.Role,
+				Target: "operators",
+			},
+			expected: "+ role operators",
+		},
+		testCase{
+			change: models.PolicyChange{
+				Action: models.PolicyChangeActions.Update,
+				Kind:   models.PolicyChangeKinds.Permission,
+				Role:   "operators",
+				Target: "Maestro::RL::*::*",
+				Fields: []string{"alias", "condition"},
+			},
+			expected: "~ role operators permission Maestro::RL::*::* (alias, condition)",
+		},
+		testCase{
+			change: models.PolicyChange{
+				Action: models.PolicyChangeActions.Delete,
+				Kind:   models.PolicyChangeKinds.ServiceAccount,
+				Role:   "operators",
+				Target: "ci@example.com",
+			},
+			expected: "- role operators serviceAccount ci@example.com",
+		},
+	}
+	for _, tt := range tt {
+		if str := tt.change.String(); str != tt.expected {
+			t.Errorf("Expected %s. Got %s", tt.expected, str)
+		}
+	}
+}
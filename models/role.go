@@ -1,18 +1,39 @@
 package models
 
+import (
+	"time"
+
+	"github.com/go-pg/pg"
+)
+
 // Role type
 type Role struct {
 	ID         string `json:"id" pg:"id"`
 	Name       string `json:"name" pg:"name"`
 	IsBaseRole bool   `json:"isBaseRole" pg:"is_base_role" sql:",notnull"`
+	// ManagedBySCIM roles were created through SCIM, they're the only ones
+	// identity providers can see and change as groups
+	ManagedBySCIM bool `json:"managedBySCIM" pg:"managed_by_scim" sql:",notnull"`
 	// Should change updatedAt when a permission is created for role
 	CreatedUpdatedAt
 }
 
 // RoleBinding type
 type RoleBinding struct {
-	ID               string `json:"id" pg:"id"`
-	ServiceAccountID string `json:"serviceAccountId" pg:"service_account_id"`
-	RoleID           string `json:"roleId" pg:"role_id"`
+	ID               string      `json:"id" pg:"id"`
+	ServiceAccountID string      `json:"serviceAccountId" pg:"service_account_id"`
+	RoleID           string      `json:"roleId" pg:"role_id"`
+	ExpiresAt        pg.NullTime `json:"expiresAt" pg:"expires_at"`
+	// ManagedByRule bindings are added and removed by RoleBindingRules as
+	// service accounts log in
+	ManagedByRule bool `json:"managedByRule" pg:"managed_by_rule" sql:",notnull"`
+	// ManagedBySCIM bindings are added and removed by identity providers
+	// through SCIM group members
+	ManagedBySCIM bool `json:"managedBySCIM" pg:"managed_by_scim" sql:",notnull"`
 	CreatedUpdatedAt
 }
+
+// Expired checks if rb has an expiration date and it has already passed
+func (rb RoleBinding) Expired() bool {
+	return !rb.ExpiresAt.IsZero() && !rb.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RoleBindingRule binds service accounts to the roles named Role when they
+// log in with a login provider that tells they're in any of Groups, or with
+// an email matching any of Emails. Emails are glob patterns, like
+// *@eng.example.com, and both are case insensitive
+type RoleBindingRule struct {
+	Role   string   `mapstructure:"role"`
+	Groups []string `mapstructure:"groups"`
+	Emails []string `mapstructure:"emails"`
+}
+
+// Validate RoleBindingRule fields
+func (r RoleBindingRule) Validate() error {
+	if r.Role == "" {
+		return fmt.Errorf("role is required")
+	}
+	if len(r.Groups) == 0 && len(r.Emails) == 0 {
+		return fmt.Errorf("rule for %s needs groups or emails", r.Role)
+	}
+	for _, pattern := range r.Emails {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("rule for %s has malformed email %s", r.Role, pattern)
+		}
+	}
+	return nil
+}
+
+// Matches tells if a service account with email, in groups, should be bound
+// to r.Role
+func (r RoleBindingRule) Matches(email string, groups []string) bool {
+	for _, rg := range r.Groups {
+		for _, g := range groups {
+			if strings.EqualFold(rg, g) {
+				return true
+			}
+		}
+	}
+	email = strings.ToLower(email)
+	for _, pattern := range r.Emails {
+		if ok, _ := path.Match(strings.ToLower(pattern), email); ok {
+			return true
+		}
+	}
+	return false
+}
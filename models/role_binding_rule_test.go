@@ -0,0 +1,54 @@
+// +build unit
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+func TestRoleBindingRuleMatches(t *testing.T) {
+	rule := models.RoleBindingRule{
+		Role:   "engineering",
+		Groups: []string{"Engineering", "oncall"},
+		Emails: []string{"*@eng.example.com", "cto@example.com"},
+	}
+	type testCase struct {
+		email   string
+		groups  []string
+		matches bool
+	}
+	testCases := []testCase{
+		{"john@example.com", []string{"sales", "engineering"}, true},
+		{"john@example.com", []string{"sales"}, false},
+		{"john@example.com", nil, false},
+		{"John@Eng.Example.com", nil, true},
+		{"john@sub.eng.example.com", nil, false},
+		{"cto@example.com", nil, true},
+	}
+	for _, tt := range testCases {
+		if matches := rule.Matches(tt.email, tt.groups); matches != tt.matches {
+			t.Errorf("Expected %v for %s in %v. Got %v", tt.matches, tt.email, tt.groups, matches)
+		}
+	}
+}
+
+func TestRoleBindingRuleValidate(t *testing.T) {
+	type testCase struct {
+		rule  models.RoleBindingRule
+		valid bool
+	}
+	testCases := []testCase{
+		{models.RoleBindingRule{Role: "r", Groups: []string{"g"}}, true},
+		{models.RoleBindingRule{Role: "r", Emails: []string{"*@example.com"}}, true},
+		{models.RoleBindingRule{Groups: []string{"g"}}, false},
+		{models.RoleBindingRule{Role: "r"}, false},
+		{models.RoleBindingRule{Role: "r", Emails: []string{"[@example.com"}}, false},
+	}
+	for _, tt := range testCases {
+		if err := tt.rule.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Expected valid %v for %#v. Got %v", tt.valid, tt.rule, err)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SCIM 2.0 schema URNs, as defined by RFC 7643 and RFC 7644
+const (
+	SCIMUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMGroupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// SCIMError is a SCIM error response, which is also an error
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// NewSCIMError ctor. scimType is one of RFC 7644 3.12 error types, or empty
+func NewSCIMError(status int, scimType, detail string) *SCIMError {
+	return &SCIMError{
+		Schemas:  []string{SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	}
+}
+
+func (e *SCIMError) Error() string {
+	return fmt.Sprintf("scim: %s %s: %s", e.Status, e.SCIMType, e.Detail)
+}
+
+// StatusCode is the HTTP status e is responded with
+func (e *SCIMError) StatusCode() int {
+	status, _ := strconv.Atoi(e.Status)
+	return status
+}
+
+// SCIMMeta is the meta attribute of SCIM resources
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+// SCIMName is the name attribute of a SCIM user
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail is an email of a SCIM user
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUser is a SCIM user, which is an oauth2 ServiceAccount. userName is
+// its email. Attributes without a ServiceAccount counterpart, like
+// externalId, are accepted but not stored
+type SCIMUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *SCIMName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []SCIMEmail `json:"emails,omitempty"`
+	Active      bool        `json:"active"`
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// NewSCIMUser builds the SCIMUser of sa
+func NewSCIMUser(sa *ServiceAccount) *SCIMUser {
+	return &SCIMUser{
+		Schemas:     []string{SCIMUserSchema},
+		ID:          sa.ID,
+		UserName:    sa.Email,
+		DisplayName: sa.Name,
+		Emails:      []SCIMEmail{{Value: sa.Email, Type: "work", Primary: true}},
+		Active:      sa.IsActive(),
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Location:     "/scim/v2/Users/" + sa.ID,
+		},
+	}
+}
+
+// ServiceAccountName is the name u service account should have
+func (u SCIMUser) ServiceAccountName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil && u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	if u.Name != nil && (u.Name.GivenName != "" || u.Name.FamilyName != "") {
+		return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	}
+	return u.UserName
+}
+
+// Validate SCIMUser fields
+func (u SCIMUser) Validate() error {
+	if u.UserName == "" {
+		return NewSCIMError(400, "invalidValue", "userName is required")
+	}
+	return nil
+}
+
+// SCIMMember is a member of a SCIM group, whose value is a ServiceAccount id
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMGroup is a SCIM group, which is a Role that isn't a base role. Its
+// members are service accounts bound to it
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// NewSCIMGroup builds the SCIMGroup of r, with members sas
+func NewSCIMGroup(r *Role, sas []ServiceAccount) *SCIMGroup {
+	g := &SCIMGroup{
+		Schemas:     []string{SCIMGroupSchema},
+		ID:          r.ID,
+		DisplayName: r.Name,
+		Members:     make([]SCIMMember, len(sas)),
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Location:     "/scim/v2/Groups/" + r.ID,
+		},
+	}
+	for i := range sas {
+		g.Members[i] = SCIMMember{Value: sas[i].ID, Display: sas[i].Name}
+	}
+	return g
+}
+
+// MembersIDs returns g members ids, without repetitions
+func (g SCIMGroup) MembersIDs() []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, m := range g.Members {
+		if !seen[m.Value] {
+			seen[m.Value] = true
+			ids = append(ids, m.Value)
+		}
+	}
+	return ids
+}
+
+// Validate SCIMGroup fields
+func (g SCIMGroup) Validate() error {
+	if g.DisplayName == "" {
+		return NewSCIMError(400, "invalidValue", "displayName is required")
+	}
+	for _, m := range g.Members {
+		if m.Value == "" {
+			return NewSCIMError(400, "invalidValue", "members value is required")
+		}
+	}
+	return nil
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMFilter is an `attribute eq "value"` filter, the only kind supported.
+// Attribute is lowercased since SCIM attribute names are case insensitive
+type SCIMFilter struct {
+	Attribute string
+	Value     string
+}
+
+var scimFilterRegexp = regexp.MustCompile(
+	`^\s*([A-Za-z][\w.]*)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`,
+)
+
+// ParseSCIMFilter parses filter, returning nil if it's empty
+func ParseSCIMFilter(filter string) (*SCIMFilter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+	m := scimFilterRegexp.FindStringSubmatch(filter)
+	if m == nil {
+		return nil, NewSCIMError(
+			400, "invalidFilter", `only attribute eq "value" filters are supported`,
+		)
+	}
+	value, err := strconv.Unquote(`"` + m[2] + `"`)
+	if err != nil {
+		return nil, NewSCIMError(400, "invalidFilter", err.Error())
+	}
+	return &SCIMFilter{Attribute: strings.ToLower(m[1]), Value: value}, nil
+}
+
+// SCIMPatchRequest is a SCIM PATCH request body
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is an operation of a SCIMPatchRequest
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (o SCIMPatchOperation) op() (string, error) {
+	op := strings.ToLower(o.Op)
+	switch op {
+	case "add", "replace", "remove":
+		return op, nil
+	}
+	return "", NewSCIMError(400, "invalidSyntax", fmt.Sprintf("unknown op %s", o.Op))
+}
+
+// attributes returns the attributes o sets, lowercased. Operations without
+// path set the attributes of their value object
+func (o SCIMPatchOperation) attributes() (map[string]json.RawMessage, error) {
+	if o.Path != "" {
+		return map[string]json.RawMessage{strings.ToLower(o.Path): o.Value}, nil
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(o.Value, &raw); err != nil {
+		return nil, NewSCIMError(400, "invalidValue", "value must be an object")
+	}
+	attrs := map[string]json.RawMessage{}
+	for k, v := range raw {
+		attrs[strings.ToLower(k)] = v
+	}
+	return attrs, nil
+}
+
+// ApplyPatch applies ops to u. Attributes without a ServiceAccount
+// counterpart are ignored
+func (u *SCIMUser) ApplyPatch(ops []SCIMPatchOperation) error {
+	for _, o := range ops {
+		op, err := o.op()
+		if err != nil {
+			return err
+		}
+		if op == "remove" {
+			continue
+		}
+		attrs, err := o.attributes()
+		if err != nil {
+			return err
+		}
+		for attr, value := range attrs {
+			switch attr {
+			case "active":
+				active, err := scimBool(value)
+				if err != nil {
+					return err
+				}
+				u.Active = active
+			case "displayname":
+				if err := scimString(value, &u.DisplayName); err != nil {
+					return err
+				}
+			case "username":
+				if err := scimString(value, &u.UserName); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return u.Validate()
+}
+
+// ApplyPatch applies ops to g
+func (g *SCIMGroup) ApplyPatch(ops []SCIMPatchOperation) error {
+	for _, o := range ops {
+		op, err := o.op()
+		if err != nil {
+			return err
+		}
+		if op == "remove" {
+			if err := g.removeMembers(o); err != nil {
+				return err
+			}
+			continue
+		}
+		attrs, err := o.attributes()
+		if err != nil {
+			return err
+		}
+		for attr, value := range attrs {
+			switch attr {
+			case "displayname":
+				if err := scimString(value, &g.DisplayName); err != nil {
+					return err
+				}
+			case "members":
+				members := []SCIMMember{}
+				if err := json.Unmarshal(value, &members); err != nil {
+					return NewSCIMError(400, "invalidValue", "members must be an array")
+				}
+				if op == "replace" {
+					g.Members = members
+				} else {
+					g.Members = append(g.Members, members...)
+				}
+			}
+		}
+	}
+	return g.Validate()
+}
+
+var scimMemberPathRegexp = regexp.MustCompile(
+	`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]$`,
+)
+
+// removeMembers removes members selected by o path, or listed in its value
+func (g *SCIMGroup) removeMembers(o SCIMPatchOperation) error {
+	remove := map[string]bool{}
+	if m := scimMemberPathRegexp.FindStringSubmatch(o.Path); m != nil {
+		remove[m[1]] = true
+	} else if strings.ToLower(o.Path) != "members" {
+		return nil
+	} else if len(o.Value) == 0 || string(o.Value) == "null" {
+		g.Members = []SCIMMember{}
+		return nil
+	} else {
+		members := []SCIMMember{}
+		if err := json.Unmarshal(o.Value, &members); err != nil {
+			return NewSCIMError(400, "invalidValue", "members must be an array")
+		}
+		for _, m := range members {
+			remove[m.Value] = true
+		}
+	}
+	kept := []SCIMMember{}
+	for _, m := range g.Members {
+		if !remove[m.Value] {
+			kept = append(kept, m)
+		}
+	}
+	g.Members = kept
+	return nil
+}
+
+// scimBool decodes a boolean, also accepting "True" and "False" strings as
+// sent by some identity providers
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return b, nil
+		}
+	}
+	return false, NewSCIMError(400, "invalidValue", "active must be a boolean")
+}
+
+func scimString(value json.RawMessage, s *string) error {
+	if err := json.Unmarshal(value, s); err != nil {
+		return NewSCIMError(400, "invalidValue", "expected a string")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// +build unit
+
+package models_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	type testCase struct {
+		filter string
+		parsed *models.SCIMFilter
+		valid  bool
+	}
+	testCases := []testCase{
+		{"", nil, true},
+		{`userName eq "john@test.com"`, &models.SCIMFilter{Attribute: "username", Value: "john@test.com"}, true},
+		{`displayName EQ "a \"quoted\" name"`, &models.SCIMFilter{Attribute: "displayname", Value: `a "quoted" name`}, true},
+		{`emails.value eq "john@test.com"`, &models.SCIMFilter{Attribute: "emails.value", Value: "john@test.com"}, true},
+		{`userName sw "john"`, nil, false},
+		{`userName eq "a" and active eq true`, nil, false},
+	}
+	for _, tt := range testCases {
+		parsed, err := models.ParseSCIMFilter(tt.filter)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected valid %v for %s. Got %v", tt.valid, tt.filter, err)
+			continue
+		}
+		if err != nil {
+			if scimErr, ok := err.(*models.SCIMError); !ok || scimErr.StatusCode() != 400 {
+				t.Errorf("Expected 400 SCIMError for %s. Got %v", tt.filter, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(parsed, tt.parsed) {
+			t.Errorf("Expected %#v for %s. Got %#v", tt.parsed, tt.filter, parsed)
+		}
+	}
+}
+
+func unmarshalSCIMPatch(t *testing.T, body string) []models.SCIMPatchOperation {
+	t.Helper()
+	req := &models.SCIMPatchRequest{}
+	if err := json.Unmarshal([]byte(body), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return req.Operations
+}
+
+func TestSCIMUserApplyPatch(t *testing.T) {
+	u := models.NewSCIMUser(&models.ServiceAccount{
+		ID: "id", Name: "John", Email: "john@test.com",
+		Status: models.ServiceAccountStatuses.Active,
+	})
+	err := u.ApplyPatch(unmarshalSCIMPatch(t, `{"Operations": [
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "replace", "value": {"displayName": "John Doe"}},
+		{"op": "remove", "path": "title"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if u.Active || u.DisplayName != "John Doe" || u.UserName != "john@test.com" {
+		t.Errorf("Unexpected patched user %#v", u)
+	}
+	err = u.ApplyPatch(unmarshalSCIMPatch(t, `{"Operations": [
+		{"op": "replace", "path": "userName", "value": ""}
+	]}`))
+	if err == nil {
+		t.Errorf("Expected error for empty userName")
+	}
+	err = u.ApplyPatch(unmarshalSCIMPatch(t, `{"Operations": [
+		{"op": "move", "path": "active", "value": true}
+	]}`))
+	if err == nil {
+		t.Errorf("Expected error for unknown op")
+	}
+}
+
+func TestSCIMGroupApplyPatch(t *testing.T) {
+	g := models.NewSCIMGroup(
+		&models.Role{ID: "id", Name: "devs"},
+		[]models.ServiceAccount{{ID: "a"}, {ID: "b"}},
+	)
+	type testCase struct {
+		patch   string
+		members []string
+	}
+	testCases := []testCase{
+		{`{"op": "add", "path": "members", "value": [{"value": "c"}, {"value": "a"}]}`, []string{"a", "b", "c"}},
+		{`{"op": "remove", "path": "members[value eq \"b\"]"}`, []string{"a", "c"}},
+		{`{"op": "remove", "path": "members", "value": [{"value": "a"}]}`, []string{"c"}},
+		{`{"op": "replace", "value": {"members": [{"value": "d"}], "displayName": "ops"}}`, []string{"d"}},
+		{`{"op": "remove", "path": "members"}`, []string{}},
+	}
+	for _, tt := range testCases {
+		err := g.ApplyPatch(unmarshalSCIMPatch(t, `{"Operations": [`+tt.patch+`]}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ids := g.MembersIDs(); !reflect.DeepEqual(ids, tt.members) {
+			t.Errorf("Expected members %v after %s. Got %v", tt.members, tt.patch, ids)
+		}
+	}
+	if g.DisplayName != "ops" {
+		t.Errorf("Expected displayName ops. Got %s", g.DisplayName)
+	}
+}
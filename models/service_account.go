@@ -1,20 +1,52 @@
 package models
 
-import "github.com/gofrs/uuid"
+import (
+	"time"
 
-// ServiceAccount type
+	"github.com/gofrs/uuid"
+)
+
+// ServiceAccount type. KeyID and KeySecret are only set when a keypair
+// service account is built, its key pairs are ServiceAccountKeys. Will.IAM
+// access tokens issued up to TokensRevokedAt are rejected
 type ServiceAccount struct {
-	ID                 string             `json:"id" pg:"id"`
-	Name               string             `json:"name" pg:"name"`
-	KeyID              string             `json:"keyId" pg:"key_id"`
-	KeySecret          string             `json:"keySecret" pg:"key_secret"`
-	Email              string             `json:"email" pg:"email"`
-	Picture            string             `json:"picture" pg:"picture"`
-	BaseRoleID         string             `json:"baseRoleId" pg:"base_role_id"`
-	AuthenticationType AuthenticationType `json:"authenticationType" pg:"-"`
+	ID                 string               `json:"id" pg:"id"`
+	Name               string               `json:"name" pg:"name"`
+	KeyID              string               `json:"keyId,omitempty" pg:"-"`
+	KeySecret          string               `json:"keySecret,omitempty" pg:"-"`
+	Email              string               `json:"email" pg:"email"`
+	Picture            string               `json:"picture" pg:"picture"`
+	BaseRoleID         string               `json:"baseRoleId" pg:"base_role_id"`
+	AuthenticationType AuthenticationType   `json:"authenticationType" pg:"authentication_type"`
+	Status             ServiceAccountStatus `json:"status" pg:"status"`
+	TokensRevokedAt    *time.Time           `json:"-" pg:"tokens_revoked_at"`
 	CreatedUpdatedAt
 }
 
+// ServiceAccountStatus type
+type ServiceAccountStatus string
+
+// ServiceAccountStatuses possible. Only active service accounts
+// authenticate, deleted ones lost their roles and permissions
+var ServiceAccountStatuses = struct {
+	Active   ServiceAccountStatus
+	Disabled ServiceAccountStatus
+	Deleted  ServiceAccountStatus
+}{
+	Active:   "active",
+	Disabled: "disabled",
+	Deleted:  "deleted",
+}
+
+func (status ServiceAccountStatus) String() string {
+	return string(status)
+}
+
+// IsActive tells if sa may authenticate
+func (sa ServiceAccount) IsActive() bool {
+	return sa.Status == ServiceAccountStatuses.Active
+}
+
 // AuthenticationType type
 type AuthenticationType string
 
@@ -44,16 +76,18 @@ func (authType AuthenticationType) Valid() bool {
 // BuildKeyPairServiceAccount generates random KeyID and KeySecret
 func BuildKeyPairServiceAccount(name string) *ServiceAccount {
 	return &ServiceAccount{
-		Name:      name,
-		KeyID:     uuid.Must(uuid.NewV4()).String(),
-		KeySecret: uuid.Must(uuid.NewV4()).String(),
+		Name:               name,
+		KeyID:              uuid.Must(uuid.NewV4()).String(),
+		KeySecret:          uuid.Must(uuid.NewV4()).String(),
+		AuthenticationType: AuthenticationTypes.KeyPair,
 	}
 }
 
 // BuildOAuth2ServiceAccount generates a ServiceAccount with Name and Email
 func BuildOAuth2ServiceAccount(name, email string) *ServiceAccount {
 	return &ServiceAccount{
-		Name:  name,
-		Email: email,
+		Name:               name,
+		Email:              email,
+		AuthenticationType: AuthenticationTypes.OAuth2,
 	}
 }
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/gofrs/uuid"
+)
+
+// ServiceAccountKey is a key pair of a keypair service account, which may
+// have many to rotate them. Only a salted hash of the secret is stored,
+// Secret is just set when the key is built
+type ServiceAccountKey struct {
+	ID               string      `json:"id" pg:"id"`
+	ServiceAccountID string      `json:"serviceAccountId" pg:"service_account_id"`
+	Secret           string      `json:"secret,omitempty" pg:"-"`
+	SecretHash       string      `json:"-" pg:"secret_hash"`
+	CreatedAt        time.Time   `json:"createdAt" pg:"created_at"`
+	LastUsedAt       pg.NullTime `json:"lastUsedAt" pg:"last_used_at"`
+}
+
+// BuildServiceAccountKey generates a random key pair for serviceAccountID
+func BuildServiceAccountKey(serviceAccountID string) *ServiceAccountKey {
+	return NewServiceAccountKey(
+		serviceAccountID,
+		uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String(),
+	)
+}
+
+// NewServiceAccountKey builds the key keyID of serviceAccountID, hashing
+// secret
+func NewServiceAccountKey(
+	serviceAccountID, keyID, secret string,
+) *ServiceAccountKey {
+	return &ServiceAccountKey{
+		ID:               keyID,
+		ServiceAccountID: serviceAccountID,
+		Secret:           secret,
+		SecretHash:       HashKeySecret(secret),
+	}
+}
+
+// keySecretHashPrefix identifies the hash function, for it to be changed
+const keySecretHashPrefix = "sha256"
+
+// HashKeySecret returns sha256$<salt>$<sha256(salt + secret)>, hex encoded.
+// Secrets are random, so a fast hash is enough and keeps key pair
+// authentication, done on every request, cheap
+func HashKeySecret(secret string) string {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf(
+		"%s$%s$%s", keySecretHashPrefix,
+		hex.EncodeToString(salt), hashKeySecret(salt, secret),
+	)
+}
+
+func hashKeySecret(salt []byte, secret string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Matches tells if secret is the key secret
+func (k ServiceAccountKey) Matches(secret string) bool {
+	parts := strings.Split(k.SecretHash, "$")
+	if len(parts) != 3 || parts[0] != keySecretHashPrefix {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(
+		[]byte(hashKeySecret(salt, secret)), []byte(parts[2]),
+	) == 1
+}
@@ -0,0 +1,50 @@
+// +build unit
+
+package models_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+func TestServiceAccountKeyMatches(t *testing.T) {
+	k := models.BuildServiceAccountKey("sa-id")
+	if k.ID == "" || k.Secret == "" || k.ServiceAccountID != "sa-id" {
+		t.Fatalf("Unexpected key %#v", k)
+	}
+	if strings.Contains(k.SecretHash, k.Secret) {
+		t.Errorf("Expected secret not to be stored")
+	}
+	if !k.Matches(k.Secret) {
+		t.Errorf("Expected key to match its secret")
+	}
+	if k.Matches("wrong") {
+		t.Errorf("Expected key not to match a wrong secret")
+	}
+	other := models.NewServiceAccountKey("sa-id", k.ID, k.Secret)
+	if other.SecretHash == k.SecretHash {
+		t.Errorf("Expected hashes of the same secret to be salted")
+	}
+}
+
+func TestServiceAccountKeyMatchesMigratedHash(t *testing.T) {
+	// hashes computed by the service_account_keys migration
+	salt := []byte("0123456789abcdef")
+	sum := sha256.Sum256(append(append([]byte{}, salt...), "secret"...))
+	k := models.ServiceAccountKey{
+		SecretHash: "sha256$" + hex.EncodeToString(salt) + "$" +
+			hex.EncodeToString(sum[:]),
+	}
+	if !k.Matches("secret") {
+		t.Errorf("Expected migrated hash to match")
+	}
+	for _, hash := range []string{"", "secret", "md5$00$00", "sha256$zz$00"} {
+		if (models.ServiceAccountKey{SecretHash: hash}).Matches("secret") {
+			t.Errorf("Expected malformed hash %s not to match", hash)
+		}
+	}
+}
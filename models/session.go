@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Session is a login of a service account with a login provider. Tokens
+// refreshed from the one issued at login belong to the same session, so
+// deleting it revokes all of them
+type Session struct {
+	ID         string    `json:"id" pg:"id"`
+	Email      string    `json:"email" pg:"email"`
+	Provider   string    `json:"provider" pg:"provider"`
+	UserAgent  string    `json:"userAgent" pg:"user_agent"`
+	IP         string    `json:"ip" pg:"ip"`
+	CreatedAt  time.Time `json:"createdAt" pg:"created_at"`
+	LastUsedAt time.Time `json:"lastUsedAt" pg:"last_used_at"`
+}
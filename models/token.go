@@ -15,6 +15,10 @@ type Token struct {
 	Expiry       time.Time   `json:"expiry" pg:"expiry"`
 	ExpiredAt    pg.NullTime `json:"expiredAt" pg:"expired_at"`
 	Email        string      `json:"email" pg:"email"`
+	// Provider is the name of the login provider that issued the token
+	Provider string `json:"provider" pg:"provider"`
+	// SessionID is the session the token was issued or refreshed for
+	SessionID string `json:"-" pg:"session_id"`
 	CreatedUpdatedAt
 }
 
@@ -44,4 +48,8 @@ type AuthResult struct {
 	AccessToken string `json:"accessToken"`
 	Email       string `json:"email"`
 	Picture     string `json:"picture"`
+	// Groups are only known by providers exposing a groups claim
+	Groups []string `json:"groups,omitempty"`
+	// Provider is the name of the login provider that issued AccessToken
+	Provider string `json:"provider"`
 }
@@ -22,6 +22,8 @@ const userEndpoint = "https://www.googleapis.com/oauth2/v2/userinfo"
 // GoogleConfig are the basic required informations to use Google
 // as oauth2 provider
 type GoogleConfig struct {
+	// Name is recorded in the tokens it issues, google by default
+	Name          string
 	ClientID      string
 	ClientSecret  string
 	RedirectURL   string
@@ -46,6 +48,7 @@ func mapToQueryStrings(m map[string]string) string {
 type Google struct {
 	config GoogleConfig
 	repo   *repositories.All
+	ctx    context.Context
 	client *http.Client
 }
 
@@ -92,8 +95,7 @@ func (g *Google) ExchangeCode(code string) (*models.AuthResult, error) {
 		return nil, errors.NewNonAllowedEmailDomainError(userInfo.HostedDomain)
 	}
 	t.Email = userInfo.Email
-	// TODO: don't return sso_access_token to user, return 2 tokens to sso
-	t.Expiry = time.Now().UTC().Add(14 * 24 * 3600 * time.Second)
+	t.Provider = g.config.Name
 	if err := g.repo.Tokens.Save(t); err != nil {
 		return nil, err
 	}
@@ -101,6 +103,7 @@ func (g *Google) ExchangeCode(code string) (*models.AuthResult, error) {
 		AccessToken: t.AccessToken,
 		Email:       t.Email,
 		Picture:     userInfo.Picture,
+		Provider:    t.Provider,
 	}, nil
 }
 
@@ -141,6 +144,9 @@ func (g *Google) postToTokenEndpoint(
 	}
 	defer res.Body.Close()
 	body, _ := ioutil.ReadAll(res.Body)
+	if err := checkTokenResponse(res.StatusCode, body); err != nil {
+		return nil, err
+	}
 	gt := &GoogleToken{}
 	err = json.Unmarshal(body, gt)
 	if err != nil {
@@ -216,77 +222,63 @@ func (g *Google) buildRefreshTokenForm(refreshToken string) string {
 	return v.Encode()
 }
 
-func (g *Google) maybeRefresh(t *models.Token) (*userInfo, error) {
-	if t.Expiry.After(time.Now().UTC()) || !t.ExpiredAt.IsZero() {
-		return nil, nil
-	}
-	rtf := g.buildRefreshTokenForm(t.RefreshToken)
-	gt, err := g.postToTokenEndpoint(rtf)
-	if err != nil {
-		return nil, err
-	}
-	oldT := t.Clone()
-	oldT.ExpiredAt.Time = time.Now().UTC()
-	t.ID = ""
-	t.AccessToken = gt.AccessToken
-	t.Expiry = time.Now().UTC().Add(
-		time.Second * time.Duration(gt.ExpiresIn),
-	)
-	userInfo, err := g.getUserInfo(t.AccessToken)
-	if err != nil {
-		return nil, err
-	}
-	if err := g.repo.WithPGTx(
-		context.Background(), func(repo *repositories.All,
-		) error {
-			if err := g.repo.Tokens.Save(t); err != nil {
-				return err
-			}
-			if err := g.repo.Tokens.Save(oldT); err != nil {
-				return err
-			}
-			return nil
-		}); err != nil {
-		return nil, err
-	}
-	return userInfo, nil
-}
-
 // Authenticate verifies if an accessToken is valid and maybe refresh it
 func (g *Google) Authenticate(accessToken string) (*models.AuthResult, error) {
 	t, err := g.repo.Tokens.Get(accessToken)
 	if err != nil {
 		return nil, err
 	}
-	var userInfo *userInfo
-	if userInfo, err = g.maybeRefresh(t); err != nil {
-		return nil, err
-	}
-	if err != nil {
+	var ui *userInfo
+	if _, err := maybeRefresh(g.ctx, g.repo, t, func(
+		t *models.Token,
+	) (*models.Token, error) {
+		gt, err := g.postToTokenEndpoint(g.buildRefreshTokenForm(t.RefreshToken))
+		if err != nil {
+			return nil, err
+		}
+		if ui, err = g.getUserInfo(gt.AccessToken); err != nil {
+			return nil, err
+		}
+		return &models.Token{
+			AccessToken:  gt.AccessToken,
+			RefreshToken: gt.RefreshToken,
+			TokenType:    gt.TokenType,
+			Expiry: time.Now().UTC().Add(
+				time.Second * time.Duration(gt.ExpiresIn),
+			),
+		}, nil
+	}); err != nil {
 		return nil, err
 	}
 	authResult := &models.AuthResult{
 		AccessToken: t.AccessToken,
 		Email:       t.Email,
+		Provider:    t.Provider,
 	}
-	if userInfo != nil {
-		authResult.Picture = userInfo.Picture
+	if ui != nil {
+		authResult.Picture = ui.Picture
 	}
 	return authResult, nil
 }
 
 // WithContext returns a new instance of *Google using ctx
 func (g Google) WithContext(ctx context.Context) Provider {
-	return NewGoogle(g.config, g.repo.WithContext(ctx))
+	ng := NewGoogle(g.config, g.repo.WithContext(ctx))
+	ng.ctx = ctx
+	return ng
 }
 
 // NewGoogle ctor
 func NewGoogle(
 	config GoogleConfig, repo *repositories.All,
 ) *Google {
+	if config.Name == "" {
+		config.Name = "google"
+	}
 	return &Google{
 		config: config,
 		repo:   repo,
+		ctx:    context.Background(),
 		client: extensionsHttp.New(),
 	}
 }
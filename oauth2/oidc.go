@@ -0,0 +1,467 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/jwt"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+	extensionsHttp "github.com/topfreegames/extensions/http"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// idTokenLeeway is the clock skew tolerated when validating ID tokens
+const idTokenLeeway = time.Minute
+
+// jwksMinRefreshInterval limits how often an unknown kid refetches JWKS
+const jwksMinRefreshInterval = time.Minute
+
+// OIDCConfig are the informations required to use a standards-based OpenID
+// Connect provider, e.g. Okta, Keycloak or Azure AD
+type OIDCConfig struct {
+	// Name is recorded in the tokens it issues, oidc by default
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes default to openid, email and profile
+	Scopes []string
+	// HostedDomains restricts the domains of emails allowed to login
+	HostedDomains []string
+	// EmailClaim, PictureClaim and GroupsClaim name the ID token claims, and
+	// default to email, picture and groups. Nested claims are separated by
+	// dots, e.g. realm_access.roles
+	EmailClaim   string
+	PictureClaim string
+	GroupsClaim  string
+	// TokenEndpointAuthMethod is either client_secret_basic (default) or
+	// client_secret_post
+	TokenEndpointAuthMethod string
+}
+
+func (c *OIDCConfig) setDefaults() {
+	if c.Name == "" {
+		c.Name = "oidc"
+	}
+	c.Issuer = strings.TrimRight(c.Issuer, "/")
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "email", "profile"}
+	}
+	if c.EmailClaim == "" {
+		c.EmailClaim = "email"
+	}
+	if c.PictureClaim == "" {
+		c.PictureClaim = "picture"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+	if c.TokenEndpointAuthMethod == "" {
+		c.TokenEndpointAuthMethod = "client_secret_basic"
+	}
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcState is shared by every OIDC returned by WithContext, so discovery and
+// JWKS are fetched once per process
+type oidcState struct {
+	mu              sync.Mutex
+	discovery       *oidcDiscovery
+	keys            jwt.KeySet
+	keysRefreshedAt time.Time
+}
+
+// OIDC implements Provider for any OpenID Connect compliant identity
+// provider. Endpoints come from the issuer discovery document and ID tokens
+// are verified against its JWKS
+type OIDC struct {
+	config OIDCConfig
+	repo   *repositories.All
+	ctx    context.Context
+	client *http.Client
+	state  *oidcState
+}
+
+// NewOIDC ctor. The discovery document is only fetched when first needed,
+// so Will.IAM starts even if the identity provider is unreachable
+func NewOIDC(config OIDCConfig, repo *repositories.All) *OIDC {
+	config.setDefaults()
+	return &OIDC{
+		config: config,
+		repo:   repo,
+		ctx:    context.Background(),
+		client: extensionsHttp.New(),
+		state:  &oidcState{},
+	}
+}
+
+// SetHTTPClient replaces the client used to reach the identity provider
+func (o *OIDC) SetHTTPClient(client *http.Client) {
+	o.client = client
+}
+
+// WithContext returns a new instance of *OIDC using ctx
+func (o OIDC) WithContext(ctx context.Context) Provider {
+	return &OIDC{
+		config: o.config,
+		repo:   o.repo.WithContext(ctx),
+		ctx:    ctx,
+		client: o.client,
+		state:  o.state,
+	}
+}
+
+// BuildAuthURL returns an URL to authenticate with the identity provider, or
+// an empty string if its discovery document can't be fetched
+func (o *OIDC) BuildAuthURL(state string) string {
+	d, err := o.getDiscovery()
+	if err != nil {
+		return ""
+	}
+	v := url.Values{}
+	v.Add("state", state)
+	v.Add("redirect_uri", o.config.RedirectURL)
+	v.Add("client_id", o.config.ClientID)
+	v.Add("scope", strings.Join(o.config.Scopes, " "))
+	v.Add("response_type", "code")
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s%s", d.AuthorizationEndpoint, sep, v.Encode())
+}
+
+// ExchangeCode will trade code for tokens with the identity provider and
+// verify the returned ID token
+func (o *OIDC) ExchangeCode(code string) (*models.AuthResult, error) {
+	v := url.Values{}
+	v.Add("code", code)
+	v.Add("redirect_uri", o.config.RedirectURL)
+	v.Add("grant_type", "authorization_code")
+	ot, err := o.postToTokenEndpoint(v)
+	if err != nil {
+		return nil, err
+	}
+	if ot.IDToken == "" {
+		return nil, fmt.Errorf("oidc: token response has no id_token")
+	}
+	ui, err := o.verifyIDToken(ot.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if ui.Email == "" {
+		return nil, fmt.Errorf("oidc: id_token has no %s claim", o.config.EmailClaim)
+	}
+	if !o.checkHostedDomain(ui.Email) {
+		return nil, errors.NewNonAllowedEmailDomainError(emailDomain(ui.Email))
+	}
+	t := ot.toToken()
+	t.Email = ui.Email
+	t.Provider = o.config.Name
+	if err := o.repo.Tokens.Save(t); err != nil {
+		return nil, err
+	}
+	return &models.AuthResult{
+		AccessToken: t.AccessToken,
+		Email:       t.Email,
+		Picture:     ui.Picture,
+		Groups:      ui.Groups,
+		Provider:    t.Provider,
+	}, nil
+}
+
+// Authenticate verifies if an accessToken is valid and maybe refresh it
+func (o *OIDC) Authenticate(accessToken string) (*models.AuthResult, error) {
+	t, err := o.repo.Tokens.Get(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	var ui *oidcUserInfo
+	if _, err := maybeRefresh(o.ctx, o.repo, t, func(
+		t *models.Token,
+	) (*models.Token, error) {
+		newT, refreshedUI, err := o.refresh(t)
+		ui = refreshedUI
+		return newT, err
+	}); err != nil {
+		return nil, err
+	}
+	authResult := &models.AuthResult{
+		AccessToken: t.AccessToken,
+		Email:       t.Email,
+		Provider:    t.Provider,
+	}
+	if ui != nil {
+		authResult.Picture = ui.Picture
+		authResult.Groups = ui.Groups
+	}
+	return authResult, nil
+}
+
+func (o *OIDC) refresh(t *models.Token) (*models.Token, *oidcUserInfo, error) {
+	v := url.Values{}
+	v.Add("refresh_token", t.RefreshToken)
+	v.Add("grant_type", "refresh_token")
+	ot, err := o.postToTokenEndpoint(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ui *oidcUserInfo
+	if ot.IDToken != "" {
+		if ui, err = o.verifyIDToken(ot.IDToken); err != nil {
+			return nil, nil, err
+		}
+		if ui.Email != "" && ui.Email != t.Email {
+			return nil, nil, fmt.Errorf(
+				"oidc: refreshed id_token belongs to %s", ui.Email,
+			)
+		}
+	}
+	return ot.toToken(), ui, nil
+}
+
+// oidcToken is the expected response from token endpoints
+type oidcToken struct {
+	AccessToken  string  `json:"access_token"`
+	IDToken      string  `json:"id_token"`
+	RefreshToken string  `json:"refresh_token"`
+	TokenType    string  `json:"token_type"`
+	ExpiresIn    float64 `json:"expires_in"`
+}
+
+// Validate oidcToken
+func (ot oidcToken) Validate() *models.Validation {
+	validation := &models.Validation{}
+	if ot.AccessToken == "" {
+		validation.AddError("access_token", "required")
+	}
+	if ot.TokenType == "" {
+		validation.AddError("token_type", "required")
+	}
+	if ot.ExpiresIn < 0 {
+		validation.AddError("expires_in", "should not be negative")
+	}
+	return validation
+}
+
+func (ot oidcToken) toToken() *models.Token {
+	expiresIn := time.Duration(ot.ExpiresIn) * time.Second
+	if expiresIn == 0 {
+		expiresIn = time.Hour
+	}
+	return &models.Token{
+		AccessToken:  ot.AccessToken,
+		RefreshToken: ot.RefreshToken,
+		TokenType:    ot.TokenType,
+		Expiry:       time.Now().UTC().Add(expiresIn),
+	}
+}
+
+func (o *OIDC) postToTokenEndpoint(form url.Values) (*oidcToken, error) {
+	d, err := o.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	if o.config.TokenEndpointAuthMethod == "client_secret_post" {
+		form.Add("client_id", o.config.ClientID)
+		form.Add("client_secret", o.config.ClientSecret)
+	}
+	req, err := http.NewRequest(
+		"POST", d.TokenEndpoint, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Accept", "application/json")
+	if o.config.TokenEndpointAuthMethod != "client_secret_post" {
+		req.SetBasicAuth(
+			url.QueryEscape(o.config.ClientID),
+			url.QueryEscape(o.config.ClientSecret),
+		)
+	}
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	if err := checkTokenResponse(res.StatusCode, body); err != nil {
+		return nil, err
+	}
+	ot := &oidcToken{}
+	if err := json.Unmarshal(body, ot); err != nil {
+		return nil, err
+	}
+	v := ot.Validate()
+	if !v.Valid() {
+		return nil, v.Error()
+	}
+	return ot, nil
+}
+
+type oidcUserInfo struct {
+	Email   string
+	Picture string
+	Groups  []string
+}
+
+func (o *OIDC) verifyIDToken(raw string) (*oidcUserInfo, error) {
+	keys, err := o.getKeys(false)
+	if err != nil {
+		return nil, err
+	}
+	t, err := jwt.Verify(raw, keys)
+	if err == jwt.ErrUnknownKey {
+		// the identity provider may have rotated its keys
+		if keys, err = o.getKeys(true); err != nil {
+			return nil, err
+		}
+		t, err = jwt.Verify(raw, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Claims.Validate(
+		o.config.Issuer, o.config.ClientID, time.Now(), idTokenLeeway,
+	); err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	if err := t.UnmarshalClaims(&claims); err != nil {
+		return nil, err
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, fmt.Errorf("oidc: email is not verified")
+	}
+	ui := &oidcUserInfo{}
+	ui.Email, _ = claimValue(claims, o.config.EmailClaim).(string)
+	ui.Picture, _ = claimValue(claims, o.config.PictureClaim).(string)
+	switch groups := claimValue(claims, o.config.GroupsClaim).(type) {
+	case string:
+		ui.Groups = []string{groups}
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				ui.Groups = append(ui.Groups, s)
+			}
+		}
+	}
+	return ui, nil
+}
+
+// claimValue follows dot separated path into claims
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	parts := strings.SplitN(path, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	nested, ok := claims[parts[0]].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return claimValue(nested, parts[1])
+}
+
+func (o *OIDC) getDiscovery() (*oidcDiscovery, error) {
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+	if o.state.discovery != nil {
+		return o.state.discovery, nil
+	}
+	d := &oidcDiscovery{}
+	if err := o.getJSON(o.config.Issuer+discoveryPath, d); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(d.Issuer, "/") != o.config.Issuer {
+		return nil, fmt.Errorf(
+			"oidc: discovery issuer %s doesn't match %s", d.Issuer, o.config.Issuer,
+		)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: incomplete discovery document")
+	}
+	o.state.discovery = d
+	return d, nil
+}
+
+// getKeys returns the cached JWKS, fetching it if there is none or if
+// refresh is asked and it wasn't refreshed recently
+func (o *OIDC) getKeys(refresh bool) (jwt.KeySet, error) {
+	d, err := o.getDiscovery()
+	if err != nil {
+		return jwt.KeySet{}, err
+	}
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+	fetched := !o.state.keysRefreshedAt.IsZero()
+	if fetched && (!refresh ||
+		time.Since(o.state.keysRefreshedAt) < jwksMinRefreshInterval) {
+		return o.state.keys, nil
+	}
+	keys := jwt.KeySet{}
+	if err := o.getJSON(d.JWKSURI, &keys); err != nil {
+		return jwt.KeySet{}, err
+	}
+	o.state.keys = keys
+	o.state.keysRefreshedAt = time.Now()
+	return keys, nil
+}
+
+func (o *OIDC) getJSON(endpoint string, v interface{}) error {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s returned %d", endpoint, res.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (o *OIDC) checkHostedDomain(email string) bool {
+	if len(o.config.HostedDomains) == 0 {
+		return true
+	}
+	hd := emailDomain(email)
+	for _, allowed := range o.config.HostedDomains {
+		if strings.EqualFold(hd, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func emailDomain(email string) string {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return email[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+// +build integration
+
+package oauth2_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/oauth2"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func newOIDC(
+	t *testing.T, idp *helpers.OIDCServer, config oauth2.OIDCConfig,
+) *oauth2.OIDC {
+	t.Helper()
+	config.Issuer = idp.URL
+	config.ClientID = idp.ClientID
+	config.ClientSecret = idp.ClientSecret
+	config.RedirectURL = "http://localhost:4040/sso/auth/done"
+	return oauth2.NewOIDC(config, helpers.GetRepo(t))
+}
+
+func TestOIDCBuildAuthURL(t *testing.T) {
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	authURL := o.BuildAuthURL("http://referer")
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		t.Fatalf("Expected authorization endpoint. Got %s", authURL)
+	}
+	for _, param := range []string{
+		"client_id=will-iam", "response_type=code",
+		"scope=openid+email+profile", "state=http%3A%2F%2Freferer",
+	} {
+		if !strings.Contains(authURL, param) {
+			t.Errorf("Expected %s in %s", param, authURL)
+		}
+	}
+}
+
+func TestOIDCExchangeCode(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{
+		HostedDomains: []string{"test.com"},
+		GroupsClaim:   "realm_access.roles",
+	})
+	authResult, err := o.ExchangeCode(idp.Code(map[string]interface{}{
+		"email":          "user@test.com",
+		"email_verified": true,
+		"picture":        "http://picture",
+		"realm_access": map[string]interface{}{
+			"roles": []string{"engineering", "oncall"},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if authResult.Email != "user@test.com" {
+		t.Errorf("Expected email user@test.com. Got %s", authResult.Email)
+	}
+	if authResult.Picture != "http://picture" {
+		t.Errorf("Expected picture http://picture. Got %s", authResult.Picture)
+	}
+	if strings.Join(authResult.Groups, ",") != "engineering,oncall" {
+		t.Errorf("Expected groups engineering,oncall. Got %v", authResult.Groups)
+	}
+	authenticated, err := o.Authenticate(authResult.AccessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if authenticated.AccessToken != authResult.AccessToken {
+		t.Errorf("Expected access token not to be refreshed")
+	}
+}
+
+func TestOIDCExchangeCodeNonAllowedDomain(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{HostedDomains: []string{"test.com"}})
+	_, err := o.ExchangeCode(idp.Code(map[string]interface{}{
+		"email": "contractor@other.com",
+	}))
+	if _, ok := err.(*errors.NonAllowedEmailDomainError); !ok {
+		t.Errorf("Expected NonAllowedEmailDomainError. Got %v", err)
+	}
+}
+
+func TestOIDCExchangeCodeUnverifiedEmail(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	_, err := o.ExchangeCode(idp.Code(map[string]interface{}{
+		"email":          "user@test.com",
+		"email_verified": false,
+	}))
+	if err == nil {
+		t.Errorf("Expected unverified email to be rejected")
+	}
+}
+
+func TestOIDCExchangeCodeKeyRotation(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	claims := map[string]interface{}{"email": "user@test.com"}
+	if _, err := o.ExchangeCode(idp.Code(claims)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	idp.RotateKey(t, "key2")
+	// JWKS was just fetched, so the new kid is only trusted a minute later
+	if _, err := o.ExchangeCode(idp.Code(claims)); err == nil {
+		t.Errorf("Expected unknown key to be rejected")
+	}
+}
+
+func TestOIDCAuthenticateRefreshesExpiredToken(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	authResult, err := o.ExchangeCode(idp.Code(map[string]interface{}{
+		"email":   "user@test.com",
+		"picture": "http://picture",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := helpers.GetStorage(t).PG.DB.Exec(
+		`UPDATE tokens SET expiry = ? WHERE access_token = ?`,
+		time.Now().Add(-time.Minute), authResult.AccessToken,
+	); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	refreshed, err := o.Authenticate(authResult.AccessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refreshed.AccessToken == authResult.AccessToken {
+		t.Errorf("Expected access token to be refreshed")
+	}
+	if refreshed.Email != "user@test.com" || refreshed.Picture != "http://picture" {
+		t.Errorf("Unexpected auth result %#v", refreshed)
+	}
+	if _, err := o.Authenticate(refreshed.AccessToken); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func exchangeOIDCCode(
+	t *testing.T, o *oauth2.OIDC, idp *helpers.OIDCServer, expiry time.Time,
+) string {
+	t.Helper()
+	authResult, err := o.ExchangeCode(idp.Code(map[string]interface{}{
+		"email": "user@test.com",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := helpers.GetStorage(t).PG.DB.Exec(
+		`UPDATE tokens SET expiry = ? WHERE access_token = ?`,
+		expiry, authResult.AccessToken,
+	); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return authResult.AccessToken
+}
+
+func TestOIDCAuthenticateRefreshesTokenNearExpiry(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	accessToken := exchangeOIDCCode(t, o, idp, time.Now().Add(2*time.Minute))
+	refreshed, err := o.Authenticate(accessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refreshed.AccessToken == accessToken {
+		t.Fatalf("Expected access token to be refreshed")
+	}
+	// requests sent along with the refreshing one still get through
+	old, err := o.Authenticate(accessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if old.AccessToken != accessToken {
+		t.Errorf("Expected expired access token not to be refreshed again")
+	}
+}
+
+func TestOIDCAuthenticateRevokesRejectedToken(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	defer idp.Close()
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	accessToken := exchangeOIDCCode(t, o, idp, time.Now().Add(-time.Minute))
+	if _, err := helpers.GetStorage(t).PG.DB.Exec(
+		`UPDATE tokens SET refresh_token = 'revoked' WHERE access_token = ?`,
+		accessToken,
+	); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err := o.Authenticate(accessToken)
+		if _, ok := err.(*errors.EntityNotFoundError); !ok {
+			t.Errorf("Expected EntityNotFoundError. Got %v", err)
+		}
+	}
+}
+
+func TestOIDCAuthenticateIdentityProviderDown(t *testing.T) {
+	helpers.CleanupPG(t)
+	idp := helpers.NewOIDCServer(t)
+	o := newOIDC(t, idp, oauth2.OIDCConfig{})
+	valid := exchangeOIDCCode(t, o, idp, time.Now().Add(2*time.Minute))
+	expired := exchangeOIDCCode(t, o, idp, time.Now().Add(-time.Minute))
+	idp.Close()
+	authResult, err := o.Authenticate(valid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if authResult.AccessToken != valid {
+		t.Errorf("Expected access token not to be refreshed")
+	}
+	if _, err := o.Authenticate(expired); err == nil {
+		t.Errorf("Expected expired token not to be accepted")
+	}
+}
@@ -0,0 +1,116 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// refreshBefore is how long before expiring provider tokens are refreshed,
+// so they're renewed while still valid at the provider
+const refreshBefore = 5 * time.Minute
+
+// tokenRejectedError happens when a token endpoint refuses a grant, e.g.
+// because the user revoked Will.IAM access or the refresh token expired
+type tokenRejectedError struct {
+	statusCode int
+	body       []byte
+}
+
+func (e *tokenRejectedError) Error() string {
+	return fmt.Sprintf(
+		"oauth2: token endpoint rejected grant with %d: %s", e.statusCode, e.body,
+	)
+}
+
+// checkTokenResponse returns a *tokenRejectedError if the token endpoint
+// refused the grant and an error if it failed otherwise
+func checkTokenResponse(statusCode int, body []byte) error {
+	switch statusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return &tokenRejectedError{statusCode: statusCode, body: body}
+	}
+	return fmt.Errorf(
+		"oauth2: token endpoint returned %d: %s", statusCode, body,
+	)
+}
+
+// refreshFunc trades t refresh token for a new token at the provider
+type refreshFunc func(t *models.Token) (*models.Token, error)
+
+// maybeRefresh replaces t with a refreshed token when it's about to expire,
+// expiring the old one, and tells if it did. Refreshes of a token are
+// serialized by locking it, requests that lose the race keep using it during
+// its grace period. Tokens the provider refuses to refresh are revoked, while
+// tokens that can't be refreshed because the provider is unreachable are
+// still accepted until they expire
+func maybeRefresh(
+	ctx context.Context, repo *repositories.All,
+	t *models.Token, refresh refreshFunc,
+) (bool, error) {
+	now := time.Now().UTC()
+	if !t.ExpiredAt.IsZero() || t.Expiry.Sub(now) > refreshBefore {
+		return false, nil
+	}
+	expired := !t.Expiry.After(now)
+	if t.RefreshToken == "" {
+		if expired {
+			return false, errors.NewEntityNotFoundError(models.Token{}, t.AccessToken)
+		}
+		return false, nil
+	}
+	var refreshed *models.Token
+	err := repo.WithPGTx(ctx, func(repo *repositories.All) error {
+		locked, err := repo.Tokens.GetForUpdate(t.AccessToken)
+		if err != nil {
+			return err
+		}
+		if !locked.ExpiredAt.IsZero() {
+			return nil
+		}
+		newT, err := refresh(locked)
+		if err != nil {
+			return err
+		}
+		newT.Email = locked.Email
+		newT.Provider = locked.Provider
+		newT.SessionID = locked.SessionID
+		if newT.RefreshToken == "" {
+			// providers that don't rotate refresh tokens omit them
+			newT.RefreshToken = locked.RefreshToken
+		}
+		if err := repo.Tokens.Save(newT); err != nil {
+			return err
+		}
+		locked.ExpiredAt.Time = time.Now().UTC()
+		if err := repo.Tokens.Save(locked); err != nil {
+			return err
+		}
+		refreshed = newT
+		return nil
+	})
+	if _, ok := err.(*tokenRejectedError); ok {
+		if err := repo.Tokens.Revoke(t.AccessToken); err != nil {
+			return false, err
+		}
+		return false, errors.NewEntityNotFoundError(models.Token{}, t.AccessToken)
+	}
+	if err != nil {
+		if !expired {
+			return false, nil
+		}
+		return false, err
+	}
+	if refreshed == nil {
+		return false, nil
+	}
+	*t = *refreshed
+	return true, nil
+}
@@ -0,0 +1,115 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// Registry holds login providers by name and implements Provider itself:
+// auth URLs and code exchanges go to the default provider, while access
+// tokens are authenticated by the provider that issued them
+type Registry struct {
+	providers   map[string]Provider
+	defaultName string
+	repo        *repositories.All
+}
+
+// NewRegistry ctor
+func NewRegistry(repo *repositories.All) *Registry {
+	return &Registry{
+		providers: map[string]Provider{},
+		repo:      repo,
+	}
+}
+
+// Register adds p as name, names are case insensitive. The first registered
+// provider is the default one
+func (r *Registry) Register(name string, p Provider) {
+	name = strings.ToLower(name)
+	if len(r.providers) == 0 {
+		r.defaultName = name
+	}
+	r.providers[name] = p
+}
+
+// SetDefault picks the provider used when none is named
+func (r *Registry) SetDefault(name string) error {
+	name = strings.ToLower(name)
+	if _, ok := r.providers[name]; !ok {
+		return fmt.Errorf("oauth2 provider %s is not registered", name)
+	}
+	r.defaultName = name
+	return nil
+}
+
+// DefaultName is the name of the default provider
+func (r *Registry) DefaultName() string {
+	return r.defaultName
+}
+
+// Names of all registered providers, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the provider registered as name, or the default one if name
+// is empty
+func (r *Registry) Get(name string) (Provider, bool) {
+	if name == "" {
+		name = r.defaultName
+	}
+	p, ok := r.providers[strings.ToLower(name)]
+	return p, ok
+}
+
+// BuildAuthURL with the default provider
+func (r *Registry) BuildAuthURL(state string) string {
+	return r.providers[r.defaultName].BuildAuthURL(state)
+}
+
+// ExchangeCode with the default provider
+func (r *Registry) ExchangeCode(code string) (*models.AuthResult, error) {
+	return r.providers[r.defaultName].ExchangeCode(code)
+}
+
+// Authenticate accessToken with the provider that issued it. Tokens issued
+// before providers were named belong to the default provider
+func (r *Registry) Authenticate(accessToken string) (*models.AuthResult, error) {
+	if len(r.providers) == 1 {
+		return r.providers[r.defaultName].Authenticate(accessToken)
+	}
+	t, err := r.repo.Tokens.Get(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := r.Get(t.Provider)
+	if !ok {
+		// the provider was removed from config, so are its sessions
+		return nil, errors.NewEntityNotFoundError(models.Token{}, accessToken)
+	}
+	return p.Authenticate(accessToken)
+}
+
+// WithContext returns a new *Registry whose providers use ctx
+func (r *Registry) WithContext(ctx context.Context) Provider {
+	providers := make(map[string]Provider, len(r.providers))
+	for name, p := range r.providers {
+		providers[name] = p.WithContext(ctx)
+	}
+	return &Registry{
+		providers:   providers,
+		defaultName: r.defaultName,
+		repo:        r.repo.WithContext(ctx),
+	}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/jwt"
+)
+
+// AccessTokensClient calls /access_tokens and /.well-known/jwks.json
+type AccessTokensClient struct {
+	c *Client
+}
+
+// Issue a short-lived access token for the authenticated service account
+func (atc *AccessTokensClient) Issue(ctx context.Context) (*IssuedAccessToken, error) {
+	at := &IssuedAccessToken{}
+	if _, err := atc.c.do(ctx, request{
+		method: http.MethodPost, path: "/access_tokens",
+		expected: []int{http.StatusCreated},
+	}, at); err != nil {
+		return nil, err
+	}
+	return at, nil
+}
+
+// ClientCredentials exchanges a key pair for an access token at
+// POST /oauth2/token, whatever Auth the client was built with
+func (atc *AccessTokensClient) ClientCredentials(
+	ctx context.Context, keyID, keySecret string,
+) (*OAuth2Token, error) {
+	t := &OAuth2Token{}
+	if _, err := atc.c.do(ctx, request{
+		method: http.MethodPost, path: "/oauth2/token",
+		form: url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {keyID},
+			"client_secret": {keySecret},
+		},
+		anonymous: true,
+		expected:  []int{http.StatusOK}, idempotent: true,
+	}, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// KeySet returns the keys access tokens are signed with
+func (atc *AccessTokensClient) KeySet(ctx context.Context) (jwt.KeySet, error) {
+	keys := jwt.KeySet{}
+	if _, err := atc.c.do(ctx, request{
+		method: http.MethodGet, path: "/.well-known/jwks.json",
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, &keys); err != nil {
+		return jwt.KeySet{}, err
+	}
+	return keys, nil
+}
+
+// AccessTokenClaims are the claims of access tokens signed by Will.IAM
+type AccessTokenClaims struct {
+	jwt.Claims
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+	// Permissions are only present if Will.IAM is configured to include them
+	Permissions []string `json:"perms,omitempty"`
+}
+
+// ServiceAccountID is the service account the token was issued to
+func (c AccessTokenClaims) ServiceAccountID() string {
+	return c.Subject
+}
+
+// verifierLeeway is the clock skew tolerated when verifying access tokens
+const verifierLeeway = 30 * time.Second
+
+// verifierMinRefreshInterval limits how often an unknown kid refetches keys
+const verifierMinRefreshInterval = time.Minute
+
+// Verifier verifies access tokens signed by Will.IAM offline. Keys are
+// fetched once and refetched when a token is signed by an unknown one
+type Verifier struct {
+	atc       *AccessTokensClient
+	issuer    string
+	mu        sync.Mutex
+	keys      jwt.KeySet
+	fetchedAt time.Time
+}
+
+// NewVerifier verifies tokens issued by issuer, as set in Will.IAM
+// accessTokens.issuer, with the keys published by c
+func NewVerifier(c *Client, issuer string) *Verifier {
+	return &Verifier{atc: c.AccessTokens, issuer: issuer}
+}
+
+// Verify checks token signature, issuer and expiration
+func (v *Verifier) Verify(
+	ctx context.Context, token string,
+) (*AccessTokenClaims, error) {
+	keys, err := v.getKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	t, err := jwt.Verify(token, keys)
+	if err == jwt.ErrUnknownKey {
+		if keys, err = v.getKeys(ctx, true); err != nil {
+			return nil, err
+		}
+		t, err = jwt.Verify(token, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Claims.Validate(v.issuer, "", time.Now(), verifierLeeway); err != nil {
+		return nil, err
+	}
+	claims := &AccessTokenClaims{}
+	if err := t.UnmarshalClaims(claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("access token has no subject")
+	}
+	return claims, nil
+}
+
+func (v *Verifier) getKeys(ctx context.Context, refresh bool) (jwt.KeySet, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fetched := !v.fetchedAt.IsZero()
+	if fetched && (!refresh ||
+		time.Since(v.fetchedAt) < verifierMinRefreshInterval) {
+		return v.keys, nil
+	}
+	keys, err := v.atc.KeySet(ctx)
+	if err != nil {
+		return jwt.KeySet{}, err
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	return keys, nil
+}
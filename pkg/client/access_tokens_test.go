@@ -0,0 +1,124 @@
+// +build unit
+
+package client_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/jwt"
+	"github.com/topfreegames/Will.IAM/pkg/client"
+)
+
+func TestVerifier(t *testing.T) {
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var fetches int32
+	c, ts := newTestClient(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/jwks.json" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(jwt.KeySet{Keys: []jwt.JSONWebKey{
+			jwt.NewJSONWebKey(&key1.PublicKey, "key1"),
+		}})
+	})
+	defer ts.Close()
+	v := client.NewVerifier(c, "Will.IAM")
+	ctx := context.Background()
+	claims := map[string]interface{}{
+		"iss":   "Will.IAM",
+		"sub":   "sa-id",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"perms": []string{"Maestro::RL::Do::*"},
+	}
+	token, _ := jwt.Sign(claims, key1, "key1")
+	atc, err := v.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if atc.ServiceAccountID() != "sa-id" || len(atc.Permissions) != 1 {
+		t.Errorf("Unexpected claims %#v", atc)
+	}
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unknown, _ := jwt.Sign(claims, key2, "key2")
+	if _, err := v.Verify(ctx, unknown); err != jwt.ErrUnknownKey {
+		t.Errorf("Expected ErrUnknownKey. Got %v", err)
+	}
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	expired, _ := jwt.Sign(claims, key1, "key1")
+	if _, err := v.Verify(ctx, expired); err == nil {
+		t.Errorf("Expected expired token to be rejected")
+	}
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	claims["iss"] = "other"
+	otherIssuer, _ := jwt.Sign(claims, key1, "key1")
+	if _, err := v.Verify(ctx, otherIssuer); err == nil {
+		t.Errorf("Expected token from other issuer to be rejected")
+	}
+	// keys were fetched less than a minute before the unknown kid was seen,
+	// so they aren't fetched again
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("Expected 1 JWKS fetch. Got %d", n)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	var exchanges int32
+	c, ts := newTestClient(t, client.ClientCredentials("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/oauth2/token":
+				if auth := r.Header.Get("Authorization"); auth != "" {
+					t.Errorf("Unexpected Authorization %s", auth)
+				}
+				r.ParseForm()
+				if r.PostForm.Get("grant_type") != "client_credentials" ||
+					r.PostForm.Get("client_id") != "id" ||
+					r.PostForm.Get("client_secret") != "secret" {
+					t.Errorf("Unexpected form %v", r.PostForm)
+				}
+				n := atomic.AddInt32(&exchanges, 1)
+				json.NewEncoder(w).Encode(client.OAuth2Token{
+					AccessToken: fmt.Sprintf("token%d", n),
+					TokenType:   "Bearer",
+					ExpiresIn:   900,
+				})
+			case "/permissions/has":
+				// token1 is revoked after the first call
+				auth := r.Header.Get("Authorization")
+				if auth == "Bearer token1" && r.URL.Query().Get("permission") == "first" {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				if auth == "Bearer token2" {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		})
+	defer ts.Close()
+	ctx := context.Background()
+	if _, err := c.Permissions.Has(ctx, "first"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := c.Permissions.Has(ctx, "second"); !client.IsUnauthorized(err) {
+		t.Fatalf("Expected unauthorized error. Got %v", err)
+	}
+	if _, err := c.Permissions.Has(ctx, "third"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&exchanges); n != 2 {
+		t.Errorf("Expected 2 token exchanges. Got %d", n)
+	}
+}
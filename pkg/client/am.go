@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// AMClient calls /am
+type AMClient struct {
+	c *Client
+}
+
+// List actions, or resource hierarchies under prefix, the authenticated
+// service account has access to
+func (ac *AMClient) List(ctx context.Context, prefix string) ([]AM, error) {
+	q := url.Values{}
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	ams := []AM{}
+	if _, err := ac.c.do(ctx, request{
+		method: http.MethodGet, path: "/am", query: q,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, &ams); err != nil {
+		return nil, err
+	}
+	return ams, nil
+}
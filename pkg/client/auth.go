@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Auth builds the Authorization header sent to Will.IAM
+type Auth interface {
+	Authorization() string
+	// refresh is called with the access token Will.IAM sends back in
+	// x-access-token, if any
+	refresh(string)
+}
+
+type keyPairAuth struct {
+	keyID, keySecret string
+}
+
+// KeyPair authenticates as a key pair service account
+func KeyPair(keyID, keySecret string) Auth {
+	return &keyPairAuth{keyID: keyID, keySecret: keySecret}
+}
+
+func (kp *keyPairAuth) Authorization() string {
+	return fmt.Sprintf("KeyPair %s:%s", kp.keyID, kp.keySecret)
+}
+
+func (kp *keyPairAuth) refresh(string) {}
+
+type bearerAuth struct {
+	mu          sync.RWMutex
+	accessToken string
+}
+
+// Bearer authenticates with an OAuth2 access token. When Will.IAM refreshes
+// it, the new one is used from then on
+func Bearer(accessToken string) Auth {
+	return &bearerAuth{accessToken: accessToken}
+}
+
+func (b *bearerAuth) Authorization() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return fmt.Sprintf("Bearer %s", b.accessToken)
+}
+
+func (b *bearerAuth) refresh(accessToken string) {
+	if accessToken == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.accessToken = accessToken
+}
+
+// AccessToken returns the current access token of a Bearer Auth, "" for any
+// other Auth
+func AccessToken(a Auth) string {
+	b, ok := a.(*bearerAuth)
+	if !ok {
+		return ""
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.accessToken
+}
+
+// tokenAuth is an Auth that may call Will.IAM to build the Authorization
+// header. Its token is dropped when Will.IAM responds 401
+type tokenAuth interface {
+	authorizationContext(context.Context) (string, error)
+	invalidate()
+}
+
+// binder is an Auth that needs the Client it's configured in
+type binder interface {
+	bind(*Client)
+}
+
+func authorizationContext(ctx context.Context, a Auth) (string, error) {
+	if ta, ok := a.(tokenAuth); ok {
+		return ta.authorizationContext(ctx)
+	}
+	return a.Authorization(), nil
+}
+
+// clientCredentialsRenewBefore is how long before expiring access tokens
+// obtained with client credentials are renewed
+const clientCredentialsRenewBefore = time.Minute
+
+type clientCredentialsAuth struct {
+	keyID, keySecret string
+	mu               sync.Mutex
+	atc              *AccessTokensClient
+	accessToken      string
+	expiresAt        time.Time
+}
+
+// ClientCredentials authenticates as a key pair service account with
+// short-lived access tokens, exchanging the key pair at POST /oauth2/token
+// only when they're about to expire. It must be the Auth of the Config a
+// Client is built with, that Client is used to get tokens. Will.IAM must
+// have accessTokens enabled
+func ClientCredentials(keyID, keySecret string) Auth {
+	return &clientCredentialsAuth{keyID: keyID, keySecret: keySecret}
+}
+
+func (cc *clientCredentialsAuth) bind(c *Client) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.atc = c.AccessTokens
+}
+
+// Authorization gets a token without deadline, Client and GRPCClient calls
+// use their context instead
+func (cc *clientCredentialsAuth) Authorization() string {
+	authorization, _ := cc.authorizationContext(context.Background())
+	return authorization
+}
+
+func (cc *clientCredentialsAuth) authorizationContext(
+	ctx context.Context,
+) (string, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.accessToken != "" &&
+		time.Until(cc.expiresAt) > clientCredentialsRenewBefore {
+		return fmt.Sprintf("Bearer %s", cc.accessToken), nil
+	}
+	if cc.atc == nil {
+		return "", fmt.Errorf("client credentials auth isn't bound to a Client")
+	}
+	t, err := cc.atc.ClientCredentials(ctx, cc.keyID, cc.keySecret)
+	if err != nil {
+		return "", err
+	}
+	cc.accessToken = t.AccessToken
+	cc.expiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	return fmt.Sprintf("Bearer %s", cc.accessToken), nil
+}
+
+// refresh is a noop, Will.IAM doesn't refresh its own access tokens
+func (cc *clientCredentialsAuth) refresh(string) {}
+
+func (cc *clientCredentialsAuth) invalidate() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.accessToken = ""
+}
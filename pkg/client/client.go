@@ -0,0 +1,220 @@
+// Package client is a typed Go client for Will.IAM API
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls Will.IAM. Its fields group calls by resource
+type Client struct {
+	url           string
+	auth          Auth
+	callerService string
+	httpClient    *http.Client
+	maxRetries    int
+	retryBackoff  time.Duration
+
+	Services            *ServicesClient
+	ServiceAccounts     *ServiceAccountsClient
+	Roles               *RolesClient
+	Permissions         *PermissionsClient
+	PermissionsRequests *PermissionsRequestsClient
+	AM                  *AMClient
+	AccessTokens        *AccessTokensClient
+}
+
+// New Client ctor
+func New(cnf *Config) *Client {
+	httpClient := cnf.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: cnf.Timeout}
+	}
+	c := &Client{
+		url:           strings.TrimSuffix(cnf.URL, "/"),
+		auth:          cnf.Auth,
+		callerService: cnf.CallerService,
+		httpClient:    httpClient,
+		maxRetries:    cnf.MaxRetries,
+		retryBackoff:  cnf.RetryBackoff,
+	}
+	c.Services = &ServicesClient{c}
+	c.ServiceAccounts = &ServiceAccountsClient{c}
+	c.Roles = &RolesClient{c}
+	c.Permissions = &PermissionsClient{c}
+	c.PermissionsRequests = &PermissionsRequestsClient{c}
+	c.AM = &AMClient{c}
+	c.AccessTokens = &AccessTokensClient{c}
+	if b, ok := c.auth.(binder); ok {
+		b.bind(c)
+	}
+	return c
+}
+
+// ListOptions paginates list calls
+type ListOptions struct {
+	Page     int
+	PageSize int
+}
+
+func (lo *ListOptions) query() url.Values {
+	q := url.Values{}
+	if lo == nil {
+		return q
+	}
+	q.Set("page", fmt.Sprint(lo.Page))
+	if lo.PageSize > 0 {
+		q.Set("pageSize", fmt.Sprint(lo.PageSize))
+	}
+	return q
+}
+
+// ConditionsContext is what permissions conditions are evaluated against,
+// besides the time of the check: the IP of the request being authorized and
+// attributes conditions refer to as context.<attribute>. Will.IAM only takes
+// them from clients in its conditions.trustedCallers
+type ConditionsContext struct {
+	IP         string
+	Attributes map[string]string
+}
+
+func (cc *ConditionsContext) query() url.Values {
+	q := url.Values{}
+	if cc == nil {
+		return q
+	}
+	if cc.IP != "" {
+		q.Set("ip", cc.IP)
+	}
+	for k, v := range cc.Attributes {
+		q.Set("context."+k, v)
+	}
+	return q
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	// form is sent url encoded instead of body
+	form url.Values
+	// anonymous requests don't send Auth
+	anonymous bool
+	// expected status codes, any other is returned as *Error
+	expected []int
+	// idempotent requests are retried on network errors and 5xx
+	idempotent bool
+}
+
+// do sends req, retrying it when possible, and decodes the response into out
+// if it isn't nil. It returns the response status code
+func (c *Client) do(ctx context.Context, req request, out interface{}) (int, error) {
+	var body []byte
+	if req.form != nil {
+		body = []byte(req.form.Encode())
+	} else if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return 0, err
+		}
+	}
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		statusCode, retry, err := c.doOnce(ctx, req, body, out)
+		if !retry || !req.idempotent || attempt >= c.maxRetries {
+			return statusCode, err
+		}
+		select {
+		case <-ctx.Done():
+			return statusCode, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) doOnce(
+	ctx context.Context, req request, body []byte, out interface{},
+) (int, bool, error) {
+	u := c.url + req.path
+	if len(req.query) > 0 {
+		u = u + "?" + req.query.Encode()
+	}
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequest(req.method, u, bodyReader)
+	if err != nil {
+		return 0, false, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	if req.form != nil {
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	auth := c.auth
+	if req.anonymous {
+		auth = nil
+	}
+	if auth != nil {
+		authorization, err := authorizationContext(ctx, auth)
+		if err != nil {
+			return 0, false, err
+		}
+		httpReq.Header.Set("Authorization", authorization)
+	}
+	if c.callerService != "" {
+		httpReq.Header.Set("x-caller-service", c.callerService)
+	}
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, ctx.Err() == nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, true, err
+	}
+	if auth != nil {
+		auth.refresh(res.Header.Get("x-access-token"))
+		if res.StatusCode == http.StatusUnauthorized {
+			if ta, ok := auth.(tokenAuth); ok {
+				ta.invalidate()
+			}
+		}
+	}
+	if !contains(req.expected, res.StatusCode) {
+		return res.StatusCode, res.StatusCode >= 500, &Error{
+			Method:     req.method,
+			Path:       req.path,
+			StatusCode: res.StatusCode,
+			Body:       resBody,
+		}
+	}
+	if out != nil && len(resBody) > 0 {
+		if err := json.Unmarshal(resBody, out); err != nil {
+			return res.StatusCode, false, err
+		}
+	}
+	return res.StatusCode, false, nil
+}
+
+func contains(sl []int, v int) bool {
+	for _, s := range sl {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+// +build unit
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/pkg/client"
+)
+
+func newTestClient(
+	t *testing.T, auth client.Auth, handler http.HandlerFunc,
+) (*client.Client, *httptest.Server) {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	cnf := client.NewConfig()
+	cnf.URL = ts.URL
+	cnf.Auth = auth
+	cnf.CallerService = "Maestro"
+	cnf.RetryBackoff = time.Millisecond
+	return client.New(cnf), ts
+}
+
+func TestPermissionsHas(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/permissions/has" {
+				t.Errorf("Unexpected path %s", r.URL.Path)
+			}
+			if auth := r.Header.Get("Authorization"); auth != "KeyPair id:secret" {
+				t.Errorf("Unexpected Authorization %s", auth)
+			}
+			if cs := r.Header.Get("x-caller-service"); cs != "Maestro" {
+				t.Errorf("Unexpected x-caller-service %s", cs)
+			}
+			if r.URL.Query().Get("permission") == "Maestro::RL::Do::*" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		},
+	)
+	defer ts.Close()
+	has, err := c.Permissions.Has(context.Background(), "Maestro::RL::Do::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected has to be true")
+	}
+	has, err = c.Permissions.Has(context.Background(), "Maestro::RL::Other::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected has to be false")
+	}
+}
+
+func TestPermissionsHasMany(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			permissions := []string{}
+			if err := json.NewDecoder(r.Body).Decode(&permissions); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			has := make([]bool, len(permissions))
+			for i := range permissions {
+				has[i] = permissions[i] == "Maestro::RL::Do::*"
+			}
+			json.NewEncoder(w).Encode(has)
+		},
+	)
+	defer ts.Close()
+	has, err := c.Permissions.HasMany(context.Background(), []string{
+		"Maestro::RL::Do::*", "Maestro::RL::Other::*",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(has) != 2 || !has[0] || has[1] {
+		t.Errorf("Expected [true false]. Got %v", has)
+	}
+}
+
+func TestPermissionsHasInSendsConditionsContext(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			qs := r.URL.Query()
+			if qs.Get("permission") != "Maestro::RL::Deploy::prod::*" {
+				t.Errorf("Unexpected permission %s", qs.Get("permission"))
+			}
+			if qs.Get("ip") == "10.8.1.2" && qs.Get("context.ticket") == "OPS-1" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		},
+	)
+	defer ts.Close()
+	has, err := c.Permissions.HasIn(
+		context.Background(), "Maestro::RL::Deploy::prod::*",
+		&client.ConditionsContext{
+			IP: "10.8.1.2", Attributes: map[string]string{"ticket": "OPS-1"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected has to be true")
+	}
+	has, err = c.Permissions.Has(context.Background(), "Maestro::RL::Deploy::prod::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected has to be false")
+	}
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var calls int32
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(`[{"id":"1","name":"Maestro"}]`))
+		},
+	)
+	defer ts.Close()
+	ss, err := c.Services.List(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ss) != 1 || ss[0].Name != "Maestro" {
+		t.Errorf("Unexpected services %#v", ss)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls. Got %d", calls)
+	}
+}
+
+func TestDoesntRetryNonIdempotentRequests(t *testing.T) {
+	var calls int32
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	)
+	defer ts.Close()
+	err := c.Services.Create(context.Background(), &client.Service{Name: "x"})
+	if e, ok := err.(*client.Error); !ok || e.StatusCode != 500 {
+		t.Fatalf("Expected *client.Error with 500. Got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call. Got %d", calls)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+	)
+	defer ts.Close()
+	_, err := c.Roles.Get(context.Background(), "id")
+	if !client.IsNotFound(err) {
+		t.Errorf("Expected not found error. Got %v", err)
+	}
+	if client.IsForbidden(err) {
+		t.Errorf("Expected not to be forbidden error")
+	}
+}
+
+func TestBearerRefresh(t *testing.T) {
+	auth := client.Bearer("old")
+	c, ts := newTestClient(t, auth,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Bearer old" {
+				w.Header().Set("x-access-token", "new")
+			}
+			w.Write([]byte(`[]`))
+		},
+	)
+	defer ts.Close()
+	if _, err := c.AM.List(context.Background(), ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token := client.AccessToken(auth); token != "new" {
+		t.Errorf("Expected refreshed access token new. Got %s", token)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	)
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Permissions.Has(ctx, "Maestro::RL::Do::*"); err == nil {
+		t.Errorf("Expected error with canceled context")
+	}
+}
+
+func TestServiceAccountKeys(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.URL.Path {
+			case "POST /service_accounts/sa-id/keys":
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id":"key2","serviceAccountId":"sa-id","secret":"s2","lastUsedAt":null}`))
+			case "GET /service_accounts/sa-id/keys":
+				w.Write([]byte(`[{"id":"key1","lastUsedAt":"2026-10-16T10:00:00Z"},{"id":"key2","lastUsedAt":null}]`))
+			case "DELETE /service_accounts/sa-id/keys/key1":
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		},
+	)
+	defer ts.Close()
+	ctx := context.Background()
+	k, err := c.ServiceAccounts.CreateKey(ctx, "sa-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if k.ID != "key2" || k.Secret != "s2" || k.LastUsedAt != nil {
+		t.Errorf("Unexpected key %#v", k)
+	}
+	ks, err := c.ServiceAccounts.ListKeys(ctx, "sa-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ks) != 2 || ks[0].LastUsedAt == nil || ks[1].LastUsedAt != nil {
+		t.Errorf("Unexpected keys %#v", ks)
+	}
+	if err := c.ServiceAccounts.DeleteKey(ctx, "sa-id", "key1"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestServiceAccountSessions(t *testing.T) {
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.URL.Path {
+			case "GET /service_accounts/sa-id/sessions":
+				w.Write([]byte(`[{"id":"s1","userAgent":"curl/7.0","ip":"10.0.0.1"}]`))
+			case "DELETE /service_accounts/sa-id/sessions/s1",
+				"DELETE /service_accounts/sa-id/sessions":
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		},
+	)
+	defer ts.Close()
+	ctx := context.Background()
+	ss, err := c.ServiceAccounts.ListSessions(ctx, "sa-id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ss) != 1 || ss[0].UserAgent != "curl/7.0" || ss[0].IP != "10.0.0.1" {
+		t.Errorf("Unexpected sessions %#v", ss)
+	}
+	if err := c.ServiceAccounts.RevokeSession(ctx, "sa-id", "s1"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := c.ServiceAccounts.RevokeSessions(ctx, "sa-id"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestServiceAccountLifecycle(t *testing.T) {
+	requests := []string{}
+	c, ts := newTestClient(t, client.KeyPair("id", "secret"),
+		func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	defer ts.Close()
+	ctx := context.Background()
+	for _, fn := range []func(context.Context, string) error{
+		c.ServiceAccounts.Disable, c.ServiceAccounts.Reactivate,
+		c.ServiceAccounts.Delete, c.ServiceAccounts.Offboard,
+	} {
+		if err := fn(ctx, "sa-id"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	expected := []string{
+		"POST /service_accounts/sa-id/disable",
+		"POST /service_accounts/sa-id/reactivate",
+		"DELETE /service_accounts/sa-id",
+		"POST /service_accounts/sa-id/offboard",
+	}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected requests %v. Got %v", expected, requests)
+	}
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// Config holds everything needed to build a Client
+type Config struct {
+	// URL is Will.IAM base address
+	URL string
+	// Auth authenticates every request, see KeyPair, Bearer and
+	// ClientCredentials
+	Auth Auth
+	// CallerService is sent in x-caller-service, for decision logs
+	CallerService string
+	// HTTPClient is used to do requests, built from Timeout if nil
+	HTTPClient *http.Client
+	Timeout    time.Duration
+	// MaxRetries is how many times failed idempotent requests are retried,
+	// waiting RetryBackoff before the first retry and doubling it after
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// NewConfig returns the config struct with default values
+func NewConfig() *Config {
+	return &Config{
+		URL:          "http://localhost:4040",
+		Timeout:      5 * time.Second,
+		MaxRetries:   2,
+		RetryBackoff: 100 * time.Millisecond,
+	}
+}
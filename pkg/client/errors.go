@@ -0,0 +1,41 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is returned when Will.IAM responds an unexpected status code
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"Will.IAM %s %s responded %d: %s",
+		e.Method, e.Path, e.StatusCode, string(e.Body),
+	)
+}
+
+// IsNotFound tells whether err is a 404 from Will.IAM
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsForbidden tells whether err is a 403 from Will.IAM
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+// IsUnauthorized tells whether err is a 401 from Will.IAM
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == statusCode
+}
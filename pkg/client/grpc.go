@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+
+	"github.com/topfreegames/Will.IAM/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCClient calls Will.IAM gRPC authorization API, authenticated the same
+// way as Client
+type GRPCClient struct {
+	conn   *grpc.ClientConn
+	auth   Auth
+	client rpc.AuthorizationClient
+}
+
+// DialGRPC connects to Will.IAM gRPC API at target. opts are passed to
+// grpc.Dial, e.g. grpc.WithInsecure() or transport credentials
+func DialGRPC(
+	target string, auth Auth, opts ...grpc.DialOption,
+) (*GRPCClient, error) {
+	opts = append(opts, GRPCCredentials(auth))
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewGRPCClient(conn, auth), nil
+}
+
+// NewGRPCClient wraps an existing connection, whose calls must already be
+// authenticated, e.g. dialed with GRPCCredentials
+func NewGRPCClient(conn *grpc.ClientConn, auth Auth) *GRPCClient {
+	return &GRPCClient{
+		conn:   conn,
+		auth:   auth,
+		client: rpc.NewAuthorizationClient(conn),
+	}
+}
+
+// GRPCCredentials sends auth as the authorization metadata of every call
+func GRPCCredentials(auth Auth) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(&grpcCredentials{auth})
+}
+
+// Close the underlying connection
+func (gc *GRPCClient) Close() error {
+	return gc.conn.Close()
+}
+
+// Authenticate tells who the authenticated service account is
+func (gc *GRPCClient) Authenticate(
+	ctx context.Context,
+) (*rpc.AuthenticateResponse, error) {
+	var md metadata.MD
+	res, err := gc.client.Authenticate(
+		ctx, &rpc.AuthenticateRequest{}, grpc.Header(&md),
+	)
+	gc.refresh(md)
+	return res, err
+}
+
+// HasPermission tells whether the authenticated service account has
+// permission
+func (gc *GRPCClient) HasPermission(
+	ctx context.Context, permission string,
+) (bool, error) {
+	return gc.HasPermissionIn(ctx, permission, nil)
+}
+
+// HasPermissionIn is HasPermission evaluating permissions conditions in cc
+func (gc *GRPCClient) HasPermissionIn(
+	ctx context.Context, permission string, cc *ConditionsContext,
+) (bool, error) {
+	req := &rpc.HasPermissionRequest{Permission: permission}
+	if cc != nil {
+		req.Ip = cc.IP
+		req.Attributes = cc.Attributes
+	}
+	var md metadata.MD
+	res, err := gc.client.HasPermission(ctx, req, grpc.Header(&md))
+	gc.refresh(md)
+	if err != nil {
+		return false, err
+	}
+	return res.Has, nil
+}
+
+// HasMany tells, in a single call, whether the authenticated service account
+// has each of permissions
+func (gc *GRPCClient) HasMany(
+	ctx context.Context, permissions []string,
+) ([]bool, error) {
+	return gc.HasManyIn(ctx, permissions, nil)
+}
+
+// HasManyIn is HasMany evaluating permissions conditions in cc
+func (gc *GRPCClient) HasManyIn(
+	ctx context.Context, permissions []string, cc *ConditionsContext,
+) ([]bool, error) {
+	req := &rpc.HasPermissionsRequest{Permissions: permissions}
+	if cc != nil {
+		req.Ip = cc.IP
+		req.Attributes = cc.Attributes
+	}
+	var md metadata.MD
+	res, err := gc.client.HasPermissions(ctx, req, grpc.Header(&md))
+	gc.refresh(md)
+	if err != nil {
+		return nil, err
+	}
+	return res.Has, nil
+}
+
+// ListServiceAccountsWithPermission lists service accounts having permission
+func (gc *GRPCClient) ListServiceAccountsWithPermission(
+	ctx context.Context, permission string, lo *ListOptions,
+) (*ServiceAccountsList, error) {
+	req := &rpc.ListServiceAccountsWithPermissionRequest{Permission: permission}
+	if lo != nil {
+		req.Page = int32(lo.Page)
+		req.PageSize = int32(lo.PageSize)
+	}
+	var md metadata.MD
+	res, err := gc.client.ListServiceAccountsWithPermission(
+		ctx, req, grpc.Header(&md),
+	)
+	gc.refresh(md)
+	if err != nil {
+		return nil, err
+	}
+	l := &ServiceAccountsList{
+		Count:   res.Count,
+		Results: make([]ServiceAccount, len(res.ServiceAccounts)),
+	}
+	for i, sa := range res.ServiceAccounts {
+		l.Results[i] = ServiceAccount{
+			ID:                 sa.Id,
+			Name:               sa.Name,
+			Email:              sa.Email,
+			Picture:            sa.Picture,
+			BaseRoleID:         sa.BaseRoleId,
+			AuthenticationType: AuthenticationType(sa.AuthenticationType),
+		}
+	}
+	return l, nil
+}
+
+func (gc *GRPCClient) refresh(md metadata.MD) {
+	if gc.auth == nil {
+		return
+	}
+	if tokens := md.Get("x-access-token"); len(tokens) > 0 {
+		gc.auth.refresh(tokens[0])
+	}
+}
+
+type grpcCredentials struct {
+	auth Auth
+}
+
+func (gc *grpcCredentials) GetRequestMetadata(
+	ctx context.Context, uri ...string,
+) (map[string]string, error) {
+	if gc.auth == nil {
+		return map[string]string{}, nil
+	}
+	authorization, err := authorizationContext(ctx, gc.auth)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": authorization}, nil
+}
+
+// RequireTransportSecurity is false so Will.IAM can be reached in plain text
+// inside private networks, dial with TLS credentials everywhere else
+func (gc *grpcCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,122 @@
+// +build unit
+
+package client_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/pkg/client"
+	"github.com/topfreegames/Will.IAM/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAuthorizationServer grants permissions to Bearer old and Bearer new,
+// refreshing old into new
+type fakeAuthorizationServer struct{}
+
+func (fakeAuthorizationServer) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	switch auth := md.Get("authorization"); {
+	case len(auth) == 0:
+		return status.Error(codes.Unauthenticated, "authorization is required")
+	case auth[0] == "Bearer old":
+		grpc.SetHeader(ctx, metadata.Pairs("x-access-token", "new"))
+	case auth[0] != "Bearer new":
+		return status.Error(codes.Unauthenticated, "unknown token")
+	}
+	return nil
+}
+
+func (f fakeAuthorizationServer) Authenticate(
+	ctx context.Context, req *rpc.AuthenticateRequest,
+) (*rpc.AuthenticateResponse, error) {
+	if err := f.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return &rpc.AuthenticateResponse{ServiceAccountId: "id"}, nil
+}
+
+func (f fakeAuthorizationServer) HasPermission(
+	ctx context.Context, req *rpc.HasPermissionRequest,
+) (*rpc.HasPermissionResponse, error) {
+	if err := f.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return &rpc.HasPermissionResponse{Has: req.Permission == "Maestro::RL::Do::*"}, nil
+}
+
+func (f fakeAuthorizationServer) HasPermissions(
+	ctx context.Context, req *rpc.HasPermissionsRequest,
+) (*rpc.HasPermissionsResponse, error) {
+	if err := f.authorize(ctx); err != nil {
+		return nil, err
+	}
+	has := make([]bool, len(req.Permissions))
+	for i := range req.Permissions {
+		has[i] = req.Permissions[i] == "Maestro::RL::Do::*"
+	}
+	return &rpc.HasPermissionsResponse{Has: has}, nil
+}
+
+func (f fakeAuthorizationServer) ListServiceAccountsWithPermission(
+	ctx context.Context, req *rpc.ListServiceAccountsWithPermissionRequest,
+) (*rpc.ListServiceAccountsWithPermissionResponse, error) {
+	if err := f.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return &rpc.ListServiceAccountsWithPermissionResponse{
+		Count:           1,
+		ServiceAccounts: []*rpc.ServiceAccount{{Id: "id", Name: "sa"}},
+	}, nil
+}
+
+func TestGRPCClient(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	rpc.RegisterAuthorizationServer(s, fakeAuthorizationServer{})
+	go s.Serve(lis)
+	defer s.Stop()
+	auth := client.Bearer("old")
+	gc, err := client.DialGRPC("bufnet", auth,
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer gc.Close()
+	ctx := context.Background()
+	has, err := gc.HasPermission(ctx, "Maestro::RL::Do::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected has to be true")
+	}
+	if token := client.AccessToken(auth); token != "new" {
+		t.Errorf("Expected refreshed access token new. Got %s", token)
+	}
+	hasMany, err := gc.HasMany(ctx, []string{"Maestro::RL::Do::*", "Maestro::RL::Other::*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hasMany) != 2 || !hasMany[0] || hasMany[1] {
+		t.Errorf("Expected [true false]. Got %v", hasMany)
+	}
+	l, err := gc.ListServiceAccountsWithPermission(ctx, "Maestro::RL::Do::*", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if l.Count != 1 || l.Results[0].ID != "id" {
+		t.Errorf("Unexpected list %#v", l)
+	}
+}
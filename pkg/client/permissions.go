@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PermissionsClient calls /permissions
+type PermissionsClient struct {
+	c *Client
+}
+
+// Has tells whether the authenticated service account has permission
+func (pc *PermissionsClient) Has(
+	ctx context.Context, permission string,
+) (bool, error) {
+	return pc.HasIn(ctx, permission, nil)
+}
+
+// HasIn is Has evaluating permissions conditions in cc
+func (pc *PermissionsClient) HasIn(
+	ctx context.Context, permission string, cc *ConditionsContext,
+) (bool, error) {
+	query := cc.query()
+	query.Set("permission", permission)
+	statusCode, err := pc.c.do(ctx, request{
+		method: http.MethodGet, path: "/permissions/has",
+		query:      query,
+		expected:   []int{http.StatusOK, http.StatusForbidden},
+		idempotent: true,
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+	return statusCode == http.StatusOK, nil
+}
+
+// HasMany tells, in a single request, whether the authenticated service
+// account has each of permissions
+func (pc *PermissionsClient) HasMany(
+	ctx context.Context, permissions []string,
+) ([]bool, error) {
+	return pc.HasManyIn(ctx, permissions, nil)
+}
+
+// HasManyIn is HasMany evaluating permissions conditions in cc
+func (pc *PermissionsClient) HasManyIn(
+	ctx context.Context, permissions []string, cc *ConditionsContext,
+) ([]bool, error) {
+	has := []bool{}
+	if _, err := pc.c.do(ctx, request{
+		method: http.MethodPost, path: "/permissions/hasMany", body: permissions,
+		query: cc.query(), expected: []int{http.StatusOK}, idempotent: true,
+	}, &has); err != nil {
+		return nil, err
+	}
+	return has, nil
+}
+
+// Delete a permission by id
+func (pc *PermissionsClient) Delete(ctx context.Context, id string) error {
+	_, err := pc.c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/permissions/%s", url.PathEscape(id)),
+		// deleting an unknown permission is a no-op
+		expected:   []int{http.StatusOK, http.StatusNoContent},
+		idempotent: true,
+	}, nil)
+	return err
+}
+
+// Attribute permissions to roles
+func (pc *PermissionsClient) Attribute(
+	ctx context.Context, pa *PermissionsAttribute,
+) error {
+	_, err := pc.c.do(ctx, request{
+		method: http.MethodPut, path: "/permissions/attribute", body: pa,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, nil)
+	return err
+}
+
+// AttributeToEmails attributes permissions to service accounts by email
+func (pc *PermissionsClient) AttributeToEmails(
+	ctx context.Context, pa *PermissionsAttributeToEmails,
+) error {
+	_, err := pc.c.do(ctx, request{
+		method: http.MethodPut, path: "/permissions/attribute_to_emails", body: pa,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, nil)
+	return err
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PermissionsRequestsClient calls /permissions/requests
+type PermissionsRequestsClient struct {
+	c *Client
+}
+
+// ListOpen lists open requests the authenticated service account can moderate
+func (prc *PermissionsRequestsClient) ListOpen(
+	ctx context.Context, lo *ListOptions,
+) (*PermissionsRequestsList, error) {
+	l := &PermissionsRequestsList{}
+	if _, err := prc.c.do(ctx, request{
+		method: http.MethodGet, path: "/permissions/requests/open",
+		query: lo.query(), expected: []int{http.StatusOK}, idempotent: true,
+	}, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Create a request for the authenticated service account
+func (prc *PermissionsRequestsClient) Create(
+	ctx context.Context, pr *PermissionRequest,
+) error {
+	_, err := prc.c.do(ctx, request{
+		method: http.MethodPost, path: "/permissions/requests", body: pr,
+		// accepted when an equal request is already open
+		expected: []int{http.StatusCreated, http.StatusAccepted},
+	}, nil)
+	return err
+}
+
+// Grant an open request
+func (prc *PermissionsRequestsClient) Grant(ctx context.Context, id string) error {
+	return prc.moderate(ctx, id, "grant")
+}
+
+// Deny an open request
+func (prc *PermissionsRequestsClient) Deny(ctx context.Context, id string) error {
+	return prc.moderate(ctx, id, "deny")
+}
+
+func (prc *PermissionsRequestsClient) moderate(
+	ctx context.Context, id, verb string,
+) error {
+	_, err := prc.c.do(ctx, request{
+		method:   http.MethodPut,
+		path:     fmt.Sprintf("/permissions/requests/%s/%s", url.PathEscape(id), verb),
+		expected: []int{http.StatusAccepted},
+	}, nil)
+	return err
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// RolesClient calls /roles
+type RolesClient struct {
+	c *Client
+}
+
+// List roles
+func (rc *RolesClient) List(
+	ctx context.Context, lo *ListOptions,
+) (*RolesList, error) {
+	return rc.list(ctx, "/roles", lo.query())
+}
+
+// Search roles by name
+func (rc *RolesClient) Search(
+	ctx context.Context, term string, lo *ListOptions,
+) (*RolesList, error) {
+	q := lo.query()
+	q.Set("term", term)
+	return rc.list(ctx, "/roles/search", q)
+}
+
+func (rc *RolesClient) list(
+	ctx context.Context, path string, q url.Values,
+) (*RolesList, error) {
+	l := &RolesList{}
+	if _, err := rc.c.do(ctx, request{
+		method: http.MethodGet, path: path, query: q,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Get a role, with its permissions and service accounts, by id
+func (rc *RolesClient) Get(ctx context.Context, id string) (*RoleDetails, error) {
+	rd := &RoleDetails{}
+	if _, err := rc.c.do(ctx, request{
+		method: http.MethodGet, path: rolePath(id),
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, rd); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// Create a role with its permissions and service accounts
+func (rc *RolesClient) Create(ctx context.Context, rwn *RoleWithNested) error {
+	_, err := rc.c.do(ctx, request{
+		method: http.MethodPost, path: "/roles", body: rwn,
+		expected: []int{http.StatusCreated},
+	}, nil)
+	return err
+}
+
+// Update a role along with its permissions and service accounts
+func (rc *RolesClient) Update(
+	ctx context.Context, id string, rwn *RoleWithNested,
+) error {
+	_, err := rc.c.do(ctx, request{
+		method: http.MethodPut, path: rolePath(id), body: rwn,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, nil)
+	return err
+}
+
+// CreatePermission adds permission to a role
+func (rc *RolesClient) CreatePermission(
+	ctx context.Context, id, permission string,
+) error {
+	_, err := rc.c.do(ctx, request{
+		method: http.MethodPost, path: rolePath(id) + "/permissions",
+		query:    url.Values{"permission": []string{permission}},
+		expected: []int{http.StatusCreated},
+	}, nil)
+	return err
+}
+
+func rolePath(id string) string {
+	return fmt.Sprintf("/roles/%s", url.PathEscape(id))
+}
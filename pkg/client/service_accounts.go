@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ServiceAccountsClient calls /service_accounts
+type ServiceAccountsClient struct {
+	c *Client
+}
+
+// List service accounts
+func (sac *ServiceAccountsClient) List(
+	ctx context.Context, lo *ListOptions,
+) (*ServiceAccountsList, error) {
+	return sac.list(ctx, "/service_accounts", lo.query())
+}
+
+// ListWithPermission lists service accounts having permission
+func (sac *ServiceAccountsClient) ListWithPermission(
+	ctx context.Context, permission string, lo *ListOptions,
+) (*ServiceAccountsList, error) {
+	q := lo.query()
+	q.Set("permission", permission)
+	return sac.list(ctx, "/service_accounts/with_permission", q)
+}
+
+// Search service accounts by name or email
+func (sac *ServiceAccountsClient) Search(
+	ctx context.Context, term string, lo *ListOptions,
+) (*ServiceAccountsList, error) {
+	q := lo.query()
+	q.Set("term", term)
+	return sac.list(ctx, "/service_accounts/search", q)
+}
+
+func (sac *ServiceAccountsClient) list(
+	ctx context.Context, path string, q url.Values,
+) (*ServiceAccountsList, error) {
+	l := &ServiceAccountsList{}
+	if _, err := sac.c.do(ctx, request{
+		method: http.MethodGet, path: path, query: q,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Get a service account, with its permissions and roles, by id
+func (sac *ServiceAccountsClient) Get(
+	ctx context.Context, id string,
+) (*ServiceAccountWithNested, error) {
+	sawn := &ServiceAccountWithNested{}
+	if _, err := sac.c.do(ctx, request{
+		method: http.MethodGet, path: serviceAccountPath(id),
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, sawn); err != nil {
+		return nil, err
+	}
+	return sawn, nil
+}
+
+// Create a service account with its permissions and roles
+func (sac *ServiceAccountsClient) Create(
+	ctx context.Context, sawn *ServiceAccountWithNested,
+) error {
+	_, err := sac.c.do(ctx, request{
+		method: http.MethodPost, path: "/service_accounts", body: sawn,
+		expected: []int{http.StatusCreated},
+	}, nil)
+	return err
+}
+
+// Update a service account along with its permissions and roles
+func (sac *ServiceAccountsClient) Update(
+	ctx context.Context, sawn *ServiceAccountWithNested,
+) error {
+	_, err := sac.c.do(ctx, request{
+		method: http.MethodPut, path: serviceAccountPath(sawn.ID), body: sawn,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, nil)
+	return err
+}
+
+// Disable stops a service account from authenticating until it's
+// reactivated
+func (sac *ServiceAccountsClient) Disable(ctx context.Context, id string) error {
+	return sac.lifecycle(ctx, http.MethodPost, serviceAccountPath(id)+"/disable")
+}
+
+// Delete soft deletes a service account, dropping its roles and permissions
+func (sac *ServiceAccountsClient) Delete(ctx context.Context, id string) error {
+	return sac.lifecycle(ctx, http.MethodDelete, serviceAccountPath(id))
+}
+
+// Reactivate lets a disabled or deleted service account authenticate again
+func (sac *ServiceAccountsClient) Reactivate(ctx context.Context, id string) error {
+	return sac.lifecycle(ctx, http.MethodPost, serviceAccountPath(id)+"/reactivate")
+}
+
+// Offboard deletes a service account, revokes its sessions and denies its
+// open permission requests
+func (sac *ServiceAccountsClient) Offboard(ctx context.Context, id string) error {
+	return sac.lifecycle(ctx, http.MethodPost, serviceAccountPath(id)+"/offboard")
+}
+
+func (sac *ServiceAccountsClient) lifecycle(
+	ctx context.Context, method, path string,
+) error {
+	_, err := sac.c.do(ctx, request{
+		method: method, path: path,
+		expected: []int{http.StatusNoContent}, idempotent: true,
+	}, nil)
+	return err
+}
+
+// ListKeys lists the key pairs of a keypair service account, without secrets
+func (sac *ServiceAccountsClient) ListKeys(
+	ctx context.Context, id string,
+) ([]ServiceAccountKey, error) {
+	ks := []ServiceAccountKey{}
+	if _, err := sac.c.do(ctx, request{
+		method: http.MethodGet, path: serviceAccountPath(id) + "/keys",
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, &ks); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// CreateKey generates a key pair for a keypair service account. Its secret
+// can't be read again
+func (sac *ServiceAccountsClient) CreateKey(
+	ctx context.Context, id string,
+) (*ServiceAccountKey, error) {
+	k := &ServiceAccountKey{}
+	if _, err := sac.c.do(ctx, request{
+		method: http.MethodPost, path: serviceAccountPath(id) + "/keys",
+		expected: []int{http.StatusCreated},
+	}, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// DeleteKey revokes a key pair of a service account
+func (sac *ServiceAccountsClient) DeleteKey(
+	ctx context.Context, id, keyID string,
+) error {
+	_, err := sac.c.do(ctx, request{
+		method: http.MethodDelete,
+		path: fmt.Sprintf(
+			"%s/keys/%s", serviceAccountPath(id), url.PathEscape(keyID),
+		),
+		expected: []int{http.StatusNoContent}, idempotent: true,
+	}, nil)
+	return err
+}
+
+// ListSessions lists the login sessions of a service account still in use
+func (sac *ServiceAccountsClient) ListSessions(
+	ctx context.Context, id string,
+) ([]Session, error) {
+	ss := []Session{}
+	if _, err := sac.c.do(ctx, request{
+		method: http.MethodGet, path: serviceAccountPath(id) + "/sessions",
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, &ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// RevokeSession ends a login session of a service account
+func (sac *ServiceAccountsClient) RevokeSession(
+	ctx context.Context, id, sessionID string,
+) error {
+	_, err := sac.c.do(ctx, request{
+		method: http.MethodDelete,
+		path: fmt.Sprintf(
+			"%s/sessions/%s", serviceAccountPath(id), url.PathEscape(sessionID),
+		),
+		expected: []int{http.StatusNoContent}, idempotent: true,
+	}, nil)
+	return err
+}
+
+// RevokeSessions ends every login session of a service account, revoking the
+// Will.IAM access tokens issued to it so far too
+func (sac *ServiceAccountsClient) RevokeSessions(
+	ctx context.Context, id string,
+) error {
+	_, err := sac.c.do(ctx, request{
+		method: http.MethodDelete, path: serviceAccountPath(id) + "/sessions",
+		expected: []int{http.StatusNoContent}, idempotent: true,
+	}, nil)
+	return err
+}
+
+func serviceAccountPath(id string) string {
+	return fmt.Sprintf("/service_accounts/%s", url.PathEscape(id))
+}
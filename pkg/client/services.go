@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ServicesClient calls /services
+type ServicesClient struct {
+	c *Client
+}
+
+// List all services
+func (sc *ServicesClient) List(ctx context.Context) ([]Service, error) {
+	ss := []Service{}
+	_, err := sc.c.do(ctx, request{
+		method: http.MethodGet, path: "/services",
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, &ss)
+	return ss, err
+}
+
+// Get a service by id
+func (sc *ServicesClient) Get(ctx context.Context, id string) (*Service, error) {
+	s := &Service{}
+	if _, err := sc.c.do(ctx, request{
+		method: http.MethodGet, path: servicePath(id),
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create a service
+func (sc *ServicesClient) Create(ctx context.Context, s *Service) error {
+	_, err := sc.c.do(ctx, request{
+		method: http.MethodPost, path: "/services", body: s,
+		expected: []int{http.StatusCreated},
+	}, nil)
+	return err
+}
+
+// Update a service
+func (sc *ServicesClient) Update(ctx context.Context, s *Service) error {
+	_, err := sc.c.do(ctx, request{
+		method: http.MethodPut, path: servicePath(s.ID), body: s,
+		expected: []int{http.StatusOK}, idempotent: true,
+	}, nil)
+	return err
+}
+
+func servicePath(id string) string {
+	return fmt.Sprintf("/services/%s", url.PathEscape(id))
+}
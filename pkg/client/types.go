@@ -0,0 +1,183 @@
+package client
+
+import "time"
+
+// Service is a Will.IAM client service
+type Service struct {
+	ID                      string `json:"id,omitempty"`
+	Name                    string `json:"name"`
+	PermissionName          string `json:"permissionName"`
+	ServiceAccountID        string `json:"serviceAccountID,omitempty"`
+	CreatorServiceAccountID string `json:"creatorServiceAccountID,omitempty"`
+	AMURL                   string `json:"amUrl"`
+}
+
+// AuthenticationType is either oauth2 or keypair
+type AuthenticationType string
+
+// AuthenticationTypes of service accounts
+var AuthenticationTypes = struct {
+	OAuth2  AuthenticationType
+	KeyPair AuthenticationType
+}{
+	OAuth2:  "oauth2",
+	KeyPair: "keypair",
+}
+
+// ServiceAccount is an user or application
+type ServiceAccount struct {
+	ID                 string             `json:"id"`
+	Name               string             `json:"name"`
+	Email              string             `json:"email"`
+	Picture            string             `json:"picture"`
+	BaseRoleID         string             `json:"baseRoleId"`
+	AuthenticationType AuthenticationType `json:"authenticationType"`
+	// Status is active, disabled or deleted
+	Status string `json:"status,omitempty"`
+}
+
+// ServiceAccountKey is a key pair of a keypair service account. Secret is
+// only set when it's created
+type ServiceAccountKey struct {
+	ID               string     `json:"id"`
+	ServiceAccountID string     `json:"serviceAccountId"`
+	Secret           string     `json:"secret,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	LastUsedAt       *time.Time `json:"lastUsedAt"`
+}
+
+// Session is a login of a service account with a login provider
+type Session struct {
+	ID         string    `json:"id"`
+	Email      string    `json:"email"`
+	Provider   string    `json:"provider"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// ServiceAccountWithNested is a service account along with its permissions
+// and roles
+type ServiceAccountWithNested struct {
+	ID                    string               `json:"id,omitempty"`
+	Name                  string               `json:"name"`
+	Email                 string               `json:"email"`
+	Picture               string               `json:"picture"`
+	Permissions           []string             `json:"permissions"`
+	PermissionsAliases    map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+	RolesIDs              []string             `json:"rolesIds,omitempty"`
+	RolesExpiresAt        map[string]time.Time `json:"rolesExpiresAt"`
+	Roles                 []Role               `json:"roles,omitempty"`
+	AuthenticationType    AuthenticationType   `json:"authenticationType"`
+}
+
+// ServiceAccountsList is a page of service accounts
+type ServiceAccountsList struct {
+	Count   int64            `json:"count"`
+	Results []ServiceAccount `json:"results"`
+}
+
+// Role is a set of permissions bound to service accounts
+type Role struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	IsBaseRole bool   `json:"isBaseRole"`
+}
+
+// RoleWithNested is the data to create or update a role
+type RoleWithNested struct {
+	Name                     string               `json:"name"`
+	Permissions              []string             `json:"permissions"`
+	PermissionsAliases       map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt     map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions    map[string]string    `json:"permissionsConditions"`
+	ServiceAccountsIDs       []string             `json:"serviceAccountsIds"`
+	ServiceAccountsExpiresAt map[string]time.Time `json:"serviceAccountsExpiresAt"`
+}
+
+// RoleDetails is a role along with its permissions and service accounts
+type RoleDetails struct {
+	ID                       string               `json:"id"`
+	Name                     string               `json:"name"`
+	Permissions              []string             `json:"permissions"`
+	PermissionsAliases       map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt     map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions    map[string]string    `json:"permissionsConditions"`
+	ServiceAccounts          []ServiceAccount     `json:"serviceAccounts"`
+	ServiceAccountsExpiresAt map[string]time.Time `json:"serviceAccountsExpiresAt"`
+}
+
+// RolesList is a page of roles
+type RolesList struct {
+	Count   int64  `json:"count"`
+	Results []Role `json:"results"`
+}
+
+// PermissionsAttribute is the data to attribute permissions to roles
+type PermissionsAttribute struct {
+	RolesIDs              []string             `json:"rolesIds"`
+	Permissions           []string             `json:"permissions"`
+	PermissionsAliases    map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+}
+
+// PermissionsAttributeToEmails is the data to attribute permissions to
+// service accounts by email
+type PermissionsAttributeToEmails struct {
+	Emails                []string             `json:"emails"`
+	Permissions           []string             `json:"permissions"`
+	PermissionsAliases    map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+}
+
+// PermissionRequest is a service account asking for a permission
+type PermissionRequest struct {
+	ID                        string     `json:"id,omitempty"`
+	Service                   string     `json:"service"`
+	OwnershipLevel            string     `json:"ownershipLevel"`
+	Action                    string     `json:"action"`
+	ResourceHierarchy         string     `json:"resourceHierarchy"`
+	Alias                     string     `json:"alias"`
+	Message                   string     `json:"message"`
+	State                     string     `json:"state,omitempty"`
+	ServiceAccountID          string     `json:"serviceAccountId,omitempty"`
+	RequesterPicture          string     `json:"requesterPicture,omitempty"`
+	RequesterName             string     `json:"requesterName,omitempty"`
+	ModeratorServiceAccountID string     `json:"moderatorServiceAccountId,omitempty"`
+	ExpiresAt                 *time.Time `json:"expiresAt,omitempty"`
+}
+
+// PermissionsRequestsList is a page of permissions requests
+type PermissionsRequestsList struct {
+	Count   int64               `json:"count"`
+	Results []PermissionRequest `json:"results"`
+}
+
+// AM is an action or resource hierarchy the requester has access to
+type AM struct {
+	Prefix   string `json:"prefix"`
+	Alias    string `json:"alias"`
+	Owner    bool   `json:"owner"`
+	Lender   bool   `json:"lender"`
+	Complete bool   `json:"complete"`
+}
+
+// IssuedAccessToken is a short-lived access token signed by Will.IAM
+type IssuedAccessToken struct {
+	AccessToken string    `json:"accessToken"`
+	TokenType   string    `json:"tokenType"`
+	ExpiresIn   int64     `json:"expiresIn"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// OAuth2Token is an access token obtained with the client_credentials grant
+type OAuth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
@@ -0,0 +1,259 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("Will.IAM circuit breaker is open")
+
+// checker asks Will.IAM whether a request has a permission, through the
+// cache and circuit breaker enabled in config. Concurrent checks of the same
+// token and permission share a single call
+type checker struct {
+	iamURL  string
+	service string
+	cache   *decisionCache
+	breaker *circuitBreaker
+
+	mu       sync.Mutex
+	inFlight map[string]*checkCall
+}
+
+type checkCall struct {
+	done   chan struct{}
+	status *auth
+	err    error
+}
+
+func newChecker(cnf *config) *checker {
+	c := &checker{
+		iamURL:   cnf.URL,
+		service:  cnf.Permission.Service,
+		inFlight: map[string]*checkCall{},
+	}
+	if cnf.Cache != nil && cnf.Cache.Enabled {
+		c.cache = newDecisionCache(
+			cnf.Cache.TTL, cnf.Cache.NegativeTTL, cnf.Cache.MaxEntries,
+		)
+	}
+	if cnf.CircuitBreaker != nil && cnf.CircuitBreaker.Enabled {
+		c.breaker = newCircuitBreaker(
+			cnf.CircuitBreaker.FailureThreshold, cnf.CircuitBreaker.OpenTimeout,
+		)
+	}
+	return c
+}
+
+func (c *checker) check(permission, authorization string) (*auth, error) {
+	key := checkKey(permission, authorization)
+	if status, ok := c.cache.get(key); ok {
+		return status, nil
+	}
+	c.mu.Lock()
+	if call, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.status, call.err
+	}
+	call := &checkCall{done: make(chan struct{})}
+	c.inFlight[key] = call
+	c.mu.Unlock()
+
+	call.status, call.err = c.checkThroughBreaker(permission, authorization)
+	if call.err == nil {
+		c.cache.set(key, call.status)
+	}
+
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	c.mu.Unlock()
+	close(call.done)
+	return call.status, call.err
+}
+
+func (c *checker) checkThroughBreaker(
+	permission, authorization string,
+) (*auth, error) {
+	if !c.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+	status, err := c.getAuthStatus(permission, authorization)
+	if err != nil || status.code >= http.StatusInternalServerError {
+		c.breaker.failure()
+	} else {
+		c.breaker.success()
+	}
+	return status, err
+}
+
+func (c *checker) getAuthStatus(permission, authorization string) (*auth, error) {
+	url := fmt.Sprintf("%s/permissions/has?permission=%s",
+		c.iamURL, url.QueryEscape(permission))
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("x-caller-service", c.service)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return &auth{
+		code:  res.StatusCode,
+		token: res.Header.Get("x-access-token"),
+		email: res.Header.Get("x-email"),
+	}, nil
+}
+
+// checkKey hashes authorization so tokens and key secrets aren't kept in
+// memory as they are
+func checkKey(permission, authorization string) string {
+	sum := sha256.Sum256([]byte(authorization + "\x00" + permission))
+	return hex.EncodeToString(sum[:])
+}
+
+// decisionCache keeps granted (200) and denied (403) answers, a nil
+// *decisionCache caches nothing
+type decisionCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	mu      sync.Mutex
+	entries map[string]decisionCacheEntry
+}
+
+type decisionCacheEntry struct {
+	status    *auth
+	expiresAt time.Time
+}
+
+func newDecisionCache(
+	ttl, negativeTTL time.Duration, maxEntries int,
+) *decisionCache {
+	return &decisionCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		entries:     map[string]decisionCacheEntry{},
+	}
+}
+
+func (dc *decisionCache) get(key string) (*auth, bool) {
+	if dc == nil {
+		return nil, false
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	e, ok := dc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(e.expiresAt) {
+		delete(dc.entries, key)
+		return nil, false
+	}
+	return e.status, true
+}
+
+func (dc *decisionCache) set(key string, status *auth) {
+	if dc == nil {
+		return
+	}
+	var ttl time.Duration
+	switch status.code {
+	case http.StatusOK:
+		ttl = dc.ttl
+	case http.StatusForbidden:
+		ttl = dc.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if len(dc.entries) >= dc.maxEntries {
+		for k, e := range dc.entries {
+			if !now.Before(e.expiresAt) {
+				delete(dc.entries, k)
+			}
+		}
+	}
+	// still full of live entries, drop any of them
+	for k := range dc.entries {
+		if len(dc.entries) < dc.maxEntries {
+			break
+		}
+		delete(dc.entries, k)
+	}
+	dc.entries[key] = decisionCacheEntry{status: status, expiresAt: now.Add(ttl)}
+}
+
+// circuitBreaker opens after threshold consecutive failures, rejecting calls
+// for openTimeout, then lets a single call through to probe Will.IAM. A nil
+// *circuitBreaker is always closed
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, openTimeout: openTimeout}
+}
+
+func (cb *circuitBreaker) allow() bool {
+	if cb == nil {
+		return true
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.failures < cb.threshold {
+		return true
+	}
+	if time.Now().Before(cb.openUntil) || cb.probing {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.probing = false
+}
+
+func (cb *circuitBreaker) failure() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures++
+	cb.probing = false
+	if cb.failures >= cb.threshold {
+		cb.openUntil = time.Now().Add(cb.openTimeout)
+	}
+}
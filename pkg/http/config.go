@@ -2,15 +2,21 @@ package http
 
 import (
 	"net/http"
+	"sync"
 	"time"
 )
 
 type (
 	config struct {
-		HTTP       *configHTTP
-		URL        string
-		Middleware *configMiddleware
-		Permission *configPermission
+		HTTP           *configHTTP
+		URL            string
+		Middleware     *configMiddleware
+		Permission     *configPermission
+		Cache          *configCache
+		CircuitBreaker *configCircuitBreaker
+
+		checkerOnce sync.Once
+		checker     *checker
 	}
 
 	configHTTP struct {
@@ -21,11 +27,31 @@ type (
 
 	configMiddleware struct {
 		Enabled bool
+		// FailOpen lets requests through when Will.IAM can't answer,
+		// otherwise they're answered with 5xx
+		FailOpen bool
 	}
 
 	configPermission struct {
 		Service string
 	}
+
+	// configCache caches Will.IAM answers by token and permission
+	configCache struct {
+		Enabled bool
+		// TTL of granted checks and NegativeTTL of denied ones
+		TTL         time.Duration
+		NegativeTTL time.Duration
+		MaxEntries  int
+	}
+
+	// configCircuitBreaker stops calling Will.IAM for OpenTimeout after
+	// FailureThreshold consecutive failures
+	configCircuitBreaker struct {
+		Enabled          bool
+		FailureThreshold int
+		OpenTimeout      time.Duration
+	}
 )
 
 // NewConfig returns the config struct with default values
@@ -38,10 +64,22 @@ func NewConfig() *config {
 		},
 		URL: "http://localhost:4040",
 		Middleware: &configMiddleware{
-			Enabled: false,
+			Enabled:  false,
+			FailOpen: false,
 		},
 		Permission: &configPermission{
 			Service: "service",
 		},
+		Cache: &configCache{
+			Enabled:     false,
+			TTL:         5 * time.Second,
+			NegativeTTL: time.Second,
+			MaxEntries:  10000,
+		},
+		CircuitBreaker: &configCircuitBreaker{
+			Enabled:          false,
+			FailureThreshold: 5,
+			OpenTimeout:      10 * time.Second,
+		},
 	}
 }
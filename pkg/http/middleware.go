@@ -3,7 +3,6 @@ package http
 import (
 	"fmt"
 	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -16,8 +15,9 @@ type Middleware struct {
 	logger     logrus.FieldLogger
 	permission *permission
 	resource   func(*http.Request) string
-	iamURL     string
+	checker    *checker
 	enabled    bool
+	failOpen   bool
 
 	next http.Handler
 }
@@ -42,6 +42,10 @@ func NewMiddleware(
 		ehttp.Instrument(client)
 	}
 
+	cnf.checkerOnce.Do(func() {
+		cnf.checker = newChecker(cnf)
+	})
+
 	return func(next http.Handler) http.Handler {
 		return &Middleware{
 			logger: logger,
@@ -51,9 +55,10 @@ func NewMiddleware(
 				Action:         action,
 				Resource:       resource,
 			},
-			iamURL:  cnf.URL,
-			enabled: cnf.Middleware.Enabled,
-			next:    next,
+			checker:  cnf.checker,
+			enabled:  cnf.Middleware.Enabled,
+			failOpen: cnf.Middleware.FailOpen,
+			next:     next,
 		}
 	}
 }
@@ -73,7 +78,17 @@ func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status, err := m.getAuthStatus(m.permission.build(r), authorization)
+	status, err := m.checker.check(m.permission.build(r), authorization)
+	if m.failOpen && (err != nil || status.code >= http.StatusInternalServerError) {
+		m.logger.WithError(err).Warn("failed to get auth status, failing open")
+		m.next.ServeHTTP(w, r)
+		return
+	}
+	if err == errCircuitOpen {
+		m.logger.WithError(err).Error("failed to get auth status")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		m.logger.WithError(err).Error("failed to get auth status")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -105,28 +120,6 @@ type auth struct {
 	email string
 }
 
-func (m *Middleware) getAuthStatus(permission, authorization string) (*auth, error) {
-	url := fmt.Sprintf("%s/permissions/has?permission=%s",
-		m.iamURL, url.QueryEscape(permission))
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", authorization)
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	return &auth{
-		code:  res.StatusCode,
-		token: res.Header.Get("x-access-token"),
-		email: res.Header.Get("x-email"),
-	}, nil
-}
-
 // Permission holds information to build the full Will.IAM permission
 // string.
 // For the description on service, ownershipLevel and action
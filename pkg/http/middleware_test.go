@@ -0,0 +1,116 @@
+// +build unit
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestMiddleware(
+	t *testing.T, cnf *config, iamHandler http.HandlerFunc,
+) (http.Handler, *httptest.Server) {
+	t.Helper()
+	iam := httptest.NewServer(iamHandler)
+	cnf.URL = iam.URL
+	cnf.Middleware.Enabled = true
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	mw := NewMiddleware(logger, cnf, "RL", "Do", func(*http.Request) string {
+		return "*"
+	})
+	return mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})), iam
+}
+
+func doMiddlewareRequest(handler http.Handler, token string) int {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestMiddlewareCache(t *testing.T) {
+	cnf := NewConfig()
+	cnf.Cache.Enabled = true
+	var calls int32
+	handler, iam := newTestMiddleware(t, cnf,
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			if r.Header.Get("Authorization") == "Bearer granted" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		},
+	)
+	defer iam.Close()
+	for i := 0; i < 3; i++ {
+		if code := doMiddlewareRequest(handler, "granted"); code != http.StatusOK {
+			t.Errorf("Expected status 200. Got %d", code)
+		}
+		if code := doMiddlewareRequest(handler, "denied"); code != http.StatusForbidden {
+			t.Errorf("Expected status 403. Got %d", code)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls to Will.IAM. Got %d", calls)
+	}
+}
+
+func TestMiddlewareFailOpen(t *testing.T) {
+	cnf := NewConfig()
+	cnf.Middleware.FailOpen = true
+	handler, iam := newTestMiddleware(t, cnf,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	)
+	defer iam.Close()
+	if code := doMiddlewareRequest(handler, "token"); code != http.StatusOK {
+		t.Errorf("Expected status 200. Got %d", code)
+	}
+}
+
+func TestMiddlewareCircuitBreaker(t *testing.T) {
+	cnf := NewConfig()
+	cnf.CircuitBreaker.Enabled = true
+	cnf.CircuitBreaker.FailureThreshold = 2
+	cnf.CircuitBreaker.OpenTimeout = 50 * time.Millisecond
+	var calls int32
+	var healthy int32
+	handler, iam := newTestMiddleware(t, cnf,
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			if atomic.LoadInt32(&healthy) == 1 {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	)
+	defer iam.Close()
+	for i := 0; i < 2; i++ {
+		if code := doMiddlewareRequest(handler, "token"); code != http.StatusBadGateway {
+			t.Errorf("Expected status 502. Got %d", code)
+		}
+	}
+	if code := doMiddlewareRequest(handler, "token"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 with open circuit. Got %d", code)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls to Will.IAM. Got %d", calls)
+	}
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(60 * time.Millisecond)
+	if code := doMiddlewareRequest(handler, "token"); code != http.StatusOK {
+		t.Errorf("Expected status 200 after circuit closed. Got %d", code)
+	}
+}
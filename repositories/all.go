@@ -8,11 +8,19 @@ import (
 
 // All holds a reference to each possible repository interface
 type All struct {
+	AuditEvents
+	Decisions
+	ExpiredGrants
+	Groups
+	JobRuns
+	Locks
 	Permissions
 	PermissionsRequests
 	Roles
+	ServiceAccountKeys
 	ServiceAccounts
 	Services
+	Sessions
 	Tokens
 	Healthcheck
 	storage *Storage
@@ -21,11 +29,19 @@ type All struct {
 // New All ctor
 func New(s *Storage) *All {
 	return &All{
+		AuditEvents:         NewAuditEvents(s),
+		Decisions:           NewDecisions(s),
+		ExpiredGrants:       NewExpiredGrants(s),
+		Groups:              NewGroups(s),
+		JobRuns:             NewJobRuns(s),
+		Locks:               NewLocks(s),
 		Permissions:         NewPermissions(s),
 		PermissionsRequests: NewPermissionsRequests(s),
 		Roles:               NewRoles(s),
+		ServiceAccountKeys:  NewServiceAccountKeys(s),
 		ServiceAccounts:     NewServiceAccounts(s),
 		Services:            NewServices(s),
+		Sessions:            NewSessions(s),
 		Tokens:              NewTokens(s),
 		Healthcheck:         NewHealthcheck(s),
 		storage:             s,
@@ -61,19 +77,35 @@ func (a *All) WithPGTx(ctx context.Context, fn func(repo *All) error) error {
 
 func (a *All) cloneWithStorage(s *Storage) *All {
 	c := &All{
+		AuditEvents:         a.AuditEvents.Clone(),
+		Decisions:           a.Decisions.Clone(),
+		ExpiredGrants:       a.ExpiredGrants.Clone(),
+		Groups:              a.Groups.Clone(),
+		JobRuns:             a.JobRuns.Clone(),
+		Locks:               a.Locks.Clone(),
 		Permissions:         a.Permissions.Clone(),
 		PermissionsRequests: a.PermissionsRequests.Clone(),
 		Roles:               a.Roles.Clone(),
+		ServiceAccountKeys:  a.ServiceAccountKeys.Clone(),
 		ServiceAccounts:     a.ServiceAccounts.Clone(),
 		Services:            a.Services.Clone(),
+		Sessions:            a.Sessions.Clone(),
 		Tokens:              a.Tokens.Clone(),
 		storage:             s,
 	}
+	c.AuditEvents.setStorage(s)
+	c.Decisions.setStorage(s)
+	c.ExpiredGrants.setStorage(s)
+	c.Groups.setStorage(s)
+	c.JobRuns.setStorage(s)
+	c.Locks.setStorage(s)
 	c.Permissions.setStorage(s)
 	c.PermissionsRequests.setStorage(s)
 	c.Roles.setStorage(s)
+	c.ServiceAccountKeys.setStorage(s)
 	c.ServiceAccounts.setStorage(s)
 	c.Services.setStorage(s)
+	c.Sessions.setStorage(s)
 	c.Tokens.setStorage(s)
 	return c
 }
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// AuditEventsFilter narrows down AuditEvents.List results, zero values
+// match everything
+type AuditEventsFilter struct {
+	ActorServiceAccountID string
+	Action                string
+	TargetType            string
+	TargetID              string
+	RequestID             string
+	From                  pg.NullTime
+	To                    pg.NullTime
+}
+
+func (f AuditEventsFilter) args() []interface{} {
+	return []interface{}{
+		f.ActorServiceAccountID, f.Action, f.TargetType, f.TargetID,
+		f.RequestID, f.From, f.To,
+	}
+}
+
+const auditEventsFilterSQL = `
+    WHERE (?0 = '' OR actor_service_account_id::text = ?0)
+      AND (?1 = '' OR action = ?1)
+      AND (?2 = '' OR target_type = ?2)
+      AND (?3 = '' OR target_id = ?3)
+      AND (?4 = '' OR request_id = ?4)
+      AND (?5::timestamptz IS NULL OR created_at >= ?5)
+      AND (?6::timestamptz IS NULL OR created_at < ?6)
+`
+
+// AuditEvents repository
+type AuditEvents interface {
+	Clone() AuditEvents
+	Create(*models.AuditEvent) error
+	List(*AuditEventsFilter, *ListOptions) ([]models.AuditEvent, error)
+	ListCount(*AuditEventsFilter) (int64, error)
+	setStorage(*Storage)
+}
+
+type auditEvents struct {
+	*withStorage
+}
+
+func (aes *auditEvents) Clone() AuditEvents {
+	return NewAuditEvents(aes.storage.Clone())
+}
+
+// Create appends ae to the audit log
+func (aes *auditEvents) Create(ae *models.AuditEvent) error {
+	_, err := aes.storage.PG.DB.Query(
+		ae, `INSERT INTO audit_events (actor_service_account_id, action,
+		target_type, target_id, before, after, request_id)
+		VALUES (NULLIF(?actor_service_account_id, '')::uuid, ?action,
+		?target_type, ?target_id, ?before, ?after, ?request_id)
+		RETURNING id, created_at`, ae,
+	)
+	return err
+}
+
+// List audit events matching f, newest first
+func (aes *auditEvents) List(
+	f *AuditEventsFilter, lo *ListOptions,
+) ([]models.AuditEvent, error) {
+	var aeSl []models.AuditEvent
+	args := append(f.args(), lo.Limit(), lo.Offset())
+	if _, err := aes.storage.PG.DB.Query(
+		&aeSl, `
+    SELECT id, actor_service_account_id, action, target_type, target_id,
+    before, after, request_id, created_at FROM audit_events
+    `+auditEventsFilterSQL+`
+    ORDER BY created_at DESC, id LIMIT ?7 OFFSET ?8
+    `, args...,
+	); err != nil {
+		return nil, err
+	}
+	return aeSl, nil
+}
+
+// ListCount counts audit events matching f
+func (aes *auditEvents) ListCount(f *AuditEventsFilter) (int64, error) {
+	var count int64
+	if _, err := aes.storage.PG.DB.Query(
+		&count, `SELECT COUNT(*) FROM audit_events`+auditEventsFilterSQL,
+		f.args()...,
+	); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// NewAuditEvents ctor
+func NewAuditEvents(s *Storage) AuditEvents {
+	return &auditEvents{&withStorage{storage: s}}
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// AuthorizationChangesChannel is the channel Postgres notifies
+// models.AuthorizationChange on
+const AuthorizationChangesChannel = "authorization_changes"
+
+// ListenAuthorizationChanges calls fn with every change notified until ctx is
+// done. fn is called with nil whenever notifications may have been missed,
+// e.g. after the connection dropped, so callers can drop everything derived
+// from authorization data
+func (s *Storage) ListenAuthorizationChanges(
+	ctx context.Context, fn func(*models.AuthorizationChange),
+	onError func(error),
+) {
+	ln := s.PG.DB.WithContext(ctx).Listen(AuthorizationChangesChannel)
+	defer ln.Close()
+	for ctx.Err() == nil {
+		_, payload, err := ln.ReceiveTimeout(time.Second)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			onError(err)
+			fn(nil)
+			time.Sleep(time.Second)
+			continue
+		}
+		ac := &models.AuthorizationChange{}
+		if err := json.Unmarshal([]byte(payload), ac); err != nil {
+			onError(err)
+			fn(nil)
+			continue
+		}
+		fn(ac)
+	}
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// Decisions repository
+type Decisions interface {
+	Clone() Decisions
+	Create(*models.Decision) error
+	DeleteBefore(time.Time) (int, error)
+	setStorage(*Storage)
+}
+
+type decisions struct {
+	*withStorage
+}
+
+func (ds *decisions) Clone() Decisions {
+	return NewDecisions(ds.storage.Clone())
+}
+
+// Create appends d to the decision log
+func (ds *decisions) Create(d *models.Decision) error {
+	_, err := ds.storage.PG.DB.Query(
+		d, `INSERT INTO decisions (service_account_id, permission, allowed,
+		matching_grant_id, matching_grant, latency_ms, caller_service, request_id,
+		created_at)
+		VALUES (?service_account_id, ?permission, ?allowed,
+		NULLIF(?matching_grant_id, '')::uuid, ?matching_grant, ?latency_ms,
+		?caller_service, ?request_id, ?created_at)
+		RETURNING id`, d,
+	)
+	return err
+}
+
+// DeleteBefore removes decisions logged before t and returns how many were
+// removed
+func (ds *decisions) DeleteBefore(t time.Time) (int, error) {
+	res, err := ds.storage.PG.DB.Exec(
+		`DELETE FROM decisions WHERE created_at < ?`, t,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// NewDecisions ctor
+func NewDecisions(s *Storage) Decisions {
+	return &decisions{&withStorage{storage: s}}
+}
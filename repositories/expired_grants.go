@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// notExpiredSQL filters out rows from table whose expires_at already passed
+func notExpiredSQL(table string) string {
+	return fmt.Sprintf(
+		"(%[1]s.expires_at IS NULL OR %[1]s.expires_at > now())", table,
+	)
+}
+
+// ExpiredGrants repository
+type ExpiredGrants interface {
+	Clone() ExpiredGrants
+	Sweep() ([]models.ExpiredGrant, error)
+	setStorage(*Storage)
+}
+
+type expiredGrants struct {
+	*withStorage
+}
+
+func (egs *expiredGrants) Clone() ExpiredGrants {
+	return NewExpiredGrants(egs.storage.Clone())
+}
+
+// Sweep deletes all expired permissions and role bindings, recording them
+// in expired_grants
+func (egs *expiredGrants) Sweep() ([]models.ExpiredGrant, error) {
+	var egSl []models.ExpiredGrant
+	if _, err := egs.storage.PG.DB.Query(
+		&egSl, `
+    WITH dp AS (
+      DELETE FROM permissions WHERE expires_at <= now()
+      RETURNING id, role_id, service, ownership_level, action, resource_hierarchy,
+      deny, expires_at
+    ), drb AS (
+      DELETE FROM role_bindings WHERE expires_at <= now()
+      RETURNING id, role_id, service_account_id, expires_at
+    )
+    INSERT INTO expired_grants (kind, grant_id, role_id, service_account_id,
+    permission, expires_at)
+    SELECT ?0, id, role_id, NULL,
+      CONCAT(CASE WHEN deny THEN ?2 ELSE '' END, service, '::', ownership_level, '::',
+      action, '::', resource_hierarchy), expires_at FROM dp
+    UNION ALL
+    SELECT ?1, id, role_id, service_account_id, NULL, expires_at FROM drb
+    RETURNING id, kind, grant_id, role_id, service_account_id, permission,
+    expires_at, removed_at
+    `, models.ExpiredGrantKinds.Permission, models.ExpiredGrantKinds.RoleBinding,
+		models.DenyPrefix,
+	); err != nil {
+		return nil, err
+	}
+	return egSl, nil
+}
+
+// NewExpiredGrants ctor
+func NewExpiredGrants(s *Storage) ExpiredGrants {
+	return &expiredGrants{&withStorage{storage: s}}
+}
@@ -0,0 +1,338 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// Groups repository
+type Groups interface {
+	AddMember(string, string) error
+	BindingsForServiceAccountID(string) ([]models.GroupBinding, error)
+	Clone() Groups
+	Create(*models.Group) error
+	Delete(string) error
+	DropInclusions(string) error
+	DropMembers(string) error
+	DropMemberships(string) error
+	ForName(string) ([]models.Group, error)
+	Get(string) (*models.Group, error)
+	Include(string, string) error
+	Included(string) ([]models.Group, error)
+	Includes(string, string) (bool, error)
+	List(*ListOptions) ([]models.Group, error)
+	ListCount() (int64, error)
+	LockInclusions() error
+	Members(string) ([]models.ServiceAccount, error)
+	MemberOf(string) ([]models.Group, error)
+	RemoveMember(string, string) error
+	Roles(string) ([]models.Role, error)
+	RolesIDs(string) ([]string, error)
+	Search(string, *ListOptions) ([]models.Group, error)
+	SearchCount(string) (int64, error)
+	Update(*models.Group) error
+	setStorage(*Storage)
+}
+
+type groups struct {
+	*withStorage
+}
+
+func (gs *groups) Clone() Groups {
+	return NewGroups(gs.storage.Clone())
+}
+
+func (gs groups) Create(g *models.Group) error {
+	_, err := gs.storage.PG.DB.Query(
+		g, `INSERT INTO groups (name) VALUES (?name) RETURNING id`, g,
+	)
+	return err
+}
+
+func (gs groups) Update(g *models.Group) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`UPDATE groups SET name = ?name, updated_at = now() WHERE id = ?id`, g,
+	)
+	return err
+}
+
+func (gs groups) Get(id string) (*models.Group, error) {
+	g := new(models.Group)
+	if _, err := gs.storage.PG.DB.Query(
+		g, `SELECT id, name, created_at, updated_at FROM groups WHERE id = ?`, id,
+	); err != nil {
+		return nil, err
+	}
+	if g.ID == "" {
+		return nil, errors.NewEntityNotFoundError(models.Group{}, id)
+	}
+	return g, nil
+}
+
+// ForName lists groups named name regardless of case
+func (gs groups) ForName(name string) ([]models.Group, error) {
+	gSl := []models.Group{}
+	if _, err := gs.storage.PG.DB.Query(
+		&gSl, `SELECT id, name FROM groups WHERE lower(name) = lower(?)`, name,
+	); err != nil {
+		return nil, err
+	}
+	return gSl, nil
+}
+
+// Delete removes groupID, along with its members, inclusions and bindings
+func (gs groups) Delete(groupID string) error {
+	res, err := gs.storage.PG.DB.Exec(`DELETE FROM groups WHERE id = ?`, groupID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.NewEntityNotFoundError(models.Group{}, groupID)
+	}
+	return nil
+}
+
+func (gs groups) List(lo *ListOptions) ([]models.Group, error) {
+	gSl := []models.Group{}
+	if _, err := gs.storage.PG.DB.Query(
+		&gSl, `SELECT id, name FROM groups ORDER BY name ASC LIMIT ? OFFSET ?`,
+		lo.Limit(), lo.Offset(),
+	); err != nil {
+		return nil, err
+	}
+	return gSl, nil
+}
+
+func (gs groups) ListCount() (int64, error) {
+	var count int64
+	if _, err := gs.storage.PG.DB.Query(
+		&count, `SELECT count(*) FROM groups`,
+	); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (gs groups) Search(term string, lo *ListOptions) ([]models.Group, error) {
+	gSl := []models.Group{}
+	if _, err := gs.storage.PG.DB.Query(
+		&gSl, `SELECT id, name FROM groups WHERE name ILIKE ?
+		ORDER BY name ASC LIMIT ? OFFSET ?`,
+		fmt.Sprintf("%%%s%%", term), lo.Limit(), lo.Offset(),
+	); err != nil {
+		return nil, err
+	}
+	return gSl, nil
+}
+
+func (gs groups) SearchCount(term string) (int64, error) {
+	var count int64
+	if _, err := gs.storage.PG.DB.Query(
+		&count, `SELECT count(*) FROM groups WHERE name ILIKE ?`,
+		fmt.Sprintf("%%%s%%", term),
+	); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// AddMember makes serviceAccountID a member of groupID
+func (gs groups) AddMember(groupID, serviceAccountID string) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`INSERT INTO group_members (group_id, service_account_id) VALUES (?, ?)
+		ON CONFLICT DO NOTHING`, groupID, serviceAccountID,
+	)
+	return err
+}
+
+// RemoveMember removes serviceAccountID from groupID
+func (gs groups) RemoveMember(groupID, serviceAccountID string) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`DELETE FROM group_members WHERE group_id = ? AND service_account_id = ?`,
+		groupID, serviceAccountID,
+	)
+	return err
+}
+
+// DropMembers removes every direct member of groupID
+func (gs groups) DropMembers(groupID string) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`DELETE FROM group_members WHERE group_id = ?`, groupID,
+	)
+	return err
+}
+
+// DropMemberships removes serviceAccountID from every group
+func (gs groups) DropMemberships(serviceAccountID string) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`DELETE FROM group_members WHERE service_account_id = ?`, serviceAccountID,
+	)
+	return err
+}
+
+// Members returns service accounts directly in groupID
+func (gs groups) Members(groupID string) ([]models.ServiceAccount, error) {
+	sas := []models.ServiceAccount{}
+	if _, err := gs.storage.PG.DB.Query(
+		&sas, `SELECT sa.id, sa.name, sa.picture, sa.email FROM service_accounts sa
+		JOIN group_members gm ON gm.service_account_id = sa.id
+		WHERE gm.group_id = ? ORDER BY sa.name ASC`, groupID,
+	); err != nil {
+		return nil, err
+	}
+	return sas, nil
+}
+
+// MemberOf returns groups serviceAccountID is a member of, directly or
+// through groups they include
+func (gs groups) MemberOf(serviceAccountID string) ([]models.Group, error) {
+	gSl := []models.Group{}
+	if _, err := gs.storage.PG.DB.Query(
+		&gSl, `SELECT id, name FROM groups
+		WHERE id IN (`+memberGroupsSQL("?")+`) ORDER BY name ASC`,
+		serviceAccountID,
+	); err != nil {
+		return nil, err
+	}
+	return gSl, nil
+}
+
+// Include makes groupID include includedGroupID, so its members are
+// groupID members too
+func (gs groups) Include(groupID, includedGroupID string) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`INSERT INTO group_inclusions (group_id, included_group_id) VALUES (?, ?)
+		ON CONFLICT DO NOTHING`, groupID, includedGroupID,
+	)
+	return err
+}
+
+// DropInclusions removes every group groupID directly includes
+func (gs groups) DropInclusions(groupID string) error {
+	_, err := gs.storage.PG.DB.Exec(
+		`DELETE FROM group_inclusions WHERE group_id = ?`, groupID,
+	)
+	return err
+}
+
+// LockInclusions keeps other transactions from changing group inclusions
+// until the current one ends, so checking for cycles before including a
+// group holds. It must be called inside a transaction (see All.WithPGTx)
+func (gs groups) LockInclusions() error {
+	_, err := gs.storage.PG.DB.Exec(
+		`LOCK TABLE group_inclusions IN SHARE ROW EXCLUSIVE MODE`,
+	)
+	return err
+}
+
+// Included returns groups directly included by groupID
+func (gs groups) Included(groupID string) ([]models.Group, error) {
+	gSl := []models.Group{}
+	if _, err := gs.storage.PG.DB.Query(
+		&gSl, `SELECT g.id, g.name FROM groups g
+		JOIN group_inclusions gi ON gi.included_group_id = g.id
+		WHERE gi.group_id = ? ORDER BY g.name ASC`, groupID,
+	); err != nil {
+		return nil, err
+	}
+	return gSl, nil
+}
+
+// Includes tells if groupID includes otherGroupID, directly or not
+func (gs groups) Includes(groupID, otherGroupID string) (bool, error) {
+	var count int64
+	if _, err := gs.storage.PG.DB.Query(
+		&count, `SELECT count(*) FROM (`+includedGroupsSQL(`SELECT ?0::uuid`)+`)
+		included WHERE group_id = ?1 AND group_id != ?0`, groupID, otherGroupID,
+	); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Roles returns roles directly bound to groupID
+func (gs groups) Roles(groupID string) ([]models.Role, error) {
+	rsSl := []models.Role{}
+	if _, err := gs.storage.PG.DB.Query(
+		&rsSl, `SELECT r.id, r.name FROM roles r
+		JOIN group_bindings gb ON gb.role_id = r.id
+		WHERE gb.group_id = ? ORDER BY r.name ASC`, groupID,
+	); err != nil {
+		return nil, err
+	}
+	return rsSl, nil
+}
+
+// RolesIDs returns the ids of roles groupID members are bound to through
+// groups, that is roles bound to groupID or to any group including it
+func (gs groups) RolesIDs(groupID string) ([]string, error) {
+	ids := []string{}
+	if _, err := gs.storage.PG.DB.Query(
+		&ids, `SELECT DISTINCT role_id FROM group_bindings
+		WHERE group_id IN (`+includingGroupsSQL(`SELECT ?::uuid`)+`)`, groupID,
+	); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// BindingsForServiceAccountID returns the bindings of every group
+// serviceAccountID is a member of, directly or not
+func (gs groups) BindingsForServiceAccountID(
+	serviceAccountID string,
+) ([]models.GroupBinding, error) {
+	gbs := []models.GroupBinding{}
+	if _, err := gs.storage.PG.DB.Query(
+		&gbs, `SELECT id, group_id, role_id FROM group_bindings
+		WHERE group_id IN (`+memberGroupsSQL("?")+`)
+		ORDER BY group_id, role_id`, serviceAccountID,
+	); err != nil {
+		return nil, err
+	}
+	return gbs, nil
+}
+
+// includedGroupsSQL selects, as group_id, the ids of groups selected by
+// groupsSQL along with every group they include, directly or not
+func includedGroupsSQL(groupsSQL string) string {
+	return fmt.Sprintf(`WITH RECURSIVE included_groups(group_id) AS (
+      %s
+      UNION
+      SELECT gi.included_group_id FROM group_inclusions gi
+      JOIN included_groups i ON gi.group_id = i.group_id
+    ) SELECT group_id FROM included_groups`, groupsSQL)
+}
+
+// includingGroupsSQL selects, as group_id, the ids of groups selected by
+// groupsSQL along with every group including them, directly or not
+func includingGroupsSQL(groupsSQL string) string {
+	return fmt.Sprintf(`WITH RECURSIVE including_groups(group_id) AS (
+      %s
+      UNION
+      SELECT gi.group_id FROM group_inclusions gi
+      JOIN including_groups i ON gi.included_group_id = i.group_id
+    ) SELECT group_id FROM including_groups`, groupsSQL)
+}
+
+// memberGroupsSQL selects, as group_id, the ids of groups saIDExpr is a
+// member of, directly or not
+func memberGroupsSQL(saIDExpr string) string {
+	return includingGroupsSQL(fmt.Sprintf(
+		`SELECT group_id FROM group_members WHERE service_account_id = %s`,
+		saIDExpr,
+	))
+}
+
+// groupMembersSQL selects, as service_account_id, the ids of service
+// accounts that are members, directly or not, of groups selected by groupsSQL
+func groupMembersSQL(groupsSQL string) string {
+	return `SELECT service_account_id FROM group_members
+    WHERE group_id IN (` + includedGroupsSQL(groupsSQL) + `)`
+}
+
+// NewGroups groups ctor
+func NewGroups(s *Storage) Groups {
+	return &groups{&withStorage{storage: s}}
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"time"
+)
+
+// JobRuns repository keeps when each worker job last ran
+type JobRuns interface {
+	Clone() JobRuns
+	Claim(string, time.Duration) (bool, error)
+	setStorage(*Storage)
+}
+
+type jobRuns struct {
+	*withStorage
+}
+
+func (jrs *jobRuns) Clone() JobRuns {
+	return NewJobRuns(jrs.storage.Clone())
+}
+
+// Claim records that job name runs now unless it already ran in the current
+// interval, intervals being counted from the Unix epoch so all replicas
+// agree on them. It tells if name was claimed, inside a transaction (see
+// All.WithPGTx) the claim is undone on rollback
+func (jrs *jobRuns) Claim(name string, interval time.Duration) (bool, error) {
+	res, err := jrs.storage.PG.DB.Exec(
+		`INSERT INTO job_runs (name, ran_at) VALUES (?0, now())
+		ON CONFLICT (name) DO UPDATE SET ran_at = EXCLUDED.ran_at
+		WHERE floor(extract(epoch FROM job_runs.ran_at) * 1000 / ?1) <
+			floor(extract(epoch FROM EXCLUDED.ran_at) * 1000 / ?1)`,
+		name, interval.Nanoseconds()/int64(time.Millisecond),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+// NewJobRuns ctor
+func NewJobRuns(s *Storage) JobRuns {
+	return &jobRuns{&withStorage{storage: s}}
+}
@@ -0,0 +1,34 @@
+package repositories
+
+// Locks repository wraps Postgres advisory locks
+type Locks interface {
+	Clone() Locks
+	TryXact(int64) (bool, error)
+	setStorage(*Storage)
+}
+
+type locks struct {
+	*withStorage
+}
+
+func (ls *locks) Clone() Locks {
+	return NewLocks(ls.storage.Clone())
+}
+
+// TryXact tries to acquire an advisory lock on key without waiting. It must
+// be called inside a transaction (see All.WithPGTx), the lock is released on
+// commit or rollback
+func (ls *locks) TryXact(key int64) (bool, error) {
+	var acquired bool
+	if _, err := ls.storage.PG.DB.QueryOne(
+		&acquired, `SELECT pg_try_advisory_xact_lock(?)`, key,
+	); err != nil {
+		return false, err
+	}
+	return acquired, nil
+}
+
+// NewLocks ctor
+func NewLocks(s *Storage) Locks {
+	return &locks{&withStorage{storage: s}}
+}
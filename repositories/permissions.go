@@ -29,7 +29,7 @@ func (ps *permissions) Get(id string) (*models.Permission, error) {
 	p := new(models.Permission)
 	if info, err := ps.storage.PG.DB.Query(
 		p, `SELECT id, role_id, service, ownership_level,
-action, resource_hierarchy, alias FROM permissions
+action, resource_hierarchy, alias, deny, expires_at, condition FROM permissions
 	WHERE id = ?`, id,
 	); err != nil {
 		return nil, err
@@ -45,10 +45,11 @@ func (ps *permissions) ForServiceAccount(
 	permissions := []models.Permission{}
 	if _, err := ps.storage.PG.DB.Query(
 		&permissions, `SELECT p.id, p.role_id, p.service, p.ownership_level,
-p.action, p.resource_hierarchy, p.alias FROM permissions p
-	JOIN role_bindings rb ON rb.role_id = p.role_id
-	WHERE rb.service_account_id = ?
-	ORDER BY p.service, p.ownership_level, p.action, p.resource_hierarchy`, saID,
+p.action, p.resource_hierarchy, p.alias, p.deny, p.expires_at,
+p.condition FROM permissions p
+	WHERE p.role_id = ANY (`+boundRolesSQL("?0")+`) AND `+notExpiredSQL("p")+`
+	ORDER BY p.service, p.ownership_level, p.action, p.resource_hierarchy,
+	p.condition`, saID,
 	); err != nil {
 		return nil, err
 	}
@@ -59,21 +60,31 @@ func (ps *permissions) ForRole(roleID string) ([]models.Permission, error) {
 	permissions := []models.Permission{}
 	if _, err := ps.storage.PG.DB.Query(
 		&permissions, `SELECT id, role_id, service, ownership_level,
-action, resource_hierarchy, alias FROM permissions
+action, resource_hierarchy, alias, deny, expires_at, condition FROM permissions
 	WHERE role_id = ?
-	ORDER BY service, ownership_level, action, resource_hierarchy`, roleID,
+	ORDER BY service, ownership_level, action, resource_hierarchy, condition`,
+		roleID,
 	); err != nil {
 		return nil, err
 	}
 	return permissions, nil
 }
 
+// Create stores p. Permissions only merge with stored ones under the same
+// condition, keeping the latest expiration, as merging conditions or
+// expirations apart would widen grants
 func (ps *permissions) Create(p *models.Permission) error {
 	_, err := ps.storage.PG.DB.Exec(
 		`INSERT INTO permissions (role_id, service, ownership_level, action,
-		resource_hierarchy, alias) VALUES (?, ?, ?, ?, ?, ?)
-		ON CONFLICT DO NOTHING RETURNING id`, p.RoleID, p.Service, p.OwnershipLevel,
-		p.Action, p.ResourceHierarchy, p.Alias,
+		resource_hierarchy, alias, deny, expires_at, condition)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (role_id, ownership_level, action, service, resource_hierarchy, deny, condition)
+		DO UPDATE SET expires_at = CASE
+			WHEN permissions.expires_at IS NULL OR EXCLUDED.expires_at IS NULL THEN NULL
+			ELSE GREATEST(permissions.expires_at, EXCLUDED.expires_at)
+		END
+		RETURNING id`, p.RoleID, p.Service, p.OwnershipLevel,
+		p.Action, p.ResourceHierarchy, p.Alias, p.Deny, p.ExpiresAt, p.Condition,
 	)
 	return err
 }
@@ -7,6 +7,7 @@ type PermissionsRequests interface {
 	Clone() PermissionsRequests
 	Create(*models.PermissionRequest) error
 	Deny(string, string) error
+	DenyOpenForServiceAccount(string, string) error
 	Get(string) (*models.PermissionRequest, error)
 	Grant(string, string) error
 	ListOpenRequestsVisibleTo(*ListOptions, string) ([]models.PermissionRequest, error)
@@ -25,8 +26,8 @@ func (prs *permissionsRequests) Clone() PermissionsRequests {
 func (prs *permissionsRequests) Create(pr *models.PermissionRequest) error {
 	_, err := prs.storage.PG.DB.Query(
 		pr, `INSERT INTO permissions_requests (service, ownership_level, action, resource_hierarchy,
-    alias, message, state, service_account_id) VALUES (?service, ?ownership_level, ?action,
-    ?resource_hierarchy, ?alias, ?message, ?state, ?service_account_id)
+    alias, message, state, service_account_id, expires_at) VALUES (?service, ?ownership_level,
+    ?action, ?resource_hierarchy, ?alias, ?message, ?state, ?service_account_id, ?expires_at)
     ON CONFLICT (service, ownership_level, action, resource_hierarchy, service_account_id)
     WHERE state = 'open' DO NOTHING RETURNING id`, pr,
 	)
@@ -41,6 +42,21 @@ func (prs *permissionsRequests) Deny(saID, prID string) error {
 	return err
 }
 
+// DenyOpenForServiceAccount denies every open request of requesterID on
+// behalf of moderatorID, which may be empty
+func (prs *permissionsRequests) DenyOpenForServiceAccount(
+	moderatorID, requesterID string,
+) error {
+	_, err := prs.storage.PG.DB.Exec(
+		`UPDATE permissions_requests SET state = ?,
+    moderator_service_account_id = NULLIF(?, '')::uuid, updated_at = now()
+    WHERE service_account_id = ? AND state = ?`,
+		models.PermissionRequestStates.Denied, moderatorID, requesterID,
+		models.PermissionRequestStates.Open,
+	)
+	return err
+}
+
 func (prs *permissionsRequests) Get(prID string) (*models.PermissionRequest, error) {
 	var pr models.PermissionRequest
 	if _, err := prs.storage.PG.DB.Query(
@@ -70,20 +86,18 @@ func (prs *permissionsRequests) ListOpenRequestsVisibleTo(
 		&prSl, `
     SELECT DISTINCT pr.id, pr.service, pr.ownership_level, pr.action, pr.resource_hierarchy,
     pr.service_account_id, sas.picture AS requester_picture, sas.name AS requester_name, pr.state,
-    pr.message, pr.alias
+    pr.message, pr.alias, pr.expires_at
     FROM permissions_requests pr
     CROSS JOIN (SELECT service, action, resource_hierarchy FROM permissions
-        WHERE role_id = ANY (SELECT role_id FROM role_bindings WHERE service_account_id = ?)
-        AND ownership_level = 'RO') saop
+        WHERE role_id = ANY (`+boundRolesSQL("?0")+`)
+        AND ownership_level = 'RO' AND NOT deny AND condition = ''
+        AND `+notExpiredSQL("permissions")+`) saop
     INNER JOIN service_accounts sas ON sas.id = pr.service_account_id
     WHERE state = 'open'
       AND CASE WHEN saop.service = '*' THEN true ELSE pr.service = saop.service END
       AND CASE WHEN saop.action = '*' THEN true ELSE pr.action = saop.action END
-      AND CASE WHEN saop.resource_hierarchy = '*'
-        THEN true
-        ELSE pr.resource_hierarchy LIKE CONCAT(REPLACE(saop.resource_hierarchy, '*', ''), '%')
-      END
-    ORDER BY pr.service, pr.action, pr.resource_hierarchy ASC LIMIT ? OFFSET ?
+      AND rh_contains(saop.resource_hierarchy, pr.resource_hierarchy)
+    ORDER BY pr.service, pr.action, pr.resource_hierarchy ASC LIMIT ?1 OFFSET ?2
     `, saID, lo.Limit(), lo.Offset(),
 	); err != nil {
 		return nil, err
@@ -99,15 +113,13 @@ func (prs *permissionsRequests) ListOpenRequestsVisibleToCount(
 		&count, `
     SELECT COUNT(DISTINCT pr.id) FROM permissions_requests pr
     CROSS JOIN (SELECT service, action, resource_hierarchy FROM permissions
-        WHERE role_id = ANY (SELECT role_id FROM role_bindings WHERE service_account_id = ?)
-        AND ownership_level = 'RO') saop
+        WHERE role_id = ANY (`+boundRolesSQL("?0")+`)
+        AND ownership_level = 'RO' AND NOT deny AND condition = ''
+        AND `+notExpiredSQL("permissions")+`) saop
     WHERE state = 'open'
       AND CASE WHEN saop.service = '*' THEN true ELSE pr.service = saop.service END
       AND CASE WHEN saop.action = '*' THEN true ELSE pr.action = saop.action END
-      AND CASE WHEN saop.resource_hierarchy = '*'
-        THEN true
-        ELSE pr.resource_hierarchy LIKE CONCAT(REPLACE(saop.resource_hierarchy, '*', ''), '%')
-      END
+      AND rh_contains(saop.resource_hierarchy, pr.resource_hierarchy)
     `, saID,
 	); err != nil {
 		return 0, err
@@ -3,6 +3,7 @@ package repositories
 import (
 	"fmt"
 
+	"github.com/go-pg/pg"
 	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
 )
@@ -10,17 +11,34 @@ import (
 // Roles repository
 type Roles interface {
 	Bind(*models.RoleBinding) error
+	BindGroup(string, string) error
+	Bindings(string) ([]models.RoleBinding, error)
+	BindingsForServiceAccountID(string) ([]models.RoleBinding, error)
 	Clone() Roles
 	Create(*models.Role) error
+	Delete(string) error
 	DropBindings(string) error
+	DropGroupBindings(string) error
+	DropInclusions(string) error
 	DropPermissions(string) error
+	ForName(string) ([]models.Role, error)
 	ForServiceAccountID(string) ([]models.Role, error)
 	Get(string) (*models.Role, error)
+	GetGroups(string) ([]models.Group, error)
 	GetServiceAccounts(string) ([]models.ServiceAccount, error)
+	Include(string, string) error
+	Included(string) ([]models.Role, error)
+	IncludedIDs([]string) ([]string, error)
+	Includes(string, string) (bool, error)
 	List(*ListOptions) ([]models.Role, error)
+	LockInclusions() error
 	ListCount() (int64, error)
+	ListManagedBySCIM(*ListOptions) ([]models.Role, error)
+	ListManagedBySCIMCount() (int64, error)
 	Search(string, *ListOptions) ([]models.Role, error)
 	SearchCount(string) (int64, error)
+	Unbind(string, string) error
+	UnbindManagedBySCIM(string, string) error
 	Update(*models.Role) error
 	WithNamePrefix(string, int) ([]models.Role, error)
 	setStorage(*Storage)
@@ -69,8 +87,8 @@ func (rs roles) ForServiceAccountID(serviceAccountID string) ([]models.Role, err
 
 func (rs roles) Create(r *models.Role) error {
 	_, err := rs.storage.PG.DB.Query(
-		r, `INSERT INTO roles (name, is_base_role) VALUES (?name, ?is_base_role)
-		RETURNING id`, r,
+		r, `INSERT INTO roles (name, is_base_role, managed_by_scim)
+		VALUES (?name, ?is_base_role, ?managed_by_scim) RETURNING id`, r,
 	)
 	return err
 }
@@ -85,12 +103,58 @@ func (rs roles) Update(r *models.Role) error {
 
 func (rs roles) Bind(rb *models.RoleBinding) error {
 	_, err := rs.storage.PG.DB.Exec(
-		`INSERT INTO role_bindings (role_id, service_account_id)
-		VALUES (?role_id, ?service_account_id)`, rb,
+		`INSERT INTO role_bindings (role_id, service_account_id, expires_at,
+		managed_by_rule, managed_by_scim) VALUES (?role_id, ?service_account_id,
+		?expires_at, ?managed_by_rule, ?managed_by_scim)`, rb,
 	)
 	return err
 }
 
+// Unbind removes every binding of serviceAccountID to roleID
+func (rs roles) Unbind(roleID, serviceAccountID string) error {
+	_, err := rs.storage.PG.DB.Exec(
+		`DELETE FROM role_bindings WHERE role_id = ? AND service_account_id = ?`,
+		roleID, serviceAccountID,
+	)
+	return err
+}
+
+// UnbindManagedBySCIM removes bindings of serviceAccountID to roleID made
+// through SCIM, keeping those made otherwise
+func (rs roles) UnbindManagedBySCIM(roleID, serviceAccountID string) error {
+	_, err := rs.storage.PG.DB.Exec(
+		`DELETE FROM role_bindings WHERE role_id = ? AND service_account_id = ?
+		AND managed_by_scim = true`, roleID, serviceAccountID,
+	)
+	return err
+}
+
+func (rs roles) Bindings(roleID string) ([]models.RoleBinding, error) {
+	rbs := []models.RoleBinding{}
+	if _, err := rs.storage.PG.DB.Query(
+		&rbs, `SELECT id, role_id, service_account_id, expires_at,
+		managed_by_rule, managed_by_scim FROM role_bindings WHERE role_id = ?`,
+		roleID,
+	); err != nil {
+		return nil, err
+	}
+	return rbs, nil
+}
+
+func (rs roles) BindingsForServiceAccountID(
+	serviceAccountID string,
+) ([]models.RoleBinding, error) {
+	rbs := []models.RoleBinding{}
+	if _, err := rs.storage.PG.DB.Query(
+		&rbs, `SELECT id, role_id, service_account_id, expires_at,
+		managed_by_rule, managed_by_scim FROM role_bindings
+		WHERE service_account_id = ?`, serviceAccountID,
+	); err != nil {
+		return nil, err
+	}
+	return rbs, nil
+}
+
 func (rs roles) WithNamePrefix(
 	prefix string, maxResults int,
 ) ([]models.Role, error) {
@@ -125,6 +189,30 @@ func (rs roles) ListCount() (int64, error) {
 	return count, nil
 }
 
+// ListManagedBySCIM lists roles created through SCIM
+func (rs roles) ListManagedBySCIM(lo *ListOptions) ([]models.Role, error) {
+	var rsSl []models.Role
+	if _, err := rs.storage.PG.DB.Query(
+		&rsSl, `SELECT id, name, is_base_role, managed_by_scim FROM roles
+		WHERE managed_by_scim = true AND is_base_role = false
+		ORDER BY name ASC LIMIT ? OFFSET ?`, lo.Limit(), lo.Offset(),
+	); err != nil {
+		return nil, err
+	}
+	return rsSl, nil
+}
+
+func (rs roles) ListManagedBySCIMCount() (int64, error) {
+	var count int64
+	if _, err := rs.storage.PG.DB.Query(
+		&count, `SELECT count(*) FROM roles
+		WHERE managed_by_scim = true AND is_base_role = false`,
+	); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (rs roles) Search(term string, lo *ListOptions) ([]models.Role, error) {
 	var rsSl []models.Role
 	if _, err := rs.storage.PG.DB.Query(
@@ -153,8 +241,8 @@ func (rs roles) SearchCount(term string) (int64, error) {
 func (rs roles) Get(id string) (*models.Role, error) {
 	r := new(models.Role)
 	if _, err := rs.storage.PG.DB.Query(
-		r, `SELECT id, name, is_base_role, created_at, updated_at
-		FROM roles WHERE id = ?`, id,
+		r, `SELECT id, name, is_base_role, managed_by_scim, created_at,
+		updated_at FROM roles WHERE id = ?`, id,
 	); err != nil {
 		return nil, err
 	}
@@ -164,6 +252,31 @@ func (rs roles) Get(id string) (*models.Role, error) {
 	return r, nil
 }
 
+// ForName lists roles, except base ones, named name regardless of case
+func (rs roles) ForName(name string) ([]models.Role, error) {
+	rsSl := []models.Role{}
+	if _, err := rs.storage.PG.DB.Query(
+		&rsSl, `SELECT id, name, is_base_role, managed_by_scim FROM roles
+		WHERE lower(name) = lower(?) AND is_base_role = false
+		ORDER BY name ASC`, name,
+	); err != nil {
+		return nil, err
+	}
+	return rsSl, nil
+}
+
+// Delete removes roleID, along with its permissions and bindings
+func (rs roles) Delete(roleID string) error {
+	res, err := rs.storage.PG.DB.Exec(`DELETE FROM roles WHERE id = ?`, roleID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.NewEntityNotFoundError(models.Role{}, roleID)
+	}
+	return nil
+}
+
 func (rs roles) DropPermissions(roleID string) error {
 	_, err := rs.storage.PG.DB.Exec(
 		`DELETE FROM permissions WHERE role_id = ?`, roleID,
@@ -178,6 +291,127 @@ func (rs roles) DropBindings(roleID string) error {
 	return err
 }
 
+// BindGroup binds groupID, and so its members, to roleID
+func (rs roles) BindGroup(roleID, groupID string) error {
+	_, err := rs.storage.PG.DB.Exec(
+		`INSERT INTO group_bindings (role_id, group_id) VALUES (?, ?)
+		ON CONFLICT DO NOTHING`, roleID, groupID,
+	)
+	return err
+}
+
+// DropGroupBindings removes every group bound to roleID
+func (rs roles) DropGroupBindings(roleID string) error {
+	_, err := rs.storage.PG.DB.Exec(
+		`DELETE FROM group_bindings WHERE role_id = ?`, roleID,
+	)
+	return err
+}
+
+// GetGroups returns groups bound to roleID
+func (rs roles) GetGroups(roleID string) ([]models.Group, error) {
+	gSl := []models.Group{}
+	if _, err := rs.storage.PG.DB.Query(
+		&gSl, `SELECT g.id, g.name FROM groups g
+		JOIN group_bindings gb ON gb.group_id = g.id
+		WHERE gb.role_id = ? ORDER BY g.name ASC`, roleID,
+	); err != nil {
+		return nil, err
+	}
+	return gSl, nil
+}
+
+// Include makes roleID include includedRoleID, granting its permissions
+func (rs roles) Include(roleID, includedRoleID string) error {
+	_, err := rs.storage.PG.DB.Exec(
+		`INSERT INTO role_inclusions (role_id, included_role_id) VALUES (?, ?)
+		ON CONFLICT DO NOTHING`, roleID, includedRoleID,
+	)
+	return err
+}
+
+// DropInclusions removes every role roleID directly includes
+func (rs roles) DropInclusions(roleID string) error {
+	_, err := rs.storage.PG.DB.Exec(
+		`DELETE FROM role_inclusions WHERE role_id = ?`, roleID,
+	)
+	return err
+}
+
+// LockInclusions keeps other transactions from changing role inclusions
+// until the current one ends, so checking for cycles before including a
+// role holds. It must be called inside a transaction (see All.WithPGTx)
+func (rs roles) LockInclusions() error {
+	_, err := rs.storage.PG.DB.Exec(
+		`LOCK TABLE role_inclusions IN SHARE ROW EXCLUSIVE MODE`,
+	)
+	return err
+}
+
+// Included returns roles directly included by roleID
+func (rs roles) Included(roleID string) ([]models.Role, error) {
+	rsSl := []models.Role{}
+	if _, err := rs.storage.PG.DB.Query(
+		&rsSl, `SELECT r.id, r.name, r.is_base_role FROM roles r
+		JOIN role_inclusions ri ON ri.included_role_id = r.id
+		WHERE ri.role_id = ? ORDER BY r.name ASC`, roleID,
+	); err != nil {
+		return nil, err
+	}
+	return rsSl, nil
+}
+
+// IncludedIDs returns rolesIDs along with the ids of every role they
+// include, directly or not
+func (rs roles) IncludedIDs(rolesIDs []string) ([]string, error) {
+	ids := []string{}
+	if len(rolesIDs) == 0 {
+		return ids, nil
+	}
+	if _, err := rs.storage.PG.DB.Query(
+		&ids, includedRolesSQL(`SELECT unnest(?::uuid[])`),
+		pg.Array(rolesIDs),
+	); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Includes tells if roleID includes otherRoleID, directly or not
+func (rs roles) Includes(roleID, otherRoleID string) (bool, error) {
+	var count int64
+	if _, err := rs.storage.PG.DB.Query(
+		&count, `SELECT count(*) FROM (`+includedRolesSQL(`SELECT ?0::uuid`)+`)
+		included WHERE role_id = ?1 AND role_id != ?0`, roleID, otherRoleID,
+	); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// includedRolesSQL selects, as role_id, the ids of roles selected by
+// rolesSQL along with every role they include, directly or not. UNION stops
+// the recursion at roles already seen, so it ends even if there's a cycle
+func includedRolesSQL(rolesSQL string) string {
+	return fmt.Sprintf(`WITH RECURSIVE included(role_id) AS (
+      %s
+      UNION
+      SELECT ri.included_role_id FROM role_inclusions ri
+      JOIN included i ON ri.role_id = i.role_id
+    ) SELECT role_id FROM included`, rolesSQL)
+}
+
+// includingRolesSQL selects, as role_id, the ids of roles selected by
+// rolesSQL along with every role including them, directly or not
+func includingRolesSQL(rolesSQL string) string {
+	return fmt.Sprintf(`WITH RECURSIVE including(role_id) AS (
+      %s
+      UNION
+      SELECT ri.role_id FROM role_inclusions ri
+      JOIN including i ON ri.included_role_id = i.role_id
+    ) SELECT role_id FROM including`, rolesSQL)
+}
+
 // NewRoles roles ctor
 func NewRoles(s *Storage) Roles {
 	return &roles{&withStorage{storage: s}}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// ServiceAccountKeys repository
+type ServiceAccountKeys interface {
+	Clone() ServiceAccountKeys
+	Create(*models.ServiceAccountKey) error
+	Delete(string, string) error
+	ForServiceAccount(string) ([]models.ServiceAccountKey, error)
+	Get(string) (*models.ServiceAccountKey, error)
+	Touch(string) error
+	setStorage(*Storage)
+}
+
+type serviceAccountKeys struct {
+	*withStorage
+}
+
+func (saks *serviceAccountKeys) Clone() ServiceAccountKeys {
+	return NewServiceAccountKeys(saks.storage.Clone())
+}
+
+func (saks serviceAccountKeys) Create(k *models.ServiceAccountKey) error {
+	_, err := saks.storage.PG.DB.Query(
+		k, `INSERT INTO service_account_keys (id, service_account_id, secret_hash)
+		VALUES (?id, ?service_account_id, ?secret_hash) RETURNING created_at`, k,
+	)
+	return err
+}
+
+// Delete removes key keyID of serviceAccountID
+func (saks serviceAccountKeys) Delete(serviceAccountID, keyID string) error {
+	res, err := saks.storage.PG.DB.Exec(
+		`DELETE FROM service_account_keys
+		WHERE id = ? AND service_account_id = ?`, keyID, serviceAccountID,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.NewEntityNotFoundError(models.ServiceAccountKey{}, keyID)
+	}
+	return nil
+}
+
+// ForServiceAccount lists serviceAccountID keys, oldest first
+func (saks serviceAccountKeys) ForServiceAccount(
+	serviceAccountID string,
+) ([]models.ServiceAccountKey, error) {
+	ks := []models.ServiceAccountKey{}
+	if _, err := saks.storage.PG.DB.Query(
+		&ks, `SELECT id, service_account_id, created_at, last_used_at
+		FROM service_account_keys WHERE service_account_id = ?
+		ORDER BY created_at ASC`, serviceAccountID,
+	); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (saks serviceAccountKeys) Get(keyID string) (*models.ServiceAccountKey, error) {
+	k := new(models.ServiceAccountKey)
+	if _, err := saks.storage.PG.DB.Query(
+		k, `SELECT id, service_account_id, secret_hash, created_at, last_used_at
+		FROM service_account_keys WHERE id = ?`, keyID,
+	); err != nil {
+		return nil, err
+	}
+	if k.ID == "" {
+		return nil, errors.NewEntityNotFoundError(models.ServiceAccountKey{}, keyID)
+	}
+	return k, nil
+}
+
+// Touch sets keyID last_used_at to now
+func (saks serviceAccountKeys) Touch(keyID string) error {
+	_, err := saks.storage.PG.DB.Exec(
+		`UPDATE service_account_keys SET last_used_at = now() WHERE id = ?`, keyID,
+	)
+	return err
+}
+
+// NewServiceAccountKeys serviceAccountKeys ctor
+func NewServiceAccountKeys(s *Storage) ServiceAccountKeys {
+	return &serviceAccountKeys{&withStorage{storage: s}}
+}
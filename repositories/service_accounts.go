@@ -15,15 +15,19 @@ type ServiceAccounts interface {
 	DropBindings(string) error
 	ForEmail(string) (*models.ServiceAccount, error)
 	ForEmails([]string) ([]models.ServiceAccount, error)
-	ForKeyPair(string, string) (*models.ServiceAccount, error)
 	Get(string) (*models.ServiceAccount, error)
+	HasConditionedPermissions(string) (bool, error)
 	HasPermission(string, models.Permission) (bool, error)
 	List(*ListOptions) ([]models.ServiceAccount, error)
 	ListCount() (int64, error)
+	ListWithAuthenticationType(models.AuthenticationType, *ListOptions) ([]models.ServiceAccount, error)
+	ListWithAuthenticationTypeCount(models.AuthenticationType) (int64, error)
 	ListWithPermission(*ListOptions, models.Permission) ([]models.ServiceAccount, error)
 	ListWithPermissionCount(models.Permission) (int64, error)
 	Search(string, *ListOptions) ([]models.ServiceAccount, error)
 	SearchCount(string) (int64, error)
+	RevokeTokens(string) error
+	SetStatus(string, models.ServiceAccountStatus) error
 	Update(*models.ServiceAccount) error
 	setStorage(*Storage)
 }
@@ -40,7 +44,8 @@ func (sas serviceAccounts) Get(id string) (*models.ServiceAccount, error) {
 	sa := new(models.ServiceAccount)
 	if _, err := sas.storage.PG.DB.Query(
 		sa,
-		`SELECT id, name, key_id, key_secret, email, base_role_id, picture
+		`SELECT id, name, email, base_role_id, picture, authentication_type, status,
+		tokens_revoked_at
 		FROM service_accounts
 		WHERE id = ?`,
 		id,
@@ -50,9 +55,6 @@ func (sas serviceAccounts) Get(id string) (*models.ServiceAccount, error) {
 	if sa.ID == "" {
 		return nil, errors.NewEntityNotFoundError(models.ServiceAccount{}, id)
 	}
-	if sa.KeyID != "" {
-		sa.AuthenticationType = models.AuthenticationTypes.KeyPair
-	}
 	return sa, nil
 }
 
@@ -70,19 +72,97 @@ func (sas serviceAccounts) DropBindings(saID string) error {
 	return err
 }
 
+// permissionQueryArgs are the positional arguments expected by
+// allowedPermissionSQL and deniedPermissionSQL, in this order:
+// ?0 service, ?1 action, ?2 ownership level, ?3 resource hierarchy,
+// ?4 resource hierarchy matches
+func permissionQueryArgs(permission models.Permission) []interface{} {
+	return []interface{}{
+		permission.Service, permission.Action.String(),
+		permission.OwnershipLevel.String(),
+		permission.ResourceHierarchy.String(),
+		pg.Array(permission.ResourceHierarchy.PermissionMatches()),
+	}
+}
+
+// allowedPermissionSQL filters permissions p granting permissionQueryArgs.
+// Conditions can't be evaluated in SQL, so conditioned permissions never
+// grant here, while conditioned denies always deny, see deniedPermissionSQL.
+// Resource hierarchies are matched by index, only those with mid-path
+// wildcards or globs go through rh_contains
+var allowedPermissionSQL = `NOT p.deny AND p.condition = ''
+  AND (p.service = ?0 OR p.service = '*') AND (p.action = ?1 OR p.action = '*')
+  AND CASE WHEN ?2 = 'RO' THEN p.ownership_level = 'RO' ELSE true END
+  AND (p.resource_hierarchy = ANY (?4) OR (rh_has_pattern(p.resource_hierarchy)
+    AND rh_contains(p.resource_hierarchy, ?3)))
+  AND ` + notExpiredSQL("p")
+
+// boundRolesSQL selects the ids of roles currently bound to saIDExpr,
+// directly or through groups, along with the roles they include
+func boundRolesSQL(saIDExpr string) string {
+	return includedRolesSQL(fmt.Sprintf(`SELECT role_id FROM (
+        SELECT role_id FROM role_bindings WHERE service_account_id = %s AND %s
+        UNION
+        SELECT role_id FROM group_bindings WHERE group_id IN (%s)
+      ) bound`,
+		saIDExpr, notExpiredSQL("role_bindings"), memberGroupsSQL(saIDExpr),
+	))
+}
+
+// roleSubjectsSQL selects, as service_account_id, the ids of service
+// accounts currently bound to roles selected by rolesSQL, directly or
+// through groups
+func roleSubjectsSQL(rolesSQL string) string {
+	return fmt.Sprintf(`SELECT service_account_id FROM role_bindings
+    WHERE role_id IN (%s) AND %s
+    UNION
+    %s`, rolesSQL, notExpiredSQL("role_bindings"), groupMembersSQL(fmt.Sprintf(
+		`SELECT group_id FROM group_bindings WHERE role_id IN (%s)`, rolesSQL,
+	)))
+}
+
+// deniedPermissionSQL checks if there's a deny permission bound to
+// saIDExpr overlapping permissionQueryArgs, whatever its condition
+func deniedPermissionSQL(saIDExpr string) string {
+	return fmt.Sprintf(`EXISTS (
+    SELECT 1 FROM permissions dp
+    WHERE dp.deny
+    AND dp.role_id = ANY (%s)
+    AND %s
+    AND (dp.service = ?0 OR dp.service = '*' OR ?0 = '*')
+    AND (dp.action = ?1 OR dp.action = '*' OR ?1 = '*')
+    AND CASE WHEN ?2 = 'RL' THEN dp.ownership_level = 'RL' ELSE true END
+    AND rh_overlaps(dp.resource_hierarchy, ?3)
+  )`, boundRolesSQL(saIDExpr), notExpiredSQL("dp"))
+}
+
 func (sas serviceAccounts) HasPermission(
 	serviceAccountID string, permission models.Permission,
 ) (bool, error) {
 	var count int64
+	args := append(permissionQueryArgs(permission), serviceAccountID)
 	if _, err := sas.storage.PG.DB.Query(
 		&count,
-		`SELECT count(*) FROM permissions
-    WHERE (service = ? OR service = '*') AND (action = ? OR action = '*')
-    AND CASE WHEN ? = 'RO' THEN ownership_level = 'RO' ELSE true END
-    AND role_id = ANY (SELECT role_id FROM role_bindings WHERE service_account_id = ?)
-    AND resource_hierarchy = ANY (?)
-    `, permission.Service, permission.Action.String(), permission.OwnershipLevel.String(),
-		serviceAccountID, pg.Array(permission.ResourceHierarchy.PermissionMatches()),
+		`SELECT count(*) FROM permissions p
+    WHERE `+allowedPermissionSQL+`
+    AND p.role_id = ANY (`+boundRolesSQL("?5")+`)
+    AND NOT `+deniedPermissionSQL("?5"), args...,
+	); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// HasConditionedPermissions tells if serviceAccountID has any permission
+// with a condition, which HasPermission can't evaluate
+func (sas serviceAccounts) HasConditionedPermissions(
+	serviceAccountID string,
+) (bool, error) {
+	var count int64
+	if _, err := sas.storage.PG.DB.Query(
+		&count, `SELECT count(*) FROM permissions p
+    WHERE p.condition != '' AND p.role_id = ANY (`+boundRolesSQL("?0")+`)
+    AND `+notExpiredSQL("p"), serviceAccountID,
 	); err != nil {
 		return false, err
 	}
@@ -95,18 +175,13 @@ func (sas serviceAccounts) List(
 	var saSl []models.ServiceAccount
 	if _, err := sas.storage.PG.DB.Query(
 		&saSl,
-		`SELECT id, name, email, picture, base_role_id FROM service_accounts
-		ORDER BY name ASC LIMIT ? OFFSET ?`, lo.Limit(), lo.Offset(),
+		`SELECT id, name, email, picture, base_role_id, authentication_type, status
+		FROM service_accounts WHERE status != ?
+		ORDER BY name ASC LIMIT ? OFFSET ?`,
+		models.ServiceAccountStatuses.Deleted, lo.Limit(), lo.Offset(),
 	); err != nil {
 		return nil, err
 	}
-	for i := range saSl {
-		authType := models.AuthenticationTypes.OAuth2
-		if saSl[i].KeyID != "" {
-			authType = models.AuthenticationTypes.KeyPair
-		}
-		saSl[i].AuthenticationType = authType
-	}
 	return saSl, nil
 }
 
@@ -114,30 +189,68 @@ func (sas serviceAccounts) ListCount() (int64, error) {
 	var count int64
 	if _, err := sas.storage.PG.DB.Query(
 		&count,
-		`SELECT count(*) FROM service_accounts`,
+		`SELECT count(*) FROM service_accounts WHERE status != ?`,
+		models.ServiceAccountStatuses.Deleted,
 	); err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
+// ListWithAuthenticationType lists service accounts authenticating with
+// authType, except deleted ones
+func (sas serviceAccounts) ListWithAuthenticationType(
+	authType models.AuthenticationType, lo *ListOptions,
+) ([]models.ServiceAccount, error) {
+	saSl := []models.ServiceAccount{}
+	if _, err := sas.storage.PG.DB.Query(
+		&saSl,
+		`SELECT id, name, email, picture, base_role_id, authentication_type, status
+		FROM service_accounts WHERE authentication_type = ?0 AND status != ?1
+		ORDER BY name ASC LIMIT ?2 OFFSET ?3`,
+		authType, models.ServiceAccountStatuses.Deleted, lo.Limit(), lo.Offset(),
+	); err != nil {
+		return nil, err
+	}
+	return saSl, nil
+}
+
+func (sas serviceAccounts) ListWithAuthenticationTypeCount(
+	authType models.AuthenticationType,
+) (int64, error) {
+	var count int64
+	if _, err := sas.storage.PG.DB.Query(
+		&count,
+		`SELECT count(*) FROM service_accounts
+		WHERE authentication_type = ? AND status != ?`,
+		authType, models.ServiceAccountStatuses.Deleted,
+	); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListWithPermission lists active service accounts having permission, as
+// only those may act on it
 func (sas serviceAccounts) ListWithPermission(
 	lo *ListOptions, permission models.Permission,
 ) ([]models.ServiceAccount, error) {
 	var saSl []models.ServiceAccount
+	args := append(
+		permissionQueryArgs(permission),
+		lo.Limit(), lo.Offset(), models.ServiceAccountStatuses.Active,
+	)
 	if _, err := sas.storage.PG.DB.Query(
 		&saSl,
-		`SELECT DISTINCT sas.id, sas.name, sas.email, sas.picture, sas.base_role_id FROM service_accounts sas
-    INNER JOIN role_bindings rb ON rb.service_account_id = sas.id
-    WHERE rb.role_id = ANY (
-      SELECT DISTINCT(role_id) FROM permissions
-      WHERE (service = ? OR service = '*') AND (action = ? OR action = '*')
-      AND CASE WHEN ? = 'RO' THEN ownership_level = 'RO' ELSE true END
-      AND resource_hierarchy = ANY (?)
-    )
-    ORDER BY name ASC LIMIT ? OFFSET ?
-    `, permission.Service, permission.Action.String(), permission.OwnershipLevel.String(),
-		pg.Array(permission.ResourceHierarchy.PermissionMatches()), lo.Limit(), lo.Offset(),
+		`SELECT sas.id, sas.name, sas.email, sas.picture, sas.base_role_id,
+    sas.authentication_type, sas.status FROM service_accounts sas
+    WHERE sas.id IN (`+roleSubjectsSQL(includingRolesSQL(
+			`SELECT DISTINCT(p.role_id) FROM permissions p WHERE `+allowedPermissionSQL,
+		))+`)
+    AND NOT `+deniedPermissionSQL("sas.id")+`
+    AND sas.status = ?7
+    ORDER BY name ASC LIMIT ?5 OFFSET ?6
+    `, args...,
 	); err != nil {
 		return nil, err
 	}
@@ -148,16 +261,15 @@ func (sas serviceAccounts) ListWithPermissionCount(permission models.Permission)
 	var count int64
 	if _, err := sas.storage.PG.DB.Query(
 		&count,
-		`SELECT count(distinct sas.id) FROM service_accounts sas
-    INNER JOIN role_bindings rb ON rb.service_account_id = sas.id
-    WHERE rb.role_id = ANY (
-      SELECT DISTINCT(role_id) FROM permissions
-      WHERE (service = ? OR service = '*') AND (action = ? OR action = '*')
-      AND CASE WHEN ? = 'RO' THEN ownership_level = 'RO' ELSE true END
-      AND resource_hierarchy = ANY (?)
-    )
-    `, permission.Service, permission.Action.String(), permission.OwnershipLevel.String(),
-		pg.Array(permission.ResourceHierarchy.PermissionMatches()),
+		`SELECT count(*) FROM service_accounts sas
+    WHERE sas.id IN (`+roleSubjectsSQL(includingRolesSQL(
+			`SELECT DISTINCT(p.role_id) FROM permissions p WHERE `+allowedPermissionSQL,
+		))+`)
+    AND NOT `+deniedPermissionSQL("sas.id")+`
+    AND sas.status = ?5
+    `, append(
+			permissionQueryArgs(permission), models.ServiceAccountStatuses.Active,
+		)...,
 	); err != nil {
 		return 0, err
 	}
@@ -170,20 +282,14 @@ func (sas serviceAccounts) Search(
 	saSl := []models.ServiceAccount{}
 	if _, err := sas.storage.PG.DB.Query(
 		&saSl,
-		`SELECT id, name, email, picture, base_role_id FROM service_accounts
-		WHERE name ILIKE ?0 OR email ILIKE ?0
-		ORDER BY name ASC LIMIT ?1 OFFSET ?2`,
+		`SELECT id, name, email, picture, base_role_id, authentication_type, status
+		FROM service_accounts WHERE (name ILIKE ?0 OR email ILIKE ?0)
+		AND status != ?3 ORDER BY name ASC LIMIT ?1 OFFSET ?2`,
 		fmt.Sprintf("%%%s%%", term), lo.Limit(), lo.Offset(),
+		models.ServiceAccountStatuses.Deleted,
 	); err != nil {
 		return nil, err
 	}
-	for i := range saSl {
-		authType := models.AuthenticationTypes.OAuth2
-		if saSl[i].KeyID != "" {
-			authType = models.AuthenticationTypes.KeyPair
-		}
-		saSl[i].AuthenticationType = authType
-	}
 	return saSl, nil
 }
 
@@ -192,7 +298,8 @@ func (sas serviceAccounts) SearchCount(term string) (int64, error) {
 	if _, err := sas.storage.PG.DB.Query(
 		&count,
 		`SELECT count(*) FROM service_accounts
-		WHERE name ILIKE ?0 OR email ILIKE ?0`, fmt.Sprintf("%%%s%%", term),
+		WHERE (name ILIKE ?0 OR email ILIKE ?0) AND status != ?1`,
+		fmt.Sprintf("%%%s%%", term), models.ServiceAccountStatuses.Deleted,
 	); err != nil {
 		return 0, err
 	}
@@ -205,7 +312,7 @@ func (sas serviceAccounts) ForEmail(
 ) (*models.ServiceAccount, error) {
 	sa := new(models.ServiceAccount)
 	if _, err := sas.storage.PG.DB.Query(
-		sa, `SELECT id, name, key_id, key_secret, email, base_role_id, picture
+		sa, `SELECT id, name, email, base_role_id, picture, authentication_type, status
 		FROM service_accounts WHERE email = ?`, email,
 	); err != nil {
 		return nil, err
@@ -222,7 +329,7 @@ func (sas serviceAccounts) ForEmails(
 ) ([]models.ServiceAccount, error) {
 	saSl := []models.ServiceAccount{}
 	if _, err := sas.storage.PG.DB.Query(
-		&saSl, `SELECT id, name, key_id, key_secret, email, base_role_id, picture
+		&saSl, `SELECT id, name, email, base_role_id, picture, authentication_type, status
 		FROM service_accounts WHERE email = ANY(?)`, pg.Array(emails),
 	); err != nil {
 		return nil, err
@@ -230,29 +337,11 @@ func (sas serviceAccounts) ForEmails(
 	return saSl, nil
 }
 
-// ForKeyPair retrieves Service Account corresponding
-func (sas serviceAccounts) ForKeyPair(
-	keyID, keySecret string,
-) (*models.ServiceAccount, error) {
-	sa := new(models.ServiceAccount)
-	if _, err := sas.storage.PG.DB.Query(
-		sa, `SELECT id, name, key_id, key_secret, email, base_role_id
-		FROM service_accounts WHERE key_id = ? AND key_secret = ?`,
-		keyID, keySecret,
-	); err != nil {
-		return nil, err
-	}
-	if sa.ID == "" {
-		return nil, errors.NewEntityNotFoundError(models.ServiceAccount{}, keyID)
-	}
-	return sa, nil
-}
-
 func (sas serviceAccounts) Create(sa *models.ServiceAccount) error {
 	_, err := sas.storage.PG.DB.Query(
-		sa, `INSERT INTO service_accounts (id, name, email, key_id, key_secret,
-		base_role_id) VALUES (?id, ?name, ?email, ?key_id, ?key_secret,
-		?base_role_id) RETURNING id`, sa,
+		sa, `INSERT INTO service_accounts (id, name, email, authentication_type,
+		base_role_id) VALUES (?id, ?name, ?email, ?authentication_type,
+		?base_role_id) RETURNING id, status`, sa,
 	)
 	return err
 }
@@ -260,12 +349,33 @@ func (sas serviceAccounts) Create(sa *models.ServiceAccount) error {
 func (sas serviceAccounts) Update(sa *models.ServiceAccount) error {
 	_, err := sas.storage.PG.DB.Exec(
 		`UPDATE service_accounts SET name = ?name, email = ?email,
-		key_id = ?key_id, key_secret = ?key_secret, base_role_id = ?base_role_id,
+		base_role_id = ?base_role_id,
 		picture = ?picture, updated_at = now() WHERE id = ?id`, sa,
 	)
 	return err
 }
 
+// SetStatus changes id status
+func (sas serviceAccounts) SetStatus(
+	id string, status models.ServiceAccountStatus,
+) error {
+	_, err := sas.storage.PG.DB.Exec(
+		`UPDATE service_accounts SET status = ?, updated_at = now() WHERE id = ?`,
+		status, id,
+	)
+	return err
+}
+
+// RevokeTokens makes Will.IAM access tokens of id issued until now invalid
+func (sas serviceAccounts) RevokeTokens(id string) error {
+	_, err := sas.storage.PG.DB.Exec(
+		`UPDATE service_accounts SET tokens_revoked_at = now(),
+		updated_at = now() WHERE id = ?`,
+		id,
+	)
+	return err
+}
+
 // NewServiceAccounts serviceAccounts ctor
 func NewServiceAccounts(s *Storage) ServiceAccounts {
 	return &serviceAccounts{&withStorage{storage: s}}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+// sessionLastUsedResolution is how stale a session last_used_at may be, so
+// that not every request authenticated in it writes to Postgres
+const sessionLastUsedResolution = "1 min"
+
+// Sessions repository
+type Sessions interface {
+	Clone() Sessions
+	Create(*models.Session) error
+	Delete(string, string) error
+	DeleteForEmail(string) error
+	ForEmail(string) ([]models.Session, error)
+	TouchForAccessToken(string) error
+	setStorage(*Storage)
+}
+
+type sessions struct {
+	*withStorage
+}
+
+func (ss *sessions) Clone() Sessions {
+	return NewSessions(ss.storage.Clone())
+}
+
+func (ss sessions) Create(s *models.Session) error {
+	_, err := ss.storage.PG.DB.Query(
+		s, `INSERT INTO sessions (email, provider, user_agent, ip)
+		VALUES (?email, ?provider, ?user_agent, ?ip)
+		RETURNING id, created_at, last_used_at`, s,
+	)
+	return err
+}
+
+// Delete removes session sessionID of email, along with its tokens
+func (ss sessions) Delete(email, sessionID string) error {
+	res, err := ss.storage.PG.DB.Exec(
+		`DELETE FROM sessions WHERE id = ? AND email = ?`, sessionID, email,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.NewEntityNotFoundError(models.Session{}, sessionID)
+	}
+	return nil
+}
+
+// DeleteForEmail removes every session of email, along with their tokens
+func (ss sessions) DeleteForEmail(email string) error {
+	_, err := ss.storage.PG.DB.Exec(`DELETE FROM sessions WHERE email = ?`, email)
+	return err
+}
+
+// ForEmail lists email sessions having a token still in use, most recently
+// used first
+func (ss sessions) ForEmail(email string) ([]models.Session, error) {
+	s := []models.Session{}
+	if _, err := ss.storage.PG.DB.Query(
+		&s, `SELECT s.id, s.email, s.provider, s.user_agent, s.ip, s.created_at,
+		s.last_used_at FROM sessions s WHERE s.email = ? AND EXISTS (
+			SELECT 1 FROM tokens t WHERE t.session_id = s.id AND t.expired_at IS NULL
+			AND (t.refresh_token != '' OR t.expiry > now())
+		) ORDER BY s.last_used_at DESC`, email,
+	); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TouchForAccessToken sets last_used_at of accessToken session to now,
+// unless it was set less than sessionLastUsedResolution ago
+func (ss sessions) TouchForAccessToken(accessToken string) error {
+	_, err := ss.storage.PG.DB.Exec(
+		`UPDATE sessions SET last_used_at = now()
+		WHERE id = (SELECT session_id FROM tokens WHERE access_token = ?)
+		AND last_used_at < now() - INTERVAL '`+sessionLastUsedResolution+`'`,
+		accessToken,
+	)
+	return err
+}
+
+// NewSessions sessions ctor
+func NewSessions(s *Storage) Sessions {
+	return &sessions{&withStorage{storage: s}}
+}
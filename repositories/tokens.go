@@ -5,10 +5,18 @@ import (
 	"github.com/topfreegames/Will.IAM/models"
 )
 
+// tokenGracePeriod is how long a refreshed token is still accepted, so
+// requests sent concurrently with the one that refreshed it don't fail
+const tokenGracePeriod = "60 sec"
+
 // Tokens contract
 type Tokens interface {
 	Get(string) (*models.Token, error)
+	GetForUpdate(string) (*models.Token, error)
+	Revoke(string) error
+	RevokeForEmail(string) error
 	Save(*models.Token) error
+	SetSessionID(string, string) error
 	Clone() Tokens
 	setStorage(*Storage)
 	FindByEmail(string) ([]models.Token, error)
@@ -26,7 +34,7 @@ func (ts tokens) Get(accessToken string) (*models.Token, error) {
 	t := new(models.Token)
 	if _, err := ts.storage.PG.DB.Query(
 		t, `SELECT * FROM tokens WHERE access_token = ?0 AND
-		(expired_at IS NULL OR expired_at > now() - INTERVAL '60 sec')`,
+		(expired_at IS NULL OR expired_at > now() - INTERVAL '`+tokenGracePeriod+`')`,
 		accessToken,
 	); err != nil {
 		return nil, err
@@ -37,15 +45,72 @@ func (ts tokens) Get(accessToken string) (*models.Token, error) {
 	return t, nil
 }
 
+// GetForUpdate locks accessToken row until the transaction ends, it must be
+// called inside WithPGTx. Expired tokens are returned too
+func (ts tokens) GetForUpdate(accessToken string) (*models.Token, error) {
+	t := new(models.Token)
+	if _, err := ts.storage.PG.DB.Query(
+		t, `SELECT * FROM tokens WHERE access_token = ? FOR UPDATE`, accessToken,
+	); err != nil {
+		return nil, err
+	}
+	if t.AccessToken == "" {
+		return nil, errors.NewEntityNotFoundError(models.Token{}, accessToken)
+	}
+	return t, nil
+}
+
+// Revoke expires accessToken and every token sharing its refresh token past
+// their grace period, so none of them is accepted anymore
+func (ts tokens) Revoke(accessToken string) error {
+	_, err := ts.storage.PG.DB.Exec(
+		`UPDATE tokens SET expired_at = now() - INTERVAL '`+tokenGracePeriod+`',
+		updated_at = now()
+		WHERE (access_token = ?0 OR (refresh_token != '' AND refresh_token = (
+			SELECT refresh_token FROM tokens WHERE access_token = ?0
+		)))
+		AND (expired_at IS NULL OR expired_at > now() - INTERVAL '`+tokenGracePeriod+`')`,
+		accessToken,
+	)
+	return err
+}
+
+// RevokeForEmail expires every token of email past their grace period
+func (ts tokens) RevokeForEmail(email string) error {
+	_, err := ts.storage.PG.DB.Exec(
+		`UPDATE tokens SET expired_at = now() - INTERVAL '`+tokenGracePeriod+`',
+		updated_at = now()
+		WHERE email = ?
+		AND (expired_at IS NULL OR expired_at > now() - INTERVAL '`+tokenGracePeriod+`')`,
+		email,
+	)
+	return err
+}
+
 func (ts tokens) Save(token *models.Token) error {
 	_, err := ts.storage.PG.DB.Exec(`INSERT INTO tokens (access_token,
-	refresh_token, expired_at, token_type, expiry, email, updated_at)
-	VALUES (?access_token, ?refresh_token, ?expired_at, ?token_type,
-	?expiry, ?email, now()) ON CONFLICT (access_token) DO UPDATE SET
+	refresh_token, expired_at, token_type, expiry, email, provider,
+	session_id, updated_at) VALUES (?access_token, ?refresh_token, ?expired_at,
+	?token_type, ?expiry, ?email, ?provider, ?session_id, now()) ON CONFLICT (access_token) DO UPDATE SET
 	expired_at = ?expired_at, updated_at = now()`, token)
 	return err
 }
 
+// SetSessionID makes accessToken belong to sessionID
+func (ts tokens) SetSessionID(accessToken, sessionID string) error {
+	res, err := ts.storage.PG.DB.Exec(
+		`UPDATE tokens SET session_id = ?, updated_at = now()
+		WHERE access_token = ?`, sessionID, accessToken,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.NewEntityNotFoundError(models.Token{}, accessToken)
+	}
+	return nil
+}
+
 func (ts tokens) FindByEmail(email string) ([]models.Token, error) {
 	tokens := []models.Token{}
 	if _, err := ts.storage.PG.DB.Query(
@@ -0,0 +1,742 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: authorization.proto
+
+package rpc
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type AuthenticateRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthenticateRequest) Reset()         { *m = AuthenticateRequest{} }
+func (m *AuthenticateRequest) String() string { return proto.CompactTextString(m) }
+func (*AuthenticateRequest) ProtoMessage()    {}
+func (*AuthenticateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{0}
+}
+
+func (m *AuthenticateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthenticateRequest.Unmarshal(m, b)
+}
+func (m *AuthenticateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthenticateRequest.Marshal(b, m, deterministic)
+}
+func (m *AuthenticateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthenticateRequest.Merge(m, src)
+}
+func (m *AuthenticateRequest) XXX_Size() int {
+	return xxx_messageInfo_AuthenticateRequest.Size(m)
+}
+func (m *AuthenticateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthenticateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthenticateRequest proto.InternalMessageInfo
+
+type AuthenticateResponse struct {
+	ServiceAccountId     string   `protobuf:"bytes,1,opt,name=service_account_id,json=serviceAccountId,proto3" json:"service_account_id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email                string   `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	AccessToken          string   `protobuf:"bytes,4,opt,name=access_token,json=accessToken,proto3" json:"access_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthenticateResponse) Reset()         { *m = AuthenticateResponse{} }
+func (m *AuthenticateResponse) String() string { return proto.CompactTextString(m) }
+func (*AuthenticateResponse) ProtoMessage()    {}
+func (*AuthenticateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{1}
+}
+
+func (m *AuthenticateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthenticateResponse.Unmarshal(m, b)
+}
+func (m *AuthenticateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthenticateResponse.Marshal(b, m, deterministic)
+}
+func (m *AuthenticateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthenticateResponse.Merge(m, src)
+}
+func (m *AuthenticateResponse) XXX_Size() int {
+	return xxx_messageInfo_AuthenticateResponse.Size(m)
+}
+func (m *AuthenticateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthenticateResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthenticateResponse proto.InternalMessageInfo
+
+func (m *AuthenticateResponse) GetServiceAccountId() string {
+	if m != nil {
+		return m.ServiceAccountId
+	}
+	return ""
+}
+
+func (m *AuthenticateResponse) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AuthenticateResponse) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *AuthenticateResponse) GetAccessToken() string {
+	if m != nil {
+		return m.AccessToken
+	}
+	return ""
+}
+
+type HasPermissionRequest struct {
+	Permission string `protobuf:"bytes,1,opt,name=permission,proto3" json:"permission,omitempty"`
+	// ip is checked as request.ip by permissions conditions instead of the
+	// caller address. Only accepted from conditions.trustedCallers.
+	Ip string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	// attributes are checked as context.<attribute> by permissions
+	// conditions. Only accepted from conditions.trustedCallers.
+	Attributes           map[string]string `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *HasPermissionRequest) Reset()         { *m = HasPermissionRequest{} }
+func (m *HasPermissionRequest) String() string { return proto.CompactTextString(m) }
+func (*HasPermissionRequest) ProtoMessage()    {}
+func (*HasPermissionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{2}
+}
+
+func (m *HasPermissionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HasPermissionRequest.Unmarshal(m, b)
+}
+func (m *HasPermissionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HasPermissionRequest.Marshal(b, m, deterministic)
+}
+func (m *HasPermissionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HasPermissionRequest.Merge(m, src)
+}
+func (m *HasPermissionRequest) XXX_Size() int {
+	return xxx_messageInfo_HasPermissionRequest.Size(m)
+}
+func (m *HasPermissionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HasPermissionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HasPermissionRequest proto.InternalMessageInfo
+
+func (m *HasPermissionRequest) GetPermission() string {
+	if m != nil {
+		return m.Permission
+	}
+	return ""
+}
+
+func (m *HasPermissionRequest) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *HasPermissionRequest) GetAttributes() map[string]string {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+type HasPermissionResponse struct {
+	Has                  bool     `protobuf:"varint,1,opt,name=has,proto3" json:"has,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HasPermissionResponse) Reset()         { *m = HasPermissionResponse{} }
+func (m *HasPermissionResponse) String() string { return proto.CompactTextString(m) }
+func (*HasPermissionResponse) ProtoMessage()    {}
+func (*HasPermissionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{3}
+}
+
+func (m *HasPermissionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HasPermissionResponse.Unmarshal(m, b)
+}
+func (m *HasPermissionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HasPermissionResponse.Marshal(b, m, deterministic)
+}
+func (m *HasPermissionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HasPermissionResponse.Merge(m, src)
+}
+func (m *HasPermissionResponse) XXX_Size() int {
+	return xxx_messageInfo_HasPermissionResponse.Size(m)
+}
+func (m *HasPermissionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HasPermissionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HasPermissionResponse proto.InternalMessageInfo
+
+func (m *HasPermissionResponse) GetHas() bool {
+	if m != nil {
+		return m.Has
+	}
+	return false
+}
+
+type HasPermissionsRequest struct {
+	Permissions []string `protobuf:"bytes,1,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	// ip and attributes are as in HasPermissionRequest.
+	Ip                   string            `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Attributes           map[string]string `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *HasPermissionsRequest) Reset()         { *m = HasPermissionsRequest{} }
+func (m *HasPermissionsRequest) String() string { return proto.CompactTextString(m) }
+func (*HasPermissionsRequest) ProtoMessage()    {}
+func (*HasPermissionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{4}
+}
+
+func (m *HasPermissionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HasPermissionsRequest.Unmarshal(m, b)
+}
+func (m *HasPermissionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HasPermissionsRequest.Marshal(b, m, deterministic)
+}
+func (m *HasPermissionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HasPermissionsRequest.Merge(m, src)
+}
+func (m *HasPermissionsRequest) XXX_Size() int {
+	return xxx_messageInfo_HasPermissionsRequest.Size(m)
+}
+func (m *HasPermissionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HasPermissionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HasPermissionsRequest proto.InternalMessageInfo
+
+func (m *HasPermissionsRequest) GetPermissions() []string {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
+func (m *HasPermissionsRequest) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *HasPermissionsRequest) GetAttributes() map[string]string {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+type HasPermissionsResponse struct {
+	Has                  []bool   `protobuf:"varint,1,rep,packed,name=has,proto3" json:"has,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HasPermissionsResponse) Reset()         { *m = HasPermissionsResponse{} }
+func (m *HasPermissionsResponse) String() string { return proto.CompactTextString(m) }
+func (*HasPermissionsResponse) ProtoMessage()    {}
+func (*HasPermissionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{5}
+}
+
+func (m *HasPermissionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HasPermissionsResponse.Unmarshal(m, b)
+}
+func (m *HasPermissionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HasPermissionsResponse.Marshal(b, m, deterministic)
+}
+func (m *HasPermissionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HasPermissionsResponse.Merge(m, src)
+}
+func (m *HasPermissionsResponse) XXX_Size() int {
+	return xxx_messageInfo_HasPermissionsResponse.Size(m)
+}
+func (m *HasPermissionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HasPermissionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HasPermissionsResponse proto.InternalMessageInfo
+
+func (m *HasPermissionsResponse) GetHas() []bool {
+	if m != nil {
+		return m.Has
+	}
+	return nil
+}
+
+type ListServiceAccountsWithPermissionRequest struct {
+	Permission           string   `protobuf:"bytes,1,opt,name=permission,proto3" json:"permission,omitempty"`
+	Page                 int32    `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize             int32    `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListServiceAccountsWithPermissionRequest) Reset() {
+	*m = ListServiceAccountsWithPermissionRequest{}
+}
+func (m *ListServiceAccountsWithPermissionRequest) String() string { return proto.CompactTextString(m) }
+func (*ListServiceAccountsWithPermissionRequest) ProtoMessage()    {}
+func (*ListServiceAccountsWithPermissionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{6}
+}
+
+func (m *ListServiceAccountsWithPermissionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListServiceAccountsWithPermissionRequest.Unmarshal(m, b)
+}
+func (m *ListServiceAccountsWithPermissionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListServiceAccountsWithPermissionRequest.Marshal(b, m, deterministic)
+}
+func (m *ListServiceAccountsWithPermissionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListServiceAccountsWithPermissionRequest.Merge(m, src)
+}
+func (m *ListServiceAccountsWithPermissionRequest) XXX_Size() int {
+	return xxx_messageInfo_ListServiceAccountsWithPermissionRequest.Size(m)
+}
+func (m *ListServiceAccountsWithPermissionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListServiceAccountsWithPermissionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListServiceAccountsWithPermissionRequest proto.InternalMessageInfo
+
+func (m *ListServiceAccountsWithPermissionRequest) GetPermission() string {
+	if m != nil {
+		return m.Permission
+	}
+	return ""
+}
+
+func (m *ListServiceAccountsWithPermissionRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *ListServiceAccountsWithPermissionRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+type ServiceAccount struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email                string   `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Picture              string   `protobuf:"bytes,4,opt,name=picture,proto3" json:"picture,omitempty"`
+	BaseRoleId           string   `protobuf:"bytes,5,opt,name=base_role_id,json=baseRoleId,proto3" json:"base_role_id,omitempty"`
+	AuthenticationType   string   `protobuf:"bytes,6,opt,name=authentication_type,json=authenticationType,proto3" json:"authentication_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServiceAccount) Reset()         { *m = ServiceAccount{} }
+func (m *ServiceAccount) String() string { return proto.CompactTextString(m) }
+func (*ServiceAccount) ProtoMessage()    {}
+func (*ServiceAccount) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{7}
+}
+
+func (m *ServiceAccount) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceAccount.Unmarshal(m, b)
+}
+func (m *ServiceAccount) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceAccount.Marshal(b, m, deterministic)
+}
+func (m *ServiceAccount) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceAccount.Merge(m, src)
+}
+func (m *ServiceAccount) XXX_Size() int {
+	return xxx_messageInfo_ServiceAccount.Size(m)
+}
+func (m *ServiceAccount) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceAccount.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceAccount proto.InternalMessageInfo
+
+func (m *ServiceAccount) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ServiceAccount) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ServiceAccount) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *ServiceAccount) GetPicture() string {
+	if m != nil {
+		return m.Picture
+	}
+	return ""
+}
+
+func (m *ServiceAccount) GetBaseRoleId() string {
+	if m != nil {
+		return m.BaseRoleId
+	}
+	return ""
+}
+
+func (m *ServiceAccount) GetAuthenticationType() string {
+	if m != nil {
+		return m.AuthenticationType
+	}
+	return ""
+}
+
+type ListServiceAccountsWithPermissionResponse struct {
+	Count                int64             `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	ServiceAccounts      []*ServiceAccount `protobuf:"bytes,2,rep,name=service_accounts,json=serviceAccounts,proto3" json:"service_accounts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ListServiceAccountsWithPermissionResponse) Reset() {
+	*m = ListServiceAccountsWithPermissionResponse{}
+}
+func (m *ListServiceAccountsWithPermissionResponse) String() string {
+	return proto.CompactTextString(m)
+}
+func (*ListServiceAccountsWithPermissionResponse) ProtoMessage() {}
+func (*ListServiceAccountsWithPermissionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1dbbe58d1e51a797, []int{8}
+}
+
+func (m *ListServiceAccountsWithPermissionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListServiceAccountsWithPermissionResponse.Unmarshal(m, b)
+}
+func (m *ListServiceAccountsWithPermissionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListServiceAccountsWithPermissionResponse.Marshal(b, m, deterministic)
+}
+func (m *ListServiceAccountsWithPermissionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListServiceAccountsWithPermissionResponse.Merge(m, src)
+}
+func (m *ListServiceAccountsWithPermissionResponse) XXX_Size() int {
+	return xxx_messageInfo_ListServiceAccountsWithPermissionResponse.Size(m)
+}
+func (m *ListServiceAccountsWithPermissionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListServiceAccountsWithPermissionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListServiceAccountsWithPermissionResponse proto.InternalMessageInfo
+
+func (m *ListServiceAccountsWithPermissionResponse) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *ListServiceAccountsWithPermissionResponse) GetServiceAccounts() []*ServiceAccount {
+	if m != nil {
+		return m.ServiceAccounts
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*AuthenticateRequest)(nil), "williamrpc.AuthenticateRequest")
+	proto.RegisterType((*AuthenticateResponse)(nil), "williamrpc.AuthenticateResponse")
+	proto.RegisterType((*HasPermissionRequest)(nil), "williamrpc.HasPermissionRequest")
+	proto.RegisterMapType((map[string]string)(nil), "williamrpc.HasPermissionRequest.AttributesEntry")
+	proto.RegisterType((*HasPermissionResponse)(nil), "williamrpc.HasPermissionResponse")
+	proto.RegisterType((*HasPermissionsRequest)(nil), "williamrpc.HasPermissionsRequest")
+	proto.RegisterMapType((map[string]string)(nil), "williamrpc.HasPermissionsRequest.AttributesEntry")
+	proto.RegisterType((*HasPermissionsResponse)(nil), "williamrpc.HasPermissionsResponse")
+	proto.RegisterType((*ListServiceAccountsWithPermissionRequest)(nil), "williamrpc.ListServiceAccountsWithPermissionRequest")
+	proto.RegisterType((*ServiceAccount)(nil), "williamrpc.ServiceAccount")
+	proto.RegisterType((*ListServiceAccountsWithPermissionResponse)(nil), "williamrpc.ListServiceAccountsWithPermissionResponse")
+}
+
+func init() { proto.RegisterFile("authorization.proto", fileDescriptor_1dbbe58d1e51a797) }
+
+var fileDescriptor_1dbbe58d1e51a797 = []byte{
+	// 625 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x55, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0x96, 0xe3, 0xa6, 0xb4, 0xd3, 0xbf, 0x68, 0x9b, 0x22, 0x2b, 0x48, 0x90, 0xf8, 0x94, 0x56,
+	0x28, 0x81, 0x02, 0x12, 0x02, 0x71, 0x08, 0x52, 0x25, 0x2a, 0x81, 0xd4, 0xba, 0x95, 0x2a, 0x71,
+	0x89, 0x36, 0xee, 0xd0, 0xac, 0x6a, 0x7b, 0x97, 0xdd, 0x75, 0x51, 0xca, 0x0b, 0x70, 0x84, 0x77,
+	0xe0, 0x1d, 0x78, 0x14, 0x0e, 0xbc, 0x0c, 0xf2, 0x7a, 0xd3, 0xda, 0xa6, 0x7f, 0xb9, 0x70, 0xca,
+	0xee, 0x37, 0xb3, 0xf3, 0xf3, 0xe5, 0x9b, 0x31, 0xac, 0xd3, 0x54, 0x8f, 0xb9, 0x64, 0xe7, 0x54,
+	0x33, 0x9e, 0xf4, 0x84, 0xe4, 0x9a, 0x13, 0xf8, 0xc2, 0xa2, 0x88, 0xd1, 0x58, 0x8a, 0xd0, 0xdf,
+	0x80, 0xf5, 0x41, 0xaa, 0xc7, 0x98, 0x68, 0x16, 0x52, 0x8d, 0x01, 0x7e, 0x4e, 0x51, 0x69, 0xff,
+	0x87, 0x03, 0xcd, 0x32, 0xae, 0x04, 0x4f, 0x14, 0x92, 0xc7, 0x40, 0x14, 0xca, 0x33, 0x16, 0xe2,
+	0x90, 0x86, 0x21, 0x4f, 0x13, 0x3d, 0x64, 0xc7, 0x9e, 0xd3, 0x76, 0xba, 0x8b, 0x41, 0xc3, 0x5a,
+	0x06, 0xb9, 0x61, 0xf7, 0x98, 0x10, 0x98, 0x4b, 0x68, 0x8c, 0x5e, 0xcd, 0xd8, 0xcd, 0x99, 0x34,
+	0xa1, 0x8e, 0x31, 0x65, 0x91, 0xe7, 0x1a, 0x30, 0xbf, 0x90, 0x0e, 0x2c, 0xd3, 0x30, 0x44, 0xa5,
+	0x86, 0x9a, 0x9f, 0x62, 0xe2, 0xcd, 0x19, 0xe3, 0x52, 0x8e, 0x1d, 0x66, 0x90, 0xff, 0xdb, 0x81,
+	0xe6, 0x3b, 0xaa, 0xf6, 0x50, 0xc6, 0x4c, 0x29, 0xc6, 0x13, 0x5b, 0x2c, 0x79, 0x08, 0x20, 0x2e,
+	0x40, 0x5b, 0x4b, 0x01, 0x21, 0xab, 0x50, 0x63, 0xc2, 0xd6, 0x50, 0x63, 0x82, 0xec, 0x01, 0x50,
+	0xad, 0x25, 0x1b, 0xa5, 0x1a, 0x95, 0xe7, 0xb6, 0xdd, 0xee, 0xd2, 0xf6, 0x93, 0xde, 0x25, 0x29,
+	0xbd, 0xab, 0xb2, 0xf4, 0x06, 0x17, 0x4f, 0x76, 0x12, 0x2d, 0x27, 0x41, 0x21, 0x46, 0xeb, 0x0d,
+	0xac, 0x55, 0xcc, 0xa4, 0x01, 0xee, 0x29, 0x4e, 0x6c, 0x35, 0xd9, 0x31, 0x6b, 0xfc, 0x8c, 0x46,
+	0xe9, 0x94, 0x8d, 0xfc, 0xf2, 0xaa, 0xf6, 0xd2, 0xf1, 0x37, 0x61, 0xa3, 0x92, 0xd2, 0xb2, 0xdd,
+	0x00, 0x77, 0x4c, 0x95, 0x09, 0xb2, 0x10, 0x64, 0x47, 0xff, 0x8f, 0x53, 0xf1, 0x55, 0x53, 0x16,
+	0xda, 0xb0, 0x74, 0xd9, 0x73, 0xf6, 0xc6, 0xcd, 0x08, 0x2c, 0x40, 0xff, 0xf0, 0xb0, 0x7f, 0x05,
+	0x0f, 0x4f, 0xaf, 0xe5, 0x41, 0xfd, 0x07, 0x22, 0xb6, 0xe0, 0x7e, 0x35, 0x67, 0x95, 0x09, 0x77,
+	0xca, 0xc4, 0x57, 0xe8, 0xbe, 0x67, 0x4a, 0x1f, 0x94, 0x34, 0xa7, 0x8e, 0x98, 0x1e, 0xcf, 0xae,
+	0x10, 0x02, 0x73, 0x82, 0x9e, 0xe4, 0x05, 0xd5, 0x03, 0x73, 0x26, 0x0f, 0x60, 0x31, 0xfb, 0x1d,
+	0x2a, 0x76, 0x8e, 0x46, 0xab, 0xf5, 0x60, 0x21, 0x03, 0x0e, 0xd8, 0x39, 0xfa, 0xbf, 0x1c, 0x58,
+	0x2d, 0x67, 0x36, 0xec, 0x4e, 0x27, 0xa1, 0xc6, 0x66, 0xd1, 0xbe, 0x07, 0xf7, 0x04, 0x0b, 0x75,
+	0x2a, 0xd1, 0xca, 0x7e, 0x7a, 0x25, 0x6d, 0x58, 0x1e, 0x51, 0x85, 0x43, 0xc9, 0x23, 0xcc, 0xe6,
+	0xac, 0x9e, 0x57, 0x9e, 0x61, 0x01, 0x8f, 0x70, 0xf7, 0x98, 0xf4, 0xf3, 0x11, 0xb7, 0x73, 0xca,
+	0x78, 0x32, 0xd4, 0x13, 0x81, 0xde, 0xbc, 0x71, 0x24, 0x65, 0xd3, 0xe1, 0x44, 0xa0, 0xff, 0xcd,
+	0x81, 0xcd, 0x3b, 0xf0, 0x66, 0x69, 0x6f, 0x42, 0xdd, 0xd8, 0x4d, 0x5f, 0x6e, 0x90, 0x5f, 0xc8,
+	0x0e, 0x34, 0x2a, 0x4b, 0x40, 0x79, 0x35, 0x23, 0x9f, 0x56, 0x51, 0x3e, 0xe5, 0x14, 0xc1, 0x5a,
+	0x79, 0x3d, 0xa8, 0xed, 0x9f, 0x2e, 0xac, 0x0c, 0x8a, 0xfb, 0x89, 0xec, 0xc3, 0x72, 0x71, 0xeb,
+	0x90, 0x47, 0xc5, 0x70, 0x57, 0xec, 0xa9, 0x56, 0xfb, 0x7a, 0x07, 0xdb, 0xc1, 0x21, 0xac, 0x94,
+	0x24, 0x45, 0xda, 0xb7, 0x4d, 0x7a, 0xab, 0x73, 0x83, 0x87, 0x8d, 0x7a, 0x04, 0xab, 0x25, 0x83,
+	0x22, 0x9d, 0x5b, 0x07, 0xa7, 0xe5, 0xdf, 0xe4, 0x62, 0x03, 0x7f, 0x77, 0xa0, 0x73, 0xeb, 0xdf,
+	0x43, 0x9e, 0x17, 0x23, 0xdd, 0x75, 0x0a, 0x5a, 0x2f, 0x66, 0x7c, 0x95, 0x97, 0xf4, 0x76, 0xeb,
+	0x63, 0xf7, 0x84, 0xe9, 0x71, 0x3a, 0xea, 0x85, 0x3c, 0xee, 0x6b, 0x2e, 0x3e, 0x49, 0xc4, 0x13,
+	0x1a, 0xa3, 0xea, 0x1f, 0xb1, 0x28, 0xea, 0xed, 0x0e, 0x3e, 0xf4, 0xa5, 0x08, 0x5f, 0x4b, 0x11,
+	0x8e, 0xe6, 0xcd, 0x17, 0xe6, 0xd9, 0xdf, 0x01, 0x00, 0xf4, 0x65, 0x76, 0x52, 0x78, 0x06, 0x00,
+	0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AuthorizationClient is the client API for Authorization service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AuthorizationClient interface {
+	// Authenticate tells who the caller is. OAuth2 callers get their access
+	// token back, refreshed if it had expired.
+	Authenticate(ctx context.Context, in *AuthenticateRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	// HasPermission tells whether the caller has permission. Permissions
+	// conditions are checked against the caller address, as the HTTP API does.
+	HasPermission(ctx context.Context, in *HasPermissionRequest, opts ...grpc.CallOption) (*HasPermissionResponse, error)
+	// HasPermissions tells, for each permission, whether the caller has it.
+	HasPermissions(ctx context.Context, in *HasPermissionsRequest, opts ...grpc.CallOption) (*HasPermissionsResponse, error)
+	// ListServiceAccountsWithPermission lists service accounts having
+	// permission. The caller must own it.
+	ListServiceAccountsWithPermission(ctx context.Context, in *ListServiceAccountsWithPermissionRequest, opts ...grpc.CallOption) (*ListServiceAccountsWithPermissionResponse, error)
+}
+
+type authorizationClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAuthorizationClient(cc *grpc.ClientConn) AuthorizationClient {
+	return &authorizationClient{cc}
+}
+
+func (c *authorizationClient) Authenticate(ctx context.Context, in *AuthenticateRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error) {
+	out := new(AuthenticateResponse)
+	err := c.cc.Invoke(ctx, "/williamrpc.Authorization/Authenticate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizationClient) HasPermission(ctx context.Context, in *HasPermissionRequest, opts ...grpc.CallOption) (*HasPermissionResponse, error) {
+	out := new(HasPermissionResponse)
+	err := c.cc.Invoke(ctx, "/williamrpc.Authorization/HasPermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizationClient) HasPermissions(ctx context.Context, in *HasPermissionsRequest, opts ...grpc.CallOption) (*HasPermissionsResponse, error) {
+	out := new(HasPermissionsResponse)
+	err := c.cc.Invoke(ctx, "/williamrpc.Authorization/HasPermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authorizationClient) ListServiceAccountsWithPermission(ctx context.Context, in *ListServiceAccountsWithPermissionRequest, opts ...grpc.CallOption) (*ListServiceAccountsWithPermissionResponse, error) {
+	out := new(ListServiceAccountsWithPermissionResponse)
+	err := c.cc.Invoke(ctx, "/williamrpc.Authorization/ListServiceAccountsWithPermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthorizationServer is the server API for Authorization service.
+type AuthorizationServer interface {
+	// Authenticate tells who the caller is. OAuth2 callers get their access
+	// token back, refreshed if it had expired.
+	Authenticate(context.Context, *AuthenticateRequest) (*AuthenticateResponse, error)
+	// HasPermission tells whether the caller has permission. Permissions
+	// conditions are checked against the caller address, as the HTTP API does.
+	HasPermission(context.Context, *HasPermissionRequest) (*HasPermissionResponse, error)
+	// HasPermissions tells, for each permission, whether the caller has it.
+	HasPermissions(context.Context, *HasPermissionsRequest) (*HasPermissionsResponse, error)
+	// ListServiceAccountsWithPermission lists service accounts having
+	// permission. The caller must own it.
+	ListServiceAccountsWithPermission(context.Context, *ListServiceAccountsWithPermissionRequest) (*ListServiceAccountsWithPermissionResponse, error)
+}
+
+func RegisterAuthorizationServer(s *grpc.Server, srv AuthorizationServer) {
+	s.RegisterService(&_Authorization_serviceDesc, srv)
+}
+
+func _Authorization_Authenticate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthenticateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).Authenticate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/williamrpc.Authorization/Authenticate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).Authenticate(ctx, req.(*AuthenticateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorization_HasPermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HasPermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).HasPermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/williamrpc.Authorization/HasPermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).HasPermission(ctx, req.(*HasPermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorization_HasPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HasPermissionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).HasPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/williamrpc.Authorization/HasPermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).HasPermissions(ctx, req.(*HasPermissionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authorization_ListServiceAccountsWithPermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServiceAccountsWithPermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).ListServiceAccountsWithPermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/williamrpc.Authorization/ListServiceAccountsWithPermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).ListServiceAccountsWithPermission(ctx, req.(*ListServiceAccountsWithPermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Authorization_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "williamrpc.Authorization",
+	HandlerType: (*AuthorizationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authenticate",
+			Handler:    _Authorization_Authenticate_Handler,
+		},
+		{
+			MethodName: "HasPermission",
+			Handler:    _Authorization_HasPermission_Handler,
+		},
+		{
+			MethodName: "HasPermissions",
+			Handler:    _Authorization_HasPermissions_Handler,
+		},
+		{
+			MethodName: "ListServiceAccountsWithPermission",
+			Handler:    _Authorization_ListServiceAccountsWithPermission_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "authorization.proto",
+}
@@ -0,0 +1,5 @@
+// Package rpc holds Will.IAM gRPC authorization API, generated from
+// authorization.proto with protoc-gen-go v1.3.1
+package rpc
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. authorization.proto
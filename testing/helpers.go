@@ -2,6 +2,8 @@ package testing
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -90,18 +92,76 @@ func GetServiceAccountsUseCase(t *testing.T) usecases.ServiceAccounts {
 		WithContext(context.Background())
 }
 
+// GetGroupsUseCase returns a usecases.Groups
+func GetGroupsUseCase(t *testing.T) usecases.Groups {
+	t.Helper()
+	return usecases.NewGroups(GetRepo(t)).WithContext(context.Background())
+}
+
 // GetServicesUseCase returns a usecases.Services
 func GetServicesUseCase(t *testing.T) usecases.Services {
 	t.Helper()
 	return usecases.NewServices(GetRepo(t)).WithContext(context.Background())
 }
 
+// GetExpiredGrantsUseCase returns a usecases.ExpiredGrants
+func GetExpiredGrantsUseCase(t *testing.T) usecases.ExpiredGrants {
+	t.Helper()
+	return usecases.NewExpiredGrants(GetRepo(t)).WithContext(context.Background())
+}
+
+// GetDecisionsUseCase returns a usecases.Decisions
+func GetDecisionsUseCase(t *testing.T) usecases.Decisions {
+	t.Helper()
+	return usecases.NewDecisions(GetRepo(t)).WithContext(context.Background())
+}
+
 // GetPermissionsRequestsUseCase returns a usecases.PermissionsRequests
 func GetPermissionsRequestsUseCase(t *testing.T) usecases.PermissionsRequests {
 	t.Helper()
 	return usecases.NewPermissionsRequests(GetRepo(t)).WithContext(context.Background())
 }
 
+// GetPermissionsUseCase returns a usecases.Permissions
+func GetPermissionsUseCase(t *testing.T) usecases.Permissions {
+	t.Helper()
+	return usecases.NewPermissions(GetRepo(t)).WithContext(context.Background())
+}
+
+// GetAuditEventsUseCase returns a usecases.AuditEvents
+func GetAuditEventsUseCase(t *testing.T) usecases.AuditEvents {
+	t.Helper()
+	return usecases.NewAuditEvents(GetRepo(t)).WithContext(context.Background())
+}
+
+// GetPoliciesUseCase returns a usecases.Policies
+func GetPoliciesUseCase(t *testing.T) usecases.Policies {
+	t.Helper()
+	return usecases.NewPolicies(GetRepo(t)).WithContext(context.Background())
+}
+
+// GetAccessTokensUseCase returns a usecases.AccessTokens signing with a
+// fresh key
+func GetAccessTokensUseCase(
+	t *testing.T, includePermissions bool,
+) usecases.AccessTokens {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ats, err := usecases.NewAccessTokens(GetRepo(t), usecases.AccessTokensConfig{
+		TTL:                15 * time.Minute,
+		SigningKeyID:       "test",
+		Keys:               []usecases.SigningKey{{KeyID: "test", PrivateKey: key}},
+		IncludePermissions: includePermissions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ats.WithContext(context.Background())
+}
+
 // CreateRootServiceAccountWithKeyPair creates a root service account with root access using KeyPair
 func CreateRootServiceAccountWithKeyPair(t *testing.T, name, email string) *models.ServiceAccount {
 	t.Helper()
@@ -162,16 +222,29 @@ func CleanupPG(t *testing.T) {
 	t.Helper()
 	storage := GetStorage(t)
 	rels := []string{
+		"decisions",
+		"expired_grants",
+		"group_bindings",
+		"group_inclusions",
+		"group_members",
+		"groups",
 		"permissions_requests",
 		"permissions",
 		"role_bindings",
+		"role_inclusions",
 		"roles",
 		"service_accounts",
 		"services",
+		"tokens",
+		"sessions",
 	}
 	for _, rel := range rels {
 		if _, err := storage.PG.DB.Exec(fmt.Sprintf("DELETE FROM %s;", rel)); err != nil {
 			panic(err)
 		}
 	}
+	// audit_events is append-only, only TRUNCATE gets past its trigger
+	if _, err := storage.PG.DB.Exec("TRUNCATE audit_events;"); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,144 @@
+package testing
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/topfreegames/Will.IAM/jwt"
+)
+
+// OIDCServer is a stand-in OpenID Connect identity provider. Codes are
+// registered with Code and exchanged at its token endpoint for ID tokens
+// carrying the registered claims
+type OIDCServer struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+	// ExpiresIn is the lifetime of issued access tokens
+	ExpiresIn time.Duration
+	key       *rsa.PrivateKey
+	kid       string
+	mu        sync.Mutex
+	codes     map[string]map[string]interface{}
+	refreshes map[string]map[string]interface{}
+}
+
+// NewOIDCServer starts an OIDCServer, Close it when done
+func NewOIDCServer(t *testing.T) *OIDCServer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &OIDCServer{
+		ClientID:     "will-iam",
+		ClientSecret: "secret",
+		ExpiresIn:    time.Hour,
+		key:          key,
+		kid:          "key1",
+		codes:        map[string]map[string]interface{}{},
+		refreshes:    map[string]map[string]interface{}{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/keys", s.keys)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Code registers claims and returns the code that exchanges for them
+func (s *OIDCServer) Code(claims map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := uuid.Must(uuid.NewV4()).String()
+	s.codes[code] = claims
+	return code
+}
+
+// RotateKey makes the server sign with a new key, published under a new kid
+func (s *OIDCServer) RotateKey(t *testing.T, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	s.kid = kid
+}
+
+func (s *OIDCServer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/keys",
+	})
+}
+
+func (s *OIDCServer) keys(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	json.NewEncoder(w).Encode(jwt.KeySet{
+		Keys: []jwt.JSONWebKey{jwt.NewJSONWebKey(&s.key.PublicKey, s.kid)},
+	})
+}
+
+func (s *OIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if id != s.ClientID || secret != s.ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claims map[string]interface{}
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		claims = s.codes[r.PostFormValue("code")]
+		delete(s.codes, r.PostFormValue("code"))
+	case "refresh_token":
+		claims = s.refreshes[r.PostFormValue("refresh_token")]
+	}
+	if claims == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	idTokenClaims := map[string]interface{}{
+		"iss": s.URL,
+		"aud": s.ClientID,
+		"sub": uuid.Must(uuid.NewV4()).String(),
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		idTokenClaims[k] = v
+	}
+	idToken, err := jwt.Sign(idTokenClaims, s.key, s.kid)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	refreshToken := uuid.Must(uuid.NewV4()).String()
+	s.refreshes[refreshToken] = claims
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  uuid.Must(uuid.NewV4()).String(),
+		"id_token":      idToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(s.ExpiresIn.Seconds()),
+	})
+}
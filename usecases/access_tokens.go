@@ -0,0 +1,181 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/jwt"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// accessTokenLeeway is the clock skew tolerated when verifying access tokens
+const accessTokenLeeway = 30 * time.Second
+
+// SigningKey is a RSA key access tokens are signed with, published as KeyID
+type SigningKey struct {
+	KeyID      string
+	PrivateKey *rsa.PrivateKey
+}
+
+// AccessTokensConfig configures access tokens signed by Will.IAM
+type AccessTokensConfig struct {
+	Issuer string
+	TTL    time.Duration
+	// SigningKeyID picks the key in Keys that signs new tokens. Every key is
+	// published and verifies tokens, so rotating is adding a key, signing
+	// with it once downstream services had time to fetch it and removing the
+	// old one after TTL
+	SigningKeyID string
+	Keys         []SigningKey
+	// IncludePermissions adds the service account permissions to tokens
+	IncludePermissions bool
+}
+
+// AccessTokens issues and verifies short-lived access tokens signed by
+// Will.IAM, which downstream services can verify offline with KeySet
+type AccessTokens interface {
+	Issue(string) (*models.IssuedAccessToken, error)
+	IsAccessToken(string) bool
+	KeySet() jwt.KeySet
+	Verify(string) (*models.AccessTokenClaims, error)
+	WithContext(context.Context) AccessTokens
+}
+
+type accessTokens struct {
+	repo       *repositories.All
+	ctx        context.Context
+	config     AccessTokensConfig
+	signingKey SigningKey
+	keySet     jwt.KeySet
+}
+
+func (ats accessTokens) WithContext(ctx context.Context) AccessTokens {
+	ats.repo = ats.repo.WithContext(ctx)
+	ats.ctx = ctx
+	return &ats
+}
+
+// NewAccessTokens accessTokens ctor
+func NewAccessTokens(
+	repo *repositories.All, config AccessTokensConfig,
+) (AccessTokens, error) {
+	if config.Issuer == "" {
+		config.Issuer = "Will.IAM"
+	}
+	if config.TTL <= 0 {
+		return nil, fmt.Errorf("access tokens ttl must be positive")
+	}
+	ats := &accessTokens{repo: repo, config: config}
+	for _, k := range config.Keys {
+		if k.KeyID == "" || k.PrivateKey == nil {
+			return nil, fmt.Errorf("access tokens keys need an id and a private key")
+		}
+		if k.KeyID == config.SigningKeyID {
+			ats.signingKey = k
+		}
+		ats.keySet.Keys = append(
+			ats.keySet.Keys, jwt.NewJSONWebKey(&k.PrivateKey.PublicKey, k.KeyID),
+		)
+	}
+	if ats.signingKey.PrivateKey == nil {
+		return nil, fmt.Errorf(
+			"access tokens signing key %s not found", config.SigningKeyID,
+		)
+	}
+	return ats, nil
+}
+
+// Issue signs an access token for serviceAccountID. With permissions
+// included it expires no later than the first of them
+func (ats accessTokens) Issue(
+	serviceAccountID string,
+) (*models.IssuedAccessToken, error) {
+	sa, err := ats.repo.ServiceAccounts.Get(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(ats.config.TTL)
+	claims := &models.AccessTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   ats.config.Issuer,
+			Subject:  sa.ID,
+			IssuedAt: now.Unix(),
+			ID:       uuid.Must(uuid.NewV4()).String(),
+		},
+		Email: sa.Email,
+		Name:  sa.Name,
+	}
+	if ats.config.IncludePermissions {
+		ps, err := serviceAccountGetPermissions(ats.repo, sa.ID)
+		if err != nil {
+			return nil, err
+		}
+		rbs, err := ats.repo.Roles.BindingsForServiceAccountID(sa.ID)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt = grantsValidUntil(expiresAt, ps, rbs)
+		claims.Permissions = make([]string, 0, len(ps))
+		for i := range ps {
+			// token holders can't evaluate conditions, so conditioned grants
+			// are left out while conditioned denies always deny
+			if ps[i].Condition != "" && !ps[i].Deny {
+				continue
+			}
+			claims.Permissions = append(claims.Permissions, ps[i].String())
+		}
+	}
+	claims.ExpiresAt = expiresAt.Unix()
+	token, err := jwt.Sign(claims, ats.signingKey.PrivateKey, ats.signingKey.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.IssuedAccessToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   claims.ExpiresAt - now.Unix(),
+		ExpiresAt:   time.Unix(claims.ExpiresAt, 0).UTC(),
+	}, nil
+}
+
+// IsAccessToken tells if raw claims to be signed by one of Will.IAM keys,
+// anything else is left for login providers
+func (ats accessTokens) IsAccessToken(raw string) bool {
+	h, err := jwt.ParseHeader(raw)
+	if err != nil || h.KeyID == "" {
+		return false
+	}
+	return ats.keySet.Key(h.KeyID) != nil
+}
+
+// KeySet returns the JWKS verifying access tokens
+func (ats accessTokens) KeySet() jwt.KeySet {
+	return ats.keySet
+}
+
+// Verify checks raw signature and expiration, it doesn't touch storage
+func (ats accessTokens) Verify(raw string) (*models.AccessTokenClaims, error) {
+	t, err := jwt.Verify(raw, ats.keySet)
+	if err != nil {
+		return nil, errors.NewInvalidAccessTokenError(err.Error())
+	}
+	if err := t.Claims.Validate(
+		ats.config.Issuer, "", time.Now(), accessTokenLeeway,
+	); err != nil {
+		return nil, errors.NewInvalidAccessTokenError(err.Error())
+	}
+	claims := &models.AccessTokenClaims{}
+	if err := t.UnmarshalClaims(claims); err != nil {
+		return nil, errors.NewInvalidAccessTokenError(err.Error())
+	}
+	if claims.Subject == "" {
+		return nil, errors.NewInvalidAccessTokenError("sub is required")
+	}
+	return claims, nil
+}
@@ -0,0 +1,125 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/oauth2"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestAccessTokensIssueAndVerify(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+		"Service1::RL::Do::*", "!Service1::RL::Do::secret::*",
+	)
+	atsUC := helpers.GetAccessTokensUseCase(t, true)
+	issued, err := atsUC.Issue(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if issued.TokenType != "Bearer" || issued.ExpiresIn <= 0 {
+		t.Errorf("Unexpected issued token %#v", issued)
+	}
+	if !atsUC.IsAccessToken(issued.AccessToken) {
+		t.Fatalf("Expected token to be recognized as an access token")
+	}
+	claims, err := atsUC.Verify(issued.AccessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if claims.Subject != sa.ID || claims.Email != "sa@test.com" {
+		t.Errorf("Unexpected claims %#v", claims)
+	}
+	perms := strings.Join(claims.Permissions, ",")
+	if !strings.Contains(perms, "Service1::RL::Do::*") ||
+		!strings.Contains(perms, "!Service1::RL::Do::secret::*") {
+		t.Errorf("Expected permissions in claims. Got %v", claims.Permissions)
+	}
+}
+
+func TestAccessTokensVerifyOtherKey(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	issued, err := helpers.GetAccessTokensUseCase(t, false).Issue(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// same kid, different key
+	_, err = helpers.GetAccessTokensUseCase(t, false).Verify(issued.AccessToken)
+	if _, ok := err.(*errors.InvalidAccessTokenError); !ok {
+		t.Errorf("Expected InvalidAccessTokenError. Got %v", err)
+	}
+}
+
+func TestServiceAccountsAuthenticateAccessTokenSignedByWillIAM(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	atsUC := helpers.GetAccessTokensUseCase(t, false)
+	issued, err := atsUC.Issue(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saUC := usecases.NewServiceAccountsWithOptions(
+		helpers.GetRepo(t), oauth2.NewProviderBlankMock(),
+		usecases.ServiceAccountsOptions{AccessTokens: atsUC},
+	).WithContext(context.Background())
+	auth, err := saUC.AuthenticateAccessToken(issued.AccessToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if auth.ServiceAccountID != sa.ID {
+		t.Errorf("Expected service account %s. Got %s", sa.ID, auth.ServiceAccountID)
+	}
+	_, err = saUC.AuthenticateAccessToken(issued.AccessToken[:len(issued.AccessToken)-4])
+	if _, ok := err.(*errors.InvalidAccessTokenError); !ok {
+		t.Errorf("Expected InvalidAccessTokenError. Got %v", err)
+	}
+}
+
+func TestServiceAccountsAuthenticateAccessTokenRevoked(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa", "sa@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	atsUC := helpers.GetAccessTokensUseCase(t, false)
+	issued, err := atsUC.Issue(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saUC := usecases.NewServiceAccountsWithOptions(
+		helpers.GetRepo(t), oauth2.NewProviderBlankMock(),
+		usecases.ServiceAccountsOptions{AccessTokens: atsUC},
+	).WithContext(context.Background())
+	if _, err := saUC.AuthenticateAccessToken(issued.AccessToken); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := saUC.RevokeSessions(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = saUC.AuthenticateAccessToken(issued.AccessToken)
+	if _, ok := err.(*errors.InvalidAccessTokenError); !ok {
+		t.Errorf("Expected InvalidAccessTokenError. Got %v", err)
+	}
+	// iat is precise to a second
+	time.Sleep(time.Second)
+	issued, err = atsUC.Issue(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := saUC.AuthenticateAccessToken(issued.AccessToken); err != nil {
+		t.Errorf("Expected token issued after revocation to be valid. Got %v", err)
+	}
+}
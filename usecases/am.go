@@ -55,7 +55,7 @@ func (a am) List(saID string, prefix string) ([]models.AM, error) {
 			is = append(is, i)
 		}
 	}
-	hasSl, err := serviceAccountHasPermissions(a.repo, saID, ps)
+	hasSl, err := serviceAccountHasPermissions(a.ctx, a.repo, saID, ps)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +177,11 @@ func (a am) listWillIAMPermissions(prefix string) ([]models.AM, error) {
 
 func (a am) listWillIAMActions(prefix string) ([]string, error) {
 	all := append(constants.RolesActions, constants.ServiceAccountsActions...)
+	all = append(all, constants.GroupsActions...)
 	all = append(all, constants.ServicesActions...)
+	all = append(all, constants.AuditActions...)
+	all = append(all, constants.SCIMActions...)
+	all = append(all, constants.PoliciesActions...)
 	keep := []string{}
 	for i := range all {
 		if ok := strings.HasPrefix(all[i], prefix); ok {
@@ -0,0 +1,302 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+type auditCtxKeyType string
+
+const auditActorCtxKey = auditCtxKeyType("actor")
+const auditRequestIDCtxKey = auditCtxKeyType("requestID")
+
+// WithAuditActor returns a copy of ctx that makes audited operations record
+// actorID as the service account responsible for them
+func WithAuditActor(ctx context.Context, actorID string) context.Context {
+	return context.WithValue(ctx, auditActorCtxKey, actorID)
+}
+
+// WithAuditRequestID returns a copy of ctx that makes audited operations
+// record requestID
+func WithAuditRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, auditRequestIDCtxKey, requestID)
+}
+
+func auditCtxValue(ctx context.Context, key auditCtxKeyType) string {
+	if ctx == nil {
+		return ""
+	}
+	v, _ := ctx.Value(key).(string)
+	return v
+}
+
+// auditSnapshot converts i to its JSON object representation
+func auditSnapshot(i interface{}) (map[string]interface{}, error) {
+	if i == nil {
+		return nil, nil
+	}
+	bts, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(bts, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// recordAuditEvent appends ae to the audit log using repo, which should be
+// the same transaction as the audited change. Actor and request ID are taken
+// from ctx unless already set
+func recordAuditEvent(
+	ctx context.Context, repo *repositories.All, ae *models.AuditEvent,
+	before, after interface{},
+) error {
+	var err error
+	if ae.Before, err = auditSnapshot(before); err != nil {
+		return err
+	}
+	if ae.After, err = auditSnapshot(after); err != nil {
+		return err
+	}
+	if ae.ActorServiceAccountID == "" {
+		ae.ActorServiceAccountID = auditCtxValue(ctx, auditActorCtxKey)
+	}
+	if ae.RequestID == "" {
+		ae.RequestID = auditCtxValue(ctx, auditRequestIDCtxKey)
+	}
+	return repo.AuditEvents.Create(ae)
+}
+
+// roleAuditState is what the audit log records about a role
+type roleAuditState struct {
+	Name                  string               `json:"name"`
+	Permissions           []string             `json:"permissions"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+	ServiceAccountsIDs    []string             `json:"serviceAccountsIds"`
+	IncludedRolesIDs      []string             `json:"includedRolesIds"`
+	GroupsIDs             []string             `json:"groupsIds"`
+}
+
+func getRoleAuditState(
+	repo *repositories.All, roleID string,
+) (*roleAuditState, error) {
+	r, err := repo.Roles.Get(roleID)
+	if err != nil {
+		return nil, err
+	}
+	pSl, err := repo.Permissions.ForRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	rbs, err := repo.Roles.Bindings(roleID)
+	if err != nil {
+		return nil, err
+	}
+	state := &roleAuditState{Name: r.Name}
+	state.Permissions, state.PermissionsExpiresAt,
+		state.PermissionsConditions = permissionsAuditState(pSl)
+	state.ServiceAccountsIDs = make([]string, len(rbs))
+	for i := range rbs {
+		state.ServiceAccountsIDs[i] = rbs[i].ServiceAccountID
+	}
+	included, err := repo.Roles.Included(roleID)
+	if err != nil {
+		return nil, err
+	}
+	state.IncludedRolesIDs = make([]string, len(included))
+	for i := range included {
+		state.IncludedRolesIDs[i] = included[i].ID
+	}
+	gs, err := repo.Roles.GetGroups(roleID)
+	if err != nil {
+		return nil, err
+	}
+	state.GroupsIDs = make([]string, len(gs))
+	for i := range gs {
+		state.GroupsIDs[i] = gs[i].ID
+	}
+	return state, nil
+}
+
+// groupAuditState is what the audit log records about a group
+type groupAuditState struct {
+	Name              string   `json:"name"`
+	MembersIDs        []string `json:"membersIds"`
+	IncludedGroupsIDs []string `json:"includedGroupsIds"`
+}
+
+func getGroupAuditState(
+	repo *repositories.All, groupID string,
+) (*groupAuditState, error) {
+	g, err := repo.Groups.Get(groupID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := repo.Groups.Members(groupID)
+	if err != nil {
+		return nil, err
+	}
+	included, err := repo.Groups.Included(groupID)
+	if err != nil {
+		return nil, err
+	}
+	state := &groupAuditState{
+		Name:              g.Name,
+		MembersIDs:        make([]string, len(members)),
+		IncludedGroupsIDs: make([]string, len(included)),
+	}
+	for i := range members {
+		state.MembersIDs[i] = members[i].ID
+	}
+	for i := range included {
+		state.IncludedGroupsIDs[i] = included[i].ID
+	}
+	return state, nil
+}
+
+// serviceAccountAuditState is what the audit log records about a service
+// account
+type serviceAccountAuditState struct {
+	Name                  string               `json:"name"`
+	Email                 string               `json:"email"`
+	Status                string               `json:"status"`
+	Permissions           []string             `json:"permissions"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+	RolesIDs              []string             `json:"rolesIds"`
+}
+
+func getServiceAccountAuditState(
+	repo *repositories.All, saID string,
+) (*serviceAccountAuditState, error) {
+	sa, err := repo.ServiceAccounts.Get(saID)
+	if err != nil {
+		return nil, err
+	}
+	pSl, err := repo.Permissions.ForRole(sa.BaseRoleID)
+	if err != nil {
+		return nil, err
+	}
+	rbs, err := repo.Roles.BindingsForServiceAccountID(saID)
+	if err != nil {
+		return nil, err
+	}
+	state := &serviceAccountAuditState{
+		Name: sa.Name, Email: sa.Email, Status: sa.Status.String(),
+	}
+	state.Permissions, state.PermissionsExpiresAt,
+		state.PermissionsConditions = permissionsAuditState(pSl)
+	state.RolesIDs = []string{}
+	for i := range rbs {
+		if rbs[i].RoleID != sa.BaseRoleID {
+			state.RolesIDs = append(state.RolesIDs, rbs[i].RoleID)
+		}
+	}
+	return state, nil
+}
+
+func permissionsAuditState(
+	pSl []models.Permission,
+) ([]string, map[string]time.Time, map[string]string) {
+	permissions := make([]string, len(pSl))
+	expiresAt := map[string]time.Time{}
+	conds := map[string]string{}
+	for i := range pSl {
+		permissions[i] = pSl[i].String()
+		if !pSl[i].ExpiresAt.IsZero() {
+			expiresAt[permissions[i]] = pSl[i].ExpiresAt.Time
+		}
+		if pSl[i].Condition != "" {
+			conds[permissions[i]] = pSl[i].Condition
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, expiresAt, conds
+}
+
+// AuditEvents define entrypoints for reading the audit log
+type AuditEvents interface {
+	List(
+		*repositories.AuditEventsFilter, *repositories.ListOptions,
+	) ([]models.AuditEvent, int64, error)
+	WithContext(context.Context) AuditEvents
+}
+
+type auditEvents struct {
+	repo *repositories.All
+	ctx  context.Context
+}
+
+func (aes auditEvents) WithContext(ctx context.Context) AuditEvents {
+	return &auditEvents{aes.repo.WithContext(ctx), ctx}
+}
+
+// List audit events matching f, newest first
+func (aes auditEvents) List(
+	f *repositories.AuditEventsFilter, lo *repositories.ListOptions,
+) ([]models.AuditEvent, int64, error) {
+	aeSl, err := aes.repo.AuditEvents.List(f, lo)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := aes.repo.AuditEvents.ListCount(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return aeSl, count, nil
+}
+
+// NewAuditEvents ctor
+func NewAuditEvents(repo *repositories.All) AuditEvents {
+	return &auditEvents{repo: repo}
+}
+
+// serviceAccountKeysAuditState is what the audit log records about a service
+// account key pairs, secrets are never recorded
+type serviceAccountKeysAuditState struct {
+	KeysIDs []string `json:"keysIds"`
+}
+
+func getServiceAccountKeysAuditState(
+	repo *repositories.All, saID string,
+) (*serviceAccountKeysAuditState, error) {
+	ks, err := repo.ServiceAccountKeys.ForServiceAccount(saID)
+	if err != nil {
+		return nil, err
+	}
+	state := &serviceAccountKeysAuditState{KeysIDs: make([]string, len(ks))}
+	for i := range ks {
+		state.KeysIDs[i] = ks[i].ID
+	}
+	return state, nil
+}
+
+// serviceAccountSessionsAuditState is what the audit log records about a
+// service account sessions
+type serviceAccountSessionsAuditState struct {
+	SessionsIDs []string `json:"sessionsIds"`
+}
+
+func getServiceAccountSessionsAuditState(
+	repo *repositories.All, email string,
+) (*serviceAccountSessionsAuditState, error) {
+	ss, err := repo.Sessions.ForEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	state := &serviceAccountSessionsAuditState{
+		SessionsIDs: make([]string, len(ss)),
+	}
+	for i := range ss {
+		state.SessionsIDs[i] = ss[i].ID
+	}
+	return state, nil
+}
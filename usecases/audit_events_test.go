@@ -0,0 +1,241 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func listAuditEvents(
+	t *testing.T, f *repositories.AuditEventsFilter,
+) []models.AuditEvent {
+	t.Helper()
+	aeSl, _, err := helpers.GetAuditEventsUseCase(t).List(
+		f, &repositories.ListOptions{},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return aeSl
+}
+
+func TestPermissionsDeleteRecordsAuditEvent(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "some name", "some@test.com", models.AuthenticationTypes.KeyPair,
+		"Service::RL::Do::x::*",
+	)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	ps, err := saUC.GetPermissions(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := usecases.WithAuditActor(context.Background(), rootSA.ID)
+	ctx = usecases.WithAuditRequestID(ctx, "some-request-id")
+	psUC := helpers.GetPermissionsUseCase(t).WithContext(ctx)
+	if err := psUC.Delete(ps[0].ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aeSl := listAuditEvents(t, &repositories.AuditEventsFilter{
+		Action: models.AuditActions.DeletePermission.String(),
+	})
+	if len(aeSl) != 1 {
+		t.Fatalf("Expected 1 audit event. Got %d", len(aeSl))
+	}
+	ae := aeSl[0]
+	if ae.ActorServiceAccountID != rootSA.ID {
+		t.Errorf("Expected actor %s. Got %s", rootSA.ID, ae.ActorServiceAccountID)
+	}
+	if ae.RequestID != "some-request-id" {
+		t.Errorf("Expected request ID some-request-id. Got %s", ae.RequestID)
+	}
+	if ae.TargetType != models.AuditTargetTypes.Permission || ae.TargetID != ps[0].ID {
+		t.Errorf("Unexpected target %s %s", ae.TargetType, ae.TargetID)
+	}
+	if ae.Before["action"] != "Do" {
+		t.Errorf("Expected before action Do. Got %v", ae.Before["action"])
+	}
+	if ae.After != nil {
+		t.Errorf("Expected after to be nil. Got %v", ae.After)
+	}
+}
+
+func TestPermissionsAttributeRecordsAuditEvent(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	rsUC := helpers.GetRolesUseCase(t)
+	rwn := &usecases.RoleWithNested{Name: "some role"}
+	if err := rsUC.Create(rwn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ps, err := models.BuildPermissions([]string{"Service::RL::Do::x::*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := usecases.WithAuditActor(context.Background(), rootSA.ID)
+	psUC := helpers.GetPermissionsUseCase(t).WithContext(ctx)
+	if err := psUC.Attribute(&usecases.PermissionsAttribute{
+		RolesIDs: []string{rwn.ID}, Permissions: ps,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aeSl := listAuditEvents(t, &repositories.AuditEventsFilter{
+		Action: models.AuditActions.AttributePermissions.String(),
+	})
+	if len(aeSl) != 1 {
+		t.Fatalf("Expected 1 audit event. Got %d", len(aeSl))
+	}
+	ae := aeSl[0]
+	if ae.ActorServiceAccountID != rootSA.ID {
+		t.Errorf("Expected actor %s. Got %s", rootSA.ID, ae.ActorServiceAccountID)
+	}
+	if ae.TargetType != models.AuditTargetTypes.Role || ae.TargetID != rwn.ID {
+		t.Errorf("Unexpected target %s %s", ae.TargetType, ae.TargetID)
+	}
+	before := ae.Before["permissions"].([]interface{})
+	after := ae.After["permissions"].([]interface{})
+	if len(before) != 0 || len(after) != 1 || after[0] != "Service::RL::Do::x::*" {
+		t.Errorf("Unexpected before/after permissions %v %v", before, after)
+	}
+}
+
+func TestRolesCreatePermissionRecordsAuditEvent(t *testing.T) {
+	helpers.CleanupPG(t)
+	rsUC := helpers.GetRolesUseCase(t)
+	rwn := &usecases.RoleWithNested{Name: "some role"}
+	if err := rsUC.Create(rwn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p, err := models.BuildPermission("Service::RL::Do::x::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := rsUC.CreatePermission(rwn.ID, &p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aeSl := listAuditEvents(t, &repositories.AuditEventsFilter{
+		Action: models.AuditActions.CreatePermission.String(),
+	})
+	if len(aeSl) != 1 {
+		t.Fatalf("Expected 1 audit event. Got %d", len(aeSl))
+	}
+	ae := aeSl[0]
+	if ae.TargetType != models.AuditTargetTypes.Role || ae.TargetID != rwn.ID {
+		t.Errorf("Unexpected target %s %s", ae.TargetType, ae.TargetID)
+	}
+	before := ae.Before["permissions"].([]interface{})
+	after := ae.After["permissions"].([]interface{})
+	if len(before) != 0 || len(after) != 1 || after[0] != "Service::RL::Do::x::*" {
+		t.Errorf("Unexpected before/after permissions %v %v", before, after)
+	}
+}
+
+func TestServiceAccountsCreateWithNestedRecordsAuditEvent(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	ps, err := models.BuildPermissions([]string{"Service::RL::Do::x::*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sawn := &usecases.ServiceAccountWithNested{
+		Name:               "some name",
+		Email:              "some@test.com",
+		Permissions:        ps,
+		AuthenticationType: models.AuthenticationTypes.OAuth2,
+	}
+	if err := saUC.CreateWithNested(sawn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aeSl := listAuditEvents(t, &repositories.AuditEventsFilter{
+		Action: models.AuditActions.CreateServiceAccount.String(),
+	})
+	if len(aeSl) != 1 || aeSl[0].TargetID != sawn.ID {
+		t.Fatalf("Expected 1 audit event for %s. Got %v", sawn.ID, aeSl)
+	}
+	if aeSl[0].Before != nil {
+		t.Errorf("Expected before to be nil. Got %v", aeSl[0].Before)
+	}
+	after := aeSl[0].After["permissions"].([]interface{})
+	if len(after) != 1 || after[0] != "Service::RL::Do::x::*" {
+		t.Errorf("Unexpected after permissions %v", after)
+	}
+}
+
+func TestRolesUpdateRecordsAuditEvent(t *testing.T) {
+	helpers.CleanupPG(t)
+	rsUC := helpers.GetRolesUseCase(t)
+	ps, err := models.BuildPermissions([]string{"Service::RL::Do::x::*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rwn := &usecases.RoleWithNested{Name: "some role", Permissions: ps}
+	if err := rsUC.Create(rwn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ps, err = models.BuildPermissions([]string{"Service::RL::Do::y::*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rwn.Name = "other role"
+	rwn.Permissions = ps
+	if err := rsUC.Update(rwn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aeSl := listAuditEvents(t, &repositories.AuditEventsFilter{
+		TargetType: models.AuditTargetTypes.Role.String(),
+		TargetID:   rwn.ID,
+	})
+	if len(aeSl) != 1 {
+		t.Fatalf("Expected 1 audit event. Got %d", len(aeSl))
+	}
+	if aeSl[0].Before["name"] != "some role" || aeSl[0].After["name"] != "other role" {
+		t.Errorf("Unexpected before/after names %v %v", aeSl[0].Before, aeSl[0].After)
+	}
+	before := aeSl[0].Before["permissions"].([]interface{})
+	after := aeSl[0].After["permissions"].([]interface{})
+	if before[0] != "Service::RL::Do::x::*" || after[0] != "Service::RL::Do::y::*" {
+		t.Errorf("Unexpected before/after permissions %v %v", before, after)
+	}
+}
+
+func TestPermissionsRequestsGrantRecordsAuditEvent(t *testing.T) {
+	helpers.CleanupPG(t)
+	rootSA := helpers.CreateRootServiceAccountWithKeyPair(t, "root", "root@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "some name", "some@test.com", models.AuthenticationTypes.KeyPair,
+	)
+	prsUC := helpers.GetPermissionsRequestsUseCase(t)
+	pr := &models.PermissionRequest{
+		ServiceAccountID:  sa.ID,
+		Service:           "SomeService",
+		OwnershipLevel:    models.OwnershipLevels.Lender,
+		Action:            "Do",
+		ResourceHierarchy: models.BuildResourceHierarchy("x::y"),
+		Message:           "Please I need it",
+	}
+	if err := prsUC.Create(pr); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := prsUC.Grant(rootSA.ID, pr.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aeSl := listAuditEvents(t, &repositories.AuditEventsFilter{
+		ActorServiceAccountID: rootSA.ID,
+	})
+	if len(aeSl) != 1 {
+		t.Fatalf("Expected 1 audit event. Got %d", len(aeSl))
+	}
+	if aeSl[0].Action != models.AuditActions.GrantPermissionRequest {
+		t.Errorf("Expected action GrantPermissionRequest. Got %s", aeSl[0].Action)
+	}
+	if aeSl[0].Before["state"] != "open" || aeSl[0].After["state"] != "granted" {
+		t.Errorf("Unexpected before/after states %v %v", aeSl[0].Before, aeSl[0].After)
+	}
+}
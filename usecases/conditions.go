@@ -0,0 +1,51 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/conditions"
+	"github.com/topfreegames/Will.IAM/models"
+)
+
+type conditionsCtxKeyType string
+
+const conditionsCtxKey = conditionsCtxKeyType("conditions")
+
+// WithConditionsContext returns a copy of ctx that makes permission checks
+// evaluate permissions conditions against cc
+func WithConditionsContext(
+	ctx context.Context, cc *conditions.Context,
+) context.Context {
+	return context.WithValue(ctx, conditionsCtxKey, cc)
+}
+
+// conditionsContext returns the conditions.Context attached to ctx, or one
+// telling only the time of the check
+func conditionsContext(ctx context.Context) *conditions.Context {
+	if ctx != nil {
+		if cc, ok := ctx.Value(conditionsCtxKey).(*conditions.Context); ok {
+			return cc
+		}
+	}
+	return &conditions.Context{Time: time.Now()}
+}
+
+// applicablePermissions returns the permissions in ps that apply to checks
+// made within ctx
+func applicablePermissions(
+	ctx context.Context, ps []models.Permission,
+) []models.Permission {
+	return models.ApplicablePermissions(ps, conditionsContext(ctx))
+}
+
+// validateConditions tells why any of conditions, by permission, is malformed
+func validateConditions(conds map[string]string) error {
+	for permission, cond := range conds {
+		if err := conditions.Validate(cond); err != nil {
+			return fmt.Errorf("%s: %s", permission, err)
+		}
+	}
+	return nil
+}
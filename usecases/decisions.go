@@ -0,0 +1,34 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// Decisions define entrypoints for the decisions table, written by the
+// postgres decision log sink
+type Decisions interface {
+	Prune(time.Time) (int, error)
+	WithContext(context.Context) Decisions
+}
+
+type decisions struct {
+	repo *repositories.All
+	ctx  context.Context
+}
+
+func (ds decisions) WithContext(ctx context.Context) Decisions {
+	return &decisions{ds.repo.WithContext(ctx), ctx}
+}
+
+// Prune removes decisions logged before t and returns how many were removed
+func (ds decisions) Prune(t time.Time) (int, error) {
+	return ds.repo.Decisions.DeleteBefore(t)
+}
+
+// NewDecisions ctor
+func NewDecisions(repo *repositories.All) Decisions {
+	return &decisions{repo: repo}
+}
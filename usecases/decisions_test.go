@@ -0,0 +1,42 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func TestDecisionsPrune(t *testing.T) {
+	helpers.CleanupPG(t)
+	repo := helpers.GetRepo(t)
+	now := time.Now()
+	for _, createdAt := range []time.Time{now.Add(-48 * time.Hour), now} {
+		if err := repo.Decisions.Create(&models.Decision{
+			ServiceAccountID: uuid.Must(uuid.NewV4()).String(),
+			Permission:       "Service::RL::Do::x",
+			CreatedAt:        createdAt,
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	dsUC := helpers.GetDecisionsUseCase(t)
+	pruned, err := dsUC.Prune(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected 1 decision pruned. Got %d", pruned)
+	}
+	pruned, err = dsUC.Prune(now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected the recent decision left. Got %d pruned", pruned)
+	}
+}
@@ -0,0 +1,43 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// ExpiredGrants define entrypoints for expiring permissions and role bindings
+type ExpiredGrants interface {
+	Sweep() ([]models.ExpiredGrant, error)
+	WithContext(context.Context) ExpiredGrants
+}
+
+type expiredGrants struct {
+	repo *repositories.All
+	ctx  context.Context
+}
+
+func (egs expiredGrants) WithContext(ctx context.Context) ExpiredGrants {
+	return &expiredGrants{egs.repo.WithContext(ctx), ctx}
+}
+
+// Sweep removes all permissions and role bindings past their expiresAt
+// and returns what was removed
+func (egs expiredGrants) Sweep() ([]models.ExpiredGrant, error) {
+	var egSl []models.ExpiredGrant
+	err := egs.repo.WithPGTx(egs.ctx, func(repo *repositories.All) error {
+		var err error
+		egSl, err = repo.ExpiredGrants.Sweep()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return egSl, nil
+}
+
+// NewExpiredGrants ctor
+func NewExpiredGrants(repo *repositories.All) ExpiredGrants {
+	return &expiredGrants{repo: repo}
+}
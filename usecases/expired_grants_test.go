@@ -0,0 +1,107 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestExpiredGrantsSweep(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	ps, err := models.BuildPermissions([]string{
+		"Service1::RL::Do1::x::*", "Service1::RL::Do2::x::*",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ps[0].ExpiresAt = pg.NullTime{Time: time.Now().Add(-time.Second)}
+	ps[1].ExpiresAt = pg.NullTime{Time: time.Now().Add(time.Hour)}
+	sa := &usecases.ServiceAccountWithNested{
+		Name:               "sa1",
+		Email:              "sa1@domain.com",
+		Permissions:        ps,
+		AuthenticationType: models.AuthenticationTypes.OAuth2,
+	}
+	if err := saUC.CreateWithNested(sa); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	has, err := saUC.HasPermissionString(sa.ID, "Service1::RL::Do1::x::y")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected expired permission not to be granted")
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Service1::RL::Do2::x::y")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected non expired permission to be granted")
+	}
+	egs, err := helpers.GetExpiredGrantsUseCase(t).Sweep()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(egs) != 1 {
+		t.Fatalf("Expected 1 expired grant. Got %d", len(egs))
+	}
+	if egs[0].Kind != models.ExpiredGrantKinds.Permission {
+		t.Errorf("Expected kind to be permission. Got %s", egs[0].Kind)
+	}
+	if egs[0].Permission != "Service1::RL::Do1::x::*" {
+		t.Errorf("Expected permission to be Service1::RL::Do1::x::*. Got %s", egs[0].Permission)
+	}
+	remaining, err := saUC.GetPermissions(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(remaining) != 1 {
+		t.Errorf("Expected 1 remaining permission. Got %d", len(remaining))
+	}
+}
+
+func TestRoleBindingExpiration(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa := &models.ServiceAccount{Name: "sa1", Email: "sa1@domain.com"}
+	if err := saUC.Create(sa); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ps, err := models.BuildPermissions([]string{"Service1::RL::Do1::*"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rwn := &usecases.RoleWithNested{
+		Name:               "on-call",
+		Permissions:        ps,
+		ServiceAccountsIDs: []string{sa.ID},
+		ServiceAccountsExpiresAt: map[string]time.Time{
+			sa.ID: time.Now().Add(-time.Second),
+		},
+	}
+	if err := helpers.GetRolesUseCase(t).Create(rwn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	has, err := saUC.HasPermissionString(sa.ID, "Service1::RL::Do1::x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected permission through expired role binding not to be granted")
+	}
+	egs, err := helpers.GetExpiredGrantsUseCase(t).Sweep()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(egs) != 1 || egs[0].Kind != models.ExpiredGrantKinds.RoleBinding {
+		t.Fatalf("Expected 1 expired role binding. Got %#v", egs)
+	}
+}
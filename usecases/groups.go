@@ -0,0 +1,335 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// Groups define entrypoints for Group actions
+type Groups interface {
+	AddMember(string, string) error
+	Create(*GroupWithNested) error
+	Delete(string) error
+	Get(string) (map[string]interface{}, error)
+	List(*repositories.ListOptions) ([]models.Group, int64, error)
+	RemoveMember(string, string) error
+	RolesIDs(string) ([]string, error)
+	Search(string, *repositories.ListOptions) ([]models.Group, int64, error)
+	Update(*GroupWithNested) error
+	WithContext(context.Context) Groups
+}
+
+type groups struct {
+	repo *repositories.All
+	ctx  context.Context
+}
+
+func (gs groups) WithContext(ctx context.Context) Groups {
+	return &groups{gs.repo.WithContext(ctx), ctx}
+}
+
+// GroupWithNested is the required data to create or update a group
+type GroupWithNested struct {
+	ID                 string   `json:"-"`
+	Name               string   `json:"name"`
+	ServiceAccountsIDs []string `json:"serviceAccountsIds"`
+	IncludedGroupsIDs  []string `json:"includedGroupsIds"`
+}
+
+// Validate GroupWithNested fields
+func (gwn GroupWithNested) Validate() models.Validation {
+	v := &models.Validation{}
+	if gwn.Name == "" {
+		v.AddError("name", "required")
+	}
+	return *v
+}
+
+func (gs groups) Create(gwn *GroupWithNested) error {
+	return gs.repo.WithPGTx(gs.ctx, func(repo *repositories.All) error {
+		if err := checkGroupNameNotTaken(repo, gwn.Name, ""); err != nil {
+			return err
+		}
+		g := &models.Group{Name: gwn.Name}
+		if err := repo.Groups.Create(g); err != nil {
+			return err
+		}
+		gwn.ID = g.ID
+		if err := replaceGroupMembers(
+			repo, g.ID, gwn.ServiceAccountsIDs,
+		); err != nil {
+			return err
+		}
+		if err := setGroupInclusions(
+			repo, g.ID, gwn.IncludedGroupsIDs,
+		); err != nil {
+			return err
+		}
+		after, err := getGroupAuditState(repo, g.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(gs.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.CreateGroup,
+			TargetType: models.AuditTargetTypes.Group,
+			TargetID:   g.ID,
+		}, nil, after)
+	})
+}
+
+func (gs groups) Update(gwn *GroupWithNested) error {
+	return gs.repo.WithPGTx(gs.ctx, func(repo *repositories.All) error {
+		before, err := getGroupAuditState(repo, gwn.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkGroupNameNotTaken(repo, gwn.Name, gwn.ID); err != nil {
+			return err
+		}
+		if err := repo.Groups.Update(
+			&models.Group{ID: gwn.ID, Name: gwn.Name},
+		); err != nil {
+			return err
+		}
+		if err := replaceGroupMembers(
+			repo, gwn.ID, gwn.ServiceAccountsIDs,
+		); err != nil {
+			return err
+		}
+		if err := setGroupInclusions(
+			repo, gwn.ID, gwn.IncludedGroupsIDs,
+		); err != nil {
+			return err
+		}
+		after, err := getGroupAuditState(repo, gwn.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(gs.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.UpdateGroup,
+			TargetType: models.AuditTargetTypes.Group,
+			TargetID:   gwn.ID,
+		}, before, after)
+	})
+}
+
+// Delete removes a group, its members lose the roles bound to it
+func (gs groups) Delete(groupID string) error {
+	return gs.repo.WithPGTx(gs.ctx, func(repo *repositories.All) error {
+		before, err := getGroupAuditState(repo, groupID)
+		if err != nil {
+			return err
+		}
+		if err := repo.Groups.Delete(groupID); err != nil {
+			return err
+		}
+		return recordAuditEvent(gs.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.DeleteGroup,
+			TargetType: models.AuditTargetTypes.Group,
+			TargetID:   groupID,
+		}, before, nil)
+	})
+}
+
+// AddMember adds a single service account to a group, keeping the others
+func (gs groups) AddMember(groupID, serviceAccountID string) error {
+	return gs.changeMembership(
+		groupID, serviceAccountID, models.AuditActions.AddGroupMember,
+		func(repo *repositories.All) error {
+			if _, err := repo.ServiceAccounts.Get(serviceAccountID); err != nil {
+				return err
+			}
+			return repo.Groups.AddMember(groupID, serviceAccountID)
+		},
+	)
+}
+
+// RemoveMember removes a single service account from a group
+func (gs groups) RemoveMember(groupID, serviceAccountID string) error {
+	return gs.changeMembership(
+		groupID, serviceAccountID, models.AuditActions.RemoveGroupMember,
+		func(repo *repositories.All) error {
+			return repo.Groups.RemoveMember(groupID, serviceAccountID)
+		},
+	)
+}
+
+func (gs groups) changeMembership(
+	groupID, serviceAccountID string, action models.AuditAction,
+	fn func(*repositories.All) error,
+) error {
+	return gs.repo.WithPGTx(gs.ctx, func(repo *repositories.All) error {
+		before, err := getGroupAuditState(repo, groupID)
+		if err != nil {
+			return err
+		}
+		if err := fn(repo); err != nil {
+			return err
+		}
+		after, err := getGroupAuditState(repo, groupID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(gs.ctx, repo, &models.AuditEvent{
+			Action:     action,
+			TargetType: models.AuditTargetTypes.Group,
+			TargetID:   groupID,
+		}, before, after)
+	})
+}
+
+// checkGroupNameNotTaken fails with *errors.GroupNameTakenError if a group
+// other than groupID is named name
+func checkGroupNameNotTaken(
+	repo *repositories.All, name, groupID string,
+) error {
+	gSl, err := repo.Groups.ForName(name)
+	if err != nil {
+		return err
+	}
+	for _, g := range gSl {
+		if g.ID != groupID {
+			return errors.NewGroupNameTakenError(name)
+		}
+	}
+	return nil
+}
+
+// replaceGroupMembers makes serviceAccountsIDs the direct members of groupID
+func replaceGroupMembers(
+	repo *repositories.All, groupID string, serviceAccountsIDs []string,
+) error {
+	if err := repo.Groups.DropMembers(groupID); err != nil {
+		return err
+	}
+	for _, saID := range serviceAccountsIDs {
+		if _, err := repo.ServiceAccounts.Get(saID); err != nil {
+			return err
+		}
+		if err := repo.Groups.AddMember(groupID, saID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setGroupInclusions makes groupID include exactly includedGroupsIDs. Groups
+// must not include themselves, directly or not, so including a group that
+// includes groupID fails with *errors.GroupInclusionCycleError. repo must be
+// a transaction, inclusions are locked until it ends so concurrent updates
+// can't form a cycle together
+func setGroupInclusions(
+	repo *repositories.All, groupID string, includedGroupsIDs []string,
+) error {
+	if err := repo.Groups.LockInclusions(); err != nil {
+		return err
+	}
+	if err := repo.Groups.DropInclusions(groupID); err != nil {
+		return err
+	}
+	for _, includedGroupID := range includedGroupsIDs {
+		if _, err := repo.Groups.Get(includedGroupID); err != nil {
+			return err
+		}
+		if includedGroupID == groupID {
+			return errors.NewGroupInclusionCycleError(groupID, includedGroupID)
+		}
+		includes, err := repo.Groups.Includes(includedGroupID, groupID)
+		if err != nil {
+			return err
+		}
+		if includes {
+			return errors.NewGroupInclusionCycleError(groupID, includedGroupID)
+		}
+		if err := repo.Groups.Include(groupID, includedGroupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gs groups) Get(id string) (map[string]interface{}, error) {
+	g, err := gs.repo.Groups.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := gs.repo.Groups.Members(id)
+	if err != nil {
+		return nil, err
+	}
+	sasFiltered := make([]map[string]interface{}, len(members))
+	for i, sa := range members {
+		sasFiltered[i] = map[string]interface{}{
+			"id":      sa.ID,
+			"name":    sa.Name,
+			"picture": sa.Picture,
+			"email":   sa.Email,
+		}
+	}
+	included, err := gs.repo.Groups.Included(id)
+	if err != nil {
+		return nil, err
+	}
+	includedGroups := make([]map[string]interface{}, len(included))
+	for i, ig := range included {
+		includedGroups[i] = map[string]interface{}{"id": ig.ID, "name": ig.Name}
+	}
+	rsSl, err := gs.repo.Groups.Roles(id)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]map[string]interface{}, len(rsSl))
+	for i, r := range rsSl {
+		roles[i] = map[string]interface{}{"id": r.ID, "name": r.Name}
+	}
+	return map[string]interface{}{
+		"id":              g.ID,
+		"name":            g.Name,
+		"includedGroups":  includedGroups,
+		"roles":           roles,
+		"serviceAccounts": sasFiltered,
+	}, nil
+}
+
+// RolesIDs returns the ids of roles groupID members get through it, bound to
+// it or to groups including it
+func (gs groups) RolesIDs(groupID string) ([]string, error) {
+	return gs.repo.Groups.RolesIDs(groupID)
+}
+
+func (gs groups) List(
+	lo *repositories.ListOptions,
+) ([]models.Group, int64, error) {
+	gSl, err := gs.repo.Groups.List(lo)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := gs.repo.Groups.ListCount()
+	if err != nil {
+		return nil, 0, err
+	}
+	return gSl, count, nil
+}
+
+// Search over Groups names
+func (gs groups) Search(
+	term string, lo *repositories.ListOptions,
+) ([]models.Group, int64, error) {
+	gSl, err := gs.repo.Groups.Search(term, lo)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := gs.repo.Groups.SearchCount(term)
+	if err != nil {
+		return nil, 0, err
+	}
+	return gSl, count, nil
+}
+
+// NewGroups ctor
+func NewGroups(repo *repositories.All) Groups {
+	return &groups{repo: repo}
+}
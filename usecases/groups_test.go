@@ -0,0 +1,130 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestGroupsGrantBoundRolesToMembers(t *testing.T) {
+	helpers.CleanupPG(t)
+	gsUC := helpers.GetGroupsUseCase(t)
+	rsUC := helpers.GetRolesUseCase(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	team := &usecases.GroupWithNested{
+		Name: "team", ServiceAccountsIDs: []string{sa.ID},
+	}
+	if err := gsUC.Create(team); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	eng := &usecases.GroupWithNested{
+		Name: "eng", IncludedGroupsIDs: []string{team.ID},
+	}
+	if err := gsUC.Create(eng); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pStr := "Maestro::RL::ListSchedulers::*"
+	p, err := models.BuildPermission(pStr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// bound to eng, which team members are in through team
+	viewer := &usecases.RoleWithNested{
+		Name:        "viewer",
+		Permissions: []models.Permission{p},
+		GroupsIDs:   []string{eng.ID},
+	}
+	if err := rsUC.Create(viewer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	has, err := saUC.HasPermissionString(sa.ID, "Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected permission to be granted through groups")
+	}
+	ps, err := saUC.GetPermissions(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := false
+	for _, p := range ps {
+		found = found || p.String() == pStr
+	}
+	if !found {
+		t.Errorf("Expected permissions to include %s. Got %v", pStr, ps)
+	}
+	repo := helpers.GetRepo(t)
+	saSl, err := repo.ServiceAccounts.ListWithPermission(
+		&repositories.ListOptions{PageSize: 10}, p,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(saSl) != 1 || saSl[0].ID != sa.ID {
+		t.Errorf("Expected to list %s through groups. Got %v", sa.ID, saSl)
+	}
+	pe, err := saUC.ExplainPermission(sa.ID, p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	explained := false
+	for _, re := range pe.Roles {
+		explained = explained || (re.ID == viewer.ID && re.BoundThroughGroup == eng.ID)
+	}
+	if !pe.Allowed || !explained {
+		t.Errorf("Expected viewer to be explained through eng. Got %#v", pe)
+	}
+
+	team.IncludedGroupsIDs = []string{eng.ID}
+	err = gsUC.Update(team)
+	if _, ok := err.(*errors.GroupInclusionCycleError); !ok {
+		t.Errorf("Expected GroupInclusionCycleError. Got %v", err)
+	}
+	err = gsUC.Create(&usecases.GroupWithNested{Name: "TEAM"})
+	if _, ok := err.(*errors.GroupNameTakenError); !ok {
+		t.Errorf("Expected GroupNameTakenError. Got %v", err)
+	}
+
+	if err := gsUC.RemoveMember(team.ID, sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected permission to be lost with membership")
+	}
+	if err := gsUC.AddMember(eng.ID, sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected permission to be granted to direct member")
+	}
+	if err := gsUC.Delete(eng.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected permission to be lost with the group")
+	}
+}
@@ -2,6 +2,7 @@ package usecases
 
 import (
 	"context"
+	"time"
 
 	"github.com/topfreegames/Will.IAM/models"
 	"github.com/topfreegames/Will.IAM/repositories"
@@ -19,18 +20,40 @@ type Permissions interface {
 
 // PermissionsAttribute are used in PUT /permissions/attribute
 type PermissionsAttribute struct {
-	RolesIDs           []string            `json:"rolesIds"`
-	PermissionsStrings []string            `json:"permissions"`
-	PermissionsAliases map[string]string   `json:"permissionsAliases"`
-	Permissions        []models.Permission `json:"-"`
+	RolesIDs              []string             `json:"rolesIds"`
+	PermissionsStrings    []string             `json:"permissions"`
+	PermissionsAliases    map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+	Permissions           []models.Permission  `json:"-"`
 }
 
 // PermissionsAttributeToEmails are used in PUT /permissions/attribute_to_emails
 type PermissionsAttributeToEmails struct {
-	Emails             []string            `json:"emails"`
-	PermissionsStrings []string            `json:"permissions"`
-	PermissionsAliases map[string]string   `json:"permissionsAliases"`
-	Permissions        []models.Permission `json:"-"`
+	Emails                []string             `json:"emails"`
+	PermissionsStrings    []string             `json:"permissions"`
+	PermissionsAliases    map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt  map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string    `json:"permissionsConditions"`
+	Permissions           []models.Permission  `json:"-"`
+}
+
+// Validate PermissionsAttribute fields
+func (pa PermissionsAttribute) Validate() models.Validation {
+	v := &models.Validation{}
+	if err := validateConditions(pa.PermissionsConditions); err != nil {
+		v.AddError("permissionsConditions", err.Error())
+	}
+	return *v
+}
+
+// Validate PermissionsAttributeToEmails fields
+func (pa PermissionsAttributeToEmails) Validate() models.Validation {
+	v := &models.Validation{}
+	if err := validateConditions(pa.PermissionsConditions); err != nil {
+		v.AddError("permissionsConditions", err.Error())
+	}
+	return *v
 }
 
 type permissions struct {
@@ -47,27 +70,61 @@ func (ps permissions) Get(id string) (*models.Permission, error) {
 }
 
 func (ps permissions) Delete(id string) error {
-	return ps.repo.Permissions.Delete(id)
+	return ps.repo.WithPGTx(ps.ctx, func(repo *repositories.All) error {
+		p, err := repo.Permissions.Get(id)
+		if err != nil {
+			return err
+		}
+		if err := repo.Permissions.Delete(id); err != nil {
+			return err
+		}
+		return recordAuditEvent(ps.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.DeletePermission,
+			TargetType: models.AuditTargetTypes.Permission,
+			TargetID:   id,
+		}, p, nil)
+	})
 }
 
 func (ps permissions) Create(p *models.Permission) error {
-	return ps.repo.Permissions.Create(p)
+	return ps.repo.WithPGTx(ps.ctx, func(repo *repositories.All) error {
+		return createRolePermission(ps.ctx, repo, p)
+	})
 }
 
+// Attribute grants pa permissions to every role in pa, recording an audit
+// event per role
 func (ps permissions) Attribute(pa *PermissionsAttribute) error {
 	return ps.repo.WithPGTx(ps.ctx, func(repo *repositories.All) error {
 		for _, roleID := range pa.RolesIDs {
+			before, err := getRoleAuditState(repo, roleID)
+			if err != nil {
+				return err
+			}
 			for _, permission := range pa.Permissions {
 				permission.RoleID = roleID
 				if err := repo.Permissions.Create(&permission); err != nil {
 					return err
 				}
 			}
+			after, err := getRoleAuditState(repo, roleID)
+			if err != nil {
+				return err
+			}
+			if err := recordAuditEvent(ps.ctx, repo, &models.AuditEvent{
+				Action:     models.AuditActions.AttributePermissions,
+				TargetType: models.AuditTargetTypes.Role,
+				TargetID:   roleID,
+			}, before, after); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
 }
 
+// AttributeToEmails grants pa permissions to every service account in pa,
+// recording an audit event per service account
 func (ps permissions) AttributeToEmails(pa *PermissionsAttributeToEmails) error {
 	sas, err := ps.repo.ServiceAccounts.ForEmails(pa.Emails)
 	if err != nil {
@@ -75,12 +132,27 @@ func (ps permissions) AttributeToEmails(pa *PermissionsAttributeToEmails) error
 	}
 	return ps.repo.WithPGTx(ps.ctx, func(repo *repositories.All) error {
 		for _, sa := range sas {
+			before, err := getServiceAccountAuditState(repo, sa.ID)
+			if err != nil {
+				return err
+			}
 			for _, permission := range pa.Permissions {
 				permission.RoleID = sa.BaseRoleID
 				if err := repo.Permissions.Create(&permission); err != nil {
 					return err
 				}
 			}
+			after, err := getServiceAccountAuditState(repo, sa.ID)
+			if err != nil {
+				return err
+			}
+			if err := recordAuditEvent(ps.ctx, repo, &models.AuditEvent{
+				Action:     models.AuditActions.AttributePermissions,
+				TargetType: models.AuditTargetTypes.ServiceAccount,
+				TargetID:   sa.ID,
+			}, before, after); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
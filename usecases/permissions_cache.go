@@ -0,0 +1,196 @@
+package usecases
+
+import (
+	"sync"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// PermissionsCache keeps service accounts effective permissions in memory.
+// Entries live up to ttl, or less if a grant expires earlier, and are dropped
+// by Invalidate as permissions, role bindings, roles and groups change
+type PermissionsCache struct {
+	ttl        time.Duration
+	mu         sync.RWMutex
+	generation uint64
+	entries    map[string]*permissionsCacheEntry
+	byRole     map[string]map[string]bool
+	byGroup    map[string]map[string]bool
+}
+
+type permissionsCacheEntry struct {
+	permissions []models.Permission
+	rolesIDs    []string
+	groupsIDs   []string
+	validUntil  time.Time
+}
+
+// NewPermissionsCache PermissionsCache ctor
+func NewPermissionsCache(ttl time.Duration) *PermissionsCache {
+	return &PermissionsCache{
+		ttl:     ttl,
+		entries: map[string]*permissionsCacheEntry{},
+		byRole:  map[string]map[string]bool{},
+		byGroup: map[string]map[string]bool{},
+	}
+}
+
+// get returns serviceAccountID effective permissions, loading them from repo
+// when they aren't cached
+func (pc *PermissionsCache) get(
+	repo *repositories.All, serviceAccountID string,
+) ([]models.Permission, error) {
+	now := time.Now()
+	pc.mu.RLock()
+	e, ok := pc.entries[serviceAccountID]
+	generation := pc.generation
+	pc.mu.RUnlock()
+	if ok && now.Before(e.validUntil) {
+		return e.permissions, nil
+	}
+	ps, err := repo.Permissions.ForServiceAccount(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	rbs, err := repo.Roles.BindingsForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	gbs, err := repo.Groups.BindingsForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	boundRolesIDs := make([]string, 0, len(rbs)+len(gbs))
+	for _, rb := range rbs {
+		boundRolesIDs = append(boundRolesIDs, rb.RoleID)
+	}
+	for _, gb := range gbs {
+		boundRolesIDs = append(boundRolesIDs, gb.RoleID)
+	}
+	// changes to roles included by bound ones affect serviceAccountID too
+	rolesIDs, err := repo.Roles.IncludedIDs(boundRolesIDs)
+	if err != nil {
+		return nil, err
+	}
+	// so are changes to bindings and inclusions of its groups
+	gs, err := repo.Groups.MemberOf(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	groupsIDs := make([]string, len(gs))
+	for i := range gs {
+		groupsIDs[i] = gs[i].ID
+	}
+	e = &permissionsCacheEntry{
+		permissions: ps,
+		rolesIDs:    rolesIDs,
+		groupsIDs:   groupsIDs,
+		validUntil:  grantsValidUntil(now.Add(pc.ttl), ps, rbs),
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	// something changed while loading, what was read may already be stale
+	if generation != pc.generation {
+		return ps, nil
+	}
+	pc.remove(serviceAccountID)
+	pc.entries[serviceAccountID] = e
+	for _, roleID := range e.rolesIDs {
+		if pc.byRole[roleID] == nil {
+			pc.byRole[roleID] = map[string]bool{}
+		}
+		pc.byRole[roleID][serviceAccountID] = true
+	}
+	for _, groupID := range e.groupsIDs {
+		if pc.byGroup[groupID] == nil {
+			pc.byGroup[groupID] = map[string]bool{}
+		}
+		pc.byGroup[groupID][serviceAccountID] = true
+	}
+	return ps, nil
+}
+
+// grantsValidUntil returns the first expiration among ps and rbs, or until
+// if none expires before it
+func grantsValidUntil(
+	until time.Time, ps []models.Permission, rbs []models.RoleBinding,
+) time.Time {
+	for _, rb := range rbs {
+		if !rb.ExpiresAt.IsZero() && rb.ExpiresAt.Before(until) {
+			until = rb.ExpiresAt.Time
+		}
+	}
+	for _, p := range ps {
+		if !p.ExpiresAt.IsZero() && p.ExpiresAt.Before(until) {
+			until = p.ExpiresAt.Time
+		}
+	}
+	return until
+}
+
+// remove must be called holding pc.mu
+func (pc *PermissionsCache) remove(serviceAccountID string) {
+	e, ok := pc.entries[serviceAccountID]
+	if !ok {
+		return
+	}
+	delete(pc.entries, serviceAccountID)
+	for _, roleID := range e.rolesIDs {
+		delete(pc.byRole[roleID], serviceAccountID)
+		if len(pc.byRole[roleID]) == 0 {
+			delete(pc.byRole, roleID)
+		}
+	}
+	for _, groupID := range e.groupsIDs {
+		delete(pc.byGroup[groupID], serviceAccountID)
+		if len(pc.byGroup[groupID]) == 0 {
+			delete(pc.byGroup, groupID)
+		}
+	}
+}
+
+// Invalidate drops what change may have affected. A nil change, or one
+// without ids, drops everything
+func (pc *PermissionsCache) Invalidate(change *models.AuthorizationChange) {
+	if change == nil ||
+		(change.ServiceAccountID == "" && change.RoleID == "" &&
+			change.GroupID == "") {
+		pc.Flush()
+		return
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.generation++
+	if change.ServiceAccountID != "" {
+		pc.remove(change.ServiceAccountID)
+	}
+	if change.RoleID != "" {
+		for saID := range pc.byRole[change.RoleID] {
+			pc.remove(saID)
+		}
+	}
+	if change.GroupID != "" {
+		for saID := range pc.byGroup[change.GroupID] {
+			pc.remove(saID)
+		}
+	}
+}
+
+// Flush drops every entry
+func (pc *PermissionsCache) Flush() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.generation++
+	pc.entries = map[string]*permissionsCacheEntry{}
+	pc.byRole = map[string]map[string]bool{}
+	pc.byGroup = map[string]map[string]bool{}
+}
+
+// Len returns how many service accounts are cached
+func (pc *PermissionsCache) Len() int {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return len(pc.entries)
+}
@@ -0,0 +1,129 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/oauth2"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestPermissionsCacheInvalidation(t *testing.T) {
+	helpers.CleanupPG(t)
+	storage := helpers.GetStorage(t)
+	cache := usecases.NewPermissionsCache(time.Hour)
+	changes := make(chan *models.AuthorizationChange, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go storage.ListenAuthorizationChanges(ctx,
+		func(ac *models.AuthorizationChange) {
+			cache.Invalidate(ac)
+			changes <- ac
+		}, func(err error) { t.Log(err) },
+	)
+	waitChange := func() {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected an authorization change to be notified")
+		}
+	}
+	saUC := usecases.NewServiceAccountsWithPermissionsCache(
+		helpers.GetRepo(t), oauth2.NewProviderBlankMock(), cache,
+	).WithContext(context.Background())
+	sa, err := saUC.CreateKeyPairType("sa1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the listener may not be subscribed yet, touch the base role until a
+	// change is seen, then let the pending ones arrive
+	for subscribed := false; !subscribed; {
+		if _, err := storage.PG.DB.Exec(
+			"UPDATE roles SET name = name WHERE id = ?", sa.BaseRoleID,
+		); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-changes:
+			subscribed = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	for drained := false; !drained; {
+		select {
+		case <-changes:
+		case <-time.After(200 * time.Millisecond):
+			drained = true
+		}
+	}
+	has, err := saUC.HasPermissionString(sa.ID, "Service1::RL::Do::x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected service account not to have permission")
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("Expected 1 cached service account. Got %d", cache.Len())
+	}
+	p, err := models.BuildPermission("Service1::RL::Do::*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := saUC.CreatePermission(sa.ID, &p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitChange()
+	if cache.Len() != 0 {
+		t.Errorf("Expected permission write to invalidate the cache")
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Service1::RL::Do::x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected service account to have permission")
+	}
+	if err := helpers.GetPermissionsUseCase(t).Delete(p.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitChange()
+	has, err = saUC.HasPermissionString(sa.ID, "Service1::RL::Do::x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected deleted permission not to be granted")
+	}
+}
+
+func TestPermissionsCacheFlush(t *testing.T) {
+	helpers.CleanupPG(t)
+	cache := usecases.NewPermissionsCache(time.Hour)
+	saUC := usecases.NewServiceAccountsWithPermissionsCache(
+		helpers.GetRepo(t), oauth2.NewProviderBlankMock(), cache,
+	).WithContext(context.Background())
+	sa, err := saUC.CreateKeyPairType("sa1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := saUC.GetPermissions(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cache.Invalidate(&models.AuthorizationChange{
+		Table: "role_bindings", ServiceAccountID: "other",
+	})
+	if cache.Len() != 1 {
+		t.Errorf("Expected other service account change to keep cache")
+	}
+	cache.Invalidate(nil)
+	if cache.Len() != 0 {
+		t.Errorf("Expected nil change to flush the cache")
+	}
+}
@@ -65,7 +65,12 @@ func (prs permissionsRequests) Deny(saID, prID string) error {
 			// TODO(ghostec): replace by proper error
 			return fmt.Errorf("user isn't owner of permission")
 		}
-		return repo.PermissionsRequests.Deny(saID, prID)
+		if err := repo.PermissionsRequests.Deny(saID, prID); err != nil {
+			return err
+		}
+		return recordPermissionRequestAuditEvent(
+			prs.ctx, repo, models.AuditActions.DenyPermissionRequest, saID, pr,
+		)
 	})
 }
 
@@ -92,13 +97,38 @@ func (prs permissionsRequests) Grant(saID, prID string) error {
 			return fmt.Errorf("user isn't owner of permission")
 		}
 		p := pr.Permission()
+		if p.Expired() {
+			// TODO(ghostec): replace by proper error
+			return fmt.Errorf("permission request already expired")
+		}
 		if err := createPermissionForServiceAccount(repo, pr.ServiceAccountID, &p); err != nil {
 			return err
 		}
-		return repo.PermissionsRequests.Grant(saID, prID)
+		if err := repo.PermissionsRequests.Grant(saID, prID); err != nil {
+			return err
+		}
+		return recordPermissionRequestAuditEvent(
+			prs.ctx, repo, models.AuditActions.GrantPermissionRequest, saID, pr,
+		)
 	})
 }
 
+func recordPermissionRequestAuditEvent(
+	ctx context.Context, repo *repositories.All, action models.AuditAction,
+	saID string, before *models.PermissionRequest,
+) error {
+	after, err := repo.PermissionsRequests.Get(before.ID)
+	if err != nil {
+		return err
+	}
+	return recordAuditEvent(ctx, repo, &models.AuditEvent{
+		ActorServiceAccountID: saID,
+		Action:                action,
+		TargetType:            models.AuditTargetTypes.PermissionRequest,
+		TargetID:              before.ID,
+	}, before, after)
+}
+
 func (prs permissionsRequests) ListOpenRequestsVisibleTo(
 	lo *repositories.ListOptions, saID string,
 ) ([]models.PermissionRequest, int64, error) {
@@ -0,0 +1,672 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// Policies define entrypoints for policy as code actions
+type Policies interface {
+	Apply(*models.Policy) ([]models.PolicyChange, error)
+	Export() (*models.Policy, error)
+	IncludedRolesIDs(*models.Policy) ([]string, error)
+	Plan(*models.Policy) ([]models.PolicyChange, error)
+	WithContext(context.Context) Policies
+}
+
+type policies struct {
+	repo *repositories.All
+	ctx  context.Context
+}
+
+func (ps policies) WithContext(ctx context.Context) Policies {
+	return &policies{ps.repo.WithContext(ctx), ctx}
+}
+
+// Plan returns the changes applying p would make, without making them
+func (ps policies) Plan(p *models.Policy) ([]models.PolicyChange, error) {
+	return newPolicyReconciler(ps.ctx, ps.repo, false).reconcile(p)
+}
+
+// Apply makes stored services and roles p declares look like p, in a single
+// transaction, and returns the changes made. Services and roles p doesn't
+// declare are left as they are, as are bindings made by role binding rules
+// or through SCIM. Creating services requires an audit actor, who is given full access to
+// them
+func (ps policies) Apply(p *models.Policy) ([]models.PolicyChange, error) {
+	var changes []models.PolicyChange
+	err := ps.repo.WithPGTx(ps.ctx, func(repo *repositories.All) error {
+		var err error
+		changes, err = newPolicyReconciler(ps.ctx, repo, true).reconcile(p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// IncludedRolesIDs returns the ids of stored roles p roles include, other
+// than those p declares. Applying p grants their permissions too
+func (ps policies) IncludedRolesIDs(p *models.Policy) ([]string, error) {
+	declared := map[string]bool{}
+	for _, r := range p.Roles {
+		declared[strings.ToLower(r.Name)] = true
+	}
+	ids := []string{}
+	for _, r := range p.Roles {
+		for _, name := range r.IncludedRoles {
+			if declared[strings.ToLower(name)] {
+				continue
+			}
+			role, err := roleForName(ps.repo, name)
+			if err != nil {
+				return nil, err
+			}
+			if role == nil {
+				return nil, errors.NewEntityNotFoundError(models.Role{}, name)
+			}
+			ids = append(ids, role.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Export returns a Policy declaring every stored service and role, other
+// than base roles. Expired permissions and bindings are left out, as are
+// bindings made by role binding rules or through SCIM
+func (ps policies) Export() (*models.Policy, error) {
+	ss, err := ps.repo.Services.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].PermissionName < ss[j].PermissionName
+	})
+	p := &models.Policy{}
+	for _, s := range ss {
+		p.Services = append(p.Services, models.PolicyService{
+			Name: s.Name, PermissionName: s.PermissionName, AMURL: s.AMURL,
+		})
+	}
+	rs, err := ps.repo.Roles.List(&repositories.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rs {
+		pr, err := exportRole(ps.repo, r)
+		if err != nil {
+			return nil, err
+		}
+		p.Roles = append(p.Roles, *pr)
+	}
+	return p, nil
+}
+
+func exportRole(
+	repo *repositories.All, r models.Role,
+) (*models.PolicyRole, error) {
+	pr := &models.PolicyRole{Name: r.Name}
+	pSl, err := repo.Permissions.ForRole(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pSl, func(i, j int) bool {
+		return pSl[i].ConditionedString() < pSl[j].ConditionedString()
+	})
+	for _, p := range pSl {
+		if !p.Expired() {
+			pr.Permissions = append(pr.Permissions, models.BuildPolicyPermission(p))
+		}
+	}
+	included, err := repo.Roles.Included(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ir := range included {
+		pr.IncludedRoles = append(pr.IncludedRoles, ir.Name)
+	}
+	sort.Strings(pr.IncludedRoles)
+	emails, err := roleServiceAccountsEmails(repo, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	rbs, err := repo.Roles.Bindings(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rb := range rbs {
+		if rb.Expired() || rb.ManagedByRule || rb.ManagedBySCIM {
+			continue
+		}
+		pb := models.PolicyBinding{Email: emails[rb.ServiceAccountID]}
+		if !rb.ExpiresAt.IsZero() {
+			expiresAt := rb.ExpiresAt.Time
+			pb.ExpiresAt = &expiresAt
+		}
+		pr.ServiceAccounts = append(pr.ServiceAccounts, pb)
+	}
+	sort.Slice(pr.ServiceAccounts, func(i, j int) bool {
+		return pr.ServiceAccounts[i].Email < pr.ServiceAccounts[j].Email
+	})
+	gs, err := repo.Roles.GetGroups(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range gs {
+		pr.Groups = append(pr.Groups, g.Name)
+	}
+	sort.Strings(pr.Groups)
+	return pr, nil
+}
+
+// roleServiceAccountsEmails returns the emails of service accounts bound to
+// roleID by their ids
+func roleServiceAccountsEmails(
+	repo *repositories.All, roleID string,
+) (map[string]string, error) {
+	sas, err := repo.Roles.GetServiceAccounts(roleID)
+	if err != nil {
+		return nil, err
+	}
+	emails := map[string]string{}
+	for _, sa := range sas {
+		emails[sa.ID] = sa.Email
+	}
+	return emails, nil
+}
+
+// roleForName returns the role, other than base roles, named name
+// regardless of case, or nil if there's none
+func roleForName(repo *repositories.All, name string) (*models.Role, error) {
+	rsSl, err := repo.Roles.ForName(name)
+	if err != nil {
+		return nil, err
+	}
+	switch len(rsSl) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &rsSl[0], nil
+	}
+	return nil, errors.NewAmbiguousRoleNameError(name)
+}
+
+// policyReconciler computes the changes applying a policy makes, making
+// them as well if apply is set
+type policyReconciler struct {
+	ctx     context.Context
+	repo    *repositories.All
+	apply   bool
+	changes []models.PolicyChange
+	// rolesIDs are the ids of declared roles by lowercased name, empty for
+	// roles yet to be created
+	rolesIDs map[string]string
+}
+
+func newPolicyReconciler(
+	ctx context.Context, repo *repositories.All, apply bool,
+) *policyReconciler {
+	return &policyReconciler{
+		ctx: ctx, repo: repo, apply: apply,
+		changes: []models.PolicyChange{}, rolesIDs: map[string]string{},
+	}
+}
+
+func (pr *policyReconciler) change(
+	action models.PolicyChangeAction, kind models.PolicyChangeKind,
+	role, target string, fields ...string,
+) {
+	pr.changes = append(pr.changes, models.PolicyChange{
+		Action: action, Kind: kind, Role: role, Target: target, Fields: fields,
+	})
+}
+
+// changedRole tells if any change was made to the role named name
+func (pr *policyReconciler) changedRole(name string) bool {
+	for _, c := range pr.changes {
+		if c.Role == name ||
+			(c.Kind == models.PolicyChangeKinds.Role && c.Target == name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pr *policyReconciler) reconcile(
+	p *models.Policy,
+) ([]models.PolicyChange, error) {
+	for _, s := range p.Services {
+		if err := pr.reconcileService(s); err != nil {
+			return nil, err
+		}
+	}
+	// roles are created first so any of them can be included by the others
+	befores := make([]*roleAuditState, len(p.Roles))
+	for i, r := range p.Roles {
+		var err error
+		if befores[i], err = pr.reconcileRole(r); err != nil {
+			return nil, err
+		}
+	}
+	for i, r := range p.Roles {
+		roleID := pr.rolesIDs[strings.ToLower(r.Name)]
+		for _, reconcile := range []func(string, models.PolicyRole) error{
+			pr.reconcilePermissions,
+			pr.reconcileIncludedRoles,
+			pr.reconcileServiceAccounts,
+			pr.reconcileGroups,
+		} {
+			if err := reconcile(roleID, r); err != nil {
+				return nil, err
+			}
+		}
+		if !pr.apply || !pr.changedRole(r.Name) {
+			continue
+		}
+		if err := pr.recordRoleAuditEvent(roleID, befores[i]); err != nil {
+			return nil, err
+		}
+	}
+	return pr.changes, nil
+}
+
+func (pr *policyReconciler) reconcileService(ps models.PolicyService) error {
+	s, err := pr.repo.Services.WithPermissionName(ps.PermissionName)
+	if err != nil {
+		return err
+	}
+	if s.ID == "" {
+		pr.change(
+			models.PolicyChangeActions.Create, models.PolicyChangeKinds.Service,
+			"", ps.PermissionName,
+		)
+		if !pr.apply {
+			return nil
+		}
+		creatorID := auditCtxValue(pr.ctx, auditActorCtxKey)
+		if creatorID == "" {
+			return fmt.Errorf("creating service %s requires an actor", ps.PermissionName)
+		}
+		return createService(pr.ctx, pr.repo, &models.Service{
+			Name:                    ps.Name,
+			PermissionName:          ps.PermissionName,
+			AMURL:                   ps.AMURL,
+			CreatorServiceAccountID: creatorID,
+		})
+	}
+	fields := []string{}
+	if s.Name != ps.Name {
+		fields = append(fields, "name")
+	}
+	if s.AMURL != ps.AMURL {
+		fields = append(fields, "amUrl")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	pr.change(
+		models.PolicyChangeActions.Update, models.PolicyChangeKinds.Service,
+		"", ps.PermissionName, fields...,
+	)
+	if !pr.apply {
+		return nil
+	}
+	s.Name = ps.Name
+	s.AMURL = ps.AMURL
+	return pr.repo.Services.Update(s)
+}
+
+// reconcileRole creates or renames the role r declares, returning its audit
+// state beforehand when applying changes to a stored role
+func (pr *policyReconciler) reconcileRole(
+	r models.PolicyRole,
+) (*roleAuditState, error) {
+	role, err := roleForName(pr.repo, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	var before *roleAuditState
+	if role == nil {
+		pr.change(
+			models.PolicyChangeActions.Create, models.PolicyChangeKinds.Role,
+			"", r.Name,
+		)
+		if !pr.apply {
+			pr.rolesIDs[strings.ToLower(r.Name)] = ""
+			return nil, nil
+		}
+		role = &models.Role{Name: r.Name}
+		if err := pr.repo.Roles.Create(role); err != nil {
+			return nil, err
+		}
+		pr.rolesIDs[strings.ToLower(r.Name)] = role.ID
+		return nil, nil
+	}
+	pr.rolesIDs[strings.ToLower(r.Name)] = role.ID
+	if pr.apply {
+		if before, err = getRoleAuditState(pr.repo, role.ID); err != nil {
+			return nil, err
+		}
+	}
+	if role.Name == r.Name {
+		return before, nil
+	}
+	pr.change(
+		models.PolicyChangeActions.Update, models.PolicyChangeKinds.Role,
+		"", r.Name, "name",
+	)
+	if pr.apply {
+		role.Name = r.Name
+		if err := pr.repo.Roles.Update(role); err != nil {
+			return nil, err
+		}
+	}
+	return before, nil
+}
+
+func (pr *policyReconciler) recordRoleAuditEvent(
+	roleID string, before *roleAuditState,
+) error {
+	after, err := getRoleAuditState(pr.repo, roleID)
+	if err != nil {
+		return err
+	}
+	ae := &models.AuditEvent{
+		Action:     models.AuditActions.UpdateRole,
+		TargetType: models.AuditTargetTypes.Role,
+		TargetID:   roleID,
+	}
+	if before == nil {
+		ae.Action = models.AuditActions.CreateRole
+		return recordAuditEvent(pr.ctx, pr.repo, ae, nil, after)
+	}
+	return recordAuditEvent(pr.ctx, pr.repo, ae, before, after)
+}
+
+// reconcilePermissions replaces roleID permissions by those r declares if
+// any of them differs
+func (pr *policyReconciler) reconcilePermissions(
+	roleID string, r models.PolicyRole,
+) error {
+	stored := []models.Permission{}
+	if roleID != "" {
+		var err error
+		if stored, err = pr.repo.Permissions.ForRole(roleID); err != nil {
+			return err
+		}
+	}
+	storedByString := map[string]models.Permission{}
+	for _, p := range stored {
+		storedByString[p.ConditionedString()] = p
+	}
+	changed := false
+	declared := make([]models.Permission, len(r.Permissions))
+	for i, pp := range r.Permissions {
+		p, err := pp.Build()
+		if err != nil {
+			return err
+		}
+		declared[i] = p
+		s, ok := storedByString[p.ConditionedString()]
+		if !ok {
+			pr.change(
+				models.PolicyChangeActions.Create,
+				models.PolicyChangeKinds.Permission, r.Name, p.ConditionedString(),
+			)
+			changed = true
+			continue
+		}
+		delete(storedByString, p.ConditionedString())
+		if fields := permissionChangedFields(s, p); len(fields) > 0 {
+			pr.change(
+				models.PolicyChangeActions.Update,
+				models.PolicyChangeKinds.Permission, r.Name, p.ConditionedString(),
+				fields...,
+			)
+			changed = true
+		}
+	}
+	for _, p := range stored {
+		if _, ok := storedByString[p.ConditionedString()]; ok {
+			pr.change(
+				models.PolicyChangeActions.Delete,
+				models.PolicyChangeKinds.Permission, r.Name, p.ConditionedString(),
+			)
+			changed = true
+		}
+	}
+	if !pr.apply || !changed {
+		return nil
+	}
+	if err := pr.repo.Roles.DropPermissions(roleID); err != nil {
+		return err
+	}
+	for i := range declared {
+		declared[i].RoleID = roleID
+		if err := createPermission(pr.repo, &declared[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permissionChangedFields lists what differs from stored to declared, both
+// being the same permission under the same condition
+func permissionChangedFields(stored, declared models.Permission) []string {
+	fields := []string{}
+	if stored.Alias != declared.Alias {
+		fields = append(fields, "alias")
+	}
+	if !stored.ExpiresAt.Time.Equal(declared.ExpiresAt.Time) {
+		fields = append(fields, "expiresAt")
+	}
+	return fields
+}
+
+// reconcileIncludedRoles makes roleID include exactly the roles r declares
+func (pr *policyReconciler) reconcileIncludedRoles(
+	roleID string, r models.PolicyRole,
+) error {
+	stored := []models.Role{}
+	if roleID != "" {
+		var err error
+		if stored, err = pr.repo.Roles.Included(roleID); err != nil {
+			return err
+		}
+	}
+	storedIDs := map[string]bool{}
+	for _, ir := range stored {
+		storedIDs[ir.ID] = true
+	}
+	changed := false
+	declaredIDs := map[string]bool{}
+	ids := make([]string, len(r.IncludedRoles))
+	for i, name := range r.IncludedRoles {
+		id, err := pr.roleID(name)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+		declaredIDs[id] = true
+		if id == "" || !storedIDs[id] {
+			pr.change(
+				models.PolicyChangeActions.Create,
+				models.PolicyChangeKinds.IncludedRole, r.Name, name,
+			)
+			changed = true
+		}
+	}
+	for _, ir := range stored {
+		if !declaredIDs[ir.ID] {
+			pr.change(
+				models.PolicyChangeActions.Delete,
+				models.PolicyChangeKinds.IncludedRole, r.Name, ir.Name,
+			)
+			changed = true
+		}
+	}
+	if !pr.apply || !changed {
+		return nil
+	}
+	return setRoleInclusions(pr.repo, roleID, ids)
+}
+
+// roleID returns the id of the role named name, declared or stored. It's
+// empty for declared roles yet to be created
+func (pr *policyReconciler) roleID(name string) (string, error) {
+	if id, ok := pr.rolesIDs[strings.ToLower(name)]; ok {
+		return id, nil
+	}
+	role, err := roleForName(pr.repo, name)
+	if err != nil {
+		return "", err
+	}
+	if role == nil {
+		return "", errors.NewEntityNotFoundError(models.Role{}, name)
+	}
+	return role.ID, nil
+}
+
+// reconcileServiceAccounts binds roleID to exactly the service accounts r
+// declares, leaving bindings made by role binding rules or through SCIM alone
+func (pr *policyReconciler) reconcileServiceAccounts(
+	roleID string, r models.PolicyRole,
+) error {
+	stored := []models.RoleBinding{}
+	emails := map[string]string{}
+	if roleID != "" {
+		var err error
+		if stored, err = pr.repo.Roles.Bindings(roleID); err != nil {
+			return err
+		}
+		if emails, err = roleServiceAccountsEmails(pr.repo, roleID); err != nil {
+			return err
+		}
+	}
+	storedBySA := map[string]models.RoleBinding{}
+	for _, rb := range stored {
+		storedBySA[rb.ServiceAccountID] = rb
+	}
+	declared := map[string]bool{}
+	for _, pb := range r.ServiceAccounts {
+		sa, err := pr.repo.ServiceAccounts.ForEmail(pb.Email)
+		if err != nil {
+			return err
+		}
+		declared[sa.ID] = true
+		rb := &models.RoleBinding{RoleID: roleID, ServiceAccountID: sa.ID}
+		if pb.ExpiresAt != nil {
+			rb.ExpiresAt = pg.NullTime{Time: *pb.ExpiresAt}
+		}
+		s, ok := storedBySA[sa.ID]
+		switch {
+		case !ok:
+			pr.change(
+				models.PolicyChangeActions.Create,
+				models.PolicyChangeKinds.ServiceAccount, r.Name, pb.Email,
+			)
+		case !s.ExpiresAt.Time.Equal(rb.ExpiresAt.Time):
+			pr.change(
+				models.PolicyChangeActions.Update,
+				models.PolicyChangeKinds.ServiceAccount, r.Name, pb.Email,
+				"expiresAt",
+			)
+			rb.ManagedByRule = s.ManagedByRule
+			rb.ManagedBySCIM = s.ManagedBySCIM
+		default:
+			continue
+		}
+		if !pr.apply {
+			continue
+		}
+		if err := pr.repo.Roles.Unbind(roleID, sa.ID); err != nil {
+			return err
+		}
+		if err := pr.repo.Roles.Bind(rb); err != nil {
+			return err
+		}
+	}
+	for _, rb := range stored {
+		if declared[rb.ServiceAccountID] || rb.ManagedByRule || rb.ManagedBySCIM {
+			continue
+		}
+		// service accounts bound more than once are unbound at once
+		declared[rb.ServiceAccountID] = true
+		pr.change(
+			models.PolicyChangeActions.Delete,
+			models.PolicyChangeKinds.ServiceAccount, r.Name,
+			emails[rb.ServiceAccountID],
+		)
+		if !pr.apply {
+			continue
+		}
+		if err := pr.repo.Roles.Unbind(roleID, rb.ServiceAccountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileGroups binds roleID to exactly the groups r declares
+func (pr *policyReconciler) reconcileGroups(
+	roleID string, r models.PolicyRole,
+) error {
+	stored := []models.Group{}
+	if roleID != "" {
+		var err error
+		if stored, err = pr.repo.Roles.GetGroups(roleID); err != nil {
+			return err
+		}
+	}
+	storedIDs := map[string]bool{}
+	for _, g := range stored {
+		storedIDs[g.ID] = true
+	}
+	changed := false
+	declaredIDs := map[string]bool{}
+	ids := make([]string, len(r.Groups))
+	for i, name := range r.Groups {
+		gSl, err := pr.repo.Groups.ForName(name)
+		if err != nil {
+			return err
+		}
+		if len(gSl) == 0 {
+			return errors.NewEntityNotFoundError(models.Group{}, name)
+		}
+		ids[i] = gSl[0].ID
+		declaredIDs[gSl[0].ID] = true
+		if !storedIDs[gSl[0].ID] {
+			pr.change(
+				models.PolicyChangeActions.Create,
+				models.PolicyChangeKinds.Group, r.Name, gSl[0].Name,
+			)
+			changed = true
+		}
+	}
+	for _, g := range stored {
+		if !declaredIDs[g.ID] {
+			pr.change(
+				models.PolicyChangeActions.Delete,
+				models.PolicyChangeKinds.Group, r.Name, g.Name,
+			)
+			changed = true
+		}
+	}
+	if !pr.apply || !changed {
+		return nil
+	}
+	return setRoleGroups(pr.repo, roleID, ids)
+}
+
+// NewPolicies ctor
+func NewPolicies(repo *repositories.All) Policies {
+	return &policies{repo: repo}
+}
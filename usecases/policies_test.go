@@ -0,0 +1,207 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestPoliciesApply(t *testing.T) {
+	helpers.CleanupPG(t)
+	repo := helpers.GetRepo(t)
+	psUC := helpers.GetPoliciesUseCase(t)
+	rsUC := helpers.GetRolesUseCase(t)
+	gsUC := helpers.GetGroupsUseCase(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	viewers := &usecases.RoleWithNested{Name: "viewers"}
+	if err := rsUC.Create(viewers); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sre := &usecases.GroupWithNested{Name: "sre"}
+	if err := gsUC.Create(sre); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	policy := &models.Policy{
+		Roles: []models.PolicyRole{
+			models.PolicyRole{
+				Name: "operators",
+				Permissions: []models.PolicyPermission{
+					models.PolicyPermission{
+						Permission: "Maestro::RL::Deploy::*", Alias: "Deploy",
+					},
+				},
+				IncludedRoles:   []string{"viewers"},
+				ServiceAccounts: []models.PolicyBinding{{Email: sa.Email}},
+				Groups:          []string{"sre"},
+			},
+		},
+	}
+	expected := []models.PolicyChange{
+		{Action: "create", Kind: "role", Target: "operators"},
+		{
+			Action: "create", Kind: "permission", Role: "operators",
+			Target: "Maestro::RL::Deploy::*",
+		},
+		{
+			Action: "create", Kind: "includedRole", Role: "operators",
+			Target: "viewers",
+		},
+		{
+			Action: "create", Kind: "serviceAccount", Role: "operators",
+			Target: sa.Email,
+		},
+		{Action: "create", Kind: "group", Role: "operators", Target: "sre"},
+	}
+
+	changes, err := psUC.Plan(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected changes %v. Got %v", expected, changes)
+	}
+	rsSl, err := repo.Roles.ForName("operators")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rsSl) != 0 {
+		t.Fatalf("Expected plan not to create roles")
+	}
+
+	changes, err = psUC.Apply(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected changes %v. Got %v", expected, changes)
+	}
+	has, err := saUC.HasPermissionString(sa.ID, "Maestro::RL::Deploy::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected permission to be granted by the applied policy")
+	}
+
+	changes, err = psUC.Plan(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected no changes after applying. Got %v", changes)
+	}
+
+	exported, err := psUC.Export()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	changes, err = psUC.Plan(exported)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected no changes from an export. Got %v", changes)
+	}
+
+	policy.Roles[0].Permissions[0].Alias = "Deploy anything"
+	policy.Roles[0].ServiceAccounts = nil
+	policy.Roles[0].Groups = nil
+	changes, err = psUC.Apply(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = []models.PolicyChange{
+		{
+			Action: "update", Kind: "permission", Role: "operators",
+			Target: "Maestro::RL::Deploy::*", Fields: []string{"alias"},
+		},
+		{
+			Action: "delete", Kind: "serviceAccount", Role: "operators",
+			Target: sa.Email,
+		},
+		{Action: "delete", Kind: "group", Role: "operators", Target: "sre"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected changes %v. Got %v", expected, changes)
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Maestro::RL::Deploy::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected permission to be revoked by the applied policy")
+	}
+}
+
+func TestPoliciesApplyIsAtomic(t *testing.T) {
+	helpers.CleanupPG(t)
+	repo := helpers.GetRepo(t)
+	psUC := helpers.GetPoliciesUseCase(t)
+	policy := &models.Policy{
+		Roles: []models.PolicyRole{
+			models.PolicyRole{Name: "operators"},
+			models.PolicyRole{
+				Name:            "viewers",
+				ServiceAccounts: []models.PolicyBinding{{Email: "nobody@test.com"}},
+			},
+		},
+	}
+	_, err := psUC.Apply(policy)
+	if _, ok := err.(*errors.EntityNotFoundError); !ok {
+		t.Fatalf("Expected EntityNotFoundError. Got %v", err)
+	}
+	rsSl, err := repo.Roles.ForName("operators")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rsSl) != 0 {
+		t.Errorf("Expected no role to be created when applying fails")
+	}
+}
+
+func TestPoliciesApplyKeepsRuleBindings(t *testing.T) {
+	helpers.CleanupPG(t)
+	repo := helpers.GetRepo(t)
+	psUC := helpers.GetPoliciesUseCase(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	policy := &models.Policy{
+		Roles: []models.PolicyRole{models.PolicyRole{Name: "engineering"}},
+	}
+	if _, err := psUC.Apply(policy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rsSl, err := repo.Roles.ForName("engineering")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.Roles.Bind(&models.RoleBinding{
+		RoleID: rsSl[0].ID, ServiceAccountID: sa.ID, ManagedByRule: true,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	changes, err := psUC.Apply(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected bindings made by rules to be left alone. Got %v", changes)
+	}
+	rbs, err := repo.Roles.Bindings(rsSl[0].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rbs) != 1 {
+		t.Errorf("Expected binding made by rule to be kept. Got %v", rbs)
+	}
+}
@@ -0,0 +1,91 @@
+// +build integration
+
+package usecases_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/oauth2"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func TestServiceAccountsApplyRoleBindingRules(t *testing.T) {
+	helpers.CleanupPG(t)
+	rsUC := helpers.GetRolesUseCase(t)
+	rolesIDs := map[string]string{}
+	for _, name := range []string{"engineering", "oncall", "contractors", "manual"} {
+		rwn := &usecases.RoleWithNested{Name: name}
+		if err := rsUC.Create(rwn); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rolesIDs[name] = rwn.ID
+	}
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@eng.test.com", models.AuthenticationTypes.OAuth2,
+	)
+	repo := helpers.GetRepo(t)
+	if err := repo.Roles.Bind(&models.RoleBinding{
+		RoleID: rolesIDs["manual"], ServiceAccountID: sa.ID,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saUC := usecases.NewServiceAccountsWithOptions(
+		repo, oauth2.NewProviderBlankMock(), usecases.ServiceAccountsOptions{
+			RoleBindingRules: []models.RoleBindingRule{
+				{Role: "engineering", Emails: []string{"*@eng.test.com"}},
+				{Role: "oncall", Groups: []string{"OnCall"}},
+				{Role: "manual", Groups: []string{"oncall"}},
+				{Role: "contractors", Emails: []string{"*@contractor.test.com"}},
+				{Role: "missing", Groups: []string{"oncall"}},
+			},
+		},
+	).WithContext(context.Background())
+	type testCase struct {
+		groups []string
+		roles  []string
+	}
+	testCases := []testCase{
+		{[]string{"oncall"}, []string{"engineering", "manual", "oncall"}},
+		{[]string{"oncall"}, []string{"engineering", "manual", "oncall"}},
+		// manual bindings stay even when rules don't match anymore
+		{[]string{}, []string{"engineering", "manual"}},
+	}
+	for _, tt := range testCases {
+		if err := saUC.ApplyRoleBindingRules(sa.ID, tt.groups); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rs, err := saUC.GetRoles(sa.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		names := []string{}
+		for _, r := range rs {
+			if !r.IsBaseRole {
+				names = append(names, r.Name)
+			}
+		}
+		sort.Strings(names)
+		if len(names) != len(tt.roles) {
+			t.Fatalf("Expected roles %v for groups %v. Got %v", tt.roles, tt.groups, names)
+		}
+		for i := range names {
+			if names[i] != tt.roles[i] {
+				t.Errorf("Expected roles %v for groups %v. Got %v", tt.roles, tt.groups, names)
+				break
+			}
+		}
+	}
+	rbs, err := repo.Roles.BindingsForServiceAccountID(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, rb := range rbs {
+		if managed := rb.RoleID == rolesIDs["engineering"]; rb.ManagedByRule != managed {
+			t.Errorf("Expected binding to %s to be managed by rule %v", rb.RoleID, managed)
+		}
+	}
+}
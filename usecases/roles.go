@@ -2,7 +2,10 @@ package usecases
 
 import (
 	"context"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
 	"github.com/topfreegames/Will.IAM/repositories"
 )
@@ -44,20 +47,100 @@ func (rs roles) Create(rwn *RoleWithNested) error {
 			}
 		}
 		for i := range rwn.ServiceAccountsIDs {
-			if err := repo.Roles.Bind(&models.RoleBinding{
-				RoleID:           role.ID,
-				ServiceAccountID: rwn.ServiceAccountsIDs[i],
-			}); err != nil {
+			if err := repo.Roles.Bind(rwn.buildRoleBinding(
+				role.ID, rwn.ServiceAccountsIDs[i],
+			)); err != nil {
 				return err
 			}
 		}
-		return nil
+		if err := setRoleGroups(repo, role.ID, rwn.GroupsIDs); err != nil {
+			return err
+		}
+		return setRoleInclusions(repo, role.ID, rwn.IncludedRolesIDs)
 	})
 }
 
+// setRoleGroups binds exactly groupsIDs to roleID
+func setRoleGroups(
+	repo *repositories.All, roleID string, groupsIDs []string,
+) error {
+	if err := repo.Roles.DropGroupBindings(roleID); err != nil {
+		return err
+	}
+	for _, groupID := range groupsIDs {
+		if _, err := repo.Groups.Get(groupID); err != nil {
+			return err
+		}
+		if err := repo.Roles.BindGroup(roleID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setRoleInclusions makes roleID include exactly includedRolesIDs. Roles
+// must not include themselves, directly or not, so including a role that
+// includes roleID fails with *errors.RoleInclusionCycleError. repo must be
+// a transaction, inclusions are locked until it ends so concurrent updates
+// can't form a cycle together
+func setRoleInclusions(
+	repo *repositories.All, roleID string, includedRolesIDs []string,
+) error {
+	if err := repo.Roles.LockInclusions(); err != nil {
+		return err
+	}
+	if err := repo.Roles.DropInclusions(roleID); err != nil {
+		return err
+	}
+	for _, includedRoleID := range includedRolesIDs {
+		if _, err := repo.Roles.Get(includedRoleID); err != nil {
+			return err
+		}
+		if includedRoleID == roleID {
+			return errors.NewRoleInclusionCycleError(roleID, includedRoleID)
+		}
+		includes, err := repo.Roles.Includes(includedRoleID, roleID)
+		if err != nil {
+			return err
+		}
+		if includes {
+			return errors.NewRoleInclusionCycleError(roleID, includedRoleID)
+		}
+		if err := repo.Roles.Include(roleID, includedRoleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (rs roles) CreatePermission(roleID string, p *models.Permission) error {
 	p.RoleID = roleID
-	return createPermission(rs.repo, p)
+	return rs.repo.WithPGTx(rs.ctx, func(repo *repositories.All) error {
+		return createRolePermission(rs.ctx, repo, p)
+	})
+}
+
+// createRolePermission grants p to its role, recording an audit event. repo
+// should be a transaction
+func createRolePermission(
+	ctx context.Context, repo *repositories.All, p *models.Permission,
+) error {
+	before, err := getRoleAuditState(repo, p.RoleID)
+	if err != nil {
+		return err
+	}
+	if err := createPermission(repo, p); err != nil {
+		return err
+	}
+	after, err := getRoleAuditState(repo, p.RoleID)
+	if err != nil {
+		return err
+	}
+	return recordAuditEvent(ctx, repo, &models.AuditEvent{
+		Action:     models.AuditActions.CreatePermission,
+		TargetType: models.AuditTargetTypes.Role,
+		TargetID:   p.RoleID,
+	}, before, after)
 }
 
 func createPermission(repo *repositories.All, p *models.Permission) error {
@@ -66,12 +149,17 @@ func createPermission(repo *repositories.All, p *models.Permission) error {
 
 // RoleWithNested is the required data to update a role
 type RoleWithNested struct {
-	ID                 string              `json:"-"`
-	Name               string              `json:"name"`
-	PermissionsStrings []string            `json:"permissions"`
-	PermissionsAliases map[string]string   `json:"permissionsAliases"`
-	Permissions        []models.Permission `json:"-"`
-	ServiceAccountsIDs []string            `json:"serviceAccountsIds"`
+	ID                       string               `json:"-"`
+	Name                     string               `json:"name"`
+	PermissionsStrings       []string             `json:"permissions"`
+	PermissionsAliases       map[string]string    `json:"permissionsAliases"`
+	PermissionsExpiresAt     map[string]time.Time `json:"permissionsExpiresAt"`
+	PermissionsConditions    map[string]string    `json:"permissionsConditions"`
+	Permissions              []models.Permission  `json:"-"`
+	ServiceAccountsIDs       []string             `json:"serviceAccountsIds"`
+	ServiceAccountsExpiresAt map[string]time.Time `json:"serviceAccountsExpiresAt"`
+	IncludedRolesIDs         []string             `json:"includedRolesIds"`
+	GroupsIDs                []string             `json:"groupsIds"`
 }
 
 // Validate RoleWithNested fields
@@ -80,11 +168,47 @@ func (rwn RoleWithNested) Validate() models.Validation {
 	if rwn.Name == "" {
 		v.AddError("name", "required")
 	}
+	if !allInTheFuture(rwn.PermissionsExpiresAt) {
+		v.AddError("permissionsExpiresAt", "must be in the future")
+	}
+	if err := validateConditions(rwn.PermissionsConditions); err != nil {
+		v.AddError("permissionsConditions", err.Error())
+	}
+	if !allInTheFuture(rwn.ServiceAccountsExpiresAt) {
+		v.AddError("serviceAccountsExpiresAt", "must be in the future")
+	}
 	return *v
 }
 
+func (rwn RoleWithNested) buildRoleBinding(
+	roleID, serviceAccountID string,
+) *models.RoleBinding {
+	rb := &models.RoleBinding{
+		RoleID:           roleID,
+		ServiceAccountID: serviceAccountID,
+	}
+	if expiresAt, ok := rwn.ServiceAccountsExpiresAt[serviceAccountID]; ok {
+		rb.ExpiresAt = pg.NullTime{Time: expiresAt}
+	}
+	return rb
+}
+
+func allInTheFuture(expiresAt map[string]time.Time) bool {
+	now := time.Now()
+	for _, t := range expiresAt {
+		if !t.After(now) {
+			return false
+		}
+	}
+	return true
+}
+
 func (rs roles) Update(rwn *RoleWithNested) error {
 	return rs.repo.WithPGTx(rs.ctx, func(repo *repositories.All) error {
+		before, err := getRoleAuditState(repo, rwn.ID)
+		if err != nil {
+			return err
+		}
 		if err := repo.Roles.DropPermissions(rwn.ID); err != nil {
 			return err
 		}
@@ -94,19 +218,44 @@ func (rs roles) Update(rwn *RoleWithNested) error {
 				return err
 			}
 		}
+		rbs, err := repo.Roles.Bindings(rwn.ID)
+		if err != nil {
+			return err
+		}
+		previous := map[string]models.RoleBinding{}
+		for _, rb := range rbs {
+			previous[rb.ServiceAccountID] = rb
+		}
 		if err := repo.Roles.DropBindings(rwn.ID); err != nil {
 			return err
 		}
 		for i := range rwn.ServiceAccountsIDs {
-			if err := repo.Roles.Bind(&models.RoleBinding{
-				RoleID:           rwn.ID,
-				ServiceAccountID: rwn.ServiceAccountsIDs[i],
-			}); err != nil {
+			rb := rwn.buildRoleBinding(rwn.ID, rwn.ServiceAccountsIDs[i])
+			rb.ManagedByRule = previous[rwn.ServiceAccountsIDs[i]].ManagedByRule
+			rb.ManagedBySCIM = previous[rwn.ServiceAccountsIDs[i]].ManagedBySCIM
+			if err := repo.Roles.Bind(rb); err != nil {
 				return err
 			}
 		}
+		if err := setRoleGroups(repo, rwn.ID, rwn.GroupsIDs); err != nil {
+			return err
+		}
+		if err := setRoleInclusions(repo, rwn.ID, rwn.IncludedRolesIDs); err != nil {
+			return err
+		}
 		role := &models.Role{ID: rwn.ID, Name: rwn.Name}
-		return repo.Roles.Update(role)
+		if err := repo.Roles.Update(role); err != nil {
+			return err
+		}
+		after, err := getRoleAuditState(repo, rwn.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(rs.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.UpdateRole,
+			TargetType: models.AuditTargetTypes.Role,
+			TargetID:   rwn.ID,
+		}, before, after)
 	})
 }
 
@@ -165,6 +314,8 @@ func (rs roles) Get(id string) (map[string]interface{}, error) {
 		return nil, err
 	}
 	permissionsAliases := map[string]string{}
+	permissionsExpiresAt := map[string]time.Time{}
+	permissionsConditions := map[string]string{}
 	permissions := make([]string, len(pSl))
 	for i := range pSl {
 		str := pSl[i].String()
@@ -172,11 +323,27 @@ func (rs roles) Get(id string) (map[string]interface{}, error) {
 		if pSl[i].Alias != "" {
 			permissionsAliases[str] = pSl[i].Alias
 		}
+		if !pSl[i].ExpiresAt.IsZero() {
+			permissionsExpiresAt[str] = pSl[i].ExpiresAt.Time
+		}
+		if pSl[i].Condition != "" {
+			permissionsConditions[str] = pSl[i].Condition
+		}
 	}
 	sas, err := rs.GetServiceAccounts(id)
 	if err != nil {
 		return nil, err
 	}
+	rbs, err := rs.repo.Roles.Bindings(id)
+	if err != nil {
+		return nil, err
+	}
+	serviceAccountsExpiresAt := map[string]time.Time{}
+	for _, rb := range rbs {
+		if !rb.ExpiresAt.IsZero() {
+			serviceAccountsExpiresAt[rb.ServiceAccountID] = rb.ExpiresAt.Time
+		}
+	}
 	sasFiltered := make([]map[string]interface{}, len(sas))
 	for i, sa := range sas {
 		sasFiltered[i] = map[string]interface{}{
@@ -186,12 +353,33 @@ func (rs roles) Get(id string) (map[string]interface{}, error) {
 			"email":   sa.Email,
 		}
 	}
+	included, err := rs.repo.Roles.Included(id)
+	if err != nil {
+		return nil, err
+	}
+	includedRoles := make([]map[string]interface{}, len(included))
+	for i, ir := range included {
+		includedRoles[i] = map[string]interface{}{"id": ir.ID, "name": ir.Name}
+	}
+	gs, err := rs.repo.Roles.GetGroups(id)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]map[string]interface{}, len(gs))
+	for i, g := range gs {
+		groups[i] = map[string]interface{}{"id": g.ID, "name": g.Name}
+	}
 	return map[string]interface{}{
-		"id":                 r.ID,
-		"name":               r.Name,
-		"permissions":        permissions,
-		"permissionsAliases": permissionsAliases,
-		"serviceAccounts":    sasFiltered,
+		"id":                       r.ID,
+		"name":                     r.Name,
+		"groups":                   groups,
+		"includedRoles":            includedRoles,
+		"permissions":              permissions,
+		"permissionsAliases":       permissionsAliases,
+		"permissionsExpiresAt":     permissionsExpiresAt,
+		"permissionsConditions":    permissionsConditions,
+		"serviceAccounts":          sasFiltered,
+		"serviceAccountsExpiresAt": serviceAccountsExpiresAt,
 	}, nil
 }
 
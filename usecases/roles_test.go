@@ -5,7 +5,9 @@ package usecases_test
 import (
 	"testing"
 
+	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
 	helpers "github.com/topfreegames/Will.IAM/testing"
 	"github.com/topfreegames/Will.IAM/usecases"
 )
@@ -46,3 +48,94 @@ func TestRolesCreatePermission(t *testing.T) {
 		t.Errorf("Expected permission to be %s. Got %s", pStr, ps[0].String())
 	}
 }
+
+func TestRolesInclusions(t *testing.T) {
+	helpers.CleanupPG(t)
+	rsUC := helpers.GetRolesUseCase(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	pStr := "Maestro::RL::ListSchedulers::*"
+	viewer := &usecases.RoleWithNested{Name: "viewer"}
+	p, err := models.BuildPermission(pStr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	viewer.Permissions = []models.Permission{p}
+	if err := rsUC.Create(viewer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "john", "john@test.com", models.AuthenticationTypes.OAuth2,
+	)
+	team := &usecases.RoleWithNested{
+		Name:               "team",
+		ServiceAccountsIDs: []string{sa.ID},
+		IncludedRolesIDs:   []string{viewer.ID},
+	}
+	if err := rsUC.Create(team); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	has, err := saUC.HasPermissionString(sa.ID, "Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !has {
+		t.Errorf("Expected permission to be inherited from included role")
+	}
+	ps, err := saUC.GetPermissions(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := false
+	for _, p := range ps {
+		found = found || p.String() == pStr
+	}
+	if !found {
+		t.Errorf("Expected permissions to include %s. Got %v", pStr, ps)
+	}
+	repo := helpers.GetRepo(t)
+	saSl, err := repo.ServiceAccounts.ListWithPermission(
+		&repositories.ListOptions{PageSize: 10}, p,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(saSl) != 1 || saSl[0].ID != sa.ID {
+		t.Errorf("Expected to list %s with inherited permission. Got %v", sa.ID, saSl)
+	}
+	ids, err := repo.Roles.IncludedIDs([]string{team.ID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected team and viewer ids. Got %v", ids)
+	}
+
+	viewer.IncludedRolesIDs = []string{team.ID}
+	err = rsUC.Update(viewer)
+	if _, ok := err.(*errors.RoleInclusionCycleError); !ok {
+		t.Errorf("Expected RoleInclusionCycleError. Got %v", err)
+	}
+	viewer.IncludedRolesIDs = []string{viewer.ID}
+	err = rsUC.Update(viewer)
+	if _, ok := err.(*errors.RoleInclusionCycleError); !ok {
+		t.Errorf("Expected RoleInclusionCycleError. Got %v", err)
+	}
+
+	deny, err := models.BuildPermission("!Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	viewer.IncludedRolesIDs = nil
+	viewer.Permissions = []models.Permission{p, deny}
+	if err := rsUC.Update(viewer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	has, err = saUC.HasPermissionString(sa.ID, "Maestro::RL::ListSchedulers::some-game")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected deny in included role to win")
+	}
+}
@@ -0,0 +1,501 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/topfreegames/Will.IAM/errors"
+	"github.com/topfreegames/Will.IAM/models"
+	"github.com/topfreegames/Will.IAM/repositories"
+)
+
+// SCIM provisions users and groups as described by SCIM 2.0, so identity
+// providers manage who's in Will.IAM. Users are oauth2 service accounts and
+// groups are roles created through SCIM, their members being the service
+// accounts bound to them
+type SCIM interface {
+	CreateGroup(*models.SCIMGroup) error
+	CreateUser(*models.SCIMUser) error
+	DeleteGroup(string) error
+	DeleteUser(string) error
+	GetGroup(string) (*models.SCIMGroup, error)
+	GetUser(string) (*models.SCIMUser, error)
+	ListGroups(*models.SCIMFilter, *repositories.ListOptions) ([]models.SCIMGroup, int64, error)
+	ListUsers(*models.SCIMFilter, *repositories.ListOptions) ([]models.SCIMUser, int64, error)
+	PatchGroup(string, []models.SCIMPatchOperation) (*models.SCIMGroup, error)
+	PatchUser(string, []models.SCIMPatchOperation) (*models.SCIMUser, error)
+	ReplaceGroup(*models.SCIMGroup) error
+	ReplaceUser(*models.SCIMUser) error
+	WithContext(context.Context) SCIM
+}
+
+type scim struct {
+	repo  *repositories.All
+	ctx   context.Context
+	sasUC ServiceAccounts
+}
+
+func (s scim) WithContext(ctx context.Context) SCIM {
+	return &scim{s.repo.WithContext(ctx), ctx, s.sasUC.WithContext(ctx)}
+}
+
+// NewSCIM scim ctor. sasUC disables, reactivates and offboards users
+func NewSCIM(repo *repositories.All, sasUC ServiceAccounts) SCIM {
+	return &scim{repo: repo, sasUC: sasUC}
+}
+
+func scimNotFound(resourceType, id string) error {
+	return models.NewSCIMError(
+		404, "", fmt.Sprintf("%s %s not found", resourceType, id),
+	)
+}
+
+// getUserServiceAccount returns the oauth2 service account of user id,
+// deleted ones are gone for SCIM
+func getUserServiceAccount(
+	repo *repositories.All, id string,
+) (*models.ServiceAccount, error) {
+	sa, err := repo.ServiceAccounts.Get(id)
+	if _, ok := err.(*errors.EntityNotFoundError); ok {
+		return nil, scimNotFound("User", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sa.AuthenticationType != models.AuthenticationTypes.OAuth2 ||
+		sa.Status == models.ServiceAccountStatuses.Deleted {
+		return nil, scimNotFound("User", id)
+	}
+	return sa, nil
+}
+
+func (s scim) GetUser(id string) (*models.SCIMUser, error) {
+	sa, err := getUserServiceAccount(s.repo, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSCIMUser(sa), nil
+}
+
+// ListUsers supports filtering by userName, which is the email
+func (s scim) ListUsers(
+	f *models.SCIMFilter, lo *repositories.ListOptions,
+) ([]models.SCIMUser, int64, error) {
+	if f != nil {
+		if f.Attribute != "username" && f.Attribute != "emails" &&
+			f.Attribute != "emails.value" {
+			return nil, 0, models.NewSCIMError(
+				400, "invalidFilter", "users can only be filtered by userName",
+			)
+		}
+		sa, err := s.repo.ServiceAccounts.ForEmail(f.Value)
+		if _, ok := err.(*errors.EntityNotFoundError); ok {
+			return []models.SCIMUser{}, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := getUserServiceAccount(s.repo, sa.ID); err != nil {
+			return []models.SCIMUser{}, 0, nil
+		}
+		return []models.SCIMUser{*models.NewSCIMUser(sa)}, 1, nil
+	}
+	oauth2 := models.AuthenticationTypes.OAuth2
+	saSl, err := s.repo.ServiceAccounts.ListWithAuthenticationType(oauth2, lo)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.repo.ServiceAccounts.ListWithAuthenticationTypeCount(oauth2)
+	if err != nil {
+		return nil, 0, err
+	}
+	users := make([]models.SCIMUser, len(saSl))
+	for i := range saSl {
+		users[i] = *models.NewSCIMUser(&saSl[i])
+	}
+	return users, count, nil
+}
+
+// CreateUser creates u service account, disabled unless u is active
+func (s scim) CreateUser(u *models.SCIMUser) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	_, err := s.repo.ServiceAccounts.ForEmail(u.UserName)
+	if err == nil {
+		return models.NewSCIMError(
+			409, "uniqueness", fmt.Sprintf("userName %s already exists", u.UserName),
+		)
+	}
+	if _, ok := err.(*errors.EntityNotFoundError); !ok {
+		return err
+	}
+	sa := models.BuildOAuth2ServiceAccount(u.ServiceAccountName(), u.UserName)
+	if err := s.sasUC.Create(sa); err != nil {
+		return err
+	}
+	if !u.Active {
+		if err := s.sasUC.Disable(sa.ID); err != nil {
+			return err
+		}
+		sa.Status = models.ServiceAccountStatuses.Disabled
+	}
+	*u = *models.NewSCIMUser(sa)
+	return nil
+}
+
+// ReplaceUser updates u name, email and status
+func (s scim) ReplaceUser(u *models.SCIMUser) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	err := s.repo.WithPGTx(s.ctx, func(repo *repositories.All) error {
+		sa, err := getUserServiceAccount(repo, u.ID)
+		if err != nil {
+			return err
+		}
+		name := u.ServiceAccountName()
+		if sa.Name == name && sa.Email == u.UserName {
+			return nil
+		}
+		if sa.Email != u.UserName {
+			other, err := repo.ServiceAccounts.ForEmail(u.UserName)
+			if err == nil && other.ID != sa.ID {
+				return models.NewSCIMError(
+					409, "uniqueness",
+					fmt.Sprintf("userName %s already exists", u.UserName),
+				)
+			}
+		}
+		before, err := getServiceAccountAuditState(repo, sa.ID)
+		if err != nil {
+			return err
+		}
+		sa.Name = name
+		sa.Email = u.UserName
+		if err := repo.ServiceAccounts.Update(sa); err != nil {
+			return err
+		}
+		after, err := getServiceAccountAuditState(repo, sa.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(s.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.UpdateServiceAccount,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   sa.ID,
+		}, before, after)
+	})
+	if err != nil {
+		return err
+	}
+	sa, err := getUserServiceAccount(s.repo, u.ID)
+	if err != nil {
+		return err
+	}
+	if u.Active && !sa.IsActive() {
+		err = s.sasUC.Reactivate(sa.ID)
+	} else if !u.Active && sa.IsActive() {
+		err = s.sasUC.Disable(sa.ID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.getUserInto(u)
+}
+
+func (s scim) PatchUser(
+	id string, ops []models.SCIMPatchOperation,
+) (*models.SCIMUser, error) {
+	u, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.ApplyPatch(ops); err != nil {
+		return nil, err
+	}
+	if err := s.ReplaceUser(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// DeleteUser offboards user id, someone who left the company
+func (s scim) DeleteUser(id string) error {
+	if _, err := getUserServiceAccount(s.repo, id); err != nil {
+		return err
+	}
+	return s.sasUC.Offboard(id)
+}
+
+func (s scim) getUserInto(u *models.SCIMUser) error {
+	got, err := s.GetUser(u.ID)
+	if err != nil {
+		return err
+	}
+	*u = *got
+	return nil
+}
+
+// isSCIMGroup tells if r is a group. Only roles created through SCIM are,
+// so provisioning can't bind anyone to roles granted otherwise nor delete them
+func isSCIMGroup(r *models.Role) bool {
+	return r.ManagedBySCIM && !r.IsBaseRole
+}
+
+// getGroupRole returns the role of group id
+func getGroupRole(repo *repositories.All, id string) (*models.Role, error) {
+	r, err := repo.Roles.Get(id)
+	if _, ok := err.(*errors.EntityNotFoundError); ok {
+		return nil, scimNotFound("Group", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isSCIMGroup(r) {
+		return nil, scimNotFound("Group", id)
+	}
+	return r, nil
+}
+
+func buildSCIMGroup(
+	repo *repositories.All, r *models.Role,
+) (*models.SCIMGroup, error) {
+	sas, err := repo.Roles.GetServiceAccounts(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSCIMGroup(r, sas), nil
+}
+
+func (s scim) GetGroup(id string) (*models.SCIMGroup, error) {
+	r, err := getGroupRole(s.repo, id)
+	if err != nil {
+		return nil, err
+	}
+	return buildSCIMGroup(s.repo, r)
+}
+
+// ListGroups lists roles created through SCIM, the only ones getGroupRole
+// finds. It supports filtering by displayName, which is the role name
+func (s scim) ListGroups(
+	f *models.SCIMFilter, lo *repositories.ListOptions,
+) ([]models.SCIMGroup, int64, error) {
+	var rsSl []models.Role
+	var count int64
+	var err error
+	if f != nil {
+		if f.Attribute != "displayname" {
+			return nil, 0, models.NewSCIMError(
+				400, "invalidFilter", "groups can only be filtered by displayName",
+			)
+		}
+		var named []models.Role
+		named, err = s.repo.Roles.ForName(f.Value)
+		for i := range named {
+			if isSCIMGroup(&named[i]) {
+				rsSl = append(rsSl, named[i])
+			}
+		}
+		count = int64(len(rsSl))
+	} else {
+		rsSl, err = s.repo.Roles.ListManagedBySCIM(lo)
+		if err == nil {
+			count, err = s.repo.Roles.ListManagedBySCIMCount()
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	groups := make([]models.SCIMGroup, len(rsSl))
+	for i := range rsSl {
+		g, err := buildSCIMGroup(s.repo, &rsSl[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		groups[i] = *g
+	}
+	return groups, count, nil
+}
+
+// CreateGroup creates a role named after g, bound to its members
+func (s scim) CreateGroup(g *models.SCIMGroup) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	return s.repo.WithPGTx(s.ctx, func(repo *repositories.All) error {
+		if err := checkGroupNameAvailable(repo, g.DisplayName, ""); err != nil {
+			return err
+		}
+		r := &models.Role{Name: g.DisplayName, ManagedBySCIM: true}
+		if err := repo.Roles.Create(r); err != nil {
+			return err
+		}
+		if err := setGroupMembers(repo, r.ID, g.MembersIDs()); err != nil {
+			return err
+		}
+		after, err := getRoleAuditState(repo, r.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordAuditEvent(s.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.CreateRole,
+			TargetType: models.AuditTargetTypes.Role,
+			TargetID:   r.ID,
+		}, nil, after); err != nil {
+			return err
+		}
+		created, err := buildSCIMGroup(repo, r)
+		if err != nil {
+			return err
+		}
+		*g = *created
+		return nil
+	})
+}
+
+// ReplaceGroup renames g role and binds it to g members, see setGroupMembers.
+// Bindings of members that stay are kept as they are, expiration included
+func (s scim) ReplaceGroup(g *models.SCIMGroup) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	return s.repo.WithPGTx(s.ctx, func(repo *repositories.All) error {
+		r, err := getGroupRole(repo, g.ID)
+		if err != nil {
+			return err
+		}
+		before, err := getRoleAuditState(repo, r.ID)
+		if err != nil {
+			return err
+		}
+		if r.Name != g.DisplayName {
+			if err := checkGroupNameAvailable(repo, g.DisplayName, r.ID); err != nil {
+				return err
+			}
+			r.Name = g.DisplayName
+			if err := repo.Roles.Update(r); err != nil {
+				return err
+			}
+		}
+		if err := setGroupMembers(repo, r.ID, g.MembersIDs()); err != nil {
+			return err
+		}
+		after, err := getRoleAuditState(repo, r.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordAuditEvent(s.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.UpdateRole,
+			TargetType: models.AuditTargetTypes.Role,
+			TargetID:   r.ID,
+		}, before, after); err != nil {
+			return err
+		}
+		replaced, err := buildSCIMGroup(repo, r)
+		if err != nil {
+			return err
+		}
+		*g = *replaced
+		return nil
+	})
+}
+
+func (s scim) PatchGroup(
+	id string, ops []models.SCIMPatchOperation,
+) (*models.SCIMGroup, error) {
+	g, err := s.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.ApplyPatch(ops); err != nil {
+		return nil, err
+	}
+	if err := s.ReplaceGroup(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// DeleteGroup deletes group id role, along with its permissions
+func (s scim) DeleteGroup(id string) error {
+	return s.repo.WithPGTx(s.ctx, func(repo *repositories.All) error {
+		r, err := getGroupRole(repo, id)
+		if err != nil {
+			return err
+		}
+		before, err := getRoleAuditState(repo, r.ID)
+		if err != nil {
+			return err
+		}
+		if err := repo.Roles.Delete(r.ID); err != nil {
+			return err
+		}
+		return recordAuditEvent(s.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.DeleteRole,
+			TargetType: models.AuditTargetTypes.Role,
+			TargetID:   r.ID,
+		}, before, nil)
+	})
+}
+
+func checkGroupNameAvailable(repo *repositories.All, name, roleID string) error {
+	rsSl, err := repo.Roles.ForName(name)
+	if err != nil {
+		return err
+	}
+	for _, r := range rsSl {
+		if r.ID != roleID {
+			return models.NewSCIMError(
+				409, "uniqueness", fmt.Sprintf("displayName %s already exists", name),
+			)
+		}
+	}
+	return nil
+}
+
+// setGroupMembers binds roleID to serviceAccountsIDs, which must be users,
+// and unbinds those it bound before that aren't members anymore. Bindings
+// made otherwise, by hand or by rules, are left alone
+func setGroupMembers(
+	repo *repositories.All, roleID string, serviceAccountsIDs []string,
+) error {
+	rbs, err := repo.Roles.Bindings(roleID)
+	if err != nil {
+		return err
+	}
+	bound := map[string]bool{}
+	managed := map[string]bool{}
+	for _, rb := range rbs {
+		bound[rb.ServiceAccountID] = true
+		if rb.ManagedBySCIM {
+			managed[rb.ServiceAccountID] = true
+		}
+	}
+	members := map[string]bool{}
+	for _, saID := range serviceAccountsIDs {
+		members[saID] = true
+		if bound[saID] {
+			continue
+		}
+		if _, err := getUserServiceAccount(repo, saID); err != nil {
+			return models.NewSCIMError(
+				400, "invalidValue", fmt.Sprintf("member %s is not a user", saID),
+			)
+		}
+		if err := repo.Roles.Bind(&models.RoleBinding{
+			RoleID: roleID, ServiceAccountID: saID, ManagedBySCIM: true,
+		}); err != nil {
+			return err
+		}
+	}
+	for saID := range managed {
+		if members[saID] {
+			continue
+		}
+		if err := repo.Roles.UnbindManagedBySCIM(roleID, saID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
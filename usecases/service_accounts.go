@@ -3,7 +3,9 @@ package usecases
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/go-pg/pg"
 	"github.com/gofrs/uuid"
 	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
@@ -13,13 +15,20 @@ import (
 
 // ServiceAccounts define entrypoints for ServiceAccount actions
 type ServiceAccounts interface {
+	ApplyRoleBindingRules(string, []string) error
 	AuthenticateAccessToken(string) (*models.AccessTokenAuth, error)
 	AuthenticateKeyPair(string, string) (*models.AccessKeyPairAuth, error)
 	Create(*models.ServiceAccount) error
+	CreateKey(string) (*models.ServiceAccountKey, error)
 	CreateKeyPairType(string) (*models.ServiceAccount, error)
 	CreateOAuth2Type(string, string) (*models.ServiceAccount, error)
 	CreatePermission(string, *models.Permission) error
+	CreateSession(string, string, string) (*models.Session, error)
 	CreateWithNested(*ServiceAccountWithNested) error
+	Delete(string) error
+	DeleteKey(string, string) error
+	Disable(string) error
+	ExplainPermission(string, models.Permission) (*models.PermissionExplanation, error)
 	ForEmail(string) (*models.ServiceAccount, error)
 	Get(string) (*models.ServiceAccount, error)
 	GetPermissions(string) ([]models.Permission, error)
@@ -31,10 +40,19 @@ type ServiceAccounts interface {
 	HasPermissions(string, []models.Permission) ([]bool, error)
 	HasPermissionsStrings(string, []string) ([]bool, error)
 	List(*repositories.ListOptions) ([]models.ServiceAccount, int64, error)
+	ListKeys(string) ([]models.ServiceAccountKey, error)
+	ListSessions(string) ([]models.Session, error)
+	Logout(string) error
 	ListWithPermission(
 		string,
 		*repositories.ListOptions, models.Permission,
 	) ([]models.ServiceAccount, int64, error)
+	MatchPermissionString(string, string) (*models.Permission, bool, error)
+	MatchPermissionsStrings(string, []string) ([]*models.Permission, []bool, error)
+	Offboard(string) error
+	Reactivate(string) error
+	RevokeSession(string, string) error
+	RevokeSessions(string) error
 	UpdateWithNested(*ServiceAccountWithNested) error
 	Search(
 		string, *repositories.ListOptions,
@@ -46,11 +64,21 @@ type serviceAccounts struct {
 	repo           *repositories.All
 	ctx            context.Context
 	oauth2Provider oauth2.Provider
+	// permissionsCache and accessTokens are nil unless set in
+	// ServiceAccountsOptions
+	permissionsCache *PermissionsCache
+	accessTokens     AccessTokens
+	roleBindingRules []models.RoleBindingRule
 }
 
 func (sas serviceAccounts) WithContext(ctx context.Context) ServiceAccounts {
+	var ats AccessTokens
+	if sas.accessTokens != nil {
+		ats = sas.accessTokens.WithContext(ctx)
+	}
 	return &serviceAccounts{
 		sas.repo.WithContext(ctx), ctx, sas.oauth2Provider.WithContext(ctx),
+		sas.permissionsCache, ats, sas.roleBindingRules,
 	}
 }
 
@@ -58,25 +86,63 @@ func (sas serviceAccounts) WithContext(ctx context.Context) ServiceAccounts {
 func NewServiceAccounts(
 	repo *repositories.All,
 	provider oauth2.Provider,
+) ServiceAccounts {
+	return NewServiceAccountsWithPermissionsCache(repo, provider, nil)
+}
+
+// NewServiceAccountsWithPermissionsCache serviceAccounts ctor answering
+// permission checks from cache
+func NewServiceAccountsWithPermissionsCache(
+	repo *repositories.All,
+	provider oauth2.Provider,
+	cache *PermissionsCache,
+) ServiceAccounts {
+	return NewServiceAccountsWithOptions(repo, provider, ServiceAccountsOptions{
+		PermissionsCache: cache,
+	})
+}
+
+// ServiceAccountsOptions are optional ServiceAccounts dependencies
+type ServiceAccountsOptions struct {
+	// PermissionsCache answers permission checks from cache
+	PermissionsCache *PermissionsCache
+	// AccessTokens authenticates access tokens signed by Will.IAM without
+	// asking the login provider
+	AccessTokens AccessTokens
+	// RoleBindingRules bind service accounts to roles as they log in
+	RoleBindingRules []models.RoleBindingRule
+}
+
+// NewServiceAccountsWithOptions serviceAccounts ctor
+func NewServiceAccountsWithOptions(
+	repo *repositories.All,
+	provider oauth2.Provider,
+	opts ServiceAccountsOptions,
 ) ServiceAccounts {
 	return &serviceAccounts{
-		repo:           repo,
-		oauth2Provider: provider,
+		repo:             repo,
+		oauth2Provider:   provider,
+		permissionsCache: opts.PermissionsCache,
+		accessTokens:     opts.AccessTokens,
+		roleBindingRules: opts.RoleBindingRules,
 	}
 }
 
 // ServiceAccountWithNested is the required data to update a role
 type ServiceAccountWithNested struct {
-	ID                 string                    `json:"id"`
-	Name               string                    `json:"name"`
-	Email              string                    `json:"email"`
-	Picture            string                    `json:"picture"`
-	PermissionsStrings []string                  `json:"permissions"`
-	PermissionsAliases map[string]string         `json:"permissionsAliases"`
-	Permissions        []models.Permission       `json:"-"`
-	RolesIDs           []string                  `json:"rolesIds,omitempty"`
-	Roles              []models.Role             `json:"roles"`
-	AuthenticationType models.AuthenticationType `json:"authenticationType"`
+	ID                    string                    `json:"id"`
+	Name                  string                    `json:"name"`
+	Email                 string                    `json:"email"`
+	Picture               string                    `json:"picture"`
+	PermissionsStrings    []string                  `json:"permissions"`
+	PermissionsAliases    map[string]string         `json:"permissionsAliases"`
+	PermissionsExpiresAt  map[string]time.Time      `json:"permissionsExpiresAt"`
+	PermissionsConditions map[string]string         `json:"permissionsConditions"`
+	Permissions           []models.Permission       `json:"-"`
+	RolesIDs              []string                  `json:"rolesIds,omitempty"`
+	RolesExpiresAt        map[string]time.Time      `json:"rolesExpiresAt"`
+	Roles                 []models.Role             `json:"roles"`
+	AuthenticationType    models.AuthenticationType `json:"authenticationType"`
 }
 
 // Validate ServiceAccountWithNested fields
@@ -92,9 +158,31 @@ func (sawn ServiceAccountWithNested) Validate() models.Validation {
 		sawn.Email == "" {
 		v.AddError("email", "required")
 	}
+	if !allInTheFuture(sawn.PermissionsExpiresAt) {
+		v.AddError("permissionsExpiresAt", "must be in the future")
+	}
+	if err := validateConditions(sawn.PermissionsConditions); err != nil {
+		v.AddError("permissionsConditions", err.Error())
+	}
+	if !allInTheFuture(sawn.RolesExpiresAt) {
+		v.AddError("rolesExpiresAt", "must be in the future")
+	}
 	return *v
 }
 
+func (sawn ServiceAccountWithNested) buildRoleBinding(
+	serviceAccountID, roleID string,
+) *models.RoleBinding {
+	rb := &models.RoleBinding{
+		RoleID:           roleID,
+		ServiceAccountID: serviceAccountID,
+	}
+	if expiresAt, ok := sawn.RolesExpiresAt[roleID]; ok {
+		rb.ExpiresAt = pg.NullTime{Time: expiresAt}
+	}
+	return rb
+}
+
 func (sas serviceAccounts) CreateWithNested(
 	sawn *ServiceAccountWithNested,
 ) error {
@@ -113,10 +201,9 @@ func (sas serviceAccounts) CreateWithNested(
 		}
 		sawn.ID = sa.ID
 		for i := range sawn.RolesIDs {
-			if err := repo.Roles.Bind(&models.RoleBinding{
-				ServiceAccountID: sawn.ID,
-				RoleID:           sawn.RolesIDs[i],
-			}); err != nil {
+			if err := repo.Roles.Bind(
+				sawn.buildRoleBinding(sawn.ID, sawn.RolesIDs[i]),
+			); err != nil {
 				return err
 			}
 		}
@@ -126,7 +213,15 @@ func (sas serviceAccounts) CreateWithNested(
 				return err
 			}
 		}
-		return nil
+		after, err := getServiceAccountAuditState(repo, sa.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.CreateServiceAccount,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   sa.ID,
+		}, nil, after)
 	})
 }
 
@@ -134,6 +229,10 @@ func (sas serviceAccounts) UpdateWithNested(
 	sawn *ServiceAccountWithNested,
 ) error {
 	return sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		before, err := getServiceAccountAuditState(repo, sawn.ID)
+		if err != nil {
+			return err
+		}
 		sa, err := repo.ServiceAccounts.Get(sawn.ID)
 		if err != nil {
 			return err
@@ -143,6 +242,10 @@ func (sas serviceAccounts) UpdateWithNested(
 		if err := repo.ServiceAccounts.Update(sa); err != nil {
 			return err
 		}
+		managed, err := managedRoleBindings(repo, sawn.ID)
+		if err != nil {
+			return err
+		}
 		if err := repo.ServiceAccounts.DropBindings(sawn.ID); err != nil {
 			return err
 		}
@@ -150,10 +253,10 @@ func (sas serviceAccounts) UpdateWithNested(
 			if roleID == sa.BaseRoleID {
 				continue
 			}
-			if err := repo.Roles.Bind(&models.RoleBinding{
-				ServiceAccountID: sa.ID,
-				RoleID:           roleID,
-			}); err != nil {
+			rb := sawn.buildRoleBinding(sa.ID, roleID)
+			rb.ManagedByRule = managed[roleID].ManagedByRule
+			rb.ManagedBySCIM = managed[roleID].ManagedBySCIM
+			if err := repo.Roles.Bind(rb); err != nil {
 				return err
 			}
 		}
@@ -166,7 +269,126 @@ func (sas serviceAccounts) UpdateWithNested(
 				return err
 			}
 		}
-		return nil
+		after, err := getServiceAccountAuditState(repo, sawn.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.UpdateServiceAccount,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   sawn.ID,
+		}, before, after)
+	})
+}
+
+// Disable stops serviceAccountID from authenticating until it's reactivated,
+// keeping its roles and permissions
+func (sas serviceAccounts) Disable(serviceAccountID string) error {
+	return sas.changeStatus(
+		serviceAccountID, models.AuditActions.DisableServiceAccount,
+		func(repo *repositories.All, sa *models.ServiceAccount) error {
+			if sa.Status == models.ServiceAccountStatuses.Deleted {
+				return errors.NewInactiveServiceAccountError(sa.ID, sa.Status.String())
+			}
+			return repo.ServiceAccounts.SetStatus(
+				sa.ID, models.ServiceAccountStatuses.Disabled,
+			)
+		},
+	)
+}
+
+// Delete soft deletes serviceAccountID: it stops authenticating and loses
+// its role bindings and base role permissions. It's kept so that audit
+// events and permission requests still refer to it
+func (sas serviceAccounts) Delete(serviceAccountID string) error {
+	return sas.changeStatus(
+		serviceAccountID, models.AuditActions.DeleteServiceAccount,
+		deleteServiceAccount,
+	)
+}
+
+// Reactivate lets a disabled or deleted serviceAccountID authenticate again.
+// Roles and permissions of deleted ones aren't restored
+func (sas serviceAccounts) Reactivate(serviceAccountID string) error {
+	return sas.changeStatus(
+		serviceAccountID, models.AuditActions.ReactivateServiceAccount,
+		func(repo *repositories.All, sa *models.ServiceAccount) error {
+			return repo.ServiceAccounts.SetStatus(
+				sa.ID, models.ServiceAccountStatuses.Active,
+			)
+		},
+	)
+}
+
+// Offboard deletes serviceAccountID, revokes its sessions and login provider
+// tokens and denies its open permission requests, e.g. when someone leaves
+// the company
+func (sas serviceAccounts) Offboard(serviceAccountID string) error {
+	return sas.changeStatus(
+		serviceAccountID, models.AuditActions.OffboardServiceAccount,
+		func(repo *repositories.All, sa *models.ServiceAccount) error {
+			if err := deleteServiceAccount(repo, sa); err != nil {
+				return err
+			}
+			if sa.Email != "" {
+				if err := repo.Sessions.DeleteForEmail(sa.Email); err != nil {
+					return err
+				}
+				if err := repo.Tokens.RevokeForEmail(sa.Email); err != nil {
+					return err
+				}
+			}
+			return repo.PermissionsRequests.DenyOpenForServiceAccount(
+				auditCtxValue(sas.ctx, auditActorCtxKey), sa.ID,
+			)
+		},
+	)
+}
+
+func deleteServiceAccount(
+	repo *repositories.All, sa *models.ServiceAccount,
+) error {
+	if err := repo.ServiceAccounts.DropBindings(sa.ID); err != nil {
+		return err
+	}
+	if err := repo.Groups.DropMemberships(sa.ID); err != nil {
+		return err
+	}
+	if err := repo.Roles.DropPermissions(sa.BaseRoleID); err != nil {
+		return err
+	}
+	return repo.ServiceAccounts.SetStatus(
+		sa.ID, models.ServiceAccountStatuses.Deleted,
+	)
+}
+
+// changeStatus applies change to serviceAccountID and records it as action
+// in the audit log, in a single transaction
+func (sas serviceAccounts) changeStatus(
+	serviceAccountID string, action models.AuditAction,
+	change func(*repositories.All, *models.ServiceAccount) error,
+) error {
+	return sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		sa, err := repo.ServiceAccounts.Get(serviceAccountID)
+		if err != nil {
+			return err
+		}
+		before, err := getServiceAccountAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		if err := change(repo, sa); err != nil {
+			return err
+		}
+		after, err := getServiceAccountAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     action,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   serviceAccountID,
+		}, before, after)
 	})
 }
 
@@ -183,6 +405,8 @@ func (sas serviceAccounts) GetWithNested(
 		return nil, err
 	}
 	permissionsAliases := map[string]string{}
+	permissionsExpiresAt := map[string]time.Time{}
+	permissionsConditions := map[string]string{}
 	permissions := make([]string, len(pSl))
 	for i := range pSl {
 		str := pSl[i].String()
@@ -190,26 +414,49 @@ func (sas serviceAccounts) GetWithNested(
 		if pSl[i].Alias != "" {
 			permissionsAliases[str] = pSl[i].Alias
 		}
+		if !pSl[i].ExpiresAt.IsZero() {
+			permissionsExpiresAt[str] = pSl[i].ExpiresAt.Time
+		}
+		if pSl[i].Condition != "" {
+			permissionsConditions[str] = pSl[i].Condition
+		}
 	}
 	roles, err := sas.repo.Roles.ForServiceAccountID(serviceAccountID)
 	if err != nil {
 		return nil, err
 	}
+	rbs, err := sas.repo.Roles.BindingsForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	rolesExpiresAt := map[string]time.Time{}
+	for _, rb := range rbs {
+		if !rb.ExpiresAt.IsZero() {
+			rolesExpiresAt[rb.RoleID] = rb.ExpiresAt.Time
+		}
+	}
 	return &ServiceAccountWithNested{
-		ID:                 sa.ID,
-		Name:               sa.Name,
-		Email:              sa.Email,
-		Picture:            sa.Picture,
-		Roles:              roles,
-		AuthenticationType: sa.AuthenticationType,
-		PermissionsStrings: permissions,
-		PermissionsAliases: permissionsAliases,
+		ID:                    sa.ID,
+		Name:                  sa.Name,
+		Email:                 sa.Email,
+		Picture:               sa.Picture,
+		Roles:                 roles,
+		AuthenticationType:    sa.AuthenticationType,
+		PermissionsStrings:    permissions,
+		PermissionsAliases:    permissionsAliases,
+		PermissionsExpiresAt:  permissionsExpiresAt,
+		PermissionsConditions: permissionsConditions,
+		RolesExpiresAt:        rolesExpiresAt,
 	}, nil
 }
 
 func (sas serviceAccounts) HasAllOwnerRolesPermissions(
 	saID string, rolesIDs []string,
 ) (bool, error) {
+	rolesIDs, err := sas.repo.Roles.IncludedIDs(rolesIDs)
+	if err != nil {
+		return false, err
+	}
 	ps := []models.Permission{}
 	for _, roleID := range rolesIDs {
 		rps, err := sas.repo.Permissions.ForRole(roleID)
@@ -239,9 +486,22 @@ func createServiceAccount(
 		return err
 	}
 	sa.BaseRoleID = r.ID
+	if sa.AuthenticationType == "" {
+		sa.AuthenticationType = models.AuthenticationTypes.OAuth2
+		if sa.KeyID != "" {
+			sa.AuthenticationType = models.AuthenticationTypes.KeyPair
+		}
+	}
 	if err := repo.ServiceAccounts.Create(sa); err != nil {
 		return err
 	}
+	if sa.KeyID != "" {
+		if err := repo.ServiceAccountKeys.Create(
+			models.NewServiceAccountKey(sa.ID, sa.KeyID, sa.KeySecret),
+		); err != nil {
+			return err
+		}
+	}
 	if err := repo.Roles.Bind(&models.RoleBinding{
 		RoleID:           r.ID,
 		ServiceAccountID: sa.ID,
@@ -263,6 +523,179 @@ func (sas serviceAccounts) CreateKeyPairType(
 	return saKP, nil
 }
 
+// CreateKey generates a new key pair for a keypair service account. The
+// returned key is the only place its secret can be read from
+func (sas serviceAccounts) CreateKey(
+	serviceAccountID string,
+) (*models.ServiceAccountKey, error) {
+	var k *models.ServiceAccountKey
+	err := sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		sa, err := repo.ServiceAccounts.Get(serviceAccountID)
+		if err != nil {
+			return err
+		}
+		if sa.AuthenticationType != models.AuthenticationTypes.KeyPair {
+			return errors.NewNotKeyPairServiceAccountError(serviceAccountID)
+		}
+		before, err := getServiceAccountKeysAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		k = models.BuildServiceAccountKey(serviceAccountID)
+		if err := repo.ServiceAccountKeys.Create(k); err != nil {
+			return err
+		}
+		after, err := getServiceAccountKeysAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.CreateServiceAccountKey,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   serviceAccountID,
+		}, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// DeleteKey revokes key pair keyID of serviceAccountID. Access tokens
+// already issued with it are valid until they expire
+func (sas serviceAccounts) DeleteKey(serviceAccountID, keyID string) error {
+	return sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		before, err := getServiceAccountKeysAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		if err := repo.ServiceAccountKeys.Delete(serviceAccountID, keyID); err != nil {
+			return err
+		}
+		after, err := getServiceAccountKeysAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.DeleteServiceAccountKey,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   serviceAccountID,
+		}, before, after)
+	})
+}
+
+// ListKeys returns serviceAccountID key pairs, without secrets
+func (sas serviceAccounts) ListKeys(
+	serviceAccountID string,
+) ([]models.ServiceAccountKey, error) {
+	if _, err := sas.repo.ServiceAccounts.Get(serviceAccountID); err != nil {
+		return nil, err
+	}
+	return sas.repo.ServiceAccountKeys.ForServiceAccount(serviceAccountID)
+}
+
+// CreateSession starts a session for accessToken, just issued by a login
+// provider to a client with userAgent and ip
+func (sas serviceAccounts) CreateSession(
+	accessToken, userAgent, ip string,
+) (*models.Session, error) {
+	var s *models.Session
+	err := sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		t, err := repo.Tokens.Get(accessToken)
+		if err != nil {
+			return err
+		}
+		s = &models.Session{
+			Email:     t.Email,
+			Provider:  t.Provider,
+			UserAgent: userAgent,
+			IP:        ip,
+		}
+		if err := repo.Sessions.Create(s); err != nil {
+			return err
+		}
+		return repo.Tokens.SetSessionID(accessToken, s.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSessions returns serviceAccountID sessions still in use
+func (sas serviceAccounts) ListSessions(
+	serviceAccountID string,
+) ([]models.Session, error) {
+	sa, err := sas.repo.ServiceAccounts.Get(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if sa.Email == "" {
+		return []models.Session{}, nil
+	}
+	return sas.repo.Sessions.ForEmail(sa.Email)
+}
+
+// RevokeSession ends session sessionID of serviceAccountID, revoking its
+// tokens
+func (sas serviceAccounts) RevokeSession(
+	serviceAccountID, sessionID string,
+) error {
+	return sas.revokeSessions(serviceAccountID, func(
+		repo *repositories.All, sa *models.ServiceAccount,
+	) error {
+		return repo.Sessions.Delete(sa.Email, sessionID)
+	})
+}
+
+// RevokeSessions ends every session of serviceAccountID and revokes all its
+// login provider tokens and Will.IAM access tokens issued so far, e.g. when
+// someone leaves the company
+func (sas serviceAccounts) RevokeSessions(serviceAccountID string) error {
+	return sas.revokeSessions(serviceAccountID, func(
+		repo *repositories.All, sa *models.ServiceAccount,
+	) error {
+		if err := repo.ServiceAccounts.RevokeTokens(sa.ID); err != nil {
+			return err
+		}
+		if sa.Email == "" {
+			return nil
+		}
+		if err := repo.Sessions.DeleteForEmail(sa.Email); err != nil {
+			return err
+		}
+		return repo.Tokens.RevokeForEmail(sa.Email)
+	})
+}
+
+func (sas serviceAccounts) revokeSessions(
+	serviceAccountID string,
+	revoke func(*repositories.All, *models.ServiceAccount) error,
+) error {
+	return sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		sa, err := repo.ServiceAccounts.Get(serviceAccountID)
+		if err != nil {
+			return err
+		}
+		before, err := getServiceAccountSessionsAuditState(repo, sa.Email)
+		if err != nil {
+			return err
+		}
+		if err := revoke(repo, sa); err != nil {
+			return err
+		}
+		after, err := getServiceAccountSessionsAuditState(repo, sa.Email)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.RevokeSessions,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   serviceAccountID,
+		}, before, after)
+	})
+}
+
 // CreateOAuth2Type creates an oauth2 service account
 func (sas serviceAccounts) CreateOAuth2Type(
 	saName, saEmail string,
@@ -361,14 +794,45 @@ func (sas serviceAccounts) Search(
 	return saSl, count, nil
 }
 
-// AuthenticateAccessToken verifies if token is valid for email, and sometimes refreshes it
+// AuthenticateAccessToken verifies if token is valid for email, and sometimes
+// refreshes it. Access tokens signed by Will.IAM are verified without
+// storage, except for checking their service account is still active and
+// didn't have its sessions revoked since they were issued. iat is precise to
+// a second, so tokens issued in the second of a revocation are revoked too
 func (sas *serviceAccounts) AuthenticateAccessToken(
 	accessToken string,
 ) (*models.AccessTokenAuth, error) {
+	if sas.accessTokens != nil && sas.accessTokens.IsAccessToken(accessToken) {
+		claims, err := sas.accessTokens.Verify(accessToken)
+		if err != nil {
+			return nil, err
+		}
+		sa, err := sas.repo.ServiceAccounts.Get(claims.Subject)
+		if err != nil {
+			return nil, err
+		}
+		if !sa.IsActive() {
+			return nil, errors.NewInactiveServiceAccountError(sa.ID, sa.Status.String())
+		}
+		if sa.TokensRevokedAt != nil &&
+			claims.IssuedAt <= sa.TokensRevokedAt.Unix() {
+			return nil, errors.NewInvalidAccessTokenError("revoked")
+		}
+		return &models.AccessTokenAuth{
+			ServiceAccountID: claims.Subject,
+			AccessToken:      accessToken,
+			Email:            claims.Email,
+		}, nil
+	}
 	authResult, err := sas.oauth2Provider.Authenticate(accessToken)
 	if err != nil {
 		return nil, err
 	}
+	if err := sas.repo.Sessions.TouchForAccessToken(
+		authResult.AccessToken,
+	); err != nil {
+		return nil, err
+	}
 	sa, err := sas.repo.ServiceAccounts.ForEmail(authResult.Email)
 	if _, ok := err.(*errors.EntityNotFoundError); ok {
 		sa = &models.ServiceAccount{
@@ -381,6 +845,8 @@ func (sas *serviceAccounts) AuthenticateAccessToken(
 		}
 	} else if err != nil {
 		return nil, err
+	} else if !sa.IsActive() {
+		return nil, errors.NewInactiveServiceAccountError(sa.ID, sa.Status.String())
 	} else if authResult.Picture != "" && authResult.Picture != sa.Picture {
 		sa.Picture = authResult.Picture
 		if err = sas.repo.ServiceAccounts.Update(sa); err != nil {
@@ -394,14 +860,143 @@ func (sas *serviceAccounts) AuthenticateAccessToken(
 	}, nil
 }
 
+// ApplyRoleBindingRules binds serviceAccountID to roles of the rules it
+// matches, given it's in groups, and unbinds it from roles of rules it
+// doesn't match anymore. Bindings not made by rules are left untouched, and
+// rules of roles that don't exist are ignored
+func (sas serviceAccounts) ApplyRoleBindingRules(
+	serviceAccountID string, groups []string,
+) error {
+	if len(sas.roleBindingRules) == 0 {
+		return nil
+	}
+	return sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		sa, err := repo.ServiceAccounts.Get(serviceAccountID)
+		if err != nil {
+			return err
+		}
+		matched := map[string]bool{}
+		for _, rule := range sas.roleBindingRules {
+			if !rule.Matches(sa.Email, groups) {
+				continue
+			}
+			rsSl, err := repo.Roles.ForName(rule.Role)
+			if err != nil {
+				return err
+			}
+			for _, r := range rsSl {
+				matched[r.ID] = true
+			}
+		}
+		rbs, err := repo.Roles.BindingsForServiceAccountID(sa.ID)
+		if err != nil {
+			return err
+		}
+		bound := map[string]bool{}
+		toUnbind := []string{}
+		for _, rb := range rbs {
+			bound[rb.RoleID] = true
+			if rb.ManagedByRule && !matched[rb.RoleID] {
+				toUnbind = append(toUnbind, rb.RoleID)
+			}
+		}
+		toBind := []string{}
+		for roleID := range matched {
+			if !bound[roleID] {
+				toBind = append(toBind, roleID)
+			}
+		}
+		if len(toBind) == 0 && len(toUnbind) == 0 {
+			return nil
+		}
+		before, err := getServiceAccountAuditState(repo, sa.ID)
+		if err != nil {
+			return err
+		}
+		for _, roleID := range toBind {
+			if err := repo.Roles.Bind(&models.RoleBinding{
+				RoleID: roleID, ServiceAccountID: sa.ID, ManagedByRule: true,
+			}); err != nil {
+				return err
+			}
+		}
+		for _, roleID := range toUnbind {
+			if err := repo.Roles.Unbind(roleID, sa.ID); err != nil {
+				return err
+			}
+		}
+		after, err := getServiceAccountAuditState(repo, sa.ID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			ActorServiceAccountID: sa.ID,
+			Action:                models.AuditActions.ApplyRoleBindingRules,
+			TargetType:            models.AuditTargetTypes.ServiceAccount,
+			TargetID:              sa.ID,
+		}, before, after)
+	})
+}
+
+// managedRoleBindings returns bindings of serviceAccountID made by rules or
+// through SCIM by role id, so that replacing its bindings keeps them managed
+// the same way
+func managedRoleBindings(
+	repo *repositories.All, serviceAccountID string,
+) (map[string]models.RoleBinding, error) {
+	rbs, err := repo.Roles.BindingsForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	managed := map[string]models.RoleBinding{}
+	for _, rb := range rbs {
+		if rb.ManagedByRule || rb.ManagedBySCIM {
+			managed[rb.RoleID] = rb
+		}
+	}
+	return managed, nil
+}
+
+// keyLastUsedResolution is how stale a key last_used_at may be, so that
+// not every request authenticated with it writes to Postgres
+const keyLastUsedResolution = time.Minute
+
+// Logout revokes a login provider access token, along with the tokens it was
+// refreshed from or into. Will.IAM access tokens can't be revoked, they're
+// short-lived instead
+func (sas serviceAccounts) Logout(accessToken string) error {
+	if sas.accessTokens != nil && sas.accessTokens.IsAccessToken(accessToken) {
+		return errors.NewInvalidAccessTokenError(
+			"access tokens can't be revoked, they expire on their own",
+		)
+	}
+	return sas.repo.Tokens.Revoke(accessToken)
+}
+
 // AuthenticateKeyPair verifies if key pair is valid
 func (sas *serviceAccounts) AuthenticateKeyPair(
 	keyID, keySecret string,
 ) (*models.AccessKeyPairAuth, error) {
-	sa, err := sas.repo.ServiceAccounts.ForKeyPair(keyID, keySecret)
+	k, err := sas.repo.ServiceAccountKeys.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if !k.Matches(keySecret) {
+		return nil, errors.NewEntityNotFoundError(models.ServiceAccount{}, keyID)
+	}
+	sa, err := sas.repo.ServiceAccounts.Get(k.ServiceAccountID)
 	if err != nil {
 		return nil, err
 	}
+	if !sa.IsActive() {
+		return nil, errors.NewInactiveServiceAccountError(sa.ID, sa.Status.String())
+	}
+	if k.LastUsedAt.IsZero() ||
+		time.Since(k.LastUsedAt.Time) > keyLastUsedResolution {
+		if err := sas.repo.ServiceAccountKeys.Touch(k.ID); err != nil {
+			return nil, err
+		}
+	}
 	return &models.AccessKeyPairAuth{
 		ServiceAccountID: sa.ID,
 		Name:             sa.Name,
@@ -417,7 +1012,22 @@ func (sas serviceAccounts) HasPermissionString(
 	if err != nil {
 		return false, err
 	}
-	return sas.repo.ServiceAccounts.HasPermission(serviceAccountID, ps)
+	if sas.permissionsCache == nil {
+		// conditions can only be evaluated loading permissions
+		conditioned, err := sas.repo.ServiceAccounts.
+			HasConditionedPermissions(serviceAccountID)
+		if err != nil {
+			return false, err
+		}
+		if !conditioned {
+			return sas.repo.ServiceAccounts.HasPermission(serviceAccountID, ps)
+		}
+	}
+	saPermissions, err := sas.GetPermissions(serviceAccountID)
+	if err != nil {
+		return false, err
+	}
+	return ps.IsPresent(applicablePermissions(sas.ctx, saPermissions)), nil
 }
 
 func (sas serviceAccounts) HasAllOwnerPermissions(
@@ -427,6 +1037,8 @@ func (sas serviceAccounts) HasAllOwnerPermissions(
 	for i := range permissions {
 		psCpy[i] = permissions[i]
 		psCpy[i].OwnershipLevel = models.OwnershipLevels.Owner
+		// owning a permission is required to attribute its deny as well
+		psCpy[i].Deny = false
 	}
 	has, err := sas.HasPermissions(serviceAccountID, psCpy)
 	if err != nil {
@@ -465,6 +1077,7 @@ func (sas serviceAccounts) HasPermissions(
 	if err != nil {
 		return nil, err
 	}
+	saPermissions = applicablePermissions(sas.ctx, saPermissions)
 	has := make([]bool, len(permissions))
 	for i := range permissions {
 		has[i] = permissions[i].IsPresent(saPermissions)
@@ -472,7 +1085,201 @@ func (sas serviceAccounts) HasPermissions(
 	return has, nil
 }
 
+// ExplainPermission evaluates, role by role, every permission of a service
+// account of the same service and action as permission against it and
+// tells which one decided it
+func (sas serviceAccounts) ExplainPermission(
+	serviceAccountID string, permission models.Permission,
+) (*models.PermissionExplanation, error) {
+	if _, err := sas.repo.ServiceAccounts.Get(serviceAccountID); err != nil {
+		return nil, err
+	}
+	rs, err := sas.repo.Roles.ForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	rbs, err := sas.repo.Roles.BindingsForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	bindings := map[string]models.RoleBinding{}
+	for _, rb := range rbs {
+		bindings[rb.RoleID] = rb
+	}
+	// roles bound through groups are explained after directly bound ones
+	gbs, err := sas.repo.Groups.BindingsForServiceAccountID(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string]string{}
+	for _, gb := range gbs {
+		if _, ok := bindings[gb.RoleID]; ok || groups[gb.RoleID] != "" {
+			continue
+		}
+		r, err := sas.repo.Roles.Get(gb.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, *r)
+		groups[gb.RoleID] = gb.GroupID
+	}
+	ers, err := explainedRoles(sas.repo, rs, bindings, groups)
+	if err != nil {
+		return nil, err
+	}
+	pe := &models.PermissionExplanation{
+		ServiceAccountID:         serviceAccountID,
+		Permission:               permission.String(),
+		ResourceHierarchyMatches: []string{},
+		Roles:                    make([]models.RoleExplanation, len(ers)),
+	}
+	cc := conditionsContext(sas.ctx)
+	effective := []models.Permission{}
+	matched := map[string]bool{}
+	for i, er := range ers {
+		r, rb := er.role, er.binding
+		pe.Roles[i] = models.RoleExplanation{
+			ID:                 r.ID,
+			Name:               r.Name,
+			IsBaseRole:         r.IsBaseRole,
+			IncludedBy:         er.includedBy,
+			BoundThroughGroup:  er.group,
+			BindingExpiresAt:   rb.ExpiresAt,
+			BindingExpired:     rb.Expired(),
+			PermissionsResults: []models.PermissionEvaluation{},
+		}
+		pSl, err := sas.repo.Permissions.ForRole(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pSl {
+			if applies, _ := p.Applies(cc); applies && !rb.Expired() {
+				effective = append(effective, p)
+			}
+			ev := permission.EvaluateIn(p, cc)
+			// permissions of other services or actions can't decide, and
+			// explaining them would disclose every grant of the account
+			if !ev.ServiceMatches || !ev.ActionMatches {
+				continue
+			}
+			pe.Roles[i].PermissionsResults = append(
+				pe.Roles[i].PermissionsResults, ev,
+			)
+			rh := p.ResourceHierarchy.String()
+			if ev.ResourceHierarchyMatches && !matched[rh] {
+				matched[rh] = true
+				pe.ResourceHierarchyMatches = append(pe.ResourceHierarchyMatches, rh)
+			}
+		}
+	}
+	match, allowed := permission.Match(effective)
+	pe.Allowed = allowed
+	switch {
+	case match == nil:
+		pe.Reason = "no permission grants the requested one"
+	case allowed:
+		pe.DecidedBy = &match.ID
+		pe.Reason = fmt.Sprintf("granted by %s", match.String())
+	default:
+		pe.DecidedBy = &match.ID
+		pe.Reason = fmt.Sprintf("denied by %s", match.String())
+	}
+	return pe, nil
+}
+
+// explainedRole is a role whose permissions a service account has through
+// binding, either because it's bound or because includedBy, a role it's
+// bound to, includes it. Roles bound through a group have no binding and
+// tell which group instead
+type explainedRole struct {
+	role       models.Role
+	binding    models.RoleBinding
+	group      string
+	includedBy string
+}
+
+// explainedRoles returns bound roles rs followed by the roles they include.
+// Roles included by many are explained through the first bound role that
+// hasn't expired, if any
+func explainedRoles(
+	repo *repositories.All, rs []models.Role,
+	bindings map[string]models.RoleBinding, groups map[string]string,
+) ([]explainedRole, error) {
+	ers := make([]explainedRole, len(rs))
+	seen := map[string]bool{}
+	for i, r := range rs {
+		ers[i] = explainedRole{
+			role: r, binding: bindings[r.ID], group: groups[r.ID],
+		}
+		seen[r.ID] = true
+	}
+	for _, expired := range []bool{false, true} {
+		for i := range rs {
+			if ers[i].binding.Expired() != expired {
+				continue
+			}
+			queue := []string{rs[i].ID}
+			for len(queue) > 0 {
+				included, err := repo.Roles.Included(queue[0])
+				if err != nil {
+					return nil, err
+				}
+				queue = queue[1:]
+				for _, ir := range included {
+					if seen[ir.ID] {
+						continue
+					}
+					seen[ir.ID] = true
+					queue = append(queue, ir.ID)
+					ers = append(ers, explainedRole{
+						role: ir, binding: ers[i].binding, group: ers[i].group,
+						includedBy: rs[i].ID,
+					})
+				}
+			}
+		}
+	}
+	return ers, nil
+}
+
+// MatchPermissionString returns the service account permission deciding
+// whether it has permissionStr, along with the decision
+func (sas serviceAccounts) MatchPermissionString(
+	serviceAccountID, permissionStr string,
+) (*models.Permission, bool, error) {
+	matches, has, err := sas.MatchPermissionsStrings(
+		serviceAccountID, []string{permissionStr},
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	return matches[0], has[0], nil
+}
+
+// MatchPermissionsStrings returns, for each permission, the service account
+// permission deciding whether it has it, along with the decisions
+func (sas serviceAccounts) MatchPermissionsStrings(
+	serviceAccountID string, permissions []string,
+) ([]*models.Permission, []bool, error) {
+	pSl, err := models.BuildPermissions(permissions)
+	if err != nil {
+		return nil, nil, err
+	}
+	saPermissions, err := sas.GetPermissions(serviceAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	saPermissions = applicablePermissions(sas.ctx, saPermissions)
+	matches := make([]*models.Permission, len(pSl))
+	has := make([]bool, len(pSl))
+	for i := range pSl {
+		matches[i], has[i] = pSl[i].Match(saPermissions)
+	}
+	return matches, has, nil
+}
+
 func serviceAccountHasPermissions(
+	ctx context.Context,
 	repo *repositories.All,
 	serviceAccountID string,
 	permissions []models.Permission,
@@ -481,6 +1288,7 @@ func serviceAccountHasPermissions(
 	if err != nil {
 		return nil, err
 	}
+	saPermissions = applicablePermissions(ctx, saPermissions)
 	has := make([]bool, len(permissions))
 	for i := range permissions {
 		has[i] = permissions[i].IsPresent(saPermissions)
@@ -491,6 +1299,9 @@ func serviceAccountHasPermissions(
 func (sas serviceAccounts) GetPermissions(
 	serviceAccountID string,
 ) ([]models.Permission, error) {
+	if sas.permissionsCache != nil {
+		return sas.permissionsCache.get(sas.repo, serviceAccountID)
+	}
 	return serviceAccountGetPermissions(sas.repo, serviceAccountID)
 }
 
@@ -503,7 +1314,26 @@ func serviceAccountGetPermissions(
 func (sas serviceAccounts) CreatePermission(
 	serviceAccountID string, permission *models.Permission,
 ) error {
-	return createPermissionForServiceAccount(sas.repo, serviceAccountID, permission)
+	return sas.repo.WithPGTx(sas.ctx, func(repo *repositories.All) error {
+		before, err := getServiceAccountAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		if err := createPermissionForServiceAccount(
+			repo, serviceAccountID, permission,
+		); err != nil {
+			return err
+		}
+		after, err := getServiceAccountAuditState(repo, serviceAccountID)
+		if err != nil {
+			return err
+		}
+		return recordAuditEvent(sas.ctx, repo, &models.AuditEvent{
+			Action:     models.AuditActions.CreatePermission,
+			TargetType: models.AuditTargetTypes.ServiceAccount,
+			TargetID:   serviceAccountID,
+		}, before, after)
+	})
 }
 
 func createPermissionForServiceAccount(
@@ -3,9 +3,16 @@
 package usecases_test
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/go-pg/pg"
+	"github.com/topfreegames/Will.IAM/conditions"
+	"github.com/topfreegames/Will.IAM/errors"
 	"github.com/topfreegames/Will.IAM/models"
 	"github.com/topfreegames/Will.IAM/repositories"
 	helpers "github.com/topfreegames/Will.IAM/testing"
@@ -146,6 +153,31 @@ var saHasPermissionTestCases = []saHasPermissionTestCase{
 		permission:                "Service1::RL::Do1::x::*",
 		want:                      false,
 	},
+	// Deny
+	saHasPermissionTestCase{
+		name:                      "Deny wins over allow",
+		serviceAccountPermissions: []string{"Service1::RL::*::*", "!Service1::RL::*::prod::*"},
+		permission:                "Service1::RL::Do1::prod::x",
+		want:                      false,
+	},
+	saHasPermissionTestCase{
+		name:                      "Deny doesn't affect other hierarchies",
+		serviceAccountPermissions: []string{"Service1::RL::*::*", "!Service1::RL::*::prod::*"},
+		permission:                "Service1::RL::Do1::stag::x",
+		want:                      true,
+	},
+	saHasPermissionTestCase{
+		name:                      "Deny inside requested open hierarchy",
+		serviceAccountPermissions: []string{"Service1::RL::*::*", "!Service1::RL::Do1::prod::*"},
+		permission:                "Service1::RL::Do1::*",
+		want:                      false,
+	},
+	saHasPermissionTestCase{
+		name:                      "Owner deny doesn't block lender",
+		serviceAccountPermissions: []string{"Service1::RO::*::*", "!Service1::RO::*::prod::*"},
+		permission:                "Service1::RL::Do1::prod::x",
+		want:                      true,
+	},
 }
 
 func TestServiceAccountsHasPermissionWhenPermissionsOnBaseRole(t *testing.T) {
@@ -264,6 +296,168 @@ var saListWithPermissionTestCases = []saListWithPermissionTestCase{
 		permission: "Service1::RO::Do1::x::z",
 		want:       []string{"rootSAKeyPair", "sa2"},
 	},
+	saListWithPermissionTestCase{
+		name: "Service Accounts with deny",
+		serviceAccountPermissions: [][]string{
+			[]string{"Service1::RL::Do1::x::*", "!Service1::RL::Do1::x::z"},
+			[]string{"Service1::RL::Do1::x::y"},
+			[]string{"Service1::RL::*::x::*"},
+		},
+		permission: "Service1::RL::Do1::x::z",
+		want:       []string{"rootSAKeyPair", "sa2"},
+	},
+	saListWithPermissionTestCase{
+		name: "Service Accounts with mid-path wildcards and globs",
+		serviceAccountPermissions: [][]string{
+			[]string{"Service1::RL::Do1::*::prod::*"},
+			[]string{"Service1::RL::Do1::*::stag::*"},
+			[]string{"Service1::RL::Do1::sniper-*::*", "!Service1::RL::Do1::*::prod::*"},
+			[]string{"Service1::RL::Do1::sniper-*::*"},
+		},
+		permission: "Service1::RL::Do1::sniper-3d::prod::red",
+		want:       []string{"rootSAKeyPair", "sa0", "sa3"},
+	},
+	saListWithPermissionTestCase{
+		name: "Service Accounts with open hierarchies containing mid-path wildcards",
+		serviceAccountPermissions: [][]string{
+			[]string{"Service1::RL::Do1::x::*"},
+			[]string{"Service1::RL::Do1::x::y::*"},
+			[]string{"Service1::RL::Do1::x::*::z"},
+		},
+		permission: "Service1::RL::Do1::x::*::z",
+		want:       []string{"rootSAKeyPair", "sa0", "sa2"},
+	},
+}
+
+func TestServiceAccountsHasPermissionWithConditions(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	ps, err := models.BuildPermissions([]string{
+		"Maestro::RL::ListSchedulers::*",
+		"Maestro::RL::Deploy::prod::*",
+		"!Maestro::RL::ListSchedulers::secret",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ps[1].Condition = `inCIDR(request.ip, "10.8.0.0/16") && context.ticket != ""`
+	ps[2].Condition = `context.env == "prod"`
+	sa := &usecases.ServiceAccountWithNested{
+		Name:               "sa1",
+		Email:              "sa1@domain.com",
+		Permissions:        ps,
+		AuthenticationType: models.AuthenticationTypes.OAuth2,
+	}
+	if err := saUC.CreateWithNested(sa); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vpn := &conditions.Context{
+		IP:         net.ParseIP("10.8.1.2"),
+		Attributes: map[string]string{"ticket": "OPS-1", "env": "dev"},
+	}
+	elsewhere := &conditions.Context{
+		IP:         net.ParseIP("192.168.1.2"),
+		Attributes: map[string]string{"ticket": "OPS-1", "env": "prod"},
+	}
+	type testCase struct {
+		cc         *conditions.Context
+		permission string
+		want       bool
+	}
+	testCases := []testCase{
+		{nil, "Maestro::RL::ListSchedulers::x", true},
+		// lacking request data, grants don't apply while denies do
+		{nil, "Maestro::RL::Deploy::prod::x", false},
+		{nil, "Maestro::RL::ListSchedulers::secret", false},
+		{vpn, "Maestro::RL::Deploy::prod::x", true},
+		{vpn, "Maestro::RL::ListSchedulers::secret", true},
+		{elsewhere, "Maestro::RL::Deploy::prod::x", false},
+		{elsewhere, "Maestro::RL::ListSchedulers::secret", false},
+	}
+	for _, tt := range testCases {
+		ctx := context.Background()
+		if tt.cc != nil {
+			ctx = usecases.WithConditionsContext(ctx, tt.cc)
+		}
+		has, err := saUC.WithContext(ctx).HasPermissionString(sa.ID, tt.permission)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if has != tt.want {
+			t.Errorf("Expected has %s to be %v. Got %v", tt.permission, tt.want, has)
+		}
+		hasSl, err := saUC.WithContext(ctx).
+			HasPermissionsStrings(sa.ID, []string{tt.permission})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if hasSl[0] != tt.want {
+			t.Errorf("Expected has many %s to be %v. Got %v", tt.permission, tt.want, hasSl[0])
+		}
+	}
+
+	// queries that can't evaluate conditions ignore conditioned grants
+	repo := helpers.GetRepo(t)
+	has, err := repo.ServiceAccounts.HasPermission(sa.ID, ps[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if has {
+		t.Errorf("Expected conditioned grant to be ignored")
+	}
+}
+
+func TestServiceAccountsCreatePermissionKeepsConditionsApart(t *testing.T) {
+	helpers.CleanupPG(t)
+	repo := helpers.GetRepo(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa1", "sa1@domain.com", models.AuthenticationTypes.OAuth2,
+	)
+	vpn := `inCIDR(request.ip, "10.8.0.0/16")`
+	office := `inCIDR(request.ip, "192.168.0.0/16")`
+	later := pg.NullTime{Time: time.Now().Add(time.Hour)}
+	grants := []struct {
+		permission string
+		condition  string
+		expiresAt  pg.NullTime
+	}{
+		{"Maestro::RL::Deploy::x", vpn, pg.NullTime{}},
+		{"Maestro::RL::Deploy::x", "", later},
+		{"Maestro::RL::Scale::x", vpn, pg.NullTime{}},
+		{"Maestro::RL::Scale::x", office, later},
+		{"Maestro::RL::Delete::x", vpn, pg.NullTime{}},
+		{"Maestro::RL::Delete::x", vpn, later},
+	}
+	for _, g := range grants {
+		p, err := models.BuildPermission(g.permission)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		p.Condition = g.condition
+		p.ExpiresAt = g.expiresAt
+		if err := saUC.CreatePermission(sa.ID, &p); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	pSl, err := repo.Permissions.ForRole(sa.BaseRoleID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expiring := map[string]bool{}
+	for _, p := range pSl {
+		expiring[p.ConditionedString()] = !p.ExpiresAt.IsZero()
+	}
+	expected := map[string]bool{
+		"Maestro::RL::Deploy::x if " + vpn:   false,
+		"Maestro::RL::Deploy::x":             true,
+		"Maestro::RL::Scale::x if " + vpn:    false,
+		"Maestro::RL::Scale::x if " + office: true,
+		"Maestro::RL::Delete::x if " + vpn:   false,
+	}
+	if !reflect.DeepEqual(expiring, expected) {
+		t.Errorf("Expected permissions %v. Got %v", expected, expiring)
+	}
 }
 
 func TestServiceAccountsListWithPermissionWhenPermissionOnBaseRole(t *testing.T) {
@@ -372,3 +566,203 @@ func TestServiceAccountsListWithPermissionWhenPermissionOnNonBaseRole(t *testing
 		})
 	}
 }
+
+func TestServiceAccountsListWithPermissionSkipsInactive(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	root := helpers.CreateRootServiceAccountWithKeyPair(t, "rootSAKeyPair", "rootSAKeyPair@test.com")
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "sa0", "sa0@domain.com", models.AuthenticationTypes.OAuth2,
+		"Service1::RL::Do1::*",
+	)
+	if err := saUC.Disable(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ps, err := models.BuildPermission("Service1::RL::Do1::x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	list, count, err := saUC.ListWithPermission(root.ID, &repositories.ListOptions{}, ps)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 1 || len(list) != 1 || list[0].ID != root.ID {
+		t.Errorf("Expected only root to be listed. Got %d: %v", count, list)
+	}
+}
+
+func TestServiceAccountsKeyRotation(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa, err := saUC.CreateKeyPairType("some name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var secretHash string
+	if _, err := helpers.GetStorage(t).PG.DB.Query(
+		&secretHash, "SELECT secret_hash FROM service_account_keys WHERE id = ?",
+		sa.KeyID,
+	); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if secretHash == "" || secretHash == sa.KeySecret {
+		t.Errorf("Expected secret to be stored hashed. Got %s", secretHash)
+	}
+	k, err := saUC.CreateKey(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, kp := range [][2]string{{sa.KeyID, sa.KeySecret}, {k.ID, k.Secret}} {
+		kpAuth, err := saUC.AuthenticateKeyPair(kp[0], kp[1])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if kpAuth.ServiceAccountID != sa.ID {
+			t.Errorf("Expected service account %s. Got %s", sa.ID, kpAuth.ServiceAccountID)
+		}
+	}
+	if _, err := saUC.AuthenticateKeyPair(k.ID, sa.KeySecret); err == nil {
+		t.Errorf("Expected key with another key secret to be rejected")
+	}
+	ks, err := saUC.ListKeys(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ks) != 2 || ks[0].ID != sa.KeyID || ks[1].ID != k.ID {
+		t.Fatalf("Unexpected keys %#v", ks)
+	}
+	for _, lk := range ks {
+		if lk.Secret != "" || lk.SecretHash != "" {
+			t.Errorf("Expected listed keys not to have secrets")
+		}
+		if lk.LastUsedAt.IsZero() || lk.CreatedAt.IsZero() {
+			t.Errorf("Expected key %s to have been used", lk.ID)
+		}
+	}
+	if err := saUC.DeleteKey(sa.ID, sa.KeyID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := saUC.AuthenticateKeyPair(sa.KeyID, sa.KeySecret); err == nil {
+		t.Errorf("Expected deleted key to be rejected")
+	}
+	if err := saUC.DeleteKey(sa.ID, sa.KeyID); err == nil {
+		t.Errorf("Expected deleting a missing key to fail")
+	}
+}
+
+func TestServiceAccountsCreateKeyOAuth2(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa, err := saUC.CreateOAuth2Type("some name", "some@test.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := saUC.CreateKey(sa.ID); err == nil {
+		t.Errorf("Expected oauth2 service account not to get keys")
+	}
+}
+
+func TestServiceAccountsDisableAndReactivate(t *testing.T) {
+	helpers.CleanupPG(t)
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	sa, err := saUC.CreateKeyPairType("some name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sa.Status != models.ServiceAccountStatuses.Active {
+		t.Errorf("Expected new service account to be active. Got %s", sa.Status)
+	}
+	if err := saUC.Disable(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = saUC.AuthenticateKeyPair(sa.KeyID, sa.KeySecret)
+	if _, ok := err.(*errors.InactiveServiceAccountError); !ok {
+		t.Errorf("Expected InactiveServiceAccountError. Got %v", err)
+	}
+	if err := saUC.Reactivate(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := saUC.AuthenticateKeyPair(sa.KeyID, sa.KeySecret); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := saUC.Delete(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := saUC.Disable(sa.ID); err == nil {
+		t.Errorf("Expected deleted service account not to be disabled")
+	}
+	sas, count, err := saUC.List(&repositories.ListOptions{PageSize: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 0 || len(sas) != 0 {
+		t.Errorf("Expected deleted service account not to be listed. Got %d", count)
+	}
+}
+
+func TestServiceAccountsOffboard(t *testing.T) {
+	helpers.CleanupPG(t)
+	sa := helpers.CreateServiceAccountWithPermissions(
+		t, "leaver", "leaver@test.com", models.AuthenticationTypes.OAuth2,
+		"SomeService::RL::Do::*",
+	)
+	repo := helpers.GetRepo(t)
+	token := &models.Token{
+		AccessToken:  "leaver-token",
+		RefreshToken: "leaver-refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour),
+		Email:        sa.Email,
+	}
+	if err := repo.Tokens.Save(token); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pr := &models.PermissionRequest{
+		ServiceAccountID:  sa.ID,
+		Service:           "SomeService",
+		OwnershipLevel:    models.OwnershipLevels.Lender,
+		Action:            "Do",
+		ResourceHierarchy: models.BuildResourceHierarchy("x::y"),
+		Message:           "Please I need it",
+	}
+	if err := helpers.GetPermissionsRequestsUseCase(t).Create(pr); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saUC := helpers.GetServiceAccountsUseCase(t)
+	if err := saUC.Offboard(sa.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	offboarded, err := saUC.Get(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if offboarded.Status != models.ServiceAccountStatuses.Deleted {
+		t.Errorf("Expected status deleted. Got %s", offboarded.Status)
+	}
+	ps, err := saUC.GetPermissions(sa.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ps) != 0 {
+		t.Errorf("Expected no permissions. Got %v", ps)
+	}
+	if _, err := repo.Tokens.Get(token.AccessToken); err == nil {
+		t.Errorf("Expected token to be revoked")
+	}
+	pr, err = repo.PermissionsRequests.Get(pr.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pr.State != models.PermissionRequestStates.Denied {
+		t.Errorf("Expected permission request to be denied. Got %s", pr.State)
+	}
+	aes, _, err := helpers.GetAuditEventsUseCase(t).List(
+		&repositories.AuditEventsFilter{TargetID: sa.ID}, &repositories.ListOptions{},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(aes) == 0 || aes[0].Action != models.AuditActions.OffboardServiceAccount {
+		t.Errorf("Expected offboarding to be audited. Got %v", aes)
+	}
+}
@@ -29,36 +29,51 @@ func (ss services) WithContext(ctx context.Context) Services {
 // Also creates an associate Service Account with full access
 // and attributes full access to creator
 func (ss services) Create(service *models.Service) error {
-	creatorSA, err := ss.repo.ServiceAccounts.Get(service.CreatorServiceAccountID)
+	return ss.repo.WithPGTx(ss.ctx, func(repo *repositories.All) error {
+		return createService(ss.ctx, repo, service)
+	})
+}
+
+func createService(
+	ctx context.Context, repo *repositories.All, service *models.Service,
+) error {
+	creatorSA, err := repo.ServiceAccounts.Get(service.CreatorServiceAccountID)
 	if err != nil {
 		return err
 	}
-	return ss.repo.WithPGTx(ss.ctx, func(repo *repositories.All) error {
-		sa := models.BuildKeyPairServiceAccount(service.Name)
-		if err := createServiceAccount(sa, repo); err != nil {
-			return err
-		}
-		service.ServiceAccountID = sa.ID
-		if err := repo.Services.Create(service); err != nil {
-			return err
-		}
-		buildFullAccessPermissionForRoleID := func(roleID string) *models.Permission {
-			return &models.Permission{
-				Service:           service.PermissionName,
-				OwnershipLevel:    models.OwnershipLevels.Owner,
-				Action:            models.Action("*"),
-				ResourceHierarchy: models.ResourceHierarchy("*"),
-				RoleID:            roleID,
-			}
+	sa := models.BuildKeyPairServiceAccount(service.Name)
+	if err := createServiceAccount(sa, repo); err != nil {
+		return err
+	}
+	service.ServiceAccountID = sa.ID
+	if err := repo.Services.Create(service); err != nil {
+		return err
+	}
+	buildFullAccessPermissionForRoleID := func(roleID string) *models.Permission {
+		return &models.Permission{
+			Service:           service.PermissionName,
+			OwnershipLevel:    models.OwnershipLevels.Owner,
+			Action:            models.Action("*"),
+			ResourceHierarchy: models.ResourceHierarchy("*"),
+			RoleID:            roleID,
 		}
-		repo.Permissions.Create(
-			buildFullAccessPermissionForRoleID(sa.BaseRoleID),
-		)
-		repo.Permissions.Create(
-			buildFullAccessPermissionForRoleID(creatorSA.BaseRoleID),
-		)
-		return nil
-	})
+	}
+	if err := repo.Permissions.Create(
+		buildFullAccessPermissionForRoleID(sa.BaseRoleID),
+	); err != nil {
+		return err
+	}
+	if err := repo.Permissions.Create(
+		buildFullAccessPermissionForRoleID(creatorSA.BaseRoleID),
+	); err != nil {
+		return err
+	}
+	return recordAuditEvent(ctx, repo, &models.AuditEvent{
+		ActorServiceAccountID: service.CreatorServiceAccountID,
+		Action:                models.AuditActions.CreateService,
+		TargetType:            models.AuditTargetTypes.Service,
+		TargetID:              service.ID,
+	}, nil, service)
 }
 
 func (ss services) List() ([]models.Service, error) {
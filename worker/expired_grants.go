@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func init() {
+	RegisterJob("expiredGrants", time.Minute, newExpiredGrantsJob)
+}
+
+func newExpiredGrantsJob(w *Worker) (Job, error) {
+	egsUC := usecases.NewExpiredGrants(w.Repo())
+	logger := w.Logger()
+	return JobFunc(func(ctx context.Context) error {
+		egs, err := egsUC.WithContext(ctx).Sweep()
+		if err != nil {
+			return err
+		}
+		for _, eg := range egs {
+			logger.WithFields(logrus.Fields{
+				"kind":             eg.Kind,
+				"grantId":          eg.GrantID,
+				"roleId":           eg.RoleID,
+				"serviceAccountId": eg.ServiceAccountID,
+				"permission":       eg.Permission,
+				"expiresAt":        eg.ExpiresAt,
+			}).Info("removed expired grant")
+		}
+		return nil
+	}), nil
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a periodic task run by the worker
+type Job interface {
+	Run(context.Context) error
+}
+
+// JobFunc adapts a plain func to Job
+type JobFunc func(context.Context) error
+
+// Run calls f(ctx)
+func (f JobFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// JobBuilder builds a Job with dependencies taken from the worker
+type JobBuilder func(*Worker) (Job, error)
+
+type jobDefinition struct {
+	name            string
+	defaultInterval time.Duration
+	builder         JobBuilder
+}
+
+var (
+	jobsMu   sync.RWMutex
+	jobsDefs = map[string]jobDefinition{}
+)
+
+// RegisterJob makes a job available to workers. It runs every
+// worker.jobs.{name}.interval (defaultInterval if not set) unless
+// worker.jobs.{name}.enabled is false. It panics if name is already taken
+func RegisterJob(name string, defaultInterval time.Duration, builder JobBuilder) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if _, ok := jobsDefs[name]; ok {
+		panic(fmt.Sprintf("worker: job %s registered twice", name))
+	}
+	jobsDefs[name] = jobDefinition{
+		name:            name,
+		defaultInterval: defaultInterval,
+		builder:         builder,
+	}
+}
+
+// RegisteredJobs returns the names of all registered jobs, sorted
+func RegisteredJobs() []string {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+	names := make([]string, 0, len(jobsDefs))
+	for name := range jobsDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func jobDefinitions() []jobDefinition {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+	defs := make([]jobDefinition, 0, len(jobsDefs))
+	for _, def := range jobsDefs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].name < defs[j].name })
+	return defs
+}
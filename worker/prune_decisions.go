@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/usecases"
+)
+
+func init() {
+	RegisterJob("pruneDecisions", time.Hour, newPruneDecisionsJob)
+}
+
+// newPruneDecisionsJob keeps the decisions table, written by the postgres
+// decision log sink, to worker.jobs.pruneDecisions.retention
+func newPruneDecisionsJob(w *Worker) (Job, error) {
+	w.Config().SetDefault("worker.jobs.pruneDecisions.retention", "720h")
+	retention := w.Config().GetDuration("worker.jobs.pruneDecisions.retention")
+	if retention <= 0 {
+		return nil, fmt.Errorf("retention must be positive")
+	}
+	dsUC := usecases.NewDecisions(w.Repo())
+	logger := w.Logger()
+	return JobFunc(func(ctx context.Context) error {
+		before := time.Now().Add(-retention)
+		pruned, err := dsUC.WithContext(ctx).Prune(before)
+		if err != nil {
+			return err
+		}
+		if pruned > 0 {
+			logger.WithField("before", before).
+				Infof("pruned %d decisions", pruned)
+		}
+		return nil
+	}), nil
+}
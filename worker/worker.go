@@ -0,0 +1,218 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/Will.IAM/constants"
+	"github.com/topfreegames/Will.IAM/repositories"
+	"github.com/topfreegames/extensions/middleware"
+)
+
+// Worker runs Will.IAM periodic jobs. Many worker replicas can run side by
+// side: each job runs once per interval, by whichever replica claims it
+// first (see repositories.JobRuns), and a Postgres advisory lock keeps runs
+// lasting longer than the interval from overlapping
+type Worker struct {
+	config          *viper.Viper
+	logger          logrus.FieldLogger
+	metricsReporter middleware.MetricsReporter
+	storage         *repositories.Storage
+	repo            *repositories.All
+	jobs            []*scheduledJob
+}
+
+type scheduledJob struct {
+	Job
+	name     string
+	interval time.Duration
+	lockKey  int64
+}
+
+func loadDefaultConfigWorker(config *viper.Viper) {
+	config.SetDefault("worker.shutdownTimeout", "30s")
+	for _, def := range jobDefinitions() {
+		config.SetDefault(fmt.Sprintf("worker.jobs.%s.enabled", def.name), true)
+		config.SetDefault(
+			fmt.Sprintf("worker.jobs.%s.interval", def.name), def.defaultInterval,
+		)
+	}
+}
+
+// NewWorker creates a new worker
+func NewWorker(
+	config *viper.Viper, logger logrus.FieldLogger,
+	storageOrNil *repositories.Storage,
+) (*Worker, error) {
+	loadDefaultConfigWorker(config)
+	mr, err := middleware.NewDogStatsD(config)
+	if err != nil {
+		return nil, err
+	}
+	if storageOrNil == nil {
+		storageOrNil = repositories.NewStorage()
+	}
+	w := &Worker{
+		config:          config,
+		logger:          logger,
+		metricsReporter: mr,
+		storage:         storageOrNil,
+	}
+	if err := w.configurePG(); err != nil {
+		return nil, err
+	}
+	w.repo = repositories.New(w.storage)
+	if err := w.configureJobs(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Worker) configurePG() error {
+	if w.storage.PG != nil {
+		return nil
+	}
+	return w.storage.ConfigurePG(w.config)
+}
+
+func (w *Worker) configureJobs() error {
+	for _, def := range jobDefinitions() {
+		if !w.config.GetBool(fmt.Sprintf("worker.jobs.%s.enabled", def.name)) {
+			w.logger.WithField("job", def.name).Info("job disabled")
+			continue
+		}
+		interval := w.config.GetDuration(
+			fmt.Sprintf("worker.jobs.%s.interval", def.name),
+		)
+		if interval < time.Millisecond {
+			return fmt.Errorf("job %s: interval must be at least 1ms", def.name)
+		}
+		job, err := def.builder(w)
+		if err != nil {
+			return fmt.Errorf("job %s: %v", def.name, err)
+		}
+		w.jobs = append(w.jobs, &scheduledJob{
+			Job:      job,
+			name:     def.name,
+			interval: interval,
+			lockKey:  jobLockKey(def.name),
+		})
+	}
+	return nil
+}
+
+// jobLockKey maps a job name to the advisory lock key shared by all replicas
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(fmt.Sprintf("%s/worker/%s", constants.AppInfo.Name, name)))
+	return int64(h.Sum64())
+}
+
+// Config returns the worker config, for use in JobBuilders
+func (w *Worker) Config() *viper.Viper {
+	return w.config
+}
+
+// Logger returns the worker logger, for use in JobBuilders
+func (w *Worker) Logger() logrus.FieldLogger {
+	return w.logger
+}
+
+// Repo returns the worker repositories, for use in JobBuilders
+func (w *Worker) Repo() *repositories.All {
+	return w.repo
+}
+
+// Start runs all enabled jobs until ctx is done. Then it stops scheduling
+// new runs and waits up to worker.shutdownTimeout for running ones to
+// finish before cancelling them
+func (w *Worker) Start(ctx context.Context) {
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	defer cancelRuns()
+	var wg sync.WaitGroup
+	for _, j := range w.jobs {
+		wg.Add(1)
+		go func(j *scheduledJob) {
+			defer wg.Done()
+			w.schedule(ctx, runCtx, j)
+		}(j)
+	}
+	<-ctx.Done()
+	w.logger.Info("stopping worker, waiting for running jobs")
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(w.config.GetDuration("worker.shutdownTimeout")):
+		w.logger.Warn("shutdown timeout reached, cancelling running jobs")
+		cancelRuns()
+		<-done
+	}
+	w.logger.Info("worker stopped")
+}
+
+func (w *Worker) schedule(ctx, runCtx context.Context, j *scheduledJob) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	w.run(runCtx, j)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.run(runCtx, j)
+		}
+	}
+}
+
+func (w *Worker) run(ctx context.Context, j *scheduledJob) {
+	l := w.logger.WithField("job", j.name)
+	start := time.Now()
+	ran := false
+	err := w.repo.WithPGTx(ctx, func(repo *repositories.All) error {
+		acquired, err := repo.Locks.TryXact(j.lockKey)
+		if err != nil || !acquired {
+			return err
+		}
+		// failed runs roll the claim back, so the job is retried
+		// within the interval
+		claimed, err := repo.JobRuns.Claim(j.name, j.interval)
+		if err != nil || !claimed {
+			return err
+		}
+		ran = true
+		return runSafely(ctx, j)
+	})
+	status := "ok"
+	if err != nil {
+		status = "error"
+		l.WithError(err).Error("job failed")
+	} else if !ran {
+		status = "skipped"
+		l.Debug("job is running or already ran this interval")
+	}
+	tags := []string{fmt.Sprintf("job:%s", j.name), fmt.Sprintf("status:%s", status)}
+	w.metricsReporter.Increment(constants.Metrics.JobRuns, tags...)
+	if ran {
+		w.metricsReporter.Timing(
+			constants.Metrics.JobRunTime, time.Since(start), tags...,
+		)
+	}
+}
+
+func runSafely(ctx context.Context, j Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.Run(ctx)
+}
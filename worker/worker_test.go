@@ -0,0 +1,137 @@
+// +build integration
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/Will.IAM/repositories"
+	helpers "github.com/topfreegames/Will.IAM/testing"
+)
+
+func getWorker(t *testing.T) *Worker {
+	t.Helper()
+	w, err := NewWorker(
+		helpers.GetConfig(t, "./../testing/config.yaml"), helpers.GetLogger(t),
+		helpers.GetStorage(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func cleanupJobRuns(t *testing.T) {
+	t.Helper()
+	if _, err := helpers.GetStorage(t).PG.DB.Exec("DELETE FROM job_runs"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerRunsJob(t *testing.T) {
+	cleanupJobRuns(t)
+	w := getWorker(t)
+	runs := 0
+	j := &scheduledJob{
+		Job: JobFunc(func(ctx context.Context) error {
+			runs++
+			return nil
+		}),
+		name:     "testRunsJob",
+		interval: 10 * time.Millisecond,
+		lockKey:  jobLockKey("testRunsJob"),
+	}
+	w.run(context.Background(), j)
+	time.Sleep(2 * j.interval)
+	w.run(context.Background(), j)
+	if runs != 2 {
+		t.Errorf("Expected job to run 2 times. Got %d", runs)
+	}
+}
+
+func TestWorkerRunsJobOncePerInterval(t *testing.T) {
+	cleanupJobRuns(t)
+	w := getWorker(t)
+	other := getWorker(t)
+	runs := 0
+	j := &scheduledJob{
+		Job: JobFunc(func(ctx context.Context) error {
+			runs++
+			return nil
+		}),
+		name:     "testRunsJobOncePerInterval",
+		interval: 500 * time.Millisecond,
+		lockKey:  jobLockKey("testRunsJobOncePerInterval"),
+	}
+	// start right after an interval begins, so both rounds fit in one
+	time.Sleep(j.interval - time.Duration(time.Now().UnixNano())%j.interval)
+	for i := 0; i < 2; i++ {
+		w.run(context.Background(), j)
+		other.run(context.Background(), j)
+	}
+	if runs != 1 {
+		t.Errorf("Expected job to run once in an interval. Got %d runs", runs)
+	}
+	time.Sleep(j.interval)
+	other.run(context.Background(), j)
+	w.run(context.Background(), j)
+	if runs != 2 {
+		t.Errorf("Expected job to run once in the next interval. Got %d runs", runs)
+	}
+}
+
+func TestWorkerSkipsJobLockedByAnotherWorker(t *testing.T) {
+	cleanupJobRuns(t)
+	w := getWorker(t)
+	other := getWorker(t)
+	runs := 0
+	j := &scheduledJob{
+		Job: JobFunc(func(ctx context.Context) error {
+			runs++
+			return nil
+		}),
+		name:     "testSkipsJob",
+		interval: time.Hour,
+		lockKey:  jobLockKey("testSkipsJob"),
+	}
+	errLocked := errors.New("locked")
+	err := other.repo.WithPGTx(
+		context.Background(), func(repo *repositories.All) error {
+			acquired, err := repo.Locks.TryXact(j.lockKey)
+			if err != nil {
+				return err
+			}
+			if !acquired {
+				t.Fatal("Expected other worker to acquire lock")
+			}
+			w.run(context.Background(), j)
+			return errLocked
+		},
+	)
+	if err != errLocked {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if runs != 0 {
+		t.Errorf("Expected job not to run while locked. Got %d runs", runs)
+	}
+	w.run(context.Background(), j)
+	if runs != 1 {
+		t.Errorf("Expected job to run after lock release. Got %d runs", runs)
+	}
+}
+
+func TestWorkerRecoversJobPanic(t *testing.T) {
+	w := getWorker(t)
+	j := &scheduledJob{
+		Job: JobFunc(func(ctx context.Context) error {
+			panic("boom")
+		}),
+		name:     "testPanics",
+		interval: time.Hour,
+		lockKey:  jobLockKey("testPanics"),
+	}
+	w.run(context.Background(), j)
+}